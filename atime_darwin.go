@@ -0,0 +1,18 @@
+//go:build darwin
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns when the file of info was last read, or its
+// modification time if the file system does not tell.
+func accessTime(info os.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Atimespec.Unix())
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime returns the modification time of the file of info, as the access
+// time is not read on this platform.
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns when the file of info was last read, or its
+// modification time if the file system does not tell. NTFS updates it
+// lazily, within an hour, if at all.
+func accessTime(info os.FileInfo) time.Time {
+	if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, data.LastAccessTime.Nanoseconds())
+	}
+	return info.ModTime()
+}
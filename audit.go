@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// auditFile is the name of the audit log in the state directory.
+const auditFile = "audit.jsonl"
+
+// auditEntry is one action recorded in the audit log.
+type auditEntry struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	Host   string    `json:"host,omitempty"`
+	Action string    `json:"action"` // move, delete, restore, purge, plugin:NAME or exec-per-group
+	Hash   string    `json:"hash,omitempty"`
+	Path   string    `json:"path"`
+	To     string    `json:"to,omitempty"`   // destination of a move
+	Keep   string    `json:"keep,omitempty"` // the copy kept of a moved or deleted file
+	Size   int64     `json:"size"`
+	Result string    `json:"result"` // ok or error
+	Error  string    `json:"error,omitempty"`
+	Prev   string    `json:"prev"` // lineHash of the previous entry, "" for the first
+}
+
+// auditLog appends every executed action to a JSONL file, whatever is
+// printed on the console, so there is a permanent record of what the tool
+// changed. The file is only ever appended to, and every entry is synced to
+// disk before the next action runs. Each entry holds the hash of the one
+// before it, so verify-log can tell if entries were edited or removed.
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+	user string
+	host string
+}
+
+// audit is the audit log of this run; nil records nothing.
+var audit *auditLog
+
+// openAuditLog returns the audit log at path, or at auditFile in the state
+// directory if path is empty. The file is created by the first action.
+func openAuditLog(path string) (*auditLog, error) {
+	if path == "" {
+		dir, err := stateDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, auditFile)
+	}
+	a := &auditLog{path: path, user: os.Getenv("USER")}
+	if u, err := user.Current(); err == nil {
+		a.user = u.Username
+	}
+	a.host, _ = os.Hostname()
+	return a, nil
+}
+
+// record appends an action and its result, err, to the log. A log that
+// cannot be written is reported, but does not stop the action.
+func (a *auditLog) record(entry auditEntry, err error) {
+	if a == nil {
+		return
+	}
+	entry.Time = time.Now().UTC()
+	entry.User, entry.Host = a.user, a.host
+	entry.Result = "ok"
+	if err != nil {
+		entry.Result, entry.Error = "error", err.Error()
+	}
+	if err := a.append(entry); err != nil {
+		log.Printf("Error writing the audit log %s: %v", a.path, err)
+	}
+}
+
+// auditLockTimeout is how long append waits for another run to finish
+// writing to the same audit log.
+var auditLockTimeout = 30 * time.Second
+
+// lock takes an exclusive lock on a file next to the log, so that runs
+// sharing the state directory do not chain two entries to the same one.
+// The log itself is not locked, since a lock on Windows would keep it from
+// being written.
+func (a *auditLog) lock() (unlock func(), err error) {
+	path := a.path + ".lock"
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	for deadline := time.Now().Add(auditLockTimeout); ; time.Sleep(10 * time.Millisecond) {
+		unlock, err := lockFile(path)
+		if err != errFileLocked {
+			return unlock, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+}
+
+func (a *auditLog) append(entry auditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return err
+	}
+	unlock, err := a.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	last, err := lastLine(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if len(last) > 0 {
+		entry.Prev = lineHash(last)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "state", auditFile)
+	a, err := openAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.record(auditEntry{Action: "move", Hash: "h", Path: "/a", To: "/b", Size: 3}, nil)
+	a.record(auditEntry{Action: "delete", Path: "/c"}, errors.New("permission denied"))
+	var none *auditLog
+	none.record(auditEntry{Action: "delete", Path: "/d"}, nil)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, Got: %+v", entries)
+	}
+	if e := entries[0]; e.Action != "move" || e.To != "/b" || e.Result != "ok" || e.Time.IsZero() || e.User != a.user {
+		t.Errorf("Unexpected first entry: %+v", e)
+	}
+	if e := entries[1]; e.Result != "error" || e.Error != "permission denied" {
+		t.Errorf("Unexpected second entry: %+v", e)
+	}
+}
+
+func TestMoveFilesAudit(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	var files []File
+	for _, name := range []string{"keep", "dup"} {
+		path := filepath.Join(tempDir, name)
+		ioutil.WriteFile(path, []byte("content"), 0644)
+		info, _ := os.Stat(path)
+		files = append(files, File{path: indexPath(path), Hash: "hash", Size: 7, ModTime: info.ModTime()})
+	}
+	archive := filepath.Join(tempDir, "archive")
+	os.Mkdir(archive, 0755)
+
+	path := filepath.Join(tempDir, auditFile)
+	audit, _ = openAuditLog(path)
+	defer func() { audit = nil }()
+	moveFiles(map[string][]File{"hash": files}, archive)
+
+	data, _ := ioutil.ReadFile(path)
+	var entry auditEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Action != "move" || entry.Path != files[1].Path() || entry.To != filepath.Join(archive, "dup") || entry.Hash != "hash" || entry.Result != "ok" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+}
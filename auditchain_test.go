@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLastLine(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	long := strings.Repeat("x", 10000)
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"", ""},
+		{"one\n", "one"},
+		{"one\ntwo\n", "two"},
+		{"one\n" + long + "\n", long},
+		{long + "\nlast", "last"},
+	}
+	path := filepath.Join(tempDir, "log")
+	for _, test := range tests {
+		ioutil.WriteFile(path, []byte(test.content), 0644)
+		f, _ := os.Open(path)
+		got, err := lastLine(f)
+		f.Close()
+		if err != nil || string(got) != test.expected {
+			t.Errorf("Expected: %.20s, Got: %.20s (%v)", test.expected, got, err)
+		}
+	}
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, auditFile)
+	// An entry written before the log was chained.
+	ioutil.WriteFile(path, []byte(`{"action":"delete","path":"/old","result":"ok"}`+"\n"), 0600)
+	a, _ := openAuditLog(path)
+	for _, name := range []string{"/a", "/b", "/c"} {
+		a.record(auditEntry{Action: "delete", Path: name}, nil)
+	}
+	data, _ := ioutil.ReadFile(path)
+	lines := bytes.SplitAfter(data, []byte("\n"))[:4]
+
+	v, err := verifyAuditLog(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Entries != 3 || v.Unchained != 1 || v.Broken != 0 || v.Last != lineHash(bytes.TrimSuffix(lines[3], []byte("\n"))) {
+		t.Errorf("Unexpected verification: %+v", v)
+	}
+
+	tests := []struct {
+		name   string
+		log    [][]byte
+		broken int
+	}{
+		{"edited", [][]byte{lines[0], lines[1], bytes.Replace(lines[2], []byte("/b"), []byte("/x"), 1), lines[3]}, 4},
+		{"removed", [][]byte{lines[0], lines[1], lines[3]}, 3},
+		{"unchained inserted", [][]byte{lines[0], lines[1], lines[0], lines[2]}, 3},
+		{"edited unchained", [][]byte{bytes.Replace(lines[0], []byte("/old"), []byte("/x"), 1), lines[1]}, 2},
+		{"truncated", [][]byte{lines[0], lines[1], lines[2][:10]}, 3},
+	}
+	for _, test := range tests {
+		v, err := verifyAuditLog(bytes.NewReader(bytes.Join(test.log, nil)))
+		if err != nil || v.Broken != test.broken {
+			t.Errorf("%s: Expected the chain to break at line %d, Got: %+v (%v)", test.name, test.broken, v, err)
+		}
+	}
+}
+
+func TestAuditLogSharedByRuns(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, auditFile)
+	// Two logs on the same file stand for two runs sharing the state directory.
+	var wg sync.WaitGroup
+	for run := 0; run < 4; run++ {
+		a, _ := openAuditLog(path)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				a.record(auditEntry{Action: "delete", Path: "/a"}, nil)
+			}
+		}()
+	}
+	wg.Wait()
+	data, _ := ioutil.ReadFile(path)
+	v, err := verifyAuditLog(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Entries != 200 || v.Broken != 0 {
+		t.Errorf("Expected an intact chain of 200 entries, Got: %+v", v)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// includeBackups, set by --include-backups, also scans the folders of backup
+// tools, whose files must never be deduplicated by hand: they are shared
+// between the snapshots, or part of a repository that the tool checks.
+var includeBackups bool
+
+// backupDirNames are the names of the folders that hold the snapshots of a
+// backup tool or file system.
+var backupDirNames = map[string]string{
+	"Backups.backupdb": "Time Machine backup",
+	".snapshot":        "file system snapshot folder",
+}
+
+// backupStore returns what kind of backup store the folder dir is, or "" if
+// it is none.
+func backupStore(dir string) string {
+	if kind := backupDirNames[filepath.Base(dir)]; kind != "" {
+		return kind
+	}
+	if info, err := os.Stat(filepath.Join(dir, "config")); err != nil || info.IsDir() {
+		return "" // restic and borg repositories both have a config file
+	}
+	if readme, err := ioutil.ReadFile(filepath.Join(dir, "README")); err == nil && bytes.HasPrefix(readme, []byte("This is a Borg Backup repository")) {
+		return "borg repository"
+	}
+	for _, sub := range []string{"data", "index", "keys", "snapshots"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			return ""
+		}
+	}
+	return "restic repository"
+}
+
+// backupStoreAbove returns the backup store that path is in or is, and what
+// kind it is.
+func backupStoreAbove(path string) (store, kind string) {
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if kind := backupStore(dir); kind != "" {
+			return dir, kind
+		}
+		if dir == filepath.Dir(dir) {
+			return "", ""
+		}
+	}
+}
+
+// warnedBackups are the backup stores a warning was logged for, so that the
+// walks of several passes log it once.
+var warnedBackups = struct {
+	sync.Mutex
+	dirs map[string]bool
+}{dirs: make(map[string]bool)}
+
+// skipBackupStore reports whether the walk leaves out the folder dir since it
+// is a backup store, logging why. With --include-backups, it warns instead.
+func skipBackupStore(dir string) bool {
+	kind := backupStore(dir)
+	if kind == "" {
+		return false
+	}
+	warnedBackups.Lock()
+	warned := warnedBackups.dirs[dir]
+	warnedBackups.dirs[dir] = true
+	warnedBackups.Unlock()
+	if !warned {
+		warnBackupStore(dir, kind)
+	}
+	return !includeBackups
+}
+
+// warnBackupStore logs that the backup store dir of kind is skipped or, with
+// --include-backups, scanned.
+func warnBackupStore(dir, kind string) {
+	if includeBackups {
+		log.Printf("WARNING: scanning %s, a %s. Moving, deleting or linking its files can corrupt the backups; use the backup tool to prune them.", dir, kind)
+	} else {
+		log.Printf("Skipping %s, a %s; pass --include-backups to scan it anyway.", dir, kind)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestBackupStore(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	for _, name := range []string{
+		"restic/config",
+		"restic/data/00/0011",
+		"restic/index/aa",
+		"restic/keys/bb",
+		"restic/snapshots/cc",
+		"borg/config",
+		"borg/data/0/1",
+		"app/config",
+		"app/data/settings.json",
+	} {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(name), 0644)
+	}
+	ioutil.WriteFile(filepath.Join(tempDir, "borg", "README"), []byte("This is a Borg Backup repository.\nSee https://borgbackup.readthedocs.io/\n"), 0644)
+
+	for _, tc := range []struct {
+		dir      string
+		expected string
+	}{
+		{"restic", "restic repository"},
+		{"borg", "borg repository"},
+		{"app", ""},
+		{"Volumes/TM/Backups.backupdb", "Time Machine backup"},
+		{"home/.snapshot", "file system snapshot folder"},
+		{"home", ""},
+	} {
+		if got := backupStore(filepath.Join(tempDir, filepath.FromSlash(tc.dir))); got != tc.expected {
+			t.Errorf("%s: Expected: %q, Got: %q", tc.dir, tc.expected, got)
+		}
+	}
+
+	store, kind := backupStoreAbove(filepath.Join(tempDir, "restic", "data", "00"))
+	if store != filepath.Join(tempDir, "restic") || kind != "restic repository" {
+		t.Errorf("Expected the restic repository, Got: %s (%s)", store, kind)
+	}
+	if store, _ := backupStoreAbove(filepath.Join(tempDir, "app", "data")); store != "" {
+		t.Errorf("Expected no backup store, Got: %s", store)
+	}
+}
+
+func TestBackupStoresSkipped(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	defer func() { includeBackups = false }()
+	for _, name := range []string{
+		"photos/a.jpg",
+		"home/.snapshot/hourly.0/a.jpg",
+		"TM/Backups.backupdb/mac/latest/a.jpg",
+	} {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte("photo"), 0644)
+	}
+	scanned := func() string {
+		fileMap, _, err := scanFolder(tempDir, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, files := range fileMap {
+			for _, file := range files {
+				rel, _ := filepath.Rel(tempDir, file.Path())
+				names = append(names, filepath.ToSlash(rel))
+			}
+		}
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+	if got := scanned(); got != "photos/a.jpg" {
+		t.Errorf("Expected: photos/a.jpg, Got: %s", got)
+	}
+	includeBackups = true
+	expected := "TM/Backups.backupdb/mac/latest/a.jpg,home/.snapshot/hourly.0/a.jpg,photos/a.jpg"
+	if got := scanned(); got != expected {
+		t.Errorf("Expected: %s, Got: %s", expected, got)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strconv"
+)
+
+// byteCompare makes the scan confirm every duplicate byte by byte
+// (--byte-compare), not only those found with a fast hash.
+var byteCompare bool
+
+// confirmFileMap confirms that the files of each group found with a fast,
+// non-cryptographic hash (or of every group with --byte-compare) are
+// identical, by a cryptographic digest computed in the same pass if the
+// files have one and by comparing their bytes otherwise. Files that differ
+// from the first of their group form groups of their own with a "-N" suffix;
+// files that cannot be read are left out. Groups with a sampled hash are
+// verified when an action is applied to them instead.
+func confirmFileMap(fileMap map[string][]File) map[string][]File {
+	confirmed := make(map[string][]File, len(fileMap))
+	for key, files := range fileMap {
+		if len(files) < 2 || isSampled(key) || !(byteCompare || hashAlgorithmOf(key).fast) {
+			confirmed[key] = files
+			continue
+		}
+		for i, sub := range sameContentGroups(files) {
+			subKey := key
+			if i > 0 {
+				subKey = key + "-" + strconv.Itoa(i+1)
+				log.Printf("%s has the same hash as %s but different content", sub[0].Path(), files[0].Path())
+			}
+			confirmed[subKey] = sub
+		}
+	}
+	return confirmed
+}
+
+// sameContentGroups splits files by content, in the order of their first
+// file.
+func sameContentGroups(files []File) [][]File {
+	var groups [][]File
+next:
+	for _, file := range files {
+		for i, group := range groups {
+			same, err := sameContent(group[0], file)
+			if err != nil {
+				log.Printf("Error comparing %s with %s: %v", file.Path(), group[0].Path(), err)
+				continue next
+			}
+			if same {
+				groups[i] = append(group, file)
+				continue next
+			}
+		}
+		groups = append(groups, []File{file})
+	}
+	return groups
+}
+
+// sameContent reports whether two files of the same size have the same
+// content.
+func sameContent(a, b File) (bool, error) {
+	for name, digest := range a.Digests {
+		algorithm, err := findHashAlgorithm(name)
+		if other, ok := b.Digests[name]; ok && err == nil && !algorithm.fast {
+			return digest == other, nil
+		}
+	}
+	fa, err := os.Open(a.Path())
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b.Path())
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA, bufB := make([]byte, 64*1024), make([]byte, 64*1024)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == io.EOF || errB == io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil && errB != io.EOF && errB != io.ErrUnexpectedEOF {
+			return false, errB
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfirmFileMap(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	write := func(name, content string) File {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(content), 0644)
+		return File{path: indexPath(path), Size: int64(len(content))}
+	}
+	a, b, c := write("a", "same"), write("b", "same"), write("c", "diff")
+	d, e := write("d", "same"), write("e", "diff")
+	missing := File{path: indexPath(filepath.Join(dir, "missing")), Size: 4}
+
+	// The fast hash collides; the MD5 group is trusted unless --byte-compare.
+	fileMap := map[string][]File{
+		"xxh64:00000000000000ff": {a, c, b, missing, e},
+		"0123456789abcdef":       {d, e},
+	}
+	confirmed := confirmFileMap(fileMap)
+	expected := map[string][]string{
+		"xxh64:00000000000000ff":   {a.Path(), b.Path()},
+		"xxh64:00000000000000ff-2": {c.Path(), e.Path()},
+		"0123456789abcdef":         {d.Path(), e.Path()},
+	}
+	if len(confirmed) != len(expected) {
+		t.Fatalf("Expected %d groups, Got: %v", len(expected), confirmed)
+	}
+	for key, paths := range expected {
+		files := confirmed[key]
+		if len(files) != len(paths) {
+			t.Errorf("Expected: %v, Got: %v (%s)", paths, files, key)
+			continue
+		}
+		for i, path := range paths {
+			if files[i].Path() != path {
+				t.Errorf("Expected: %s, Got: %s (%s)", path, files[i].Path(), key)
+			}
+		}
+	}
+
+	byteCompare = true
+	defer func() { byteCompare = false }()
+	confirmed = confirmFileMap(map[string][]File{"0123456789abcdef": {d, e}})
+	if len(confirmed["0123456789abcdef"]) != 1 || len(confirmed["0123456789abcdef-2"]) != 1 {
+		t.Errorf("Expected the group to be split with --byte-compare, Got: %v", confirmed)
+	}
+}
+
+func TestSameContentDigests(t *testing.T) {
+	// Cryptographic digests decide without reading the files; fast ones do not.
+	a := File{path: indexPath("missing-a"), Digests: map[string]string{"sha256": "aa"}}
+	b := File{path: indexPath("missing-b"), Digests: map[string]string{"sha256": "aa"}}
+	if same, err := sameContent(a, b); !same || err != nil {
+		t.Errorf("Expected the same content, Got: %v (%v)", same, err)
+	}
+	b.Digests = map[string]string{"sha256": "bb"}
+	if same, err := sameContent(a, b); same || err != nil {
+		t.Errorf("Expected different content, Got: %v (%v)", same, err)
+	}
+	a.Digests, b.Digests = map[string]string{"xxh64": "aa"}, map[string]string{"xxh64": "aa"}
+	if _, err := sameContent(a, b); err == nil {
+		t.Errorf("Expected the files to be read for a fast digest")
+	}
+}
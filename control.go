@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// The control socket accepts one command per line (status, pause, resume or
+// cancel) and answers each with a controlReply encoded as a JSON line.
+var controlCommands = []string{"status", "pause", "resume", "cancel"}
+
+// controlReply is the state of the scan after a control command.
+type controlReply struct {
+	scanProgress
+	Phase            string         `json:"phase"`
+	Paused           bool           `json:"paused"`
+	Canceled         bool           `json:"canceled"`
+	ElapsedSeconds   float64        `json:"elapsed_seconds"`
+	HashingSeconds   float64        `json:"hashing_seconds"`
+	BytesPerSecond   int64          `json:"bytes_per_second"`
+	RemainingSeconds float64        `json:"remaining_seconds,omitempty"` // with --pre-walk
+	LargeFiles       []fileProgress `json:"large_files,omitempty"`       // large files being hashed
+	Error            string         `json:"error,omitempty"`
+}
+
+// handleControl executes a control command on control.
+func handleControl(command string, control *scanControl) controlReply {
+	var reply controlReply
+	switch command {
+	case "status":
+	case "pause":
+		control.pause()
+	case "resume":
+		control.resume()
+	case "cancel":
+		control.cancel()
+	default:
+		reply.Error = fmt.Sprintf("unknown command %q (expected one of %s)", command, strings.Join(controlCommands, ", "))
+	}
+	s := control.status()
+	reply.scanProgress = s.scanProgress
+	reply.Phase, reply.Paused, reply.Canceled = s.Phase, s.Paused, s.Canceled
+	reply.ElapsedSeconds, reply.HashingSeconds = s.Elapsed.Seconds(), s.Hashing.Seconds()
+	reply.BytesPerSecond = s.rate()
+	if _, remaining, ok := estimate(s.scanProgress, s.Hashing); ok {
+		reply.RemainingSeconds = remaining.Seconds()
+	}
+	reply.LargeFiles = s.Large
+	return reply
+}
+
+// listenControl creates the control socket at path. A socket left behind by
+// a run that crashed is replaced, one that still accepts connections is not.
+func listenControl(path string) (net.Listener, error) {
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("control socket %s is in use by another run", path)
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	os.Chmod(path, 0600)
+	return l, nil
+}
+
+// serveControl answers control commands on l until l is closed.
+func serveControl(l net.Listener, control *scanControl) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			encoder := json.NewEncoder(conn)
+			for scanner.Scan() {
+				if command := strings.TrimSpace(scanner.Text()); command != "" {
+					encoder.Encode(handleControl(command, control))
+				}
+			}
+		}()
+	}
+}
+
+// startControlSocket serves control commands for control on a Unix socket
+// at path, which Windows 10 and later support as well. It returns a function
+// that closes and removes the socket.
+func startControlSocket(path string, control *scanControl) (stop func(), err error) {
+	l, err := listenControl(path)
+	if err != nil {
+		return nil, err
+	}
+	go serveControl(l, control)
+	return func() {
+		l.Close()
+		os.Remove(path)
+	}, nil
+}
+
+// sendControl sends a control command to the socket at path and returns the
+// reply.
+func sendControl(path, command string) (controlReply, error) {
+	var reply controlReply
+	conn, err := net.DialTimeout("unix", path, 5*time.Second)
+	if err != nil {
+		return reply, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return reply, err
+	}
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+		return reply, err
+	}
+	if err := json.Unmarshal(line, &reply); err != nil {
+		return reply, err
+	}
+	if reply.Error != "" {
+		return reply, errors.New(reply.Error)
+	}
+	return reply, nil
+}
+
+// runControl implements the control command, which sends a command to the
+// control socket of a running scan.
+func runControl(args []string) {
+	fs := flag.NewFlagSet("control", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "print the reply as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s control [flags] socket %s\n", os.Args[0], strings.Join(controlCommands, "|"))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	reply, err := sendControl(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	if *jsonOutput {
+		json.NewEncoder(os.Stdout).Encode(reply)
+		return
+	}
+	writeScanStatus(os.Stdout, scanStatus{
+		scanProgress: reply.scanProgress,
+		Phase:        reply.Phase,
+		Paused:       reply.Paused,
+		Canceled:     reply.Canceled,
+		Elapsed:      time.Duration(reply.ElapsedSeconds * float64(time.Second)),
+		Hashing:      time.Duration(reply.HashingSeconds * float64(time.Second)),
+		Large:        reply.LargeFiles,
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandleControl(t *testing.T) {
+	control := newScanControl()
+	control.update(scanProgress{Files: 5, Scanned: 2})
+
+	if reply := handleControl("pause", control); !reply.Paused || reply.Scanned != 2 || reply.Files != 5 {
+		t.Errorf("Unexpected reply to pause: %+v", reply)
+	}
+	if reply := handleControl("resume", control); reply.Paused {
+		t.Errorf("Unexpected reply to resume: %+v", reply)
+	}
+	if reply := handleControl("shred", control); reply.Error != `unknown command "shred" (expected one of status, pause, resume, cancel)` {
+		t.Errorf("Unexpected reply to an unknown command: %+v", reply)
+	}
+	if reply := handleControl("cancel", control); !reply.Canceled || !control.isCanceled() {
+		t.Errorf("Unexpected reply to cancel: %+v", reply)
+	}
+}
+
+func TestControlSocket(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "control.sock")
+
+	control := newScanControl()
+	stop, err := startControlSocket(path, control)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := startControlSocket(path, control); err == nil {
+		t.Errorf("Expected a second socket at %s to fail", path)
+	}
+
+	if reply, err := sendControl(path, "pause"); err != nil || !reply.Paused {
+		t.Errorf("Expected a paused scan, Got: %+v (%v)", reply, err)
+	}
+	if reply, err := sendControl(path, "status"); err != nil || !reply.Paused || reply.ElapsedSeconds <= 0 {
+		t.Errorf("Expected the status of a paused scan, Got: %+v (%v)", reply, err)
+	}
+	if _, err := sendControl(path, "bogus"); err == nil {
+		t.Errorf("Expected an error for an unknown command")
+	}
+
+	stop()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed", path)
+	}
+	if _, err := sendControl(path, "status"); err == nil {
+		t.Errorf("Expected the closed socket to refuse commands")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// copyOriginal returns the path of the file that the file at path is named
+// as a copy of, e.g. "report.pdf" for "report (1).pdf", "report copy.pdf"
+// or "Copy of report.pdf"; ok is false for names that do not look like a
+// copy.
+func copyOriginal(path string) (original string, ok bool) {
+	dir, name := filepath.Split(path)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if loc := copyNamePattern.FindStringIndex(stem); loc != nil {
+		stem = strings.TrimRight(stem[:loc[0]], " -") + stem[loc[1]:] // also the dash of "report - Copy"
+		name = stem + ext
+	} else if loc := copyNamePattern.FindStringIndex(name); loc != nil {
+		name = name[:loc[0]] + name[loc[1]:] // e.g. "report.pdf.bak"
+	} else {
+		return "", false
+	}
+	if strings.TrimSuffix(name, ext) == "" {
+		return "", false
+	}
+	return dir + name, true
+}
+
+// prioritizeCopies moves the files named as copies and the files they are
+// named after to the front of paths, keeping the order otherwise, so that
+// the most likely duplicates are hashed first.
+func prioritizeCopies(paths []string) []string {
+	likely := make(map[string]bool)
+	for _, path := range paths {
+		if original, ok := copyOriginal(path); ok {
+			likely[path] = true
+			likely[original] = true
+		}
+	}
+	if len(likely) == 0 {
+		return paths
+	}
+	sorted := append([]string(nil), paths...)
+	sort.SliceStable(sorted, func(i, j int) bool { return likely[sorted[i]] && !likely[sorted[j]] })
+	return sorted
+}
+
+// hasCopyName reports whether a file of group is named as a copy.
+func (g Group) hasCopyName() bool {
+	for _, file := range g.Files {
+		if isCopyName(file.Path()) {
+			return true
+		}
+	}
+	return false
+}
+
+// copiesFirst moves the groups with a file named as a copy to the front of
+// groups, the ones a user recognizes as duplicates at once.
+func copiesFirst(groups []Group) []Group {
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].hasCopyName() && !groups[j].hasCopyName() })
+	return groups
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyOriginal(t *testing.T) {
+	tests := []struct {
+		path, expected string
+	}{
+		{"/a/report (1).pdf", "/a/report.pdf"},
+		{"/a/report copy.pdf", "/a/report.pdf"},
+		{"/a/report copy 2.pdf", "/a/report.pdf"},
+		{"/a/report - Copy.pdf", "/a/report.pdf"},
+		{"/a/Copy of report.pdf", "/a/report.pdf"},
+		{"/a/report.pdf.bak", "/a/report.pdf"},
+		{"/a/notes~", "/a/notes"},
+		{"/a/report.pdf", ""},
+		{"/a/copy.pdf", ""},
+		{"/a/.bak", ""},
+	}
+	for _, test := range tests {
+		got, ok := copyOriginal(test.path)
+		if ok != (test.expected != "") || got != test.expected {
+			t.Errorf("%s: Expected: %q, Got: %q (%v)", test.path, test.expected, got, ok)
+		}
+	}
+}
+
+func TestPrioritizeCopies(t *testing.T) {
+	paths := []string{"a.txt", "b.txt", "report.pdf", "c.txt", "report (1).pdf"}
+	if got := strings.Join(prioritizeCopies(paths), " "); got != "report.pdf report (1).pdf a.txt b.txt c.txt" {
+		t.Errorf("Unexpected order: %s", got)
+	}
+}
+
+func TestKeepOriginalName(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	copied := filepath.Join(tempDir, "a (1).txt")
+	original := filepath.Join(tempDir, "a.txt")
+	longer := filepath.Join(tempDir, "a_copy.txt")
+	files := []File{{path: indexPath(copied)}, {path: indexPath(original)}, {path: indexPath(longer)}}
+	for _, keep := range []string{"first", "shortest-path", "longest-path"} {
+		policy, _ := newKeepPolicy(keep, nil)
+		if best := policy.best(files); files[best].Path() != original {
+			t.Errorf("%s: Expected: %s, Got: %s", keep, original, files[best].Path())
+		}
+	}
+	policy, _ := newKeepPolicy("first", nil)
+	if best := policy.best([]File{{path: indexPath(copied)}, {path: indexPath(longer)}}); best != 0 {
+		t.Errorf("Expected the first copy when all names are copies, Got: %d", best)
+	}
+}
+
+func TestCopiesListedFirst(t *testing.T) {
+	fileMap := map[string][]File{
+		"a": {{path: indexPath("/x/a")}, {path: indexPath("/y/a")}},
+		"b": {{path: indexPath("/x/b")}, {path: indexPath("/x/b (1)")}},
+	}
+	var out bytes.Buffer
+	writeGroups(&out, fileMap)
+	if i, j := strings.Index(out.String(), "/x/b (1)"), strings.Index(out.String(), "/y/a"); i < 0 || j < 0 || i > j {
+		t.Errorf("Expected the group with a copy first, Got:\n%s", out.String())
+	}
+}
+
+func TestScanHashesOriginalsWithCopies(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	for name, content := range map[string]string{
+		"report (1).pdf": "same",
+		"report.pdf":     "same",
+		"photo copy.jpg": "other",
+		"photo.jpg":      "different size",
+	} {
+		ioutil.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+	fileMap, progress, err := scanFolder(tempDir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Scanned != 4 || progress.Files != 4 {
+		t.Errorf("Expected: 4 files hashed once, Got: %d of %d", progress.Scanned, progress.Files)
+	}
+	if groups := duplicateGroups(fileMap); len(groups) != 1 || len(groups[0].Files) != 2 {
+		t.Errorf("Expected one group of the report, Got: %v", groups)
+	}
+}
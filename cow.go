@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// reflinkMethods maps the file systems whose files can share data blocks to
+// how duplicates on them are consolidated: "dedupe" asks the kernel to share
+// the blocks of identical ranges (FIDEDUPERANGE), which it verifies itself,
+// and "clone" replaces a copy with a block clone of the kept file.
+var reflinkMethods = map[string]string{"btrfs": "dedupe", "xfs": "dedupe", "zfs": "clone"}
+
+// cowCandidate is a duplicate that could share the data of the kept copy of
+// its group on a copy-on-write file system.
+type cowCandidate struct {
+	Keep string
+	Dup  string
+	Size int64
+	FS   string
+}
+
+// cowCandidates returns the duplicates that are on the same copy-on-write
+// file system as the kept copy of their group and not already hard links to
+// it.
+func cowCandidates(groups []Group) []cowCandidate {
+	var candidates []cowCandidate
+	for _, group := range groups {
+		keep := group.Files[0].Path()
+		fs := fileSystemType(keep)
+		if reflinkMethods[fs] == "" {
+			continue
+		}
+		info, err := os.Stat(keep)
+		if err != nil {
+			log.Printf("Error getting file info for %s: %v", keep, err)
+			continue
+		}
+		keepID, _, ok := fileIdentity(keep, info)
+		if !ok {
+			continue
+		}
+		for _, file := range group.Files[1:] {
+			info, err := os.Stat(file.Path())
+			if err != nil {
+				log.Printf("Error getting file info for %s: %v", file.Path(), err)
+				continue
+			}
+			id, _, ok := fileIdentity(file.Path(), info)
+			if !ok || id.dev != keepID.dev || id == keepID {
+				continue
+			}
+			candidates = append(candidates, cowCandidate{Keep: keep, Dup: file.Path(), Size: info.Size(), FS: fs})
+		}
+	}
+	return candidates
+}
+
+// writeCowReport writes how much space sharing the data of the candidates
+// would save, per file system. It is an upper bound: copies may already
+// share some of their blocks.
+func writeCowReport(w io.Writer, candidates []cowCandidate) {
+	if len(candidates) == 0 {
+		fmt.Fprintln(w, msg("cow.none"))
+		return
+	}
+	files := make(map[string]int)
+	bytes := make(map[string]int64)
+	var total int64
+	for _, c := range candidates {
+		files[c.FS]++
+		bytes[c.FS] += c.Size
+		total += c.Size
+	}
+	var names []string
+	for fs := range files {
+		names = append(names, fs)
+	}
+	sort.Strings(names)
+	for _, fs := range names {
+		fmt.Fprintln(w, msg("cow.fs", fs, formatCount(int64(files[fs])), humanReadableSize(bytes[fs])))
+	}
+	fmt.Fprintln(w, msg("cow.total", formatCount(int64(len(candidates))), humanReadableSize(total)))
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeCowScript writes a shell script that consolidates the candidates:
+// xfs_io dedupe for btrfs and XFS, and a block-cloning copy that replaces
+// the duplicate for ZFS (OpenZFS 2.2 or later).
+func writeCowScript(w io.Writer, candidates []cowCandidate) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#!/bin/sh")
+	fmt.Fprintln(bw, "# Generated by duplicate_finder reflink. Every command shares the data of a")
+	fmt.Fprintln(bw, "# duplicate with the kept copy of its group; the files stay where they are.")
+	for _, c := range candidates {
+		fmt.Fprintf(bw, "\n# %s, %d bytes\n", c.FS, c.Size)
+		if reflinkMethods[c.FS] == "dedupe" {
+			fmt.Fprintf(bw, "xfs_io -c %s %s\n", shellQuote(fmt.Sprintf("dedupe %s 0 0 %d", c.Keep, c.Size)), shellQuote(c.Dup))
+		} else {
+			tmp := shellQuote(c.Dup + ".reflink-tmp")
+			fmt.Fprintf(bw, "cp --reflink=always -p -- %s %s && mv -f -- %s %s\n", shellQuote(c.Keep), tmp, tmp, shellQuote(c.Dup))
+		}
+	}
+	return bw.Flush()
+}
+
+// applyDedupe shares the data of the candidates on file systems that
+// support FIDEDUPERANGE. Candidates that need a clone are only counted as
+// skipped.
+func applyDedupe(candidates []cowCandidate) (stats actionStats, skipped int) {
+	for _, c := range candidates {
+		if reflinkMethods[c.FS] != "dedupe" {
+			skipped++
+			continue
+		}
+		if err := checkWritable("share the data of", c.Dup); err != nil {
+			log.Printf("Error sharing the data of %s with %s: %v", c.Dup, c.Keep, err)
+			stats.Errors++
+			continue
+		}
+		deduped, err := dedupeFile(c.Keep, c.Dup, c.Size)
+		if err != nil {
+			log.Printf("Error sharing the data of %s with %s: %v", c.Dup, c.Keep, err)
+			stats.Errors++
+			continue
+		}
+		fmt.Println(msg("cow.done", c.Dup, c.Keep))
+		stats.Files++
+		stats.Bytes += deduped
+	}
+	return stats, skipped
+}
+
+// runReflink implements the reflink command, which estimates how much space
+// sharing data blocks on copy-on-write file systems would save, instead of
+// deleting duplicates, and optionally writes or performs the consolidation.
+func runReflink(args []string) {
+	fs := flag.NewFlagSet("reflink", flag.ExitOnError)
+	resultsPath := fs.String("results", "", "use a results file written by --save instead of scanning")
+	script := fs.String("script", "", "write a shell script with the consolidation commands to this file")
+	apply := fs.Bool("apply", false, "share the data of duplicates on btrfs and XFS right away (Linux only)")
+	fs.BoolVar(&readOnly, "read-only", false, "refuse to share the data of duplicates; only report")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s reflink [flags] (--results file | folder)\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if (*resultsPath == "") == (fs.NArg() == 0) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	var groups []Group
+	if *resultsPath != "" {
+		results, err := loadResults(*resultsPath)
+		if err != nil {
+			log.Fatalf("Error loading results from %s: %v", *resultsPath, err)
+		}
+		groups = results.Groups
+	} else {
+		fileMap, _, err := scanFolder(formatPath(fs.Arg(0)), nil, nil)
+		if err != nil {
+			log.Fatal("Error:", err)
+		}
+		groups = duplicateGroups(fileMap)
+	}
+
+	candidates := cowCandidates(groups)
+	writeCowReport(os.Stdout, candidates)
+	if *script != "" {
+		f, err := os.OpenFile(*script, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+		if err == nil {
+			err = writeCowScript(f, candidates)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			log.Fatalf("Error writing script to %s: %v", *script, err)
+		}
+	}
+	if *apply {
+		stats, skipped := applyDedupe(candidates)
+		fmt.Println(msg("cow.applied", formatCount(int64(stats.Files)), humanReadableSize(stats.Bytes), formatCount(int64(skipped)), formatCount(int64(stats.Errors))))
+		if stats.Errors > 0 {
+			os.Exit(1)
+		}
+	}
+}
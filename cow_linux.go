@@ -0,0 +1,88 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// fileSystemType returns the name of the file system path is on if it is
+// one of reflinkMethods, and "" otherwise.
+func fileSystemType(path string) string {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return ""
+	}
+	switch uint32(st.Type) {
+	case 0x9123683e:
+		return "btrfs"
+	case 0x58465342:
+		return "xfs"
+	case 0x2fc12fc1:
+		return "zfs"
+	}
+	return ""
+}
+
+// fideduperange is _IOWR(0x94, 54, struct file_dedupe_range).
+const fideduperange = 0xc0189436
+
+// fileDedupeRange is struct file_dedupe_range with a single destination.
+type fileDedupeRange struct {
+	srcOffset uint64
+	srcLength uint64
+	destCount uint16
+	reserved1 uint16
+	reserved2 uint32
+	// struct file_dedupe_range_info
+	destFd       int64
+	destOffset   uint64
+	bytesDeduped uint64
+	status       int32
+	reserved     uint32
+}
+
+// dedupeChunk is the length deduplicated per call; btrfs handles at most
+// 16 MiB at a time.
+const dedupeChunk = 16 << 20
+
+// dedupeFile asks the kernel to share the blocks of dest with those of src.
+// The kernel compares the contents first and refuses ranges that differ. It
+// returns the number of bytes that now share their blocks.
+func dedupeFile(src, dest string, size int64) (int64, error) {
+	s, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+	d, err := os.OpenFile(dest, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer d.Close()
+
+	var done int64
+	for done < size {
+		length := size - done
+		if length > dedupeChunk {
+			length = dedupeChunk
+		}
+		arg := fileDedupeRange{srcOffset: uint64(done), srcLength: uint64(length), destCount: 1, destFd: int64(d.Fd()), destOffset: uint64(done)}
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, s.Fd(), fideduperange, uintptr(unsafe.Pointer(&arg))); errno != 0 {
+			return done, errno
+		}
+		switch {
+		case arg.status < 0:
+			return done, syscall.Errno(-arg.status)
+		case arg.status == 1:
+			return done, errors.New("content differs")
+		case arg.bytesDeduped == 0:
+			return done, errors.New("no data was shared")
+		}
+		done += int64(arg.bytesDeduped)
+	}
+	return done, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// fileSystemType returns "" on systems where copy-on-write file systems are
+// not detected.
+func fileSystemType(path string) string {
+	return ""
+}
+
+func dedupeFile(src, dest string, size int64) (int64, error) {
+	return 0, errors.New("sharing data between files is only supported on Linux")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"/data/a.jpg", "'/data/a.jpg'"},
+		{"/data/it's.jpg", `'/data/it'\''s.jpg'`},
+		{"", "''"},
+	}
+	for _, tc := range testCases {
+		if got := shellQuote(tc.input); got != tc.expected {
+			t.Errorf("Expected: %s, Got: %s", tc.expected, got)
+		}
+	}
+}
+
+func TestWriteCowScript(t *testing.T) {
+	candidates := []cowCandidate{
+		{Keep: "/pool/a", Dup: "/pool/b c", Size: 42, FS: "btrfs"},
+		{Keep: "/tank/a", Dup: "/tank/b", Size: 7, FS: "zfs"},
+	}
+	var out bytes.Buffer
+	if err := writeCowScript(&out, candidates); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"#!/bin/sh\n",
+		"\n# btrfs, 42 bytes\nxfs_io -c 'dedupe /pool/a 0 0 42' '/pool/b c'\n",
+		"\n# zfs, 7 bytes\ncp --reflink=always -p -- '/tank/a' '/tank/b.reflink-tmp' && mv -f -- '/tank/b.reflink-tmp' '/tank/b'\n",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Expected script to contain %q, Got: %s", s, out.String())
+		}
+	}
+}
+
+func TestWriteCowReport(t *testing.T) {
+	var out bytes.Buffer
+	writeCowReport(&out, []cowCandidate{
+		{Size: 1024, FS: "xfs"},
+		{Size: 2048, FS: "btrfs"},
+		{Size: 1024, FS: "btrfs"},
+	})
+	expected := "btrfs: 2 duplicates could share 3.00 KiB with their kept copy.\n" +
+		"xfs: 1 duplicates could share 1.00 KiB with their kept copy.\n" +
+		"Sharing the data of 3 duplicates would save up to 4.00 KiB.\n"
+	if out.String() != expected {
+		t.Errorf("Expected: %s, Got: %s", expected, out.String())
+	}
+
+	out.Reset()
+	writeCowReport(&out, nil)
+	if !strings.HasPrefix(out.String(), "No duplicates on a copy-on-write file system") {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+}
+
+func TestCowCandidates(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	keep := filepath.Join(tempDir, "keep")
+	dup := filepath.Join(tempDir, "dup")
+	link := filepath.Join(tempDir, "link")
+	ioutil.WriteFile(keep, []byte("same"), 0644)
+	ioutil.WriteFile(dup, []byte("same"), 0644)
+	linked := os.Link(keep, link) == nil
+	files := []File{{path: indexPath(keep), Size: 4}, {path: indexPath(dup), Size: 4}}
+	if linked {
+		files = append(files, File{path: indexPath(link), Size: 4})
+	}
+
+	candidates := cowCandidates([]Group{{ID: "g", Files: files}})
+	fs := fileSystemType(tempDir)
+	if reflinkMethods[fs] == "" {
+		if len(candidates) != 0 {
+			t.Errorf("Expected no candidates on %q, Got: %+v", fs, candidates)
+		}
+		return
+	}
+	// Hard links to the kept copy already share its data.
+	if len(candidates) != 1 || candidates[0] != (cowCandidate{Keep: keep, Dup: dup, Size: 4, FS: fs}) {
+		t.Errorf("Unexpected candidates: %+v", candidates)
+	}
+}
+
+func TestApplyDedupeSkipsClones(t *testing.T) {
+	stats, skipped := applyDedupe([]cowCandidate{{Keep: "/tank/a", Dup: "/tank/b", Size: 7, FS: "zfs"}})
+	if skipped != 1 || stats != (actionStats{}) {
+		t.Errorf("Expected the ZFS candidate to be skipped, Got: %+v, %d skipped", stats, skipped)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// deleteLimit caps how much a single run deletes (--max-delete-files and
+// --max-delete-bytes). Files beyond the limit are left in place for a later
+// run. A zero limit means no limit.
+type deleteLimit struct {
+	files        int
+	bytes        int64
+	deletedFiles int
+	deletedBytes int64
+}
+
+// runDeleteLimit is shared by all deletions of the run.
+var runDeleteLimit deleteLimit
+
+// parseDeleteLimit builds a limit from the values of the two flags.
+func parseDeleteLimit(files int, bytes string) (deleteLimit, error) {
+	l := deleteLimit{files: files}
+	if files < 0 {
+		return l, fmt.Errorf("invalid --max-delete-files %d", files)
+	}
+	if bytes != "" {
+		var err error
+		if l.bytes, err = parseSize(bytes); err != nil {
+			return l, fmt.Errorf("invalid --max-delete-bytes: %v", err)
+		}
+	}
+	return l, nil
+}
+
+// allows reports whether a file of size may still be deleted.
+func (l *deleteLimit) allows(size int64) bool {
+	return (l.files == 0 || l.deletedFiles < l.files) && (l.bytes == 0 || l.deletedBytes+size <= l.bytes)
+}
+
+// deleted counts a deleted file against the limit.
+func (l *deleteLimit) deleted(size int64) {
+	l.deletedFiles++
+	l.deletedBytes += size
+}
+
+// deferDelete skips deleting path because the limit is reached.
+func deferDelete(path string, stats *actionStats) {
+	if stats.Deferred == 0 {
+		log.Printf("Delete limit of this run reached, leaving %s and further files for a later run", path)
+	}
+	stats.Deferred++
+}
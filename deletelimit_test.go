@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDeleteLimit(t *testing.T) {
+	testCases := []struct {
+		files    int
+		bytes    string
+		expected string
+	}{
+		{0, "", ""},
+		{10, "1G", ""},
+		{-1, "", "invalid --max-delete-files -1"},
+		{0, "lots", `invalid --max-delete-bytes: invalid size "lots"`},
+	}
+	for _, tc := range testCases {
+		_, err := parseDeleteLimit(tc.files, tc.bytes)
+		if (err == nil && tc.expected != "") || (err != nil && err.Error() != tc.expected) {
+			t.Errorf("Expected: %q, Got: %v", tc.expected, err)
+		}
+	}
+}
+
+func TestDeleteLimitAllows(t *testing.T) {
+	l, _ := parseDeleteLimit(2, "100")
+	if !l.allows(100) || l.allows(101) {
+		t.Errorf("Unexpected byte limit")
+	}
+	l.deleted(60)
+	if !l.allows(40) || l.allows(41) {
+		t.Errorf("Unexpected byte limit after deleting 60 bytes")
+	}
+	l.deleted(10)
+	if l.allows(1) {
+		t.Errorf("Expected the file limit to be reached")
+	}
+	var unlimited deleteLimit
+	unlimited.deleted(1 << 40)
+	if !unlimited.allows(1 << 40) {
+		t.Errorf("Expected no limit")
+	}
+}
+
+func TestDeleteFilesWithLimit(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	defer func() { runDeleteLimit = deleteLimit{} }()
+
+	fileMap := make(map[string][]File)
+	var dups []string
+	for _, hash := range []string{"b", "a", "c"} {
+		for _, name := range []string{"keep", "dup"} {
+			path := filepath.Join(tempDir, hash+name)
+			if err := ioutil.WriteFile(path, []byte("1234"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			fileMap[hash] = append(fileMap[hash], File{path: indexPath(path), Hash: hash, Size: 4})
+		}
+		dups = append(dups, filepath.Join(tempDir, hash+"dup"))
+	}
+
+	runDeleteLimit, _ = parseDeleteLimit(0, "9")
+	if stats := deleteFiles(fileMap, true); stats != (actionStats{Files: 2, Bytes: 8, Deferred: 1}) {
+		t.Errorf("Unexpected delete stats: %+v", stats)
+	}
+	// Groups are deleted in the order of their keys.
+	for i, path := range []string{dups[1], dups[0], dups[2]} {
+		if _, err := os.Stat(path); os.IsNotExist(err) != (i < 2) {
+			t.Errorf("Unexpected state of %s: %v", path, err)
+		}
+	}
+
+	// The next run continues with the remaining file.
+	runDeleteLimit, _ = parseDeleteLimit(1, "")
+	if stats := deleteFiles(fileMap, true); stats.Files != 1 || stats.Deferred != 0 {
+		t.Errorf("Unexpected delete stats of the second run: %+v", stats)
+	}
+}
+
+func TestApplyPlanWithLimit(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	defer func() { runDeleteLimit = deleteLimit{} }()
+
+	var paths []string
+	for _, name := range []string{"keep", "dup1", "dup2"} {
+		path := filepath.Join(tempDir, name)
+		ioutil.WriteFile(path, []byte("1234"), 0644)
+		paths = append(paths, path)
+	}
+	hashed, _ := hashFile(paths[0])
+	group := planGroup{ID: "g", Hash: hashed.Hash, Keep: []planFile{{Path: paths[0], Size: 4, ModTime: modTime(paths[0])}}}
+	for _, path := range paths[1:] {
+		group.Actions = append(group.Actions, planAction{Action: "delete", Path: path, Size: 4, ModTime: modTime(path)})
+	}
+
+	runDeleteLimit, _ = parseDeleteLimit(1, "")
+	if stats := applyPlan(cleanupPlan{Groups: []planGroup{group}}, false); stats != (actionStats{Files: 1, Bytes: 4, Deferred: 1}) {
+		t.Errorf("Unexpected apply stats: %+v", stats)
+	}
+	if _, err := os.Stat(paths[2]); err != nil {
+		t.Errorf("Expected %s to be left for a later run: %v", paths[2], err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dependencyPreset names folders that package managers and build tools
+// regenerate, which hashing wastes time on since they are never worth
+// cleaning up by hand.
+type dependencyPreset struct {
+	name string
+	dirs []string
+	// markers are files, one of which must be next to the folder for it to
+	// count, for names that other folders have as well; none means any.
+	markers []string
+}
+
+var dependencyPresets = []dependencyPreset{
+	{"node", []string{"node_modules"}, nil},
+	{"python", []string{".venv", "__pycache__", ".tox"}, nil},
+	{"vendor", []string{"vendor"}, []string{"go.mod", "composer.json", "Gemfile"}},
+	{"target", []string{"target"}, []string{"Cargo.toml", "pom.xml"}},
+	{"build", []string{"build"}, []string{"package.json", "build.gradle", "build.gradle.kts", "CMakeLists.txt", "setup.py", "pyproject.toml"}},
+}
+
+// skippedDependencies are the presets whose folders the walk skips, set by
+// --dependency-presets and cleared by --include-dependencies.
+var skippedDependencies = allDependencyPresets()
+
+func allDependencyPresets() map[string]bool {
+	all := make(map[string]bool)
+	for _, preset := range dependencyPresets {
+		all[preset.name] = true
+	}
+	return all
+}
+
+// parseDependencyPresets parses a comma-separated list of preset names.
+func parseDependencyPresets(list string) (map[string]bool, error) {
+	presets := make(map[string]bool)
+	all := allDependencyPresets()
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !all[name] {
+			var names []string
+			for _, preset := range dependencyPresets {
+				names = append(names, preset.name)
+			}
+			return nil, fmt.Errorf("unknown preset %q (expected %s)", name, strings.Join(names, ", "))
+		}
+		presets[name] = true
+	}
+	return presets, nil
+}
+
+// isDependencyDir reports whether the folder dir is one of the skipped
+// presets.
+func isDependencyDir(dir string) bool {
+	name := filepath.Base(dir)
+	for _, preset := range dependencyPresets {
+		if !skippedDependencies[preset.name] {
+			continue
+		}
+		for _, d := range preset.dirs {
+			if d != name {
+				continue
+			}
+			if len(preset.markers) == 0 {
+				return true
+			}
+			for _, marker := range preset.markers {
+				if _, err := os.Stat(filepath.Join(filepath.Dir(dir), marker)); err == nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseDependencyPresets(t *testing.T) {
+	presets, err := parseDependencyPresets("node, python,")
+	if err != nil || len(presets) != 2 || !presets["node"] || !presets["python"] {
+		t.Errorf("Expected: node and python, Got: %v (%v)", presets, err)
+	}
+	if _, err := parseDependencyPresets("node,npm"); err == nil {
+		t.Error("Expected an error for an unknown preset")
+	}
+}
+
+func TestDependencyDirsSkipped(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	defer func() { skippedDependencies = allDependencyPresets() }()
+	for _, name := range []string{
+		"app/package.json",
+		"app/node_modules/left-pad/index.js",
+		"app/build/bundle.js",
+		"tool/__pycache__/main.cpython-311.pyc",
+		"tool/.venv/bin/python",
+		"photos/build/site.jpg",
+		"photos/target/shot.jpg",
+		"photos/vendor/invoice.pdf",
+	} {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(name), 0644)
+	}
+	scanned := func() string {
+		fileMap, _, err := scanFolder(tempDir, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, files := range fileMap {
+			for _, file := range files {
+				rel, _ := filepath.Rel(tempDir, file.Path())
+				names = append(names, filepath.ToSlash(rel))
+			}
+		}
+		sort.Strings(names)
+		return strings.Join(names, " ")
+	}
+
+	if got := scanned(); got != "app/package.json photos/build/site.jpg photos/target/shot.jpg photos/vendor/invoice.pdf" {
+		t.Errorf("Expected the dependency folders to be skipped, Got: %s", got)
+	}
+	skippedDependencies = map[string]bool{"python": true}
+	if got := scanned(); !strings.Contains(got, "app/node_modules/left-pad/index.js") || strings.Contains(got, ".venv") {
+		t.Errorf("Expected only the python folders to be skipped, Got: %s", got)
+	}
+	skippedDependencies = nil
+	if got := scanned(); len(strings.Fields(got)) != 8 {
+		t.Errorf("Expected every file to be scanned, Got: %s", got)
+	}
+}
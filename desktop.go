@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// desktopNotifyArgs returns the command that shows a native notification on
+// goos, or nil if the platform has no supported notifier.
+func desktopNotifyArgs(goos, title, body string) []string {
+	switch goos {
+	case "darwin":
+		quote := func(s string) string {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		}
+		return []string{"osascript", "-e", "display notification " + quote(body) + " with title " + quote(title)}
+	case "windows":
+		quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+		script := `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null;` +
+			`$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02);` +
+			`$text = $xml.GetElementsByTagName('text');` +
+			`$text.Item(0).AppendChild($xml.CreateTextNode(` + quote(title) + `)) | Out-Null;` +
+			`$text.Item(1).AppendChild($xml.CreateTextNode(` + quote(body) + `)) | Out-Null;` +
+			`[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('duplicate_finder').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+		return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", script}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []string{"notify-send", "--app-name=duplicate_finder", title, body}
+	}
+	return nil
+}
+
+// onInteractiveDesktop reports whether the tool runs in a terminal of a
+// graphical session, where a desktop notification reaches the user.
+func onInteractiveDesktop() bool {
+	if !isTerminal(os.Stdin) {
+		return false
+	}
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return true
+	}
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+// sendDesktopNotification shows a native notification. Missing notifier
+// commands are not an error worth reporting, so the result is ignored.
+func sendDesktopNotification(title, body string) {
+	args := desktopNotifyArgs(runtime.GOOS, title, body)
+	if args == nil {
+		return
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return
+	}
+	exec.Command(args[0], args[1:]...).Run()
+}
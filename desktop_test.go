@@ -0,0 +1,28 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDesktopNotifyArgs(t *testing.T) {
+	linux := desktopNotifyArgs("linux", "Scan finished", "3 groups")
+	if !reflect.DeepEqual(linux, []string{"notify-send", "--app-name=duplicate_finder", "Scan finished", "3 groups"}) {
+		t.Errorf("Unexpected linux command: %q", linux)
+	}
+
+	darwin := desktopNotifyArgs("darwin", "Scan finished", `say "hi"`)
+	if len(darwin) != 3 || darwin[2] != `display notification "say \"hi\"" with title "Scan finished"` {
+		t.Errorf("Unexpected macOS command: %q", darwin)
+	}
+
+	windows := desktopNotifyArgs("windows", "Scan finished", "it's done")
+	if len(windows) != 5 || !strings.Contains(windows[4], "'it''s done'") {
+		t.Errorf("Unexpected Windows command: %q", windows)
+	}
+
+	if args := desktopNotifyArgs("plan9", "a", "b"); args != nil {
+		t.Errorf("Expected no command, Got: %q", args)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// directIO makes hashing read large files with direct IO, bypassing the page
+// cache entirely (--direct-io, Linux only).
+var directIO bool
+
+const (
+	// directIOMinSize is the smallest file read with direct IO; smaller
+	// files are cheaper to read through the page cache.
+	directIOMinSize = 8 << 20
+	// directIOAlign is the alignment of direct IO buffers, which covers
+	// devices with 512 byte and 4 KiB logical blocks.
+	directIOAlign = 4096
+	// directIOBufferSize is the size of each direct read.
+	directIOBufferSize = 1 << 20
+)
+
+// directBuffers pools the aligned buffers of direct reads.
+var directBuffers = sync.Pool{New: func() interface{} {
+	b := make([]byte, directIOBufferSize+directIOAlign)
+	offset := 0
+	if rem := int(uintptr(unsafe.Pointer(&b[0])) % directIOAlign); rem != 0 {
+		offset = directIOAlign - rem
+	}
+	b = b[offset : offset+directIOBufferSize]
+	return &b
+}}
+
+// directReader reads a file opened by openDirect in aligned blocks. Past the
+// size the file had when it was opened, or if the file system rejects a
+// direct read, it switches the file back to buffered reads. The buffer goes
+// back to the pool once the reader returns an error, including io.EOF.
+type directReader struct {
+	file   *os.File
+	size   int64
+	offset int64
+	direct bool
+	buf    *[]byte
+	data   []byte
+	err    error
+}
+
+func newDirectReader(file *os.File) *directReader {
+	r := &directReader{file: file, direct: true, buf: directBuffers.Get().(*[]byte)}
+	if info, err := file.Stat(); err == nil {
+		r.size = info.Size()
+	}
+	return r
+}
+
+func (r *directReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		if r.err != nil {
+			if r.buf != nil {
+				directBuffers.Put(r.buf)
+				r.buf = nil
+			}
+			return 0, r.err
+		}
+		if r.direct && r.offset >= r.size {
+			r.buffered()
+		}
+		n, err := r.file.Read(*r.buf)
+		if r.direct && errors.Is(err, syscall.EINVAL) {
+			r.buffered()
+			continue
+		}
+		r.offset += int64(n)
+		r.data, r.err = (*r.buf)[:n], err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// buffered turns direct IO off for the rest of the file.
+func (r *directReader) buffered() {
+	r.direct = false
+	clearDirect(r.file)
+}
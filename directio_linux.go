@@ -0,0 +1,24 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const directIOSupported = true
+
+// openDirect opens path for reading with O_DIRECT. File systems without
+// direct IO, such as tmpfs, fail with EINVAL.
+func openDirect(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDONLY|syscall.O_DIRECT, 0)
+}
+
+// clearDirect turns O_DIRECT off for file.
+func clearDirect(file *os.File) {
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), syscall.F_GETFL, 0)
+	if errno == 0 {
+		syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), syscall.F_SETFL, flags&^syscall.O_DIRECT)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+const directIOSupported = false
+
+func openDirect(path string) (*os.File, error) {
+	return nil, errors.New("direct IO is only supported on Linux")
+}
+
+func clearDirect(file *os.File) {}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+)
+
+func TestDirectReader(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "large.bin")
+	data := bytes.Repeat([]byte("0123456789abcdef"), directIOMinSize/16+100)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	open := map[string]func(string) (*os.File, error){"buffered": os.Open}
+	if file, err := openDirect(path); err == nil {
+		file.Close()
+		open["direct"] = openDirect
+	}
+	for name, openFile := range open {
+		file, err := openFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := newDirectReader(file)
+		if addr := uintptr(unsafe.Pointer(&(*r.buf)[0])); addr%directIOAlign != 0 {
+			t.Errorf("Expected an aligned buffer, Got: address %x", addr)
+		}
+		read, err := ioutil.ReadAll(r)
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(read, data) {
+			t.Errorf("Expected %d bytes, Got: %d (%s)", len(data), len(read), name)
+		}
+		if r.buf != nil {
+			t.Errorf("Expected the buffer to go back to the pool (%s)", name)
+		}
+	}
+}
+
+func TestOpenForHashingDirect(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	small := filepath.Join(dir, "small.txt")
+	if err := ioutil.WriteFile(small, []byte("small"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	directIO = true
+	defer func() { directIO = false }()
+	file, direct, err := openForHashing(small)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if direct {
+		t.Errorf("Expected small files to be read through the page cache")
+	}
+}
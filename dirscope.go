@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// dirScopes are the values of --dir-scope, which restricts the duplicate
+// groups by where their copies are.
+var dirScopes = []string{"all", "cross", "within"}
+
+func validDirScope(scope string) error {
+	for _, name := range dirScopes {
+		if name == scope {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --dir-scope %q (expected one of %s)", scope, strings.Join(dirScopes, ", "))
+}
+
+// topLevelDir returns the folder directly below root that contains path, or
+// "" for files directly in root.
+func topLevelDir(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return filepath.Dir(path)
+	}
+	if i := strings.IndexRune(rel, filepath.Separator); i >= 0 {
+		return rel[:i]
+	}
+	return ""
+}
+
+// scopeFileMap returns the duplicate groups of fileMap that match scope,
+// keyed like fileMap. With "cross", only groups whose copies are in at least
+// two different top-level folders of root are kept, e.g. between /backup and
+// /live. With "within", groups only keep the copies that share a folder with
+// another copy, such as file.jpg and file (1).jpg; a group with such copies
+// in several folders is split like a matcher plugin would, by adding "-2",
+// "-3", ... to the key.
+func scopeFileMap(fileMap map[string][]File, root, scope string) map[string][]File {
+	if scope == "all" {
+		return fileMap
+	}
+	scoped := make(map[string][]File)
+	for key, files := range fileMap {
+		if len(files) < 2 {
+			continue
+		}
+		if scope == "within" {
+			for i, sub := range sameDirGroups(files) {
+				subKey := key
+				if i > 0 {
+					subKey = key + "-" + strconv.Itoa(i+1)
+				}
+				scoped[subKey] = sub
+			}
+			continue
+		}
+		for _, file := range files[1:] {
+			if topLevelDir(root, file.Path()) != topLevelDir(root, files[0].Path()) {
+				scoped[key] = files
+				break
+			}
+		}
+	}
+	return scoped
+}
+
+// sameDirGroups splits files by folder and returns the folders with more
+// than one of them, in the order of their first file.
+func sameDirGroups(files []File) [][]File {
+	var dirs []string
+	byDir := make(map[string][]File)
+	for _, file := range files {
+		dir := filepath.Dir(file.Path())
+		if byDir[dir] == nil {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], file)
+	}
+	var groups [][]File
+	for _, dir := range dirs {
+		if len(byDir[dir]) > 1 {
+			groups = append(groups, byDir[dir])
+		}
+	}
+	return groups
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTopLevelDir(t *testing.T) {
+	root := filepath.FromSlash("/data")
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"/data/backup/2024/a.jpg", "backup"},
+		{"/data/live/a.jpg", "live"},
+		{"/data/a.jpg", ""},
+	}
+	for _, tc := range testCases {
+		if got := topLevelDir(root, filepath.FromSlash(tc.path)); got != tc.expected {
+			t.Errorf("%s: Expected: %q, Got: %q", tc.path, tc.expected, got)
+		}
+	}
+}
+
+func TestScopeFileMap(t *testing.T) {
+	root := filepath.FromSlash("/data")
+	file := func(path string) File { return File{path: indexPath(filepath.FromSlash(path))} }
+	fileMap := map[string][]File{
+		"cross":  {file("/data/backup/a.jpg"), file("/data/live/a.jpg")},
+		"within": {file("/data/live/b.jpg"), file("/data/live/old/b.jpg")},
+		"rooted": {file("/data/c.jpg"), file("/data/live/c.jpg")},
+		"unique": {file("/data/live/d.jpg")},
+	}
+
+	if scoped := scopeFileMap(fileMap, root, "all"); len(scoped) != len(fileMap) {
+		t.Errorf("Expected all groups, Got: %v", scoped)
+	}
+	scoped := scopeFileMap(fileMap, root, "cross")
+	if len(scoped) != 2 || scoped["cross"] == nil || scoped["rooted"] == nil {
+		t.Errorf("Expected the groups spanning top-level folders, Got: %v", scoped)
+	}
+
+	fileMap["split"] = []File{file("/data/a/e.jpg"), file("/data/b/e.jpg"), file("/data/a/e (1).jpg"), file("/data/b/e (1).jpg"), file("/data/c/e.jpg")}
+	scoped = scopeFileMap(fileMap, root, "within")
+	expected := map[string][]string{
+		"split":   {"/data/a/e.jpg", "/data/a/e (1).jpg"},
+		"split-2": {"/data/b/e.jpg", "/data/b/e (1).jpg"},
+	}
+	if len(scoped) != len(expected) {
+		t.Fatalf("Expected: %v, Got: %v", expected, scoped)
+	}
+	for key, paths := range expected {
+		files := scoped[key]
+		if len(files) != len(paths) || files[0].Path() != filepath.FromSlash(paths[0]) || files[1].Path() != filepath.FromSlash(paths[1]) {
+			t.Errorf("%s: Expected: %v, Got: %v", key, paths, files)
+		}
+	}
+
+	if err := validDirScope("nearby"); err == nil || err.Error() != `invalid --dir-scope "nearby" (expected one of all, cross, within)` {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "sync"
+
+// scanQuota, if not nil, stops the scan once enough duplication was found
+// (--max-groups, --max-waste), for quick probes of whether a tree is worth
+// cleaning up.
+var scanQuota *duplicateQuota
+
+// duplicateQuota counts the duplicate groups and the space they waste while
+// a scan runs, and cancels the scan once either limit is reached. Groups
+// found with a fast hash are counted before they are confirmed.
+type duplicateQuota struct {
+	mu        sync.Mutex
+	maxGroups int   // 0 for no limit
+	maxWaste  int64 // 0 for no limit
+	control   *scanControl
+	counts    map[string]int
+	groups    int
+	waste     int64
+	reached   bool
+}
+
+func newDuplicateQuota(maxGroups int, maxWaste int64, control *scanControl) *duplicateQuota {
+	return &duplicateQuota{maxGroups: maxGroups, maxWaste: maxWaste, control: control, counts: make(map[string]int)}
+}
+
+// add records a hashed file.
+func (q *duplicateQuota) add(file File) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.counts[file.Hash]++
+	switch q.counts[file.Hash] {
+	case 1:
+		return
+	case 2:
+		q.groups++
+	}
+	q.waste += file.Size
+	if !q.reached && ((q.maxGroups > 0 && q.groups >= q.maxGroups) || (q.maxWaste > 0 && q.waste >= q.maxWaste)) {
+		q.reached = true
+		q.control.cancel()
+	}
+}
+
+// stopped reports whether the quota stopped the scan, with the groups and
+// waste found until then.
+func (q *duplicateQuota) stopped() (bool, int, int64) {
+	if q == nil {
+		return false, 0, 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.reached, q.groups, q.waste
+}
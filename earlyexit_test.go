@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestDuplicateQuota(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxGroups int
+		maxWaste  int64
+		stopAfter int // files added when the scan is canceled, 0 for never
+	}{
+		{"groups", 2, 0, 5},
+		{"waste", 0, 25, 3},
+		{"not reached", 5, 1000, 0},
+	}
+	files := []File{
+		{path: indexPath("a1"), Hash: "a", Size: 10},
+		{path: indexPath("a2"), Hash: "a", Size: 10},
+		{path: indexPath("a3"), Hash: "a", Size: 20},
+		{path: indexPath("b1"), Hash: "b", Size: 5},
+		{path: indexPath("b2"), Hash: "b", Size: 5},
+	}
+	for _, test := range tests {
+		control := newScanControl()
+		q := newDuplicateQuota(test.maxGroups, test.maxWaste, control)
+		canceledAt := 0
+		for i, file := range files {
+			q.add(file)
+			if canceledAt == 0 && control.isCanceled() {
+				canceledAt = i + 1
+			}
+		}
+		if canceledAt != test.stopAfter {
+			t.Errorf("Expected the scan to stop after %d files, Got: %d (%s)", test.stopAfter, canceledAt, test.name)
+		}
+		if stopped, groups, waste := q.stopped(); stopped != (test.stopAfter > 0) || groups != 2 || waste != 35 {
+			t.Errorf("Unexpected quota: %v, %d groups, %d bytes (%s)", stopped, groups, waste, test.name)
+		}
+	}
+
+	var none *duplicateQuota
+	none.add(files[0])
+	if stopped, _, _ := none.stopped(); stopped {
+		t.Errorf("Expected no quota to never stop")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// exifInfo holds the EXIF fields the tool uses to describe and group photos.
+type exifInfo struct {
+	Make             string
+	Model            string
+	DateTimeOriginal string // "2006:01:02 15:04:05"
+	SerialNumber     string
+	BurstID          string // BurstUUID of the Apple maker note, shared by the photos of a burst
+}
+
+const (
+	exifTagMake             = 0x010f
+	exifTagModel            = 0x0110
+	exifTagDateTime         = 0x0132
+	exifTagExifIFD          = 0x8769
+	exifTagDateTimeOriginal = 0x9003
+	exifTagMakerNote        = 0x927c
+	exifTagBodySerialNumber = 0xa431
+	exifTagSerialNumber     = 0xc62f // DNG camera serial number
+)
+
+var errNoExif = errors.New("no EXIF data")
+
+// readExif reads the EXIF metadata of a JPEG or HEIF file.
+func readExif(path string) (exifInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return exifInfo{}, err
+	}
+	defer f.Close()
+	if isHEIF(path) {
+		info, err := f.Stat()
+		if err != nil {
+			return exifInfo{}, err
+		}
+		return readHEIFExif(f, info.Size())
+	}
+	return readJPEGExif(bufio.NewReader(f))
+}
+
+// isHEIF reports whether path has the extension of a HEIF image, such as
+// the HEIC photos of iPhones.
+func isHEIF(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".heic", ".heif":
+		return true
+	}
+	return false
+}
+
+// readJPEGExif scans the JPEG markers up to the image data for the APP1
+// segment that carries the EXIF TIFF structure.
+func readJPEGExif(r io.Reader) (exifInfo, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xff, 0xd8} {
+		return exifInfo{}, errNoExif
+	}
+	for {
+		var marker [4]byte
+		if _, err := io.ReadFull(r, marker[:]); err != nil || marker[0] != 0xff {
+			return exifInfo{}, errNoExif
+		}
+		if marker[1] == 0xda || marker[1] == 0xd9 { // start of scan or end of image
+			return exifInfo{}, errNoExif
+		}
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if length < 0 {
+			return exifInfo{}, errNoExif
+		}
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return exifInfo{}, errNoExif
+		}
+		if marker[1] == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return parseTIFFExif(segment[6:])
+		}
+	}
+}
+
+// fits reports whether the n bytes at offset lie within size bytes. It
+// compares in 64 bits, since the offsets would wrap to negative ints on 32-bit
+// systems.
+func fits(offset, n uint32, size int) bool {
+	return uint64(offset)+uint64(n) <= uint64(size)
+}
+
+// parseTIFFExif extracts the fields of exifInfo from a TIFF structure.
+func parseTIFFExif(data []byte) (exifInfo, error) {
+	var info exifInfo
+	if len(data) < 8 {
+		return info, errNoExif
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return info, errNoExif
+	}
+
+	var dateTime string
+	var walk func(offset uint32, depth int)
+	walk = func(offset uint32, depth int) {
+		if depth > 2 || !fits(offset, 2, len(data)) {
+			return
+		}
+		count := int(order.Uint16(data[offset:]))
+		for i := 0; i < count; i++ {
+			entry := int(offset) + 2 + i*12
+			if entry+12 > len(data) {
+				return
+			}
+			tag := order.Uint16(data[entry:])
+			typ := order.Uint16(data[entry+2:])
+			n := order.Uint32(data[entry+4:])
+			value := data[entry+8 : entry+12]
+			if tag == exifTagExifIFD {
+				walk(order.Uint32(value), depth+1)
+				continue
+			}
+			if tag == exifTagMakerNote {
+				if start := order.Uint32(value); n > 4 && fits(start, n, len(data)) {
+					info.BurstID = appleBurstID(data[start : start+n])
+				}
+				continue
+			}
+			if typ != 2 { // only ASCII values are used
+				continue
+			}
+			var raw []byte
+			if n <= 4 {
+				raw = value[:n]
+			} else if start := order.Uint32(value); fits(start, n, len(data)) {
+				raw = data[start : start+n]
+			}
+			text := strings.TrimSpace(strings.TrimRight(string(raw), "\x00"))
+			switch tag {
+			case exifTagMake:
+				info.Make = text
+			case exifTagModel:
+				info.Model = text
+			case exifTagDateTime:
+				dateTime = text
+			case exifTagDateTimeOriginal:
+				info.DateTimeOriginal = text
+			case exifTagBodySerialNumber, exifTagSerialNumber:
+				info.SerialNumber = text
+			}
+		}
+	}
+	walk(order.Uint32(data[4:]), 0)
+
+	if info.DateTimeOriginal == "" {
+		info.DateTimeOriginal = dateTime
+	}
+	if info == (exifInfo{}) {
+		return info, errNoExif
+	}
+	return info, nil
+}
+
+// appleMakerNote starts the maker note of iPhone photos, which is followed by
+// a big-endian IFD whose offsets count from the start of the maker note.
+const appleMakerNote = "Apple iOS\x00\x00\x01MM"
+
+const appleTagBurstUUID = 0x000b
+
+// appleBurstID returns the BurstUUID of an Apple maker note, or "" if the
+// maker note is not Apple's or names no burst.
+func appleBurstID(note []byte) string {
+	if !bytes.HasPrefix(note, []byte(appleMakerNote)) || len(note) < len(appleMakerNote)+2 {
+		return ""
+	}
+	offset := len(appleMakerNote)
+	count := int(binary.BigEndian.Uint16(note[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(note) {
+			return ""
+		}
+		if binary.BigEndian.Uint16(note[entry:]) != appleTagBurstUUID || binary.BigEndian.Uint16(note[entry+2:]) != 2 {
+			continue
+		}
+		n := binary.BigEndian.Uint32(note[entry+4:])
+		value := note[entry+8 : entry+12]
+		var raw []byte
+		if n <= 4 {
+			raw = value[:n]
+		} else if start := binary.BigEndian.Uint32(value); fits(start, n, len(note)) {
+			raw = note[start : start+n]
+		}
+		return strings.TrimSpace(strings.TrimRight(string(raw), "\x00"))
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// testExifJPEG returns a small JPEG image carrying an EXIF segment with the
+// given camera make and original capture time.
+func testExifJPEG(t *testing.T, order binary.ByteOrder, cameraMake, taken string) []byte {
+	t.Helper()
+	var tiff bytes.Buffer
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	put16 := func(v uint16) { binary.Write(&tiff, order, v) }
+	put32 := func(v uint32) { binary.Write(&tiff, order, v) }
+	makeValue := cameraMake + "\x00"
+	takenValue := taken + "\x00"
+
+	// Header, IFD0 with two entries at 8, Exif IFD with one entry at 38,
+	// string values from 56.
+	put16(42)
+	put32(8)
+	put16(2)
+	put16(exifTagMake)
+	put16(2)
+	put32(uint32(len(makeValue)))
+	put32(56)
+	put16(exifTagExifIFD)
+	put16(4)
+	put32(1)
+	put32(38)
+	put32(0)
+	put16(1)
+	put16(exifTagDateTimeOriginal)
+	put16(2)
+	put32(uint32(len(takenValue)))
+	put32(uint32(56 + len(makeValue)))
+	put32(0)
+	tiff.WriteString(makeValue)
+	tiff.WriteString(takenValue)
+
+	var img bytes.Buffer
+	if err := jpeg.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 3)), nil); err != nil {
+		t.Fatal(err)
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var out bytes.Buffer
+	out.Write([]byte{0xff, 0xd8, 0xff, 0xe1})
+	binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(img.Bytes()[2:])
+	return out.Bytes()
+}
+
+func TestReadJPEGExif(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			data := testExifJPEG(t, order, "Canon", "2021:07:04 10:30:00")
+			info, err := readJPEGExif(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Make != "Canon" {
+				t.Errorf("Expected: %s, Got: %s", "Canon", info.Make)
+			}
+			if info.DateTimeOriginal != "2021:07:04 10:30:00" {
+				t.Errorf("Expected: %s, Got: %s", "2021:07:04 10:30:00", info.DateTimeOriginal)
+			}
+		})
+	}
+
+	var plain bytes.Buffer
+	jpeg.Encode(&plain, image.NewGray(image.Rect(0, 0, 1, 1)), nil)
+	if _, err := readJPEGExif(bytes.NewReader(plain.Bytes())); err != errNoExif {
+		t.Errorf("Expected: %v, Got: %v", errNoExif, err)
+	}
+	if _, err := readJPEGExif(bytes.NewReader([]byte("not a jpeg"))); err != errNoExif {
+		t.Errorf("Expected: %v, Got: %v", errNoExif, err)
+	}
+}
+
+func TestParseTIFFExifOutOfRange(t *testing.T) {
+	// IFD0 offset and a string value offset near 4 GiB, which wrap to
+	// negative ints on 32-bit systems.
+	var tiff bytes.Buffer
+	tiff.WriteString("II")
+	binary.Write(&tiff, binary.LittleEndian, uint16(42))
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(1))
+	binary.Write(&tiff, binary.LittleEndian, uint16(exifTagMake))
+	binary.Write(&tiff, binary.LittleEndian, uint16(2))
+	binary.Write(&tiff, binary.LittleEndian, uint32(16))
+	binary.Write(&tiff, binary.LittleEndian, uint32(0xfffffff8))
+	if _, err := parseTIFFExif(tiff.Bytes()); err != errNoExif {
+		t.Errorf("Expected: %v, Got: %v", errNoExif, err)
+	}
+	data := append([]byte("II*\x00"), 0xf0, 0xff, 0xff, 0xff)
+	if _, err := parseTIFFExif(data); err != errNoExif {
+		t.Errorf("Expected: %v, Got: %v", errNoExif, err)
+	}
+}
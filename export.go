@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scanExport is everything a scan produced, as written by the --export formats.
+type scanExport struct {
+	Root      string
+	ScannedAt time.Time
+	Progress  scanProgress
+	FileMap   map[string][]File
+}
+
+// exportFormats maps the format names accepted by --export to their writers.
+var exportFormats = map[string]func(path string, data scanExport) error{
+	"sqlite":  exportSQLite,
+	"sql":     exportSQL,
+	"parquet": exportParquet,
+	"xml":     exportXML,
+}
+
+// exportTarget is a parsed --export FORMAT:PATH value.
+type exportTarget struct {
+	format string
+	path   string
+}
+
+func parseExport(spec string) (exportTarget, error) {
+	i := strings.IndexByte(spec, ':')
+	if i <= 0 || i == len(spec)-1 {
+		return exportTarget{}, fmt.Errorf("invalid export %q: expected FORMAT:PATH", spec)
+	}
+	target := exportTarget{format: strings.ToLower(spec[:i]), path: spec[i+1:]}
+	if _, ok := exportFormats[target.format]; !ok {
+		var names []string
+		for name := range exportFormats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return exportTarget{}, fmt.Errorf("invalid export format %q (expected one of %s)", target.format, strings.Join(names, ", "))
+	}
+	return target, nil
+}
+
+func (t exportTarget) write(data scanExport) error {
+	return exportFormats[t.format](t.path, data)
+}
+
+// exportedFile is a scanned file with its duplicate group, the row written
+// by the per-file export formats. Group is "" for files without duplicates.
+type exportedFile struct {
+	File
+	Group string
+	Kept  bool
+}
+
+// exportedFiles returns every scanned file ordered by path.
+func exportedFiles(fileMap map[string][]File) []exportedFile {
+	var files []exportedFile
+	for key, group := range fileMap {
+		id := ""
+		if len(group) > 1 {
+			id = groupID(key)
+		}
+		for i, file := range group {
+			files = append(files, exportedFile{File: file, Group: id, Kept: id != "" && i == 0})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path() < files[j].Path() })
+	return files
+}
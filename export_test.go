@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExport(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		expected exportTarget
+		valid    bool
+	}{
+		{"sqlite:results.db", exportTarget{"sqlite", "results.db"}, true},
+		{"SQL:C:/out/dump.sql", exportTarget{"sql", "C:/out/dump.sql"}, true},
+		{"results.db", exportTarget{}, false},
+		{"sqlite:", exportTarget{}, false},
+		{"csv:out.csv", exportTarget{}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			target, err := parseExport(tc.spec)
+			if (err == nil) != tc.valid {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if target != tc.expected {
+				t.Errorf("Expected: %+v, Got: %+v", tc.expected, target)
+			}
+		})
+	}
+}
+
+func TestExportedFiles(t *testing.T) {
+	fileMap := map[string][]File{
+		"aaaaaaaaaaaaaaaa": {{path: indexPath("b"), Hash: "aaaaaaaaaaaaaaaa"}, {path: indexPath("a"), Hash: "aaaaaaaaaaaaaaaa"}},
+		"cccccccccccccccc": {{path: indexPath("c"), Hash: "cccccccccccccccc"}},
+	}
+	expected := []exportedFile{
+		{File: File{path: indexPath("a"), Hash: "aaaaaaaaaaaaaaaa"}, Group: "aaaaaaaaaaaa"},
+		{File: File{path: indexPath("b"), Hash: "aaaaaaaaaaaaaaaa"}, Group: "aaaaaaaaaaaa", Kept: true},
+		{File: File{path: indexPath("c"), Hash: "cccccccccccccccc"}},
+	}
+	if got := exportedFiles(fileMap); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected: %+v, Got: %+v", expected, got)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// sortRunSize is the number of files an external sort holds in memory; each
+// full run is sorted and written to a file of its own.
+var sortRunSize = 1 << 18
+
+// sortMergeWidth is the number of run files merged at once, to stay below the
+// limit of open files; more runs are merged in several passes.
+var sortMergeWidth = 256
+
+// entryBefore orders files by size, hash and path.
+func entryBefore(a, b shardEntry) bool {
+	if a.Size != b.Size {
+		return a.Size < b.Size
+	}
+	if a.Hash != b.Hash {
+		return a.Hash < b.Hash
+	}
+	return a.Path < b.Path
+}
+
+// externalSorter groups files by size and hash with an external merge sort,
+// so that the memory it takes does not grow with the number of files.
+type externalSorter struct {
+	dir  string // for the run files
+	run  []shardEntry
+	runs []string
+}
+
+func newExternalSorter(dir string) *externalSorter {
+	return &externalSorter{dir: dir}
+}
+
+// add adds a file; files without a hash are left out.
+func (s *externalSorter) add(e shardEntry) error {
+	if e.Hash == "" {
+		return nil
+	}
+	s.run = append(s.run, e)
+	if len(s.run) >= sortRunSize {
+		return s.flush()
+	}
+	return nil
+}
+
+// flush writes the files held in memory to a sorted run file.
+func (s *externalSorter) flush() error {
+	if len(s.run) == 0 {
+		return nil
+	}
+	sort.Slice(s.run, func(i, j int) bool { return entryBefore(s.run[i], s.run[j]) })
+	path, err := s.writeRun(func(write func(shardEntry) error) error {
+		for _, e := range s.run {
+			if err := write(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, path)
+	s.run = s.run[:0]
+	return nil
+}
+
+// writeRun writes the files that fill passes to write to a new run file.
+func (s *externalSorter) writeRun(fill func(write func(shardEntry) error) error) (string, error) {
+	f, err := ioutil.TempFile(s.dir, "run-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	err = fill(func(e shardEntry) error { return enc.Encode(e) })
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// groups calls emit with every set of at least two files that share their
+// size and hash, each sorted by path.
+func (s *externalSorter) groups(emit func([]File)) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	// Merge runs until they can be read all at once.
+	for len(s.runs) > sortMergeWidth {
+		batch := s.runs[:sortMergeWidth]
+		path, err := s.writeRun(func(write func(shardEntry) error) error { return mergeRuns(batch, write) })
+		if err != nil {
+			return err
+		}
+		for _, run := range batch {
+			os.Remove(run)
+		}
+		s.runs = append(s.runs[sortMergeWidth:], path)
+	}
+	var group []File
+	flushGroup := func() {
+		if len(group) > 1 {
+			emit(group)
+		}
+		group = nil
+	}
+	err := mergeRuns(s.runs, func(e shardEntry) error {
+		if len(group) > 0 && (group[0].Size != e.Size || group[0].Hash != e.Hash) {
+			flushGroup()
+		}
+		group = append(group, e.file())
+		return nil
+	})
+	flushGroup()
+	return err
+}
+
+// close removes the run files.
+func (s *externalSorter) close() {
+	for _, run := range s.runs {
+		os.Remove(run)
+	}
+	s.runs = nil
+}
+
+// runReader reads the files of a run file one after the other.
+type runReader struct {
+	f    *os.File
+	dec  *json.Decoder
+	next shardEntry
+}
+
+// advance reads the next file; ok is false at the end of the run.
+func (r *runReader) advance() (ok bool, err error) {
+	r.next = shardEntry{}
+	if err := r.dec.Decode(&r.next); err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// runHeap orders run readers by their next file.
+type runHeap []*runReader
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return entryBefore(h[i].next, h[j].next) }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// mergeRuns calls write with the files of the sorted run files at paths in
+// sorted order.
+func mergeRuns(paths []string, write func(shardEntry) error) error {
+	var h runHeap
+	defer func() {
+		for _, r := range h {
+			r.f.Close()
+		}
+	}()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		r := &runReader{f: f, dec: json.NewDecoder(bufio.NewReader(f))}
+		ok, err := r.advance()
+		if err != nil || !ok {
+			f.Close()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		h = append(h, r)
+	}
+	heap.Init(&h)
+	for len(h) > 0 {
+		r := h[0]
+		if err := write(r.next); err != nil {
+			return err
+		}
+		ok, err := r.advance()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			r.f.Close()
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestExternalSorter(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	defer func(size, width int) { sortRunSize, sortMergeWidth = size, width }(sortRunSize, sortMergeWidth)
+	sortRunSize, sortMergeWidth = 3, 2 // several runs, merged in several passes
+
+	sorter := newExternalSorter(tempDir)
+	defer sorter.close()
+	for i := 0; i < 20; i++ {
+		// Files 0, 5, 10 and 15 share a hash, and so do 1, 6, 11 and 16;
+		// 2 and 7 have the same hash as 0 but a different size.
+		e := shardEntry{Path: fmt.Sprintf("/f%02d", i), Hash: fmt.Sprintf("h%d", i%5), Size: 10}
+		if i%5 > 2 {
+			e.Hash = fmt.Sprintf("unique%d", i)
+		} else if i%5 == 2 {
+			e.Hash, e.Size = "h0", 20
+		}
+		if err := sorter.add(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sorter.add(shardEntry{Path: "/unhashed", Size: 10})
+	if len(sorter.runs) < 3 {
+		t.Errorf("Expected several runs, Got: %d", len(sorter.runs))
+	}
+
+	var groups []string
+	err := sorter.groups(func(files []File) {
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, file.Path())
+		}
+		groups = append(groups, fmt.Sprintf("%d %s: %s", files[0].Size, files[0].Hash, strings.Join(paths, " ")))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"10 h0: /f00 /f05 /f10 /f15",
+		"10 h1: /f01 /f06 /f11 /f16",
+		"20 h0: /f02 /f07 /f12 /f17",
+	}
+	if !sort.StringsAreSorted(groups) || strings.Join(groups, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected groups:\n%s", strings.Join(groups, "\n"))
+	}
+
+	sorter.close()
+	if left, _ := ioutil.ReadDir(tempDir); len(left) != 0 {
+		t.Errorf("Expected the run files to be removed, Got: %d files", len(left))
+	}
+}
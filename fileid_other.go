@@ -0,0 +1,11 @@
+//go:build plan9
+
+package main
+
+import "os"
+
+// fileIdentity reports ok as false, as Plan 9 has no inode numbers or hard
+// links.
+func fileIdentity(path string, info os.FileInfo) (id fileID, links uint64, ok bool) {
+	return fileID{}, 0, false
+}
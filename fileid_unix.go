@@ -0,0 +1,18 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode of a file and its number of hard
+// links. ok is false if the file system does not provide them.
+func fileIdentity(path string, info os.FileInfo) (id fileID, links uint64, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, 0, false
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, uint64(st.Nlink), true
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the volume serial number and file index of a file
+// and its number of hard links. ok is false if the file cannot be opened.
+func fileIdentity(path string, info os.FileInfo) (id fileID, links uint64, ok bool) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return fileID{}, 0, false
+	}
+	h, err := syscall.CreateFile(name, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return fileID{}, 0, false
+	}
+	defer syscall.CloseHandle(h)
+	var fi syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(h, &fi); err != nil {
+		return fileID{}, 0, false
+	}
+	return fileID{dev: uint64(fi.VolumeSerialNumber), ino: uint64(fi.FileIndexHigh)<<32 | uint64(fi.FileIndexLow)}, uint64(fi.NumberOfLinks), true
+}
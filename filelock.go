@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+// errFileLocked is returned by lockFile when another process holds a lock on
+// the file.
+var errFileLocked = errors.New("file is locked by another process")
+
+// withFileLock runs action while holding an exclusive lock on path, so files
+// that another process has locked are not moved or deleted under it. It
+// returns errFileLocked without running action if the lock is taken.
+func withFileLock(path string, action func() error) error {
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return action()
+}
+
+// skipLocked logs a file that was skipped because it is locked and counts it
+// in stats. It reports whether err was errFileLocked.
+func skipLocked(path string, err error, stats *actionStats) bool {
+	if err != errFileLocked {
+		return false
+	}
+	log.Printf("Skipping %s, it is locked by another process", path)
+	stats.Locked++
+	return true
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+
+package main
+
+// lockFile does not lock anything on platforms without flock; files are
+// moved and deleted without checking for locks of other processes.
+func lockFile(path string) (unlock func(), err error) {
+	return func() {}, nil
+}
+
+// isSharingViolation reports whether err means that another process has the
+// file open without sharing it, which only happens on Windows.
+func isSharingViolation(err error) bool {
+	return false
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithFileLock(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "file")
+	ioutil.WriteFile(path, []byte("content"), 0644)
+
+	ran := false
+	if err := withFileLock(path, func() error { ran = true; return nil }); err != nil || !ran {
+		t.Fatalf("Expected the action to run, Got: %v", err)
+	}
+
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran = false
+	if err := withFileLock(path, func() error { ran = true; return nil }); err != errFileLocked || ran {
+		t.Errorf("Expected: %v, Got: %v (action ran: %v)", errFileLocked, err, ran)
+	}
+	unlock()
+
+	if err := withFileLock(path, func() error { return os.Remove(path) }); err != nil {
+		t.Errorf("Expected the file to be removed under the lock, Got: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed", path)
+	}
+}
+
+func TestDeleteFilesSkipsLockedFiles(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	var files []File
+	for _, name := range []string{"keep", "dup"} {
+		path := filepath.Join(tempDir, name)
+		ioutil.WriteFile(path, []byte("content"), 0644)
+		file, _ := hashFile(path)
+		files = append(files, file)
+	}
+
+	unlock, err := lockFile(files[1].Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	if stats := deleteFiles(map[string][]File{"hash": files}, true); stats != (actionStats{Locked: 1}) {
+		t.Errorf("Unexpected delete stats: %+v", stats)
+	}
+	if _, err := os.Stat(files[1].Path()); err != nil {
+		t.Errorf("Locked file was deleted: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+
+	errorSharingViolation = syscall.Errno(32)
+	errorLockViolation    = syscall.Errno(33)
+)
+
+// isSharingViolation reports whether err means that another process has the
+// file open without sharing it, or has locked part of it.
+func isSharingViolation(err error) bool {
+	var errno syscall.Errno
+	return errors.As(err, &errno) && (errno == errorSharingViolation || errno == errorLockViolation)
+}
+
+// lockFile checks with a non-blocking exclusive LockFileEx lock that no other
+// process has locked any part of path, then holds a shared lock instead. An
+// exclusive lock would also keep moveFile from reading the file to copy it to
+// another volume. The file is opened with FILE_SHARE_DELETE so it can still
+// be renamed or deleted while the lock is held.
+func lockFile(path string) (unlock func(), err error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(name, syscall.GENERIC_READ,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if isSharingViolation(err) {
+		return nil, errFileLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	var overlapped syscall.Overlapped
+	lock := func(flags uintptr) error {
+		r, _, err := procLockFileEx.Call(uintptr(h), flags, 0, 0xffffffff, 0xffffffff, uintptr(unsafe.Pointer(&overlapped)))
+		if r != 0 {
+			return nil
+		}
+		if err == errorLockViolation {
+			return errFileLocked
+		}
+		return err
+	}
+	release := func() {
+		procUnlockFileEx.Call(uintptr(h), 0, 0xffffffff, 0xffffffff, uintptr(unsafe.Pointer(&overlapped)))
+	}
+	if err := lock(lockfileExclusiveLock | lockfileFailImmediately); err != nil {
+		syscall.CloseHandle(h)
+		return nil, err
+	}
+	release()
+	if err := lock(lockfileFailImmediately); err != nil {
+		syscall.CloseHandle(h)
+		return nil, err
+	}
+	return func() {
+		release()
+		syscall.CloseHandle(h)
+	}, nil
+}
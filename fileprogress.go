@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hashChunk is the size of the reads of the hashing (--read-size).
+var hashChunk = 32 << 10
+
+// progressOver is the size from which a file being hashed is listed with its
+// own progress in status reports (--progress-over), so a huge file that is
+// still being read can be told apart from a hung scan. Files of this size
+// that are moved to another volume are copied with copyResumable.
+var progressOver int64 = 1 << 30
+
+// fileProgress is the progress of hashing one large file.
+type fileProgress struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	Read    int64     `json:"read"` // bytes hashed so far
+	Started time.Time `json:"started"`
+}
+
+// largeFiles are the large files being hashed.
+var largeFiles = struct {
+	sync.Mutex
+	active map[*fileProgress]bool
+}{active: make(map[*fileProgress]bool)}
+
+// trackFile starts reporting the progress of hashing a file and returns r
+// wrapped to count the bytes read, and a function that stops the reporting.
+func trackFile(r io.Reader, path string, size int64) (io.Reader, func()) {
+	if size < progressOver {
+		return r, func() {}
+	}
+	p := &fileProgress{Path: path, Size: size, Started: time.Now()}
+	largeFiles.Lock()
+	largeFiles.active[p] = true
+	largeFiles.Unlock()
+	return progressReader{r, p}, func() {
+		largeFiles.Lock()
+		delete(largeFiles.active, p)
+		largeFiles.Unlock()
+	}
+}
+
+// activeLargeFiles returns the progress of the large files being hashed,
+// ordered by path.
+func activeLargeFiles() []fileProgress {
+	largeFiles.Lock()
+	defer largeFiles.Unlock()
+	var files []fileProgress
+	for p := range largeFiles.active {
+		files = append(files, fileProgress{Path: p.Path, Size: p.Size, Read: atomic.LoadInt64(&p.Read), Started: p.Started})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// progressReader counts the bytes read from r.
+type progressReader struct {
+	r io.Reader
+	p *fileProgress
+}
+
+func (r progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	atomic.AddInt64(&r.p.Read, int64(n))
+	return n, err
+}
+
+// chunkBuffers pools the buffers of hashChunk bytes hashFile copies through.
+var chunkBuffers = sync.Pool{New: func() interface{} {
+	b := make([]byte, hashChunk)
+	return &b
+}}
+
+// copyChunks copies r to w in reads of hashChunk bytes.
+func copyChunks(w io.Writer, r io.Reader) error {
+	buf := chunkBuffers.Get().(*[]byte)
+	if len(*buf) != hashChunk {
+		b := make([]byte, hashChunk) // hashChunk changed
+		buf = &b
+	}
+	defer chunkBuffers.Put(buf)
+	// Hide io.WriterTo and io.ReaderFrom, which pick their own read size.
+	_, err := io.CopyBuffer(struct{ io.Writer }{w}, struct{ io.Reader }{r}, *buf)
+	return err
+}
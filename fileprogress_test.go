@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestTrackFile(t *testing.T) {
+	defer func(size int64) { progressOver = size }(progressOver)
+	progressOver = 10
+
+	r, untrack := trackFile(strings.NewReader("small"), "small", 5)
+	if _, ok := r.(progressReader); ok {
+		t.Errorf("Expected small files not to be tracked")
+	}
+	untrack()
+
+	r, untrack = trackFile(strings.NewReader("0123456789abcdef"), "large", 16)
+	buf := make([]byte, 10)
+	r.Read(buf)
+	active := activeLargeFiles()
+	if len(active) != 1 || active[0].Path != "large" || active[0].Read != 10 || active[0].Size != 16 {
+		t.Errorf("Unexpected progress: %+v", active)
+	}
+	var out bytes.Buffer
+	writeScanStatus(&out, scanStatus{Large: active})
+	if !strings.Contains(out.String(), "Hashing large: 10.00 B of 16.00 B (62.5%)") {
+		t.Errorf("Expected the file in the status report, Got: %s", out.String())
+	}
+	untrack()
+	if active := activeLargeFiles(); len(active) != 0 {
+		t.Errorf("Expected no tracked files, Got: %+v", active)
+	}
+}
+
+func TestCopyChunks(t *testing.T) {
+	defer func(size int) { hashChunk = size }(hashChunk)
+	hashChunk = 4096
+	data := bytes.Repeat([]byte("x"), 10000)
+	var reads []int
+	r := readSizes{bytes.NewReader(data), &reads}
+	var out bytes.Buffer
+	if err := copyChunks(&out, r); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != len(data) || reads[0] != 4096 {
+		t.Errorf("Expected reads of 4096 bytes, Got: %v", reads)
+	}
+	read, _ := ioutil.ReadAll(&out)
+	if !bytes.Equal(read, data) {
+		t.Errorf("Expected the data to be copied")
+	}
+}
+
+// readSizes records the size of every read.
+type readSizes struct {
+	r     *bytes.Reader
+	sizes *[]int
+}
+
+func (r readSizes) Read(p []byte) (int, error) {
+	*r.sizes = append(*r.sizes, len(p))
+	return r.r.Read(p)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// errFreeSpaceUnknown is returned by freeSpace on systems that cannot tell
+// the free space of a volume.
+var errFreeSpaceUnknown = errors.New("free space cannot be determined on this system")
+
+// pendingMove is a file that is about to be moved to dest.
+type pendingMove struct {
+	source, dest string
+	size         int64
+}
+
+// checkFreeSpace verifies that every volume the files are moved to has room
+// for the files copied to it, so a move is refused up front instead of
+// failing halfway through. Files moved within their volume are renamed and
+// need no room, and hard links to one file are copied once (see linkMover).
+// Moves whose source or destination cannot be read are left to fail on their
+// own.
+func checkFreeSpace(moves []pendingMove) error {
+	type volume struct {
+		dir    string // a folder on the volume
+		needed int64
+	}
+	volumes := make(map[uint64]*volume)
+	var order []uint64
+	copied := make(map[fileID]bool) // hard links are copied once
+	for _, move := range moves {
+		dir := existingDir(filepath.Dir(move.dest))
+		dirInfo, err := os.Stat(dir)
+		if err != nil {
+			continue
+		}
+		destID, _, ok := fileIdentity(dir, dirInfo)
+		if !ok {
+			continue
+		}
+		if info, err := os.Stat(move.source); err == nil {
+			if sourceID, _, ok := fileIdentity(move.source, info); ok {
+				if sourceID.dev == destID.dev || copied[sourceID] {
+					continue
+				}
+				copied[sourceID] = true
+			}
+		}
+		v, seen := volumes[destID.dev]
+		if !seen {
+			v = &volume{dir: dir}
+			volumes[destID.dev] = v
+			order = append(order, destID.dev)
+		}
+		v.needed += move.size
+	}
+	for _, dev := range order {
+		v := volumes[dev]
+		free, err := freeSpace(v.dir)
+		if err == errFreeSpaceUnknown {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot determine the free space of %s: %v", v.dir, err)
+		}
+		if free < v.needed {
+			return fmt.Errorf("not enough free space on the volume of %s: the moves need %s, %s are available", v.dir, humanReadableSize(v.needed), humanReadableSize(free))
+		}
+	}
+	return nil
+}
+
+// existingDir returns dir or, if it does not exist yet, the closest of its
+// parents that does.
+func existingDir(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+// freeSpace cannot tell the free space on this system.
+func freeSpace(path string) (int64, error) {
+	return 0, errFreeSpaceUnknown
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFreeSpace(t *testing.T) {
+	free, err := freeSpace(".")
+	if err == errFreeSpaceUnknown {
+		t.Skip(err)
+	}
+	if err != nil || free <= 0 {
+		t.Errorf("Expected the free space of the current folder, Got: %d, %v", free, err)
+	}
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	if _, err := freeSpace("."); err != nil {
+		t.Skip(err)
+	}
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	source := filepath.Join(tempDir, "source.txt")
+	ioutil.WriteFile(source, []byte("content"), 0644)
+	dest := filepath.Join(tempDir, "new", "folder", "source.txt")
+
+	// A rename within the volume needs no room, whatever the size.
+	if err := checkFreeSpace([]pendingMove{{source, dest, 1 << 62}}); err != nil {
+		t.Errorf("Expected no error for a move within the volume, Got: %v", err)
+	}
+	// A source that cannot be checked may be on another volume.
+	missing := filepath.Join(tempDir, "missing.txt")
+	err := checkFreeSpace([]pendingMove{{missing, dest, 1 << 62}})
+	if err == nil || !strings.Contains(err.Error(), "not enough free space") {
+		t.Errorf("Expected not enough free space, Got: %v", err)
+	}
+	if err := checkFreeSpace([]pendingMove{{missing, dest, 1}}); err != nil {
+		t.Errorf("Expected no error for a small move, Got: %v", err)
+	}
+}
+
+func TestExistingDir(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	if got := existingDir(filepath.Join(tempDir, "a", "b")); got != filepath.Clean(tempDir) {
+		t.Errorf("Expected: %s, Got: %s", filepath.Clean(tempDir), got)
+	}
+	if got := existingDir(tempDir); got != tempDir {
+		t.Errorf("Expected: %s, Got: %s", tempDir, got)
+	}
+}
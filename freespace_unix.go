@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package main
+
+import "syscall"
+
+// freeSpace returns the bytes available to unprivileged users on the volume
+// of path.
+func freeSpace(path string) (int64, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return 0, err
+	}
+	return int64(fs.Bavail) * int64(fs.Bsize), nil
+}
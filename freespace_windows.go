@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceExW = kernel32.NewProc("GetDiskFreeSpaceExW")
+
+// freeSpace returns the bytes available to the user on the volume of path,
+// taking disk quotas into account.
+func freeSpace(path string) (int64, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var available uint64
+	if ok, _, err := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&available)), 0, 0); ok == 0 {
+		return 0, err
+	}
+	return int64(available), nil
+}
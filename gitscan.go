@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// includeGit, set by --include-git, also scans the .git folders of
+	// repositories, whose object stores are never worth cleaning up.
+	includeGit bool
+	// respectGitignore, set by --gitignore, skips the files that the
+	// .gitignore files of the scanned folders ignore.
+	respectGitignore bool
+	// cleanCheckouts, set by --clean-checkouts, lets the actions remove files
+	// of git working trees that are duplicates of files in other checkouts.
+	cleanCheckouts bool
+)
+
+// walkFilter decides which folders and files a walk of root skips.
+type walkFilter struct {
+	root   string
+	ignore map[string][]gitignoreRule // by the folder of their .gitignore
+}
+
+func newWalkFilter(root string) *walkFilter {
+	return &walkFilter{root: filepath.Clean(root), ignore: make(map[string][]gitignoreRule)}
+}
+
+// skip reports whether the walk leaves out path, which it found with info.
+// It loads the .gitignore of every folder it is asked about, so it must see
+// each folder before its contents, as filepath.Walk does.
+func (f *walkFilter) skip(path string, info os.FileInfo) bool {
+	path = filepath.Clean(path) // the walk passes the root as it was given
+	if path == f.root {
+		if respectGitignore && info.IsDir() {
+			f.load(path)
+		}
+		return false
+	}
+	if info.IsDir() && (info.Name() == ".git" && !includeGit || isDependencyDir(path) || skipBackupStore(path) || shardDirs[path]) {
+		return true
+	}
+	if !respectGitignore {
+		return false
+	}
+	if f.ignored(path, info.IsDir()) {
+		return true
+	}
+	if info.IsDir() {
+		f.load(path)
+	}
+	return false
+}
+
+func (f *walkFilter) load(dir string) {
+	if rules, err := readGitignore(filepath.Join(dir, ".gitignore")); err == nil && len(rules) > 0 {
+		f.ignore[dir] = rules
+	}
+}
+
+// ignored reports whether the rules of the .gitignore files in the folders
+// above path, up to the root, ignore it. Like git, the last matching rule of
+// the deepest .gitignore with one decides.
+func (f *walkFilter) ignored(path string, isDir bool) bool {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if rules := f.ignore[dir]; len(rules) > 0 {
+			if rel, err := filepath.Rel(dir, path); err == nil {
+				rel = filepath.ToSlash(rel)
+				for i := len(rules) - 1; i >= 0; i-- {
+					if rules[i].matches(rel, isDir) {
+						return !rules[i].negate
+					}
+				}
+			}
+		}
+		if dir == f.root || dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
+
+// gitignoreRule is one pattern of a .gitignore file.
+type gitignoreRule struct {
+	pattern *regexp.Regexp // matches the path relative to the .gitignore
+	negate  bool           // a pattern starting with !
+	dirOnly bool           // a pattern ending with /
+}
+
+func (r gitignoreRule) matches(rel string, isDir bool) bool {
+	return (isDir || !r.dirOnly) && r.pattern.MatchString(rel)
+}
+
+// readGitignore reads the rules of a .gitignore file.
+func readGitignore(path string) ([]gitignoreRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseGitignoreRule(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// parseGitignoreRule parses one line of a .gitignore file; ok is false for
+// blank lines and comments.
+func parseGitignoreRule(line string) (rule gitignoreRule, ok bool) {
+	line = strings.TrimRight(line, " \r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate, line = true, line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:] // escaped # or !
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	// A pattern with a slash before its end is relative to the .gitignore,
+	// any other matches a name at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return rule, false
+	}
+	var re strings.Builder
+	if anchored {
+		re.WriteString("^")
+	} else {
+		re.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case strings.HasPrefix(line[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "/**") && i+3 == len(line):
+			re.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(line):
+			i++
+			re.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	pattern, err := regexp.Compile(re.String())
+	if err != nil {
+		return rule, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// gitCheckouts finds the git working tree that files belong to.
+type gitCheckouts map[string]string // top-level folder of each folder looked up, "" outside of one
+
+// root returns the top-level folder of the working tree that holds path, or
+// "" if it is not in one.
+func (c gitCheckouts) root(path string) string {
+	if isRemotePath(path) {
+		return ""
+	}
+	dir := filepath.Dir(path)
+	var visited []string
+	root := ""
+	for {
+		if r, ok := c[dir]; ok {
+			root = r
+			break
+		}
+		visited = append(visited, dir)
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			root = dir
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	for _, dir := range visited {
+		c[dir] = root
+	}
+	return root
+}
+
+// checkoutFileMap takes the files of git working trees out of the groups
+// that have copies in more than one checkout, unless they are the kept copy,
+// so that cleaning up never breaks a clone. It returns the number of files
+// taken out.
+func checkoutFileMap(fileMap map[string][]File) (map[string][]File, int) {
+	checkouts := make(gitCheckouts)
+	protected := 0
+	result := make(map[string][]File, len(fileMap))
+	for key, files := range fileMap {
+		if len(files) < 2 {
+			result[key] = files
+			continue
+		}
+		roots := make(map[string]bool)
+		for _, file := range files {
+			if root := checkouts.root(file.Path()); root != "" {
+				roots[root] = true
+			}
+		}
+		if len(roots) < 2 {
+			result[key] = files
+			continue
+		}
+		left := []File{files[0]}
+		for _, file := range files[1:] {
+			if checkouts.root(file.Path()) != "" {
+				protected++
+				continue
+			}
+			left = append(left, file)
+		}
+		result[key] = left
+	}
+	return result, protected
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseGitignoreRule(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		isDir         bool
+		expected      bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"*.log", "debug.log.txt", false, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"build/", "src/build", true, true},
+		{"/build", "src/build", true, false},
+		{"/build", "build", false, true},
+		{"doc/*.txt", "doc/notes.txt", false, true},
+		{"doc/*.txt", "doc/server/arch.txt", false, false},
+		{"doc/**/*.txt", "doc/server/arch.txt", false, true},
+		{"**/foo", "a/b/foo", false, true},
+		{"abc/**", "abc/x/y", false, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"file?.[ch]", "file1.c", false, true},
+		{"file?.[!ch]", "file1.c", false, false},
+		{`\#notes`, "#notes", false, true},
+	}
+	for _, test := range tests {
+		rule, ok := parseGitignoreRule(test.pattern)
+		if !ok {
+			t.Errorf("Expected %q to be a rule", test.pattern)
+			continue
+		}
+		if got := rule.matches(test.path, test.isDir); got != test.expected {
+			t.Errorf("%q on %s: Expected: %v, Got: %v", test.pattern, test.path, test.expected, got)
+		}
+	}
+	for _, line := range []string{"", "   ", "# comment", "/"} {
+		if _, ok := parseGitignoreRule(line); ok {
+			t.Errorf("Expected %q to be no rule", line)
+		}
+	}
+}
+
+func TestWalkFilter(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	defer func() { includeGit, respectGitignore = false, false }()
+	for name, content := range map[string]string{
+		".git/objects/ab/cdef":  "object",
+		".gitignore":            "*.log\nbuild/\n",
+		"main.go":               "package main",
+		"debug.log":             "log",
+		"build/out":             "binary",
+		"docs/.gitignore":       "!keep.log\n",
+		"docs/keep.log":         "kept",
+		"docs/other.log":        "log",
+		"vendor/.git/HEAD":      "ref",
+		"vendor/lib/.gitignore": "",
+	} {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(content), 0644)
+	}
+	scanned := func() string {
+		fileMap, _, err := scanFolder(tempDir, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, files := range fileMap {
+			for _, file := range files {
+				rel, _ := filepath.Rel(tempDir, file.Path())
+				names = append(names, filepath.ToSlash(rel))
+			}
+		}
+		sort.Strings(names)
+		return strings.Join(names, " ")
+	}
+
+	if got := scanned(); got != ".gitignore build/out debug.log docs/.gitignore docs/keep.log docs/other.log main.go vendor/lib/.gitignore" {
+		t.Errorf("Expected the .git folders to be skipped, Got: %s", got)
+	}
+	respectGitignore = true
+	if got := scanned(); got != ".gitignore docs/.gitignore docs/keep.log main.go vendor/lib/.gitignore" {
+		t.Errorf("Expected the ignored files to be skipped, Got: %s", got)
+	}
+	includeGit, respectGitignore = true, false
+	if got := scanned(); !strings.Contains(got, ".git/objects/ab/cdef") || !strings.Contains(got, "vendor/.git/HEAD") {
+		t.Errorf("Expected the .git folders to be scanned, Got: %s", got)
+	}
+}
+
+func TestCheckoutFileMap(t *testing.T) {
+	// Outside of the working tree the tests may run in.
+	tempDir, err := ioutil.TempDir("", "checkouts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	for _, dir := range []string{"clone1/.git", "clone2/.git", "backup"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	file := func(name string) File { return File{path: indexPath(filepath.Join(tempDir, filepath.FromSlash(name)))} }
+	fileMap := map[string][]File{
+		"across": {file("clone1/src/a.go"), file("clone2/src/a.go"), file("backup/a.go")},
+		"within": {file("clone1/a.txt"), file("clone1/b.txt")},
+		"other":  {file("backup/x"), file("clone2/x")},
+	}
+	result, protected := checkoutFileMap(fileMap)
+	if protected != 1 {
+		t.Errorf("Expected: 1 protected file, Got: %d", protected)
+	}
+	if files := result["across"]; len(files) != 2 || files[1].Path() != file("backup/a.go").Path() {
+		t.Errorf("Expected the copy in the other clone to be left alone, Got: %v", files)
+	}
+	if len(result["within"]) != 2 || len(result["other"]) != 2 {
+		t.Errorf("Expected groups within one checkout to be kept, Got: %v", result)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Group is a set of files with identical content. Files[0] is the copy that
+// is kept when duplicates are moved or deleted.
+type Group struct {
+	ID    string `json:"id"`
+	Hash  string `json:"hash"`
+	Files []File `json:"files"`
+}
+
+// groupIDLength is the number of hash characters used for a group ID.
+const groupIDLength = 12
+
+// groupID derives the ID of a duplicate group from its content hash, so the
+// same content gets the same ID in every scan, listing and results file.
+// A "-N" suffix added when a matcher plugin splits a group is preserved.
+func groupID(key string) string {
+	hash, suffix := key, ""
+	if i := strings.IndexByte(key, '-'); i >= 0 {
+		hash, suffix = key[:i], key[i:]
+	}
+	hash = hash[strings.LastIndexByte(hash, ':')+1:] // without the algorithm
+	if len(hash) > groupIDLength {
+		hash = hash[:groupIDLength]
+	}
+	return hash + suffix
+}
+
+// duplicateGroups returns the groups of fileMap that have more than one file,
+// ordered by ID.
+func duplicateGroups(fileMap map[string][]File) []Group {
+	var groups []Group
+	for key, files := range fileMap {
+		if len(files) > 1 {
+			groups = append(groups, Group{ID: groupID(key), Hash: files[0].Hash, Files: files})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+	return groups
+}
+
+// Waste returns the size of the redundant copies in the group.
+func (g Group) Waste() int64 {
+	var waste int64
+	for _, file := range g.Files[1:] {
+		waste += file.Size
+	}
+	return waste
+}
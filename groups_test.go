@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestGroupID(t *testing.T) {
+	if result := groupID("9c192053ffbc363705b13508c36566f6"); result != "9c192053ffbc" {
+		t.Errorf("Expected: 9c192053ffbc, Got: %s", result)
+	}
+	if result := groupID("sha256:ed7002b439e9ac845f22357d-1"); result != "ed7002b439e9-1" {
+		t.Errorf("Expected: ed7002b439e9-1, Got: %s", result)
+	}
+	if result := groupID("abc"); result != "abc" {
+		t.Errorf("Expected: abc, Got: %s", result)
+	}
+}
+
+func TestDuplicateGroups(t *testing.T) {
+	fileMap := map[string][]File{
+		"ffff00000000aaaa": {{path: indexPath("a"), Size: 10}, {path: indexPath("b"), Size: 10}, {path: indexPath("c"), Size: 10}},
+		"0000ffffffffbbbb": {{path: indexPath("d"), Size: 5}, {path: indexPath("e"), Size: 5}},
+		"1111111111111111": {{path: indexPath("unique"), Size: 1}},
+	}
+
+	groups := duplicateGroups(fileMap)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, Got: %d", len(groups))
+	}
+	if groups[0].ID != "0000ffffffff" || groups[1].ID != "ffff00000000" {
+		t.Errorf("Groups not ordered by ID: %s, %s", groups[0].ID, groups[1].ID)
+	}
+	if groups[1].Waste() != 20 {
+		t.Errorf("Expected waste: 20, Got: %d", groups[1].Waste())
+	}
+}
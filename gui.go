@@ -0,0 +1,195 @@
+package main
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+//go:embed gui.html
+var guiPage []byte
+
+// guiIdleTimeout is how long the gui command keeps running after the last
+// request from its window, which polls the status every second while open.
+const guiIdleTimeout = time.Minute
+
+// guiServer serves the web UI and runs one scan at a time on its behalf.
+type guiServer struct {
+	token string
+
+	mu       sync.Mutex
+	state    string // "idle", "scanning", "done" or "failed"
+	root     string
+	progress scanProgress
+	fileMap  map[string][]File
+	err      string
+	lastSeen time.Time
+}
+
+type guiStatus struct {
+	State    string       `json:"state"`
+	Root     string       `json:"root"`
+	Progress scanProgress `json:"progress"`
+	Error    string       `json:"error,omitempty"`
+}
+
+type guiResults struct {
+	Summary runSummary `json:"summary"`
+	Groups  []Group    `json:"groups"`
+}
+
+func newGUIServer() *guiServer {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		log.Fatal("Error:", err)
+	}
+	return &guiServer{token: hex.EncodeToString(token), state: "idle", lastSeen: time.Now()}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// api wraps an API handler with the token check that keeps other web pages
+// from driving the local server.
+func (s *guiServer) api(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != s.token {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		s.mu.Lock()
+		s.lastSeen = time.Now()
+		s.mu.Unlock()
+		handler(w, r)
+	}
+}
+
+func (s *guiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(guiPage)
+	})
+	mux.HandleFunc("/api/scan", s.api(s.handleScan))
+	mux.HandleFunc("/api/status", s.api(s.handleStatus))
+	mux.HandleFunc("/api/results", s.api(s.handleResults))
+	return mux
+}
+
+func (s *guiServer) handleScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Root string `json:"root"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Root == "" {
+		http.Error(w, "missing root", http.StatusBadRequest)
+		return
+	}
+	root := formatPath(req.Root)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		http.Error(w, fmt.Sprintf("not a folder: %s", root), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == "scanning" {
+		http.Error(w, "a scan is already running", http.StatusConflict)
+		return
+	}
+	s.state, s.root, s.progress, s.fileMap, s.err = "scanning", root, scanProgress{}, nil, ""
+	go s.scan(root)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *guiServer) scan(root string) {
+	fileMap, progress, err := scanFolder(root, func(p scanProgress) {
+		s.mu.Lock()
+		s.progress = p
+		s.mu.Unlock()
+	}, nil)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress = progress
+	s.fileMap = fileMap
+	s.state = "done"
+	if err != nil {
+		s.state, s.err = "failed", err.Error()
+	}
+}
+
+func (s *guiServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := guiStatus{State: s.state, Root: s.root, Progress: s.progress, Error: s.err}
+	s.mu.Unlock()
+	writeJSON(w, status)
+}
+
+func (s *guiServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != "done" {
+		http.Error(w, "no results", http.StatusNotFound)
+		return
+	}
+	groups := duplicateGroups(s.fileMap)
+	if groups == nil {
+		groups = []Group{}
+	}
+	writeJSON(w, guiResults{Summary: summarize(s.root, s.progress.Scanned, s.fileMap, s.progress.Errors), Groups: groups})
+}
+
+// idle reports whether the window has stopped polling the server.
+func (s *guiServer) idle() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state != "scanning" && time.Since(s.lastSeen) > guiIdleTimeout
+}
+
+// runGUI implements the gui command, which serves the web UI on localhost and
+// opens it in an application window.
+func runGUI(args []string) {
+	fs := flag.NewFlagSet("gui", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:0", "address of the local web UI")
+	noWindow := fs.Bool("no-window", false, "only print the URL instead of opening a window")
+	fs.Parse(args)
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	s := newGUIServer()
+	url := fmt.Sprintf("http://%s/#token=%s", listener.Addr(), s.token)
+	fmt.Println(msg("gui.url", url))
+	if !*noWindow {
+		if err := openAppWindow(url); err != nil {
+			log.Printf("Error opening window: %v", err)
+		}
+		go func() {
+			for range time.Tick(5 * time.Second) {
+				if s.idle() {
+					os.Exit(0)
+				}
+			}
+		}()
+	}
+	log.Fatal(http.Serve(listener, s.handler()))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGUIServer(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(tempDir, name), []byte("same"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newGUIServer()
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+
+	request := func(method, path, body, token string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Token", token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := request("GET", "/", "", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected page, Got: %s", resp.Status)
+	}
+	if resp := request("GET", "/api/status", "", "wrong"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected forbidden without token, Got: %s", resp.Status)
+	}
+	if resp := request("POST", "/api/scan", `{"root":"`+filepath.ToSlash(tempDir)+`"}`, s.token); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected scan to start, Got: %s", resp.Status)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	var status guiStatus
+	for time.Now().Before(deadline) {
+		resp := request("GET", "/api/status", "", s.token)
+		json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if status.State != "scanning" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status.State != "done" {
+		t.Fatalf("Expected finished scan, Got: %+v", status)
+	}
+
+	resp := request("GET", "/api/results", "", s.token)
+	var results guiResults
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if results.Summary.DuplicateGroups != 1 || len(results.Groups) != 1 || len(results.Groups[0].Files) != 2 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
+
+func TestAppWindowCommands(t *testing.T) {
+	for _, goos := range []string{"linux", "darwin", "windows"} {
+		commands := appWindowCommands(goos, "http://127.0.0.1:1/")
+		if len(commands) < 2 || !strings.Contains(strings.Join(commands[0].args, " "), "--app=http://127.0.0.1:1/") {
+			t.Errorf("Expected app window command first on %s, Got: %v", goos, commands)
+		}
+		last := commands[len(commands)-1].args
+		if last[len(last)-1] != "http://127.0.0.1:1/" {
+			t.Errorf("Expected default browser fallback on %s, Got: %v", goos, last)
+		}
+	}
+}
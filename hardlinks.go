@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// fileID identifies a file independently of its paths: two paths with the
+// same fileID are hard links to the same data.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// hardLinkGroup lists the paths below the scanned folder that are hard links
+// to the same file.
+type hardLinkGroup struct {
+	Paths []string `json:"paths"`
+	Size  int64    `json:"size"`
+	Links uint64   `json:"links"` // all links of the file, including those outside the folder
+	Saved int64    `json:"saved"` // the space separate copies for all paths would take in addition
+}
+
+// findHardLinks walks root and returns the files that have more than one
+// path below it, the ones saving the most space first.
+func findHardLinks(root string) ([]hardLinkGroup, error) {
+	byID := make(map[fileID]*hardLinkGroup)
+	var order []fileID
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		id, links, ok := fileIdentity(path, info)
+		if !ok || links < 2 {
+			return nil
+		}
+		group := byID[id]
+		if group == nil {
+			group = &hardLinkGroup{Size: info.Size(), Links: links}
+			byID[id] = group
+			order = append(order, id)
+		}
+		group.Paths = append(group.Paths, path)
+		return nil
+	})
+	var groups []hardLinkGroup
+	for _, id := range order {
+		if group := byID[id]; len(group.Paths) > 1 {
+			group.Saved = group.Size * int64(len(group.Paths)-1)
+			groups = append(groups, *group)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Saved > groups[j].Saved })
+	return groups, err
+}
+
+// linkMover moves files so that paths which are hard links to each other
+// stay hard links at the destination: the first path of a file is moved, the
+// others are linked to where it went. A move that separates a file from
+// links that are not moved with it stores its data twice, which is logged.
+type linkMover struct {
+	moving map[fileID]int    // paths of each file among the moves
+	moved  map[fileID]string // destination of the first moved path of a file
+}
+
+// newLinkMover prepares the moves of the source paths.
+func newLinkMover(sources []string) *linkMover {
+	m := &linkMover{moving: make(map[fileID]int), moved: make(map[fileID]string)}
+	for _, source := range sources {
+		if id, links, ok := linkIdentity(source); ok && links > 1 {
+			m.moving[id]++
+		}
+	}
+	return m
+}
+
+// move moves source to dest like moveFile.
+func (m *linkMover) move(source, dest string) error {
+	if err := checkWritable("move", source); err != nil {
+		return err
+	}
+	id, links, ok := linkIdentity(source)
+	if !ok || links < 2 {
+		return moveFile(source, dest)
+	}
+	if first, seen := m.moved[id]; seen {
+		if err := os.Link(first, dest); err == nil {
+			return os.Remove(source)
+		}
+	} else if others := int(links) - m.moving[id]; others > 0 && !sameVolume(source, dest) {
+		log.Printf("Moving %s to another volume breaks its hard links with %d other paths, which keep their own copy of the data", source, others)
+	}
+	if err := moveFile(source, dest); err != nil {
+		return err
+	}
+	m.moved[id] = dest
+	return nil
+}
+
+// linkIdentity returns the fileID of path and its number of hard links.
+func linkIdentity(path string) (fileID, uint64, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileID{}, 0, false
+	}
+	return fileIdentity(path, info)
+}
+
+// sameVolume reports whether a file can be renamed to dest, whose folder may
+// not exist yet. It is true if the volumes cannot be told.
+func sameVolume(path, dest string) bool {
+	id, _, ok := linkIdentity(path)
+	dirID, _, dirOK := linkIdentity(existingDir(filepath.Dir(dest)))
+	return !ok || !dirOK || id.dev == dirID.dev
+}
+
+// writeHardLinks lists the hard-linked files followed by their totals.
+func writeHardLinks(w io.Writer, groups []hardLinkGroup) {
+	if len(groups) == 0 {
+		fmt.Fprintln(w, msg("hardlinks.none"))
+		return
+	}
+	var paths int
+	var saved int64
+	for _, group := range groups {
+		fmt.Fprintln(w, msg("hardlinks.group", formatCount(int64(len(group.Paths))), humanReadableSize(group.Size), formatCount(int64(group.Links)), humanReadableSize(group.Saved)))
+		for _, path := range group.Paths {
+			fmt.Fprintln(w, path)
+		}
+		fmt.Fprintln(w)
+		paths += len(group.Paths)
+		saved += group.Saved
+	}
+	fmt.Fprintln(w, msg("hardlinks.total", formatCount(int64(len(groups))), formatCount(int64(paths)), humanReadableSize(saved)))
+}
+
+// runHardLinks implements the hardlinks command, which reports the files that
+// already share their data through hard links.
+func runHardLinks(args []string) {
+	fs := flag.NewFlagSet("hardlinks", flag.ExitOnError)
+	fs.StringVar(&sizeUnits, "units", sizeUnits, "size units: iec (1024-based, KiB/MiB) or si (1000-based, KB/MB)")
+	asJSON := fs.Bool("json", false, "print the hard-linked files as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s hardlinks [flags] folder\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	groups, err := findHardLinks(formatPath(fs.Arg(0)))
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	if *asJSON {
+		if groups == nil {
+			groups = []hardLinkGroup{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(groups)
+		return
+	}
+	writeHardLinks(os.Stdout, groups)
+}
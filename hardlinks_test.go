@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindHardLinks(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	big := filepath.Join(tempDir, "big")
+	small := filepath.Join(tempDir, "small")
+	ioutil.WriteFile(big, make([]byte, 2048), 0644)
+	ioutil.WriteFile(small, make([]byte, 10), 0644)
+	ioutil.WriteFile(filepath.Join(tempDir, "single"), make([]byte, 4096), 0644)
+	os.Mkdir(filepath.Join(tempDir, "sub"), 0755)
+	for _, link := range []string{"sub/big1", "sub/big2"} {
+		if err := os.Link(big, filepath.Join(tempDir, filepath.FromSlash(link))); err != nil {
+			t.Skipf("Hard links are not supported: %v", err)
+		}
+	}
+	os.Link(small, filepath.Join(tempDir, "small1"))
+
+	groups, err := findHardLinks(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, Got: %+v", groups)
+	}
+	if len(groups[0].Paths) != 3 || groups[0].Size != 2048 || groups[0].Links != 3 || groups[0].Saved != 4096 {
+		t.Errorf("Unexpected first group: %+v", groups[0])
+	}
+	if len(groups[1].Paths) != 2 || groups[1].Saved != 10 {
+		t.Errorf("Unexpected second group: %+v", groups[1])
+	}
+
+	var out bytes.Buffer
+	writeHardLinks(&out, groups)
+	expected := []string{
+		"3 paths share one file of 2.00 KiB (3 links in total), saving 4.00 KiB:\n" + big + "\n",
+		"2 hard-linked files with 5 paths save 4.01 KiB.",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Expected output to contain %q, Got: %s", s, out.String())
+		}
+	}
+}
+
+func TestWriteHardLinksNone(t *testing.T) {
+	var out bytes.Buffer
+	writeHardLinks(&out, nil)
+	if out.String() != "No hard-linked files found.\n" {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+}
+
+func TestLinkMover(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	original := filepath.Join(tempDir, "original")
+	ioutil.WriteFile(original, []byte("content"), 0644)
+	sources := []string{filepath.Join(tempDir, "link1"), filepath.Join(tempDir, "link2")}
+	for _, source := range sources {
+		if err := os.Link(original, source); err != nil {
+			t.Skipf("Hard links are not supported: %v", err)
+		}
+	}
+	archive := filepath.Join(tempDir, "archive")
+	os.Mkdir(archive, 0755)
+
+	links := newLinkMover(sources)
+	var ids []fileID
+	for _, source := range sources {
+		dest := filepath.Join(archive, filepath.Base(source))
+		if err := links.move(source, dest); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(source); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be moved, Got: %v", source, err)
+		}
+		id, _, _ := linkIdentity(dest)
+		ids = append(ids, id)
+	}
+	if id, _, _ := linkIdentity(original); ids[0] != id || ids[1] != id {
+		t.Errorf("Expected the moved paths to stay hard links of %s", original)
+	}
+	if !sameVolume(original, filepath.Join(archive, "new", "file")) {
+		t.Errorf("Expected %s and %s to be on the same volume", original, archive)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+)
+
+// hashAlgorithm is a content hash files can be compared by. The standard
+// library implements the cryptographic ones in assembly, using the SHA
+// extensions and AVX2 of amd64 and the SHA instructions of arm64 where the
+// CPU has them.
+type hashAlgorithm struct {
+	name string
+	new  func() hash.Hash
+	fast bool // not collision resistant; duplicates are confirmed
+}
+
+// hashAlgorithms are the algorithms of --hash. Hashes other than MD5 are
+// prefixed with the algorithm name, e.g. "sha256:9f86d0...", so a saved
+// result or plan can be checked with the algorithm it was made with.
+var hashAlgorithms = []hashAlgorithm{
+	{"md5", md5.New, false},
+	{"sha1", sha1.New, false},
+	{"sha256", sha256.New, false},
+	{"sha512", sha512.New, false},
+	{"xxh64", newXXH64, true},
+}
+
+// scanHash is the algorithm files are compared by in this run, the first of
+// --hash. extraHashes are the others, which are only recorded as digests,
+// e.g. SHA-256 for an integrity baseline.
+var (
+	scanHash    = hashAlgorithms[0]
+	extraHashes []hashAlgorithm
+)
+
+// parseHashList parses a comma-separated list of algorithms such as
+// "md5,sha256".
+func parseHashList(list string) (hashAlgorithm, []hashAlgorithm, error) {
+	var algorithms []hashAlgorithm
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		a, err := findHashAlgorithm(strings.TrimSpace(name))
+		if err != nil {
+			return hashAlgorithm{}, nil, err
+		}
+		if seen[a.name] {
+			return hashAlgorithm{}, nil, fmt.Errorf("hash %s is given twice", a.name)
+		}
+		seen[a.name] = true
+		algorithms = append(algorithms, a)
+	}
+	return algorithms[0], algorithms[1:], nil
+}
+
+// findHashAlgorithm returns the algorithm called name, or the fastest one on
+// this machine for "auto".
+func findHashAlgorithm(name string) (hashAlgorithm, error) {
+	if name == "auto" {
+		return fastestHash(), nil
+	}
+	var names []string
+	for _, a := range hashAlgorithms {
+		if a.name == name {
+			return a, nil
+		}
+		names = append(names, a.name)
+	}
+	return hashAlgorithm{}, fmt.Errorf("unknown hash %q (expected auto, %s)", name, strings.Join(names, ", "))
+}
+
+// fastestHash measures the throughput of every algorithm on this CPU and
+// returns the fastest. An algorithm earlier in hashAlgorithms is kept unless
+// a later one is clearly faster, so close results pick the same one on
+// every run.
+func fastestHash() hashAlgorithm {
+	data := make([]byte, 1<<20)
+	best, bestRate := hashAlgorithms[0], 0.0
+	for _, a := range hashAlgorithms {
+		h := a.new()
+		h.Write(data) // warm up
+		start := time.Now()
+		n := 0
+		for time.Since(start) < 20*time.Millisecond {
+			h.Write(data)
+			n++
+		}
+		rate := float64(n) / time.Since(start).Seconds()
+		if rate > bestRate*1.25 {
+			best, bestRate = a, rate
+		}
+	}
+	return best
+}
+
+// format returns the hash string of sum.
+func (a hashAlgorithm) format(sum []byte) string {
+	if a.name == "md5" {
+		return hex.EncodeToString(sum)
+	}
+	return a.name + ":" + hex.EncodeToString(sum)
+}
+
+// multiHash computes the hash of one algorithm and the digests of others
+// from the same writes.
+type multiHash struct {
+	io.Writer
+	algorithm hashAlgorithm
+	hash      hash.Hash
+	extra     []hashAlgorithm
+	hashes    []hash.Hash
+}
+
+func newMultiHash(algorithm hashAlgorithm, extra []hashAlgorithm) *multiHash {
+	m := &multiHash{algorithm: algorithm, hash: algorithm.new(), extra: extra}
+	m.Writer = m.hash
+	if len(extra) > 0 {
+		writers := []io.Writer{m.hash}
+		for _, a := range extra {
+			h := a.new()
+			m.hashes = append(m.hashes, h)
+			writers = append(writers, h)
+		}
+		m.Writer = io.MultiWriter(writers...)
+	}
+	return m
+}
+
+// sum returns the hash string of the main algorithm.
+func (m *multiHash) sum() string {
+	return m.algorithm.format(m.hash.Sum(nil))
+}
+
+// digests returns the hex digests of the extra algorithms, or nil if there
+// are none.
+func (m *multiHash) digests() map[string]string {
+	if len(m.extra) == 0 {
+		return nil
+	}
+	digests := make(map[string]string, len(m.extra))
+	for i, a := range m.extra {
+		digests[a.name] = hex.EncodeToString(m.hashes[i].Sum(nil))
+	}
+	return digests
+}
+
+// hashAlgorithmOf returns the algorithm a hash string was made with.
+func hashAlgorithmOf(hash string) hashAlgorithm {
+	hash = strings.TrimPrefix(hash, sampledPrefix)
+	if colon := strings.IndexByte(hash, ':'); colon >= 0 {
+		for _, a := range hashAlgorithms {
+			if a.name == hash[:colon] {
+				return a
+			}
+		}
+	}
+	return hashAlgorithms[0]
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindHashAlgorithm(t *testing.T) {
+	for _, name := range []string{"md5", "sha1", "sha256", "sha512", "xxh64"} {
+		a, err := findHashAlgorithm(name)
+		if err != nil || a.name != name {
+			t.Errorf("Expected: %s, Got: %s (%v)", name, a.name, err)
+		}
+	}
+	if a, err := findHashAlgorithm("auto"); err != nil || a.new == nil {
+		t.Errorf("Expected an algorithm for auto, Got: %q (%v)", a.name, err)
+	}
+	if _, err := findHashAlgorithm("crc7"); err == nil {
+		t.Errorf("Expected an error for an unknown hash")
+	}
+}
+
+func TestHashAlgorithmFormat(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		expected  string
+	}{
+		{"md5", "9a0364b9e99bb480dd25e1f0284c8555"},
+		{"sha1", "sha1:040f06fd774092478d450774f5ba30c5da78acc8"},
+		{"sha256", "sha256:ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"},
+	}
+	for _, test := range tests {
+		a, _ := findHashAlgorithm(test.algorithm)
+		h := a.new()
+		h.Write([]byte("content"))
+		if result := a.format(h.Sum(nil)); result != test.expected {
+			t.Errorf("Expected: %s, Got: %s", test.expected, result)
+		}
+		if of := hashAlgorithmOf(test.expected); of.name != test.algorithm {
+			t.Errorf("Expected: %s, Got: %s", test.algorithm, of.name)
+		}
+	}
+}
+
+func TestParseHashList(t *testing.T) {
+	main, extra, err := parseHashList("md5, sha256,sha512")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if main.name != "md5" || len(extra) != 2 || extra[0].name != "sha256" || extra[1].name != "sha512" {
+		t.Errorf("Expected: md5 with sha256 and sha512, Got: %s with %v", main.name, extra)
+	}
+	for _, list := range []string{"md5,md5", "md5,", "sha3"} {
+		if _, _, err := parseHashList(list); err == nil {
+			t.Errorf("Expected an error for %q", list)
+		}
+	}
+}
+
+func TestHashFileDigests(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+	ioutil.WriteFile(path, []byte("content"), 0644)
+
+	md5, extra, _ := parseHashList("md5,sha1,sha256")
+	file, err := hashFileWith(path, md5, extra...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Hash != "9a0364b9e99bb480dd25e1f0284c8555" {
+		t.Errorf("Expected: 9a0364b9e99bb480dd25e1f0284c8555, Got: %s", file.Hash)
+	}
+	expected := map[string]string{
+		"sha1":   "040f06fd774092478d450774f5ba30c5da78acc8",
+		"sha256": "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73",
+	}
+	if !reflect.DeepEqual(file.Digests, expected) {
+		t.Errorf("Expected: %v, Got: %v", expected, file.Digests)
+	}
+
+	if file, _ := hashFileWith(path, md5); file.Digests != nil {
+		t.Errorf("Expected no digests, Got: %v", file.Digests)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // decoder for image.Decode
+	"image/png"
+	"io"
+	"math/bits"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// heicJPEG, set by --heic-jpeg, is how HEIC photos and JPEG exports of the
+// same image are handled: "" ignores them, "ask" asks which format to keep
+// for every pair and "heic" or "jpeg" keeps that format.
+var heicJPEG string
+
+// heicJPEGModes are the valid values of --heic-jpeg.
+var heicJPEGModes = []string{"ask", "heic", "jpeg"}
+
+// sameImageDistance is the largest number of differing bits of the
+// perceptual hashes of two images that are considered the same image.
+const sameImageDistance = 10
+
+// imageGrid is the mean brightness of an image in 9 by 9 cells, from which
+// its perceptual hash is computed.
+type imageGrid [9][9]float64
+
+// gridOf returns the grid of img. Large images are sampled at up to 256
+// points per side.
+func gridOf(img image.Image) imageGrid {
+	var grid imageGrid
+	var counts [9][9]int
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return grid
+	}
+	stepX, stepY := w/256+1, h/256+1
+	for y := 0; y < h; y += stepY {
+		for x := 0; x < w; x += stepX {
+			gray := color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray)
+			cx, cy := x*9/w, y*9/h
+			grid[cy][cx] += float64(gray.Y)
+			counts[cy][cx]++
+		}
+	}
+	for y := range grid {
+		for x := range grid[y] {
+			if counts[y][x] > 0 {
+				grid[y][x] /= float64(counts[y][x])
+			}
+		}
+	}
+	return grid
+}
+
+// rotate returns the grid turned by 90 degrees.
+func (g imageGrid) rotate() imageGrid {
+	var r imageGrid
+	for y := range g {
+		for x := range g[y] {
+			r[x][len(g)-1-y] = g[y][x]
+		}
+	}
+	return r
+}
+
+// dhash returns the difference hash of the grid: one bit for each pair of
+// neighbouring cells in its first 8 rows, set if the left one is brighter.
+func (g imageGrid) dhash() uint64 {
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if g[y][x] > g[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// imageDistance returns the number of bits in which the perceptual hashes of
+// two images differ, with the second one turned the way it matches best,
+// since exports are often rotated while originals only record an
+// orientation.
+func imageDistance(a, b imageGrid) int {
+	best := 64
+	hash := a.dhash()
+	for i := 0; i < 4; i++ {
+		if d := bits.OnesCount64(hash ^ b.dhash()); d < best {
+			best = d
+		}
+		b = b.rotate()
+	}
+	return best
+}
+
+// decodeHEIC decodes a HEIC image, scaled down, with ffmpeg.
+var decodeHEIC = func(path string) (image.Image, error) {
+	out, err := exec.Command("ffmpeg", "-v", "error", "-i", path, "-frames:v", "1",
+		"-vf", "scale=256:256", "-f", "image2pipe", "-c:v", "png", "-").Output()
+	if err != nil {
+		return nil, err
+	}
+	return png.Decode(bytes.NewReader(out))
+}
+
+// decodeImage decodes a HEIF or JPEG image.
+func decodeImage(path string) (image.Image, error) {
+	if isHEIF(path) {
+		return decodeHEIC(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(bufio.NewReader(f))
+	return img, err
+}
+
+// formatPair is a HEIC photo and a JPEG file that show the same image.
+type formatPair struct {
+	HEIC, JPEG File
+}
+
+// sameImagePairs finds the HEIC photos of fileMap that have a JPEG export in
+// it: a JPEG file with the same camera and capture time in its EXIF data
+// whose perceptual hash is close to that of the photo. Only the first file
+// of each group is considered.
+func sameImagePairs(fileMap map[string][]File) []formatPair {
+	type shots struct{ heics, jpegs []File }
+	byShot := make(map[string]*shots)
+	for _, files := range fileMap {
+		file := files[0]
+		if !isHEIF(file.Path()) && !isJPEG(file.Path()) {
+			continue
+		}
+		info, err := readExif(file.Path())
+		if err != nil || info.DateTimeOriginal == "" {
+			continue
+		}
+		key := info.DateTimeOriginal + "\x00" + info.Make + "\x00" + info.Model
+		s := byShot[key]
+		if s == nil {
+			s = &shots{}
+			byShot[key] = s
+		}
+		if isHEIF(file.Path()) {
+			s.heics = append(s.heics, file)
+		} else {
+			s.jpegs = append(s.jpegs, file)
+		}
+	}
+
+	grids := make(map[string]*imageGrid) // nil if the image cannot be decoded
+	grid := func(path string) *imageGrid {
+		if g, ok := grids[path]; ok {
+			return g
+		}
+		var g *imageGrid
+		if img, err := decodeImage(path); err == nil {
+			computed := gridOf(img)
+			g = &computed
+		}
+		grids[path] = g
+		return g
+	}
+	var pairs []formatPair
+	for _, s := range byShot {
+		if len(s.heics) == 0 || len(s.jpegs) == 0 {
+			continue
+		}
+		sort.Slice(s.heics, func(i, j int) bool { return s.heics[i].Path() < s.heics[j].Path() })
+		sort.Slice(s.jpegs, func(i, j int) bool { return s.jpegs[i].Path() < s.jpegs[j].Path() })
+		used := make(map[string]bool)
+		for _, heic := range s.heics {
+			h := grid(heic.Path())
+			if h == nil {
+				continue
+			}
+			best, bestDistance := -1, sameImageDistance+1
+			for i, jpeg := range s.jpegs {
+				if used[jpeg.Path()] {
+					continue
+				}
+				if j := grid(jpeg.Path()); j != nil {
+					if d := imageDistance(*h, *j); d < bestDistance {
+						best, bestDistance = i, d
+					}
+				}
+			}
+			if best >= 0 {
+				used[s.jpegs[best].Path()] = true
+				pairs = append(pairs, formatPair{heic, s.jpegs[best]})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].HEIC.Path() < pairs[j].HEIC.Path() })
+	return pairs
+}
+
+// chooseFormats adds a group to fileMap for every pair whose HEIC or JPEG
+// file is to be kept, asking for each pair with mode "ask", so the actions
+// remove the other format. It returns the number of groups added.
+func chooseFormats(w io.Writer, scanner *bufio.Scanner, fileMap map[string][]File, pairs []formatPair, mode string) int {
+	if len(pairs) == 0 {
+		return 0
+	}
+	fmt.Fprintln(w, msg("heicjpeg.found", formatCount(int64(len(pairs)))))
+	added := 0
+	for _, pair := range pairs {
+		keep := mode
+		if mode == "ask" {
+			fmt.Fprintln(w, msg("heicjpeg.pair", pair.HEIC.Path(), humanReadableSize(pair.HEIC.Size), pair.JPEG.Path(), humanReadableSize(pair.JPEG.Size)))
+			fmt.Fprint(w, msg("heicjpeg.prompt"))
+			if !scanner.Scan() {
+				fmt.Fprintln(w)
+				break
+			}
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "h":
+				keep = "heic"
+			case "j":
+				keep = "jpeg"
+			default:
+				continue
+			}
+		}
+		files := []File{pair.HEIC, pair.JPEG}
+		if keep == "jpeg" {
+			files = []File{pair.JPEG, pair.HEIC}
+		}
+		fileMap[pathsKey("format", files)] = files
+		added++
+	}
+	return added
+}
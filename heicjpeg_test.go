@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPattern returns an image with some structure; inverted returns its
+// negative.
+func testPattern(inverted bool) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 90, 72))
+	for y := 0; y < 72; y++ {
+		for x := 0; x < 90; x++ {
+			v := uint8((x*x + 3*y*x/4 + y*y*2) % 256)
+			if inverted {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{v})
+		}
+	}
+	return img
+}
+
+// rotated returns img turned by 90 degrees.
+func rotated(img *image.Gray) *image.Gray {
+	b := img.Bounds()
+	r := image.NewGray(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			r.SetGray(b.Dy()-1-y, x, img.GrayAt(x, y))
+		}
+	}
+	return r
+}
+
+// testJPEGWithExif encodes img as a JPEG with the EXIF data of testExifJPEG.
+func testJPEGWithExif(t *testing.T, img image.Image, cameraMake, taken string) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatal(err)
+	}
+	payload := append([]byte("Exif\x00\x00"), testExifTIFF(t, cameraMake, taken)...)
+	var out bytes.Buffer
+	out.Write([]byte{0xff, 0xd8, 0xff, 0xe1})
+	binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(encoded.Bytes()[2:])
+	return out.Bytes()
+}
+
+func TestImageDistance(t *testing.T) {
+	original := gridOf(testPattern(false))
+	var compressed bytes.Buffer
+	jpeg.Encode(&compressed, testPattern(false), &jpeg.Options{Quality: 30})
+	decoded, _ := jpeg.Decode(&compressed)
+
+	tests := []struct {
+		name  string
+		img   image.Image
+		close bool
+	}{
+		{"same", testPattern(false), true},
+		{"compressed", decoded, true},
+		{"rotated", rotated(testPattern(false)), true},
+		{"inverted", testPattern(true), false},
+	}
+	for _, test := range tests {
+		d := imageDistance(original, gridOf(test.img))
+		if (d <= sameImageDistance) != test.close {
+			t.Errorf("%s: Unexpected distance %d", test.name, d)
+		}
+	}
+}
+
+func TestSameImagePairs(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	defer func(decode func(string) (image.Image, error)) { decodeHEIC = decode }(decodeHEIC)
+	decodeHEIC = func(string) (image.Image, error) { return testPattern(false), nil }
+
+	const taken = "2024:05:01 10:00:00"
+	files := map[string][]byte{
+		"IMG_1.HEIC":      testHEIF(t, testExifTIFF(t, "Apple", taken)),
+		"IMG_1.jpg":       testJPEGWithExif(t, rotated(testPattern(false)), "Apple", taken),
+		"other.jpg":       testJPEGWithExif(t, testPattern(true), "Apple", taken),
+		"later.jpg":       testJPEGWithExif(t, testPattern(false), "Apple", "2024:05:01 10:00:05"),
+		"other-phone.jpg": testJPEGWithExif(t, testPattern(false), "Google", taken),
+	}
+	fileMap := make(map[string][]File)
+	for name, data := range files {
+		path := filepath.Join(tempDir, name)
+		ioutil.WriteFile(path, data, 0644)
+		fileMap[name] = []File{{path: indexPath(path), Size: int64(len(data))}}
+	}
+
+	pairs := sameImagePairs(fileMap)
+	if len(pairs) != 1 || filepath.Base(pairs[0].HEIC.Path()) != "IMG_1.HEIC" || filepath.Base(pairs[0].JPEG.Path()) != "IMG_1.jpg" {
+		t.Fatalf("Unexpected pairs: %+v", pairs)
+	}
+
+	var out bytes.Buffer
+	if n := chooseFormats(&out, bufio.NewScanner(strings.NewReader("j\n")), fileMap, pairs, "ask"); n != 1 {
+		t.Errorf("Expected: 1 group, Got: %d", n)
+	}
+	group := fileMap[pathsKey("format", []File{pairs[0].JPEG, pairs[0].HEIC})]
+	if len(group) != 2 || group[0].Path() != pairs[0].JPEG.Path() {
+		t.Errorf("Expected a group keeping the JPEG, Got: %v", group)
+	}
+	if n := chooseFormats(&out, bufio.NewScanner(strings.NewReader("b\n")), map[string][]File{}, pairs, "ask"); n != 0 {
+		t.Errorf("Expected both formats to be kept, Got: %d groups", n)
+	}
+	kept := map[string][]File{}
+	chooseFormats(&out, nil, kept, pairs, "heic")
+	if group := kept[pathsKey("format", []File{pairs[0].HEIC, pairs[0].JPEG})]; len(group) != 2 {
+		t.Errorf("Expected a group keeping the HEIC, Got: %v", kept)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// heifBox is an ISO base media file format box: its type and its content,
+// which starts at offset and is size bytes long.
+type heifBox struct {
+	typ          string
+	offset, size int64
+}
+
+// heifBoxes lists the boxes in the size bytes of r from offset.
+func heifBoxes(r io.ReaderAt, offset, size int64) []heifBox {
+	var boxes []heifBox
+	end := offset + size
+	for offset+8 <= end {
+		var header [16]byte
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			break
+		}
+		length, headerSize := int64(binary.BigEndian.Uint32(header[:4])), int64(8)
+		switch length {
+		case 0: // to the end
+			length = end - offset
+		case 1: // 64-bit size
+			if _, err := r.ReadAt(header[8:], offset+8); err != nil {
+				return boxes
+			}
+			length, headerSize = int64(binary.BigEndian.Uint64(header[8:])), 16
+		}
+		if length < headerSize || offset+length > end {
+			break
+		}
+		boxes = append(boxes, heifBox{string(header[4:8]), offset + headerSize, length - headerSize})
+		offset += length
+	}
+	return boxes
+}
+
+// heifReader reads the big-endian fields of a box.
+type heifReader struct {
+	data []byte
+	err  bool
+}
+
+func (r *heifReader) uint(n int) uint64 {
+	if n == 0 {
+		return 0
+	}
+	if len(r.data) < n {
+		r.err, r.data = true, nil
+		return 0
+	}
+	var v uint64
+	for _, b := range r.data[:n] {
+		v = v<<8 | uint64(b)
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+// readHEIFExif reads the EXIF metadata of a HEIF file such as a HEIC photo:
+// the Exif item listed in the meta box, which holds a TIFF structure after
+// a 4 byte offset.
+func readHEIFExif(r io.ReaderAt, size int64) (exifInfo, error) {
+	var meta *heifBox
+	for _, box := range heifBoxes(r, 0, size) {
+		if box.typ == "meta" {
+			meta = &box
+			break
+		}
+	}
+	if meta == nil || meta.size < 4 || meta.size > 16<<20 {
+		return exifInfo{}, errNoExif
+	}
+	var exifItem uint64
+	found := false
+	var iloc []byte
+	for _, box := range heifBoxes(r, meta.offset+4, meta.size-4) { // after version and flags
+		switch box.typ {
+		case "iinf":
+			id, ok := heifExifItem(r, box)
+			exifItem, found = id, ok
+		case "iloc":
+			iloc = make([]byte, box.size)
+			if _, err := r.ReadAt(iloc, box.offset); err != nil {
+				return exifInfo{}, errNoExif
+			}
+		}
+	}
+	if !found || iloc == nil {
+		return exifInfo{}, errNoExif
+	}
+	offset, length, ok := heifItemLocation(iloc, exifItem)
+	if !ok || length < 8 || length > 16<<20 {
+		return exifInfo{}, errNoExif
+	}
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, int64(offset)); err != nil {
+		return exifInfo{}, errNoExif
+	}
+	start := 4 + uint64(binary.BigEndian.Uint32(data))
+	if start >= uint64(len(data)) {
+		return exifInfo{}, errNoExif
+	}
+	return parseTIFFExif(data[start:])
+}
+
+// heifExifItem returns the ID of the Exif item of an iinf box.
+func heifExifItem(r io.ReaderAt, iinf heifBox) (uint64, bool) {
+	var header [8]byte
+	if iinf.size < 6 {
+		return 0, false
+	}
+	if _, err := r.ReadAt(header[:4], iinf.offset); err != nil {
+		return 0, false
+	}
+	countSize := int64(2)
+	if header[0] > 0 { // version
+		countSize = 4
+	}
+	for _, infe := range heifBoxes(r, iinf.offset+4+countSize, iinf.size-4-countSize) {
+		if infe.typ != "infe" || infe.size < 12 || infe.size > 4096 {
+			continue
+		}
+		data := make([]byte, infe.size)
+		if _, err := r.ReadAt(data, infe.offset); err != nil {
+			continue
+		}
+		version := data[0]
+		fields := &heifReader{data: data[4:]}
+		idSize := 2
+		switch {
+		case version < 2:
+			continue
+		case version > 2:
+			idSize = 4
+		}
+		id := fields.uint(idSize)
+		fields.uint(2) // protection index
+		typ := fields.data
+		if !fields.err && len(typ) >= 4 && string(typ[:4]) == "Exif" {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// heifItemLocation returns where the data of an item is in the file,
+// according to an iloc box. Only items stored in one extent of the file
+// itself are supported.
+func heifItemLocation(iloc []byte, item uint64) (offset, length uint64, ok bool) {
+	r := &heifReader{data: iloc}
+	version := r.uint(1)
+	r.uint(3) // flags
+	sizes := r.uint(2)
+	offsetSize, lengthSize := int(sizes>>12), int(sizes>>8&0xf)
+	baseOffsetSize, indexSize := int(sizes>>4&0xf), 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0xf)
+	}
+	countSize, idSize := 2, 2
+	if version == 2 {
+		countSize, idSize = 4, 4
+	}
+	count := r.uint(countSize)
+	for i := uint64(0); i < count && !r.err; i++ {
+		id := r.uint(idSize)
+		method := uint64(0)
+		if version == 1 || version == 2 {
+			method = r.uint(2) & 0xf
+		}
+		r.uint(2) // data reference index
+		base := r.uint(baseOffsetSize)
+		extents := r.uint(2)
+		for e := uint64(0); e < extents && !r.err; e++ {
+			r.uint(indexSize)
+			extentOffset, extentLength := r.uint(offsetSize), r.uint(lengthSize)
+			if id == item && e == 0 {
+				offset, length = base+extentOffset, extentLength
+			}
+		}
+		if id == item {
+			return offset, length, !r.err && method == 0 && extents == 1
+		}
+	}
+	return 0, 0, false
+}
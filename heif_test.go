@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testExifTIFF returns the TIFF structure of the EXIF data of testExifJPEG.
+func testExifTIFF(t *testing.T, cameraMake, taken string) []byte {
+	t.Helper()
+	jpeg := testExifJPEG(t, binary.BigEndian, cameraMake, taken)
+	length := int(binary.BigEndian.Uint16(jpeg[4:]))
+	return jpeg[4+2+6 : 4+length]
+}
+
+// testHEIF returns a HEIF file whose meta box lists an Exif item with the
+// given TIFF structure, stored in the mdat box.
+func testHEIF(t *testing.T, tiff []byte) []byte {
+	t.Helper()
+	box := func(typ string, content ...[]byte) []byte {
+		data := bytes.Join(content, nil)
+		var b bytes.Buffer
+		binary.Write(&b, binary.BigEndian, uint32(8+len(data)))
+		b.WriteString(typ)
+		b.Write(data)
+		return b.Bytes()
+	}
+	u16 := func(v uint16) []byte {
+		b := make([]byte, 2)
+		binary.BigEndian.PutUint16(b, v)
+		return b
+	}
+	u32 := func(v uint32) []byte {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, v)
+		return b
+	}
+
+	item := append(u32(0), tiff...)
+	ftyp := box("ftyp", []byte("heic"), u32(0), []byte("mif1heic"))
+	iinf := box("iinf", u32(0), u16(2),
+		box("infe", []byte{2, 0, 0, 0}, u16(1), u16(0), []byte("hvc1")),
+		box("infe", []byte{2, 0, 0, 0}, u16(2), u16(0), []byte("Exif")))
+	// Version 0 with 4 byte offsets and lengths, no base offsets.
+	meta := func(exifOffset uint32) []byte {
+		iloc := box("iloc", u32(0), []byte{0x44, 0x00}, u16(2),
+			u16(1), u16(0), u16(1), u32(0), u32(0),
+			u16(2), u16(0), u16(1), u32(exifOffset), u32(uint32(len(item))))
+		return box("meta", u32(0), iinf, iloc)
+	}
+	offset := uint32(len(ftyp) + len(meta(0)) + 8)
+	return bytes.Join([][]byte{ftyp, meta(offset), box("mdat", item)}, nil)
+}
+
+func TestReadHEIFExif(t *testing.T) {
+	data := testHEIF(t, testExifTIFF(t, "Apple", "2024:05:01 10:00:00"))
+	info, err := readHEIFExif(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Make != "Apple" || info.DateTimeOriginal != "2024:05:01 10:00:00" {
+		t.Errorf("Unexpected EXIF data: %+v", info)
+	}
+
+	for _, broken := range [][]byte{nil, []byte("not a HEIF file"), data[:len(data)-20]} {
+		if _, err := readHEIFExif(bytes.NewReader(broken), int64(len(broken))); err != errNoExif {
+			t.Errorf("Expected: %v, Got: %v", errNoExif, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// historyFile is the name of the scan history in the state directory. It
+// holds one JSON encoded runSummary per line, oldest first.
+const historyFile = "history.jsonl"
+
+// historyBarWidth is the width of the bar showing the waste of the largest scan.
+const historyBarWidth = 30
+
+func historyPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, historyFile), nil
+}
+
+// appendHistory adds a scan or cleanup summary to the history at path.
+func appendHistory(path string, summary runSummary) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadHistory reads the history at path. Lines that cannot be parsed, e.g.
+// after an interrupted write, are skipped.
+func loadHistory(path string) ([]runSummary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []runSummary
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var entry runSummary
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Printf("Error reading %s line %d: %v", path, line, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// signedSize formats a change in bytes with an explicit sign.
+func signedSize(bytes int64) string {
+	if bytes < 0 {
+		return "-" + humanReadableSize(-bytes)
+	}
+	return "+" + humanReadableSize(bytes)
+}
+
+// writeHistory writes the history as a table: the waste found by every scan
+// with its change since the previous scan of the same root, and the space
+// reclaimed by every cleanup. Only entries for root are shown unless it is "".
+func writeHistory(w io.Writer, entries []runSummary, root string) {
+	var largest int64
+	for _, e := range entries {
+		if e.Event == "scan" && (root == "" || e.Root == root) && e.ReclaimableBytes > largest {
+			largest = e.ReclaimableBytes
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, msg("history.header"))
+	previous := make(map[string]int64)
+	for _, e := range entries {
+		if root != "" && e.Root != root {
+			continue
+		}
+		date := e.Time.Local().Format("2006-01-02 15:04")
+		if e.Event == "cleanup" {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\t\t%s\t\n", date, e.Root, e.Action, signedSize(-e.BytesReclaimed))
+			continue
+		}
+		change := ""
+		if last, ok := previous[e.Root]; ok {
+			change = signedSize(e.ReclaimableBytes - last)
+		}
+		previous[e.Root] = e.ReclaimableBytes
+		bar := ""
+		if largest > 0 {
+			bar = strings.Repeat("#", int(e.ReclaimableBytes*historyBarWidth/largest))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", date, e.Root, e.Event, formatCount(int64(e.DuplicateGroups)), humanReadableSize(e.ReclaimableBytes), change, bar)
+	}
+	tw.Flush()
+}
+
+// runHistory implements the history command.
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	root := fs.String("root", "", "only show scans of this folder")
+	path := fs.String("file", "", "history file (default: history.jsonl in the state directory)")
+	fs.Parse(args)
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	if *path == "" {
+		p, err := historyPath()
+		if err != nil {
+			log.Fatal("Error:", err)
+		}
+		*path = p
+	}
+	entries, err := loadHistory(*path)
+	if os.IsNotExist(err) {
+		fmt.Println(msg("history.empty"))
+		return
+	}
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	rootFilter := ""
+	if *root != "" {
+		rootFilter = formatPath(*root)
+	}
+	writeHistory(os.Stdout, entries, rootFilter)
+}
+
+// recordHistory appends summary to the history in the state directory.
+func recordHistory(summary runSummary) {
+	path, err := historyPath()
+	if err == nil {
+		err = appendHistory(path, summary)
+	}
+	if err != nil {
+		log.Printf("Error recording scan history: %v", err)
+	}
+}
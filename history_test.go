@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAndLoadHistory(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "state", historyFile)
+	first := runSummary{Event: "scan", Root: "/data", DuplicateGroups: 2, ReclaimableBytes: 2048}
+	second := runSummary{Event: "cleanup", Root: "/data", Action: "delete", BytesReclaimed: 1024}
+	if err := appendHistory(path, first); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("{truncated\n")
+	f.Close()
+	if err := appendHistory(path, second); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := loadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].ReclaimableBytes != 2048 || entries[1].Action != "delete" {
+		t.Errorf("Unexpected history: %+v", entries)
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.Local) }
+	entries := []runSummary{
+		{Event: "scan", Root: "/data", Time: day(1), DuplicateGroups: 10, ReclaimableBytes: 4096},
+		{Event: "scan", Root: "/other", Time: day(2), DuplicateGroups: 1, ReclaimableBytes: 100},
+		{Event: "cleanup", Root: "/data", Time: day(3), Action: "delete", BytesReclaimed: 3072},
+		{Event: "scan", Root: "/data", Time: day(4), DuplicateGroups: 3, ReclaimableBytes: 1024},
+	}
+
+	var out bytes.Buffer
+	writeHistory(&out, entries, "/data")
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected: 4 lines, Got: %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "Date") || strings.Contains(out.String(), "/other") {
+		t.Errorf("Unexpected history: %s", out.String())
+	}
+	expected := []string{
+		"2024-03-01 12:00  /data  scan    10      4.00 KiB             ##############################",
+		"2024-03-03 12:00  /data  delete                    -3.00 KiB",
+		"2024-03-04 12:00  /data  scan    3       1.00 KiB  -3.00 KiB  #######",
+	}
+	for i, line := range expected {
+		if strings.TrimRight(lines[i+1], " ") != line {
+			t.Errorf("Expected: %q, Got: %q", line, lines[i+1])
+		}
+	}
+}
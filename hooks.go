@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// splitCommand splits a command template into arguments on whitespace,
+// honouring single and double quotes so that arguments may contain spaces.
+func splitCommand(template string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range template {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", template)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return args, nil
+}
+
+// expandGroupCommand substitutes the placeholders of a per-group command:
+// {keep} is the kept file, {id} and {hash} identify the group, and an
+// argument that is exactly {dups...} expands to one argument per duplicate.
+func expandGroupCommand(args []string, group Group) []string {
+	replacer := strings.NewReplacer("{keep}", group.Files[0].Path(), "{id}", group.ID, "{hash}", group.Hash)
+	var expanded []string
+	for _, arg := range args {
+		if arg == "{dups...}" {
+			for _, file := range group.Files[1:] {
+				expanded = append(expanded, file.Path())
+			}
+			continue
+		}
+		expanded = append(expanded, replacer.Replace(arg))
+	}
+	return expanded
+}
+
+// runGroupCommand runs the command template once for the given group. The
+// command is executed directly, not through a shell, so paths never need quoting.
+func runGroupCommand(template string, group Group) error {
+	if err := checkWritable("run a command on group", group.ID); err != nil {
+		return err
+	}
+	args, err := splitCommand(template)
+	if err != nil {
+		return err
+	}
+	args = expandGroupCommand(args, group)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
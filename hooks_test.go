@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"cmd {keep} {dups...}", []string{"cmd", "{keep}", "{dups...}"}},
+		{`import --title "My Album" '{keep}'`, []string{"import", "--title", "My Album", "{keep}"}},
+		{`echo ""`, []string{"echo", ""}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := splitCommand(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected: %q, Got: %q", tc.expected, result)
+			}
+		})
+	}
+
+	if _, err := splitCommand(`echo "unterminated`); err == nil {
+		t.Error("Expected error for unterminated quote")
+	}
+}
+
+func TestExpandGroupCommand(t *testing.T) {
+	group := Group{ID: "abc", Hash: "abcdef", Files: []File{{path: indexPath("/k/a b.jpg")}, {path: indexPath("/x/1.jpg")}, {path: indexPath("/x/2.jpg")}}}
+	result := expandGroupCommand([]string{"cmd", "--group={id}", "{keep}", "{dups...}"}, group)
+	expected := []string{"cmd", "--group=abc", "/k/a b.jpg", "/x/1.jpg", "/x/2.jpg"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected: %q, Got: %q", expected, result)
+	}
+}
+
+func TestRunGroupCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	out := filepath.Join(tempDir, "out.txt")
+	group := Group{ID: "abc", Files: []File{{path: indexPath("keep.txt")}, {path: indexPath("dup1.txt")}, {path: indexPath("dup2.txt")}}}
+	if err := runGroupCommand(`sh -c 'echo "$@" > `+out+`' sh {keep} {dups...}`, group); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(data)) != "keep.txt dup1.txt dup2.txt" {
+		t.Errorf("Unexpected command output: %s", data)
+	}
+}
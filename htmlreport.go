@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"html/template"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//go:embed report.html
+var reportPage string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"msg":   msg,
+	"size":  humanReadableSize,
+	"count": func(n int) string { return formatCount(int64(n)) },
+	"pct":   func(f float64) string { return strconv.FormatFloat(f, 'f', 3, 64) + "%" },
+}).Parse(reportPage))
+
+const (
+	// thumbnailSize is the longest side of the thumbnails embedded in the
+	// HTML report.
+	thumbnailSize = 160
+	// thumbnailMaxSource skips thumbnails for images larger than this, which
+	// would take long to decode.
+	thumbnailMaxSource = 32 << 20
+)
+
+var thumbnailExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true}
+
+type htmlGroup struct {
+	Group
+	Waste     int64
+	Thumbnail template.URL
+}
+
+type htmlReport struct {
+	Lang        string
+	Summary     runSummary
+	Extensions  []wasteStat
+	Directories []wasteStat
+	Treemap     []treemapRect
+	Groups      []htmlGroup
+	// Omitted counts the groups below MinSize left out of Groups.
+	Omitted      int
+	OmittedWaste int64
+	MinSize      int64
+}
+
+// writeHTMLReport writes a self-contained HTML page with the same sections as
+// the text report and a treemap of the wasted space. Image groups get a thumbnail of the kept file when
+// thumbnails is set.
+func writeHTMLReport(w io.Writer, summary runSummary, fileMap map[string][]File, roots []string, depth int, thumbnails bool) error {
+	report := htmlReport{Lang: messageLang, Summary: summary}
+	report.Extensions, _ = wasteBy(fileMap, fileExtension)
+	report.Directories, _ = wasteBy(fileMap, directoryKey(roots, depth))
+	report.Treemap = layoutTreemap(buildTreemap(fileMap, roots))
+	groups, omitted, omittedWaste := reportedGroups(duplicateGroups(fileMap))
+	report.Omitted, report.OmittedWaste, report.MinSize = omitted, omittedWaste, reportMinSize
+	for _, group := range groups {
+		g := htmlGroup{Group: group, Waste: group.Waste()}
+		if thumbnails {
+			g.Thumbnail = thumbnail(group.Files[0].Path())
+		}
+		report.Groups = append(report.Groups, g)
+	}
+	return reportTemplate.Execute(w, report)
+}
+
+func saveHTMLReport(path string, summary runSummary, fileMap map[string][]File, roots []string, depth int, thumbnails bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeHTMLReport(f, summary, fileMap, roots, depth, thumbnails); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// thumbnail returns a JPEG data URL of the image at path scaled down to
+// thumbnailSize, or "" if the file is not a supported image.
+func thumbnail(path string) template.URL {
+	if !thumbnailExtensions[strings.ToLower(filepath.Ext(path))] {
+		return ""
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() > thumbnailMaxSource {
+		return ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleDown(img, thumbnailSize), &jpeg.Options{Quality: 75}); err != nil {
+		return ""
+	}
+	return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+// scaleDown shrinks img so that its longest side is at most size pixels,
+// averaging the source pixels that fall into each target pixel.
+func scaleDown(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+	out := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			out.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScaleDown(t *testing.T) {
+	testCases := []struct {
+		width, height int
+		expected      image.Point
+	}{
+		{100, 50, image.Pt(100, 50)},
+		{640, 480, image.Pt(160, 120)},
+		{300, 1200, image.Pt(40, 160)},
+		{5000, 2, image.Pt(160, 1)},
+	}
+	for _, tc := range testCases {
+		img := image.NewGray(image.Rect(0, 0, tc.width, tc.height))
+		if got := scaleDown(img, thumbnailSize).Bounds().Size(); got != tc.expected {
+			t.Errorf("Expected: %v, Got: %v", tc.expected, got)
+		}
+	}
+
+	// A 2x2 checkerboard averages to grey.
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.White)
+	img.Set(1, 1, color.White)
+	r, _, _, _ := scaleDown(img, 1).At(0, 0).RGBA()
+	if r>>8 != 127 {
+		t.Errorf("Expected: 127, Got: %d", r>>8)
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	var pngData bytes.Buffer
+	png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 400, 300)))
+	a := filepath.Join(tempDir, "a.png")
+	b := filepath.Join(tempDir, "b<script>.png")
+	ioutil.WriteFile(a, pngData.Bytes(), 0644)
+	ioutil.WriteFile(b, pngData.Bytes(), 0644)
+	size := int64(pngData.Len())
+	fileMap := map[string][]File{
+		"aaaaaaaaaaaaaaaa": {{path: indexPath(a), Hash: "aaaaaaaaaaaaaaaa", Size: size}, {path: indexPath(b), Hash: "aaaaaaaaaaaaaaaa", Size: size}},
+		"bbbbbbbbbbbbbbbb": {{path: indexPath(filepath.Join(tempDir, "x.txt")), Hash: "bbbbbbbbbbbbbbbb", Size: 3}, {path: indexPath(filepath.Join(tempDir, "y.txt")), Hash: "bbbbbbbbbbbbbbbb", Size: 3}},
+	}
+	summary := summarize(tempDir, 4, fileMap, 0)
+
+	testCases := []struct {
+		thumbnails bool
+		images     int
+	}{
+		{true, 1},
+		{false, 0},
+	}
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		if err := writeHTMLReport(&out, summary, fileMap, []string{tempDir}, 1, tc.thumbnails); err != nil {
+			t.Fatal(err)
+		}
+		html := out.String()
+		if got := strings.Count(html, `<img src="data:image/jpeg;base64,`); got != tc.images {
+			t.Errorf("Expected: %d thumbnails, Got: %d", tc.images, got)
+		}
+		for _, s := range []string{"Duplicate group aaaaaaaaaaaa", "Duplicate group bbbbbbbbbbbb", "b&lt;script&gt;.png", ".png", "Files scanned: 4"} {
+			if !strings.Contains(html, s) {
+				t.Errorf("Expected report to contain %q", s)
+			}
+		}
+		if strings.Contains(html, "b<script>") {
+			t.Errorf("Expected file names to be escaped")
+		}
+	}
+}
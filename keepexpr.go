@@ -0,0 +1,527 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A keep expression (--keep-expr) scores every file of a duplicate group and
+// keeps the file with the highest score, e.g.
+//
+//	path contains "/master/" ? 100 : mtime
+//
+// Expressions combine the file fields in keepExprFields with number, string
+// ("...", Go syntax) and true/false literals, the arithmetic operators + - * /,
+// the comparisons == != < <= > >=, the string operators contains, startswith,
+// endswith and matches (a regular expression), ! && || and cond ? a : b. The
+// result is a number or a boolean, which counts as 1 or 0. Ties keep the
+// earlier file of the group.
+
+// keepExprType is the type of a keep expression or one of its operands.
+type keepExprType int
+
+const (
+	exprNumber keepExprType = iota
+	exprString
+	exprBool
+)
+
+func (t keepExprType) String() string {
+	return [...]string{"number", "string", "boolean"}[t]
+}
+
+// keepExprFile is a file of a group as seen by a keep expression.
+type keepExprFile struct {
+	File
+	modTime time.Time // zero if the file cannot be read
+}
+
+// keepExprValue holds the value of a node; only the field of its type is set.
+type keepExprValue struct {
+	num float64
+	str string
+	b   bool
+}
+
+// keepExprNode is a type-checked part of an expression.
+type keepExprNode struct {
+	typ  keepExprType
+	eval func(f *keepExprFile) keepExprValue
+}
+
+// keepExprFields are the file fields an expression can use. Paths use / as
+// separator on every system.
+var keepExprFields = map[string]keepExprNode{
+	"path": {exprString, func(f *keepExprFile) keepExprValue {
+		return keepExprValue{str: filepath.ToSlash(f.Path())}
+	}},
+	"dir": {exprString, func(f *keepExprFile) keepExprValue {
+		return keepExprValue{str: filepath.ToSlash(filepath.Dir(f.Path()))}
+	}},
+	"name": {exprString, func(f *keepExprFile) keepExprValue {
+		return keepExprValue{str: filepath.Base(f.Path())}
+	}},
+	"ext": {exprString, func(f *keepExprFile) keepExprValue {
+		return keepExprValue{str: strings.ToLower(strings.TrimPrefix(filepath.Ext(f.Path()), "."))}
+	}},
+	"depth": {exprNumber, func(f *keepExprFile) keepExprValue {
+		return keepExprValue{num: float64(strings.Count(filepath.ToSlash(filepath.Clean(f.Path())), "/"))}
+	}},
+	"size": {exprNumber, func(f *keepExprFile) keepExprValue {
+		return keepExprValue{num: float64(f.Size)}
+	}},
+	"mtime": {exprNumber, func(f *keepExprFile) keepExprValue {
+		if f.modTime.IsZero() {
+			return keepExprValue{}
+		}
+		return keepExprValue{num: float64(f.modTime.Unix())}
+	}},
+	"age": {exprNumber, func(f *keepExprFile) keepExprValue {
+		if f.modTime.IsZero() {
+			return keepExprValue{}
+		}
+		return keepExprValue{num: time.Since(f.modTime).Hours() / 24}
+	}},
+}
+
+// keepExprFieldNames returns the names of keepExprFields for error messages.
+func keepExprFieldNames() string {
+	var names []string
+	for name := range keepExprFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// keepExpr is a parsed keep expression.
+type keepExpr struct {
+	source string
+	root   keepExprNode
+}
+
+func parseKeepExpr(source string) (*keepExpr, error) {
+	tokens, err := tokenizeKeepExpr(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keep expression %q: %v", source, err)
+	}
+	p := &keepExprParser{tokens: tokens}
+	root, err := p.ternary()
+	if err == nil && p.pos < len(tokens) {
+		err = p.unexpected()
+	}
+	if err == nil && root.typ == exprString {
+		err = errors.New("the result must be a number or a boolean, not a string")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid keep expression %q: %v", source, err)
+	}
+	return &keepExpr{source: source, root: root}, nil
+}
+
+// score evaluates the expression for file.
+func (e *keepExpr) score(file File) float64 {
+	f := &keepExprFile{File: file}
+	if info, err := os.Stat(file.Path()); err == nil {
+		f.modTime = info.ModTime()
+	}
+	v := e.root.eval(f)
+	if e.root.typ == exprBool {
+		if v.b {
+			return 1
+		}
+		return 0
+	}
+	if math.IsNaN(v.num) {
+		return math.Inf(-1)
+	}
+	return v.num
+}
+
+// scores evaluates the expression for every file.
+func (e *keepExpr) scores(files []File) []float64 {
+	scores := make([]float64, len(files))
+	for i, file := range files {
+		scores[i] = e.score(file)
+	}
+	return scores
+}
+
+// keepExprToken is a token of an expression: an operator, an identifier, a
+// number or a string literal.
+type keepExprToken struct {
+	text   string // as written, quotes included
+	pos    int    // byte offset in the expression
+	quoted bool
+}
+
+// keepExprOperators lists the operators, longer ones first.
+var keepExprOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "+", "-", "*", "/", "!", "?", ":", "(", ")"}
+
+func tokenizeKeepExpr(source string) ([]keepExprToken, error) {
+	var tokens []keepExprToken
+	for pos := 0; pos < len(source); {
+		rest := source[pos:]
+		c := rest[0]
+		switch {
+		case c == ' ' || c == '\t':
+			pos++
+			continue
+		case c == '"':
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d", pos+1)
+			}
+			tokens = append(tokens, keepExprToken{text: quoted, pos: pos, quoted: true})
+			pos += len(quoted)
+			continue
+		case c >= '0' && c <= '9' || c == '.':
+			end := 1
+			for end < len(rest) && (rest[end] >= '0' && rest[end] <= '9' || rest[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, keepExprToken{text: rest[:end], pos: pos})
+			pos += end
+			continue
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			end := 1
+			for end < len(rest) && (rest[end] == '_' || rest[end] >= 'a' && rest[end] <= 'z' || rest[end] >= 'A' && rest[end] <= 'Z' || rest[end] >= '0' && rest[end] <= '9') {
+				end++
+			}
+			tokens = append(tokens, keepExprToken{text: rest[:end], pos: pos})
+			pos += end
+			continue
+		}
+		matched := false
+		for _, op := range keepExprOperators {
+			if strings.HasPrefix(rest, op) {
+				tokens = append(tokens, keepExprToken{text: op, pos: pos})
+				pos += len(op)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("unexpected %q at position %d", c, pos+1)
+		}
+	}
+	return tokens, nil
+}
+
+// keepExprParser is a recursive descent parser that type-checks expressions
+// while building them, so evaluation cannot fail.
+type keepExprParser struct {
+	tokens []keepExprToken
+	pos    int
+}
+
+// peek returns the next token, or "" at the end of the expression.
+func (p *keepExprParser) peek() string {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *keepExprParser) unexpected() error {
+	if p.pos >= len(p.tokens) {
+		return errors.New("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	return fmt.Errorf("unexpected %s at position %d", token.text, token.pos+1)
+}
+
+// operands checks that the operands of op have type typ.
+func operands(op string, typ keepExprType, nodes ...keepExprNode) error {
+	for _, node := range nodes {
+		if node.typ != typ {
+			return fmt.Errorf("%s needs %s operands, got a %s", op, typ, node.typ)
+		}
+	}
+	return nil
+}
+
+// ternary parses cond ? a : b, the lowest precedence level.
+func (p *keepExprParser) ternary() (keepExprNode, error) {
+	cond, err := p.or()
+	if err != nil || p.peek() != "?" {
+		return cond, err
+	}
+	p.pos++
+	if err := operands("?", exprBool, cond); err != nil {
+		return cond, err
+	}
+	a, err := p.ternary()
+	if err != nil {
+		return a, err
+	}
+	if p.peek() != ":" {
+		return a, p.unexpected()
+	}
+	p.pos++
+	b, err := p.ternary()
+	if err != nil {
+		return b, err
+	}
+	if a.typ != b.typ {
+		return a, fmt.Errorf("both results of ?: need the same type, got a %s and a %s", a.typ, b.typ)
+	}
+	return keepExprNode{a.typ, func(f *keepExprFile) keepExprValue {
+		if cond.eval(f).b {
+			return a.eval(f)
+		}
+		return b.eval(f)
+	}}, nil
+}
+
+func (p *keepExprParser) or() (keepExprNode, error) {
+	left, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.pos++
+		var right keepExprNode
+		if right, err = p.and(); err == nil {
+			if err = operands("||", exprBool, left, right); err == nil {
+				l := left
+				left = keepExprNode{exprBool, func(f *keepExprFile) keepExprValue {
+					return keepExprValue{b: l.eval(f).b || right.eval(f).b}
+				}}
+			}
+		}
+	}
+	return left, err
+}
+
+func (p *keepExprParser) and() (keepExprNode, error) {
+	left, err := p.not()
+	for err == nil && p.peek() == "&&" {
+		p.pos++
+		var right keepExprNode
+		if right, err = p.not(); err == nil {
+			if err = operands("&&", exprBool, left, right); err == nil {
+				l := left
+				left = keepExprNode{exprBool, func(f *keepExprFile) keepExprValue {
+					return keepExprValue{b: l.eval(f).b && right.eval(f).b}
+				}}
+			}
+		}
+	}
+	return left, err
+}
+
+func (p *keepExprParser) not() (keepExprNode, error) {
+	if p.peek() != "!" {
+		return p.comparison()
+	}
+	p.pos++
+	operand, err := p.not()
+	if err == nil {
+		err = operands("!", exprBool, operand)
+	}
+	return keepExprNode{exprBool, func(f *keepExprFile) keepExprValue {
+		return keepExprValue{b: !operand.eval(f).b}
+	}}, err
+}
+
+func (p *keepExprParser) comparison() (keepExprNode, error) {
+	left, err := p.additive()
+	if err != nil {
+		return left, err
+	}
+	op := p.peek()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "contains", "startswith", "endswith", "matches":
+	default:
+		return left, nil
+	}
+	p.pos++
+
+	if op == "matches" {
+		if p.pos >= len(p.tokens) || !p.tokens[p.pos].quoted {
+			return left, errors.New("matches needs a string literal")
+		}
+		pattern, _ := strconv.Unquote(p.tokens[p.pos].text)
+		p.pos++
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return left, fmt.Errorf("invalid regular expression %q: %v", pattern, err)
+		}
+		if err := operands("matches", exprString, left); err != nil {
+			return left, err
+		}
+		return keepExprNode{exprBool, func(f *keepExprFile) keepExprValue {
+			return keepExprValue{b: re.MatchString(left.eval(f).str)}
+		}}, nil
+	}
+
+	right, err := p.additive()
+	if err != nil {
+		return right, err
+	}
+	switch op {
+	case "contains", "startswith", "endswith":
+		if err := operands(op, exprString, left, right); err != nil {
+			return left, err
+		}
+		match := map[string]func(s, substr string) bool{
+			"contains": strings.Contains, "startswith": strings.HasPrefix, "endswith": strings.HasSuffix,
+		}[op]
+		return keepExprNode{exprBool, func(f *keepExprFile) keepExprValue {
+			return keepExprValue{b: match(left.eval(f).str, right.eval(f).str)}
+		}}, nil
+	case "==", "!=":
+		if err := operands(op, left.typ, right); err != nil {
+			return left, err
+		}
+	default:
+		if left.typ == exprBool {
+			return left, fmt.Errorf("%s needs number or string operands, got a boolean", op)
+		}
+		if err := operands(op, left.typ, right); err != nil {
+			return left, err
+		}
+	}
+	return keepExprNode{exprBool, func(f *keepExprFile) keepExprValue {
+		l, r := left.eval(f), right.eval(f)
+		var c int
+		switch {
+		case left.typ == exprNumber && l.num < r.num, left.typ == exprString && l.str < r.str:
+			c = -1
+		case left.typ == exprNumber && l.num > r.num, left.typ == exprString && l.str > r.str, left.typ == exprBool && l.b != r.b:
+			c = 1
+		}
+		switch op {
+		case "==":
+			return keepExprValue{b: c == 0}
+		case "!=":
+			return keepExprValue{b: c != 0}
+		case "<":
+			return keepExprValue{b: c < 0}
+		case "<=":
+			return keepExprValue{b: c <= 0}
+		case ">":
+			return keepExprValue{b: c > 0}
+		default:
+			return keepExprValue{b: c >= 0}
+		}
+	}}, nil
+}
+
+func (p *keepExprParser) additive() (keepExprNode, error) {
+	left, err := p.multiplicative()
+	for err == nil && (p.peek() == "+" || p.peek() == "-") {
+		op := p.peek()
+		p.pos++
+		var right keepExprNode
+		if right, err = p.multiplicative(); err == nil {
+			if err = operands(op, exprNumber, left, right); err == nil {
+				left = arithmetic(op, left, right)
+			}
+		}
+	}
+	return left, err
+}
+
+func (p *keepExprParser) multiplicative() (keepExprNode, error) {
+	left, err := p.unary()
+	for err == nil && (p.peek() == "*" || p.peek() == "/") {
+		op := p.peek()
+		p.pos++
+		var right keepExprNode
+		if right, err = p.unary(); err == nil {
+			if err = operands(op, exprNumber, left, right); err == nil {
+				left = arithmetic(op, left, right)
+			}
+		}
+	}
+	return left, err
+}
+
+// arithmetic returns the node for left op right on numbers. Division by zero
+// gives 0.
+func arithmetic(op string, left, right keepExprNode) keepExprNode {
+	return keepExprNode{exprNumber, func(f *keepExprFile) keepExprValue {
+		l, r := left.eval(f).num, right.eval(f).num
+		switch op {
+		case "+":
+			return keepExprValue{num: l + r}
+		case "-":
+			return keepExprValue{num: l - r}
+		case "*":
+			return keepExprValue{num: l * r}
+		}
+		if r == 0 {
+			return keepExprValue{}
+		}
+		return keepExprValue{num: l / r}
+	}}
+}
+
+func (p *keepExprParser) unary() (keepExprNode, error) {
+	if p.peek() != "-" {
+		return p.primary()
+	}
+	p.pos++
+	operand, err := p.unary()
+	if err == nil {
+		err = operands("-", exprNumber, operand)
+	}
+	return keepExprNode{exprNumber, func(f *keepExprFile) keepExprValue {
+		return keepExprValue{num: -operand.eval(f).num}
+	}}, err
+}
+
+func (p *keepExprParser) primary() (keepExprNode, error) {
+	if p.pos >= len(p.tokens) {
+		return keepExprNode{}, p.unexpected()
+	}
+	token := p.tokens[p.pos]
+	if token.quoted {
+		p.pos++
+		s, _ := strconv.Unquote(token.text)
+		return keepExprNode{exprString, func(*keepExprFile) keepExprValue { return keepExprValue{str: s} }}, nil
+	}
+	switch c := token.text[0]; {
+	case token.text == "(":
+		p.pos++
+		node, err := p.ternary()
+		if err != nil {
+			return node, err
+		}
+		if p.peek() != ")" {
+			return node, p.unexpected()
+		}
+		p.pos++
+		return node, nil
+	case token.text == "true" || token.text == "false":
+		p.pos++
+		b := token.text == "true"
+		return keepExprNode{exprBool, func(*keepExprFile) keepExprValue { return keepExprValue{b: b} }}, nil
+	case c >= '0' && c <= '9' || c == '.':
+		n, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return keepExprNode{}, fmt.Errorf("invalid number %s at position %d", token.text, token.pos+1)
+		}
+		p.pos++
+		return keepExprNode{exprNumber, func(*keepExprFile) keepExprValue { return keepExprValue{num: n} }}, nil
+	}
+	if field, known := keepExprFields[token.text]; known {
+		p.pos++
+		return field, nil
+	}
+	if c := token.text[0]; c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+		switch token.text {
+		case "contains", "startswith", "endswith", "matches":
+		default:
+			return keepExprNode{}, fmt.Errorf("unknown field %s at position %d (expected one of %s)", token.text, token.pos+1, keepExprFieldNames())
+		}
+	}
+	return keepExprNode{}, p.unexpected()
+}
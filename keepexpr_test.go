@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeepExprScore(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "master", "Photo.JPG")
+	os.Mkdir(filepath.Dir(path), 0755)
+	ioutil.WriteFile(path, []byte("same"), 0644)
+	mtime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(path, mtime, mtime)
+	file := File{path: indexPath(path), Size: 4}
+	depth := float64(strings.Count(filepath.ToSlash(filepath.Clean(path)), "/"))
+
+	testCases := []struct {
+		expr     string
+		expected float64
+	}{
+		{`path contains "/master/" ? 100 : mtime`, 100},
+		{`path contains "/backup/" ? 100 : mtime`, float64(mtime.Unix())},
+		{`ext == "jpg" && name startswith "Photo"`, 1},
+		{`!(name endswith ".JPG") || size > 4`, 0},
+		{`size * 2 + 1 - -1`, 10},
+		{`(size + 4) / 4`, 2},
+		{`size / 0`, 0},
+		{`depth`, depth},
+		{`dir endswith "/master"`, 1},
+		{`name matches "^[A-Z][a-z]+\\.JPG$" ? 1 : 2`, 1},
+		{`"b" > "a"`, 1},
+		{`true == false`, 0},
+		{`age > 0 && age < 36500`, 1},
+		{`size > 1 ? size > 2 ? 3 : 2 : 1`, 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := parseKeepExpr(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := expr.score(file); got != tc.expected {
+				t.Errorf("Expected: %v, Got: %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseKeepExprErrors(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{`path`, "the result must be a number or a boolean, not a string"},
+		{`size contains "a"`, "contains needs string operands, got a number"},
+		{`path ? 1 : 2`, "? needs boolean operands, got a string"},
+		{`size > 1 ? 1 : "a"`, "both results of ?: need the same type, got a number and a string"},
+		{`owner == "me"`, "unknown field owner at position 1 (expected one of age, depth, dir, ext, mtime, name, path, size)"},
+		{`size >`, "unexpected end of expression"},
+		{`size 1`, "unexpected 1 at position 6"},
+		{`(size`, "unexpected end of expression"},
+		{`size # 1`, "unexpected '#' at position 6"},
+		{`name == "a`, "invalid string at position 9"},
+		{`name matches path`, "matches needs a string literal"},
+		{`name matches "("`, "invalid regular expression \"(\": error parsing regexp: missing closing ): `(`"},
+		{`true < false`, "< needs number or string operands, got a boolean"},
+		{`1.2.3`, "invalid number 1.2.3 at position 1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := parseKeepExpr(tc.expr)
+			expected := "invalid keep expression " + strconv.Quote(tc.expr) + ": " + tc.expected
+			if err == nil || err.Error() != expected {
+				t.Errorf("Expected: %s, Got: %v", expected, err)
+			}
+		})
+	}
+}
+
+func TestKeepPolicyExpr(t *testing.T) {
+	group := Group{ID: "g", Files: []File{{path: indexPath("/a/1"), Size: 1}, {path: indexPath("/master/1"), Size: 1}, {path: indexPath("/master/2"), Size: 1}}}
+	policy, _ := newKeepPolicy("first", nil)
+	var err error
+	if policy.expr, err = parseKeepExpr(`path startswith "/master/"`); err != nil {
+		t.Fatal(err)
+	}
+	keep, remove := policy.apply(group)
+	if len(keep) != 1 || keep[0].Path() != "/master/1" || len(remove) != 2 {
+		t.Errorf("Expected to keep /master/1, Got: keep %v remove %v", keep, remove)
+	}
+}
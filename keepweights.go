@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// keepCriteria are the criteria accepted by --keep-weights. Each rates a
+// file between 0 and 1 relative to the other files of its group:
+//
+//	path:FOLDER  1 for files below FOLDER, 0 for the others
+//	age          1 for the oldest file, 0 for the newest
+//	name         1 for names that do not look like a copy, 0 for the others
+//	depth        1 for the file with the fewest folders in its path, 0 for
+//	             the one with the most
+//
+// A negative weight prefers the opposite, e.g. age=-1 prefers newer files.
+var keepCriteria = []string{"path:FOLDER", "age", "name", "depth"}
+
+// copyNamePattern matches file names, with or without extension, that look
+// like the copy of another file, such as "report (2)", "Copy of report",
+// "report - Copy", "report_copy1", "report~" or "report.bak".
+var copyNamePattern = regexp.MustCompile(`(?i)(^copy of |[ _-]copy ?\d*$| - kopie$| - copie$| \(\d+\)$|~\d*$|\.(bak|old|orig)$)`)
+
+// isCopyName reports whether the name of the file at path looks like the
+// copy of another file.
+func isCopyName(path string) bool {
+	name := filepath.Base(path)
+	return copyNamePattern.MatchString(name) || copyNamePattern.MatchString(strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+// keepWeight is one term of --keep-weights.
+type keepWeight struct {
+	criterion string
+	folder    string // absolute, for the path criterion
+	weight    float64
+}
+
+// keepWeights scores the files of a group with a weighted sum of criteria.
+type keepWeights struct {
+	source string
+	terms  []keepWeight
+}
+
+// parseKeepWeights parses a comma-separated list of criterion=weight terms,
+// e.g. "path:/master=10,age=1,name=2".
+func parseKeepWeights(spec string) (*keepWeights, error) {
+	w := &keepWeights{source: spec}
+	for _, term := range strings.Split(spec, ",") {
+		term = strings.TrimSpace(term)
+		eq := strings.LastIndexByte(term, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("invalid keep weight %q (expected CRITERION=WEIGHT, e.g. age=2)", term)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(term[eq+1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight in %q", term)
+		}
+		kw := keepWeight{criterion: strings.TrimSpace(term[:eq]), weight: weight}
+		if folder := strings.TrimPrefix(kw.criterion, "path:"); folder != kw.criterion {
+			if folder == "" {
+				return nil, fmt.Errorf("missing folder in %q", term)
+			}
+			if kw.folder, err = filepath.Abs(folder); err != nil {
+				return nil, err
+			}
+			kw.criterion = "path"
+		} else if kw.criterion != "age" && kw.criterion != "name" && kw.criterion != "depth" {
+			return nil, fmt.Errorf("unknown keep criterion %q (expected one of %s)", kw.criterion, strings.Join(keepCriteria, ", "))
+		}
+		w.terms = append(w.terms, kw)
+	}
+	return w, nil
+}
+
+// scores returns the weighted score of every file.
+func (w *keepWeights) scores(files []File) []float64 {
+	scores := make([]float64, len(files))
+	for _, term := range w.terms {
+		for i, rating := range rateFiles(term, files) {
+			scores[i] += term.weight * rating
+		}
+	}
+	return scores
+}
+
+// rateFiles rates every file by the criterion of term.
+func rateFiles(term keepWeight, files []File) []float64 {
+	ratings := make([]float64, len(files))
+	switch term.criterion {
+	case "path":
+		below := keepPolicy{protect: []string{term.folder}}
+		for i, file := range files {
+			if below.protected(file.Path()) {
+				ratings[i] = 1
+			}
+		}
+	case "name":
+		for i, file := range files {
+			if !isCopyName(file.Path()) {
+				ratings[i] = 1
+			}
+		}
+	case "age", "depth":
+		// Lower values are better; files that cannot be read rate 0.
+		values := make([]float64, len(files))
+		valid := make([]bool, len(files))
+		for i, file := range files {
+			if term.criterion == "depth" {
+				values[i] = float64(strings.Count(filepath.ToSlash(filepath.Clean(file.Path())), "/"))
+				valid[i] = true
+			} else if info, err := os.Stat(file.Path()); err == nil {
+				values[i] = float64(info.ModTime().UnixNano())
+				valid[i] = true
+			}
+		}
+		normalize(ratings, values, valid)
+	}
+	return ratings
+}
+
+// normalize sets ratings to 1 for the lowest valid value and 0 for the
+// highest, scaling the others linearly. If all valid values are equal, no
+// file is preferred and the ratings stay 0.
+func normalize(ratings, values []float64, valid []bool) {
+	first := true
+	var lowest, highest float64
+	for i, v := range values {
+		if !valid[i] {
+			continue
+		}
+		if first || v < lowest {
+			lowest = v
+		}
+		if first || v > highest {
+			highest = v
+		}
+		first = false
+	}
+	if highest == lowest {
+		return
+	}
+	for i, v := range values {
+		if valid[i] {
+			ratings[i] = (highest - v) / (highest - lowest)
+		}
+	}
+}
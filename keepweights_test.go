@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseKeepWeightsErrors(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		expected string
+	}{
+		{"age", `invalid keep weight "age" (expected CRITERION=WEIGHT, e.g. age=2)`},
+		{"age=x", `invalid weight in "age=x"`},
+		{"age=1,size=2", `unknown keep criterion "size" (expected one of path:FOLDER, age, name, depth)`},
+		{"path:=3", `missing folder in "path:=3"`},
+	}
+	for _, tc := range testCases {
+		_, err := parseKeepWeights(tc.spec)
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Expected: %s, Got: %v", tc.expected, err)
+		}
+	}
+}
+
+func TestKeepWeightsScores(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	master := filepath.Join(tempDir, "master")
+	os.MkdirAll(filepath.Join(master, "sub"), 0755)
+	files := []File{
+		{path: indexPath(filepath.Join(tempDir, "photo.jpg"))},
+		{path: indexPath(filepath.Join(master, "sub", "photo (2).jpg"))},
+		{path: indexPath(filepath.Join(master, "photo - Copy.jpg"))},
+	}
+	now := time.Now()
+	for i, file := range files {
+		ioutil.WriteFile(file.Path(), []byte("same"), 0644)
+		mtime := now.Add(time.Duration(i) * time.Hour)
+		os.Chtimes(file.Path(), mtime, mtime)
+	}
+
+	testCases := []struct {
+		spec     string
+		expected []float64
+	}{
+		{"path:" + master + "=10", []float64{0, 10, 10}},
+		{"age=1", []float64{1, 0.5, 0}},
+		{"age=-1", []float64{-1, -0.5, 0}},
+		{"name=2", []float64{2, 0, 0}},
+		{"depth=1", []float64{1, 0, 0.5}},
+		{"path:" + master + "=10, name=2, age=4", []float64{6, 12, 10}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			weights, err := parseKeepWeights(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scores := weights.scores(files)
+			for i, expected := range tc.expected {
+				if scores[i] != expected {
+					t.Errorf("Expected: %v, Got: %v", tc.expected, scores)
+					break
+				}
+			}
+		})
+	}
+
+	policy, _ := newKeepPolicy("first", nil)
+	policy.weights, _ = parseKeepWeights("path:" + master + "=10,age=1")
+	if keep, _ := policy.apply(Group{Files: files}); keep[0].Path() != files[1].Path() {
+		t.Errorf("Expected: %s, Got: %s", files[1].Path(), keep[0].Path())
+	}
+}
+
+func TestCopyNamePattern(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected bool
+	}{
+		{"report", false},
+		{"report (2)", true},
+		{"Copy of report", true},
+		{"report - Copy", true},
+		{"report_copy1", true},
+		{"report~", true},
+		{"report.bak", true},
+		{"copyright", false},
+		{"photo 2021", false},
+	}
+	for _, tc := range testCases {
+		if got := copyNamePattern.MatchString(tc.name); got != tc.expected {
+			t.Errorf("%s: Expected: %v, Got: %v", tc.name, tc.expected, got)
+		}
+	}
+}
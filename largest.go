@@ -0,0 +1,113 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fileHeap is a min-heap of files by size, holding the largest files seen.
+type fileHeap []File
+
+func (h fileHeap) Len() int            { return len(h) }
+func (h fileHeap) Less(i, j int) bool  { return largerFile(h[j], h[i]) }
+func (h fileHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *fileHeap) Push(x interface{}) { *h = append(*h, x.(File)) }
+func (h *fileHeap) Pop() interface{} {
+	old := *h
+	file := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return file
+}
+
+// largerFile orders files by size, largest first, then by path.
+func largerFile(a, b File) bool {
+	if a.Size != b.Size {
+		return a.Size > b.Size
+	}
+	return a.Path() < b.Path()
+}
+
+// largestFiles returns the n largest files of fileMap, largest first. Every
+// copy of a duplicate counts, as each takes up space.
+func largestFiles(fileMap map[string][]File, n int) []File {
+	h := make(fileHeap, 0, n+1)
+	for _, files := range fileMap {
+		for _, file := range files {
+			if len(h) < n {
+				heap.Push(&h, file)
+			} else if n > 0 && largerFile(file, h[0]) {
+				h[0] = file
+				heap.Fix(&h, 0)
+			}
+		}
+	}
+	sort.Slice(h, func(i, j int) bool { return largerFile(h[i], h[j]) })
+	return h
+}
+
+// largestFolders returns the n folders below the roots whose files, including
+// the ones in their subfolders, take up the most space, largest first.
+func largestFolders(fileMap map[string][]File, roots []string, n int) []wasteStat {
+	var cleaned []string
+	for _, root := range roots {
+		cleaned = append(cleaned, filepath.Clean(root))
+	}
+	below := func(dir string) bool {
+		for _, root := range cleaned {
+			if rel, err := filepath.Rel(root, dir); err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+	byDir := make(map[string]*wasteStat)
+	for _, files := range fileMap {
+		for _, file := range files {
+			for dir := filepath.Dir(filepath.Clean(file.Path())); below(dir); dir = filepath.Dir(dir) {
+				stat, ok := byDir[dir]
+				if !ok {
+					stat = &wasteStat{Key: dir}
+					byDir[dir] = stat
+				}
+				stat.Files++
+				stat.Bytes += file.Size
+			}
+		}
+	}
+	stats := make([]wasteStat, 0, len(byDir))
+	for _, stat := range byDir {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// writeLargest writes the largest files and folders of a scan.
+func writeLargest(w io.Writer, files []File, folders []wasteStat) {
+	if len(files) > 0 {
+		fmt.Fprintln(w, msg("largest.files"))
+		for _, file := range files {
+			fmt.Fprintf(w, "  %12s  %s\n", humanReadableSize(file.Size), file.Path())
+		}
+		fmt.Fprintln(w)
+	}
+	if len(folders) > 0 {
+		fmt.Fprintln(w, msg("largest.folders"))
+		for _, folder := range folders {
+			fmt.Fprintf(w, "  %12s  %s (%s)\n", humanReadableSize(folder.Bytes), folder.Key, msg("report.files", formatCount(int64(folder.Files))))
+		}
+		fmt.Fprintln(w)
+	}
+}
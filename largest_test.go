@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLargestFiles(t *testing.T) {
+	fileMap := map[string][]File{
+		"a": {{path: indexPath("/r/a"), Size: 10}, {path: indexPath("/r/x/a"), Size: 10}},
+		"b": {{path: indexPath("/r/b"), Size: 30}},
+		"c": {{path: indexPath("/r/x/y/c"), Size: 20}},
+		"d": {{path: indexPath("/r/d"), Size: 1}},
+	}
+	var paths []string
+	for _, file := range largestFiles(fileMap, 3) {
+		paths = append(paths, file.Path())
+	}
+	if got := strings.Join(paths, " "); got != "/r/b /r/x/y/c /r/a" {
+		t.Errorf("Unexpected largest files: %s", got)
+	}
+	if files := largestFiles(fileMap, 0); len(files) != 0 {
+		t.Errorf("Expected no files, Got: %v", files)
+	}
+
+	folders := largestFolders(fileMap, []string{"/r/"}, 5)
+	if len(folders) != 2 || folders[0].Key != filepath.Clean("/r/x") || folders[0].Bytes != 30 || folders[0].Files != 2 || folders[1].Bytes != 20 {
+		t.Errorf("Unexpected largest folders: %v", folders)
+	}
+
+	var out bytes.Buffer
+	writeLargest(&out, largestFiles(fileMap, 1), folders[:1])
+	if !strings.Contains(out.String(), "30.00 B  /r/b") || !strings.Contains(out.String(), "30.00 B  "+filepath.Clean("/r/x")+" (2 files)") {
+		t.Errorf("Unexpected report: %s", out.String())
+	}
+}
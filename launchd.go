@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// launchdLabel identifies the launch agent installed on macOS.
+const launchdLabel = "com.github.halra." + serviceName
+
+func plistString(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return "<string>" + buf.String() + "</string>"
+}
+
+// launchdPlist renders a launch agent that runs the scan every interval,
+// saving the results and the scan output into reportDir.
+func launchdPlist(executable string, scanArgs []string, interval time.Duration, reportDir string, notify bool) string {
+	args := []string{executable, "--save", filepath.Join(reportDir, "results.json")}
+	if notify {
+		args = append(args, "--desktop-notify")
+	}
+	args = append(args, scanArgs...)
+
+	var programArgs bytes.Buffer
+	for _, arg := range args {
+		fmt.Fprintf(&programArgs, "\t\t%s\n", plistString(arg))
+	}
+	logPath := filepath.Join(reportDir, "last-scan.log")
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	%s
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>StartInterval</key>
+	<integer>%d</integer>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>%s</key>
+		<string>1</string>
+	</dict>
+	<key>StandardOutPath</key>
+	%s
+	<key>StandardErrorPath</key>
+	%s
+	<key>ProcessType</key>
+	<string>Background</string>
+	<key>LowPriorityIO</key>
+	<true/>
+	<key>Nice</key>
+	<integer>10</integer>
+</dict>
+</plist>
+`, plistString(launchdLabel), programArgs.String(), int64(interval/time.Second), serviceEnv, plistString(logPath), plistString(logPath))
+}
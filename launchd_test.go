@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLaunchdPlist(t *testing.T) {
+	plist := launchdPlist("/usr/local/bin/duplicate_finder", []string{"--dir-depth", "2", "/Users/me/Photos & Videos"}, 12*time.Hour, "/Users/me/reports", true)
+
+	for _, expected := range []string{
+		"<string>" + launchdLabel + "</string>",
+		"\t\t<string>/usr/local/bin/duplicate_finder</string>\n\t\t<string>--save</string>\n\t\t<string>/Users/me/reports/results.json</string>\n\t\t<string>--desktop-notify</string>\n",
+		"<string>/Users/me/Photos &amp; Videos</string>",
+		"<integer>43200</integer>",
+		"<string>/Users/me/reports/last-scan.log</string>",
+	} {
+		if !strings.Contains(plist, expected) {
+			t.Errorf("Expected %q in plist:\n%s", expected, plist)
+		}
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(plist))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err != io.EOF {
+				t.Errorf("Invalid XML: %v", err)
+			}
+			break
+		}
+	}
+}
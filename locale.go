@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+type numberFormat struct {
+	Group   string // thousands separator, empty for no grouping
+	Decimal string
+}
+
+// numberFormats maps a language code to the separators used by that locale.
+// The "C" entry is used for the POSIX locale and any unknown language.
+var numberFormats = map[string]numberFormat{
+	"C":  {Group: "", Decimal: "."},
+	"en": {Group: ",", Decimal: "."},
+	"de": {Group: ".", Decimal: ","},
+	"es": {Group: ".", Decimal: ","},
+	"it": {Group: ".", Decimal: ","},
+	"nl": {Group: ".", Decimal: ","},
+	"pt": {Group: ".", Decimal: ","},
+	"fr": {Group: "\u202f", Decimal: ","},
+	"pl": {Group: "\u00a0", Decimal: ","},
+	"ru": {Group: "\u00a0", Decimal: ","},
+	"sv": {Group: "\u00a0", Decimal: ","},
+	"ch": {Group: "'", Decimal: "."},
+}
+
+// numberLocale is the language code used by formatCount and humanReadableSize.
+var numberLocale = "C"
+
+// localeLanguage reduces a locale name such as "de_DE.UTF-8" to its language
+// code ("de"). An empty result means no locale was given.
+func localeLanguage(locale string) string {
+	if i := strings.IndexAny(locale, ".@"); i >= 0 {
+		locale = locale[:i]
+	}
+	if locale == "C" || locale == "POSIX" {
+		return "C"
+	}
+	if strings.HasSuffix(locale, "_CH") {
+		return "ch"
+	}
+	if i := strings.IndexAny(locale, "_-"); i >= 0 {
+		locale = locale[:i]
+	}
+	return strings.ToLower(locale)
+}
+
+// detectLocale returns the language code from the first non-empty locale
+// environment variable, following the POSIX precedence for numeric formatting.
+func detectLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if lang := localeLanguage(os.Getenv(name)); lang != "" {
+			return lang
+		}
+	}
+	return "C"
+}
+
+func currentNumberFormat() numberFormat {
+	if f, ok := numberFormats[numberLocale]; ok {
+		return f
+	}
+	return numberFormats["C"]
+}
+
+func groupDigits(digits, sep string) string {
+	if sep == "" || len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	lead := len(digits) % 3
+	if lead > 0 {
+		b.WriteString(digits[:lead])
+	}
+	for i := lead; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+// formatCount formats an integer with the thousands separator of the current locale.
+func formatCount(n int64) string {
+	f := currentNumberFormat()
+	digits := strconv.FormatInt(n, 10)
+	if n < 0 {
+		return "-" + groupDigits(digits[1:], f.Group)
+	}
+	return groupDigits(digits, f.Group)
+}
+
+// formatDecimal formats a float with prec fractional digits using the
+// separators of the current locale.
+func formatDecimal(v float64, prec int) string {
+	f := currentNumberFormat()
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i+1:]
+	}
+	s = sign + groupDigits(intPart, f.Group)
+	if frac != "" {
+		s += f.Decimal + frac
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func TestLocaleLanguage(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"de_DE.UTF-8", "de"},
+		{"fr_FR@euro", "fr"},
+		{"en-US", "en"},
+		{"de_CH.UTF-8", "ch"},
+		{"C", "C"},
+		{"POSIX", "C"},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result := localeLanguage(tc.input)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, Got: %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		n        int64
+		expected string
+	}{
+		{"C", 1234567, "1234567"},
+		{"en", 999, "999"},
+		{"en", 1000, "1,000"},
+		{"en", 1234567, "1,234,567"},
+		{"en", -1234567, "-1,234,567"},
+		{"de", 1234567, "1.234.567"},
+		{"fr", 1234567, "1\u202f234\u202f567"},
+		{"xx", 1234567, "1234567"},
+	}
+
+	defer func(locale string) { numberLocale = locale }(numberLocale)
+	for _, tc := range testCases {
+		t.Run(tc.locale+" "+tc.expected, func(t *testing.T) {
+			numberLocale = tc.locale
+			result := formatCount(tc.n)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, Got: %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		v        float64
+		expected string
+	}{
+		{"C", 1023.5, "1023.50"},
+		{"en", 1023.5, "1,023.50"},
+		{"de", 1023.5, "1.023,50"},
+		{"de", -0.25, "-0,25"},
+	}
+
+	defer func(locale string) { numberLocale = locale }(numberLocale)
+	for _, tc := range testCases {
+		t.Run(tc.locale+" "+tc.expected, func(t *testing.T) {
+			numberLocale = tc.locale
+			result := formatDecimal(tc.v, 2)
+			if result != tc.expected {
+				t.Errorf("Expected: %s, Got: %s", tc.expected, result)
+			}
+		})
+	}
+}
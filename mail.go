@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// mailConfig holds the SMTP settings used to deliver reports by email.
+type mailConfig struct {
+	Server   string // host:port
+	User     string
+	Password string
+	From     string
+	To       []string
+}
+
+func (c mailConfig) enabled() bool {
+	return c.Server != "" && len(c.To) > 0
+}
+
+func (c mailConfig) sender() string {
+	if c.From != "" {
+		return c.From
+	}
+	if strings.Contains(c.User, "@") {
+		return c.User
+	}
+	return "duplicate_finder@localhost"
+}
+
+// parseRecipients splits the comma-separated addresses of --mail-to,
+// dropping spaces around them and empty entries.
+func parseRecipients(list string) []string {
+	var to []string
+	for _, addr := range strings.Split(list, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	return to
+}
+
+// buildMail renders a plain text UTF-8 message with quoted-printable body.
+func buildMail(from string, to []string, subject, body string, date time.Time) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&msg)
+	qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	qp.Close()
+	return msg.Bytes()
+}
+
+// sendMail delivers a message through the configured SMTP server. The
+// connection is upgraded with STARTTLS when the server supports it; plain
+// authentication is only attempted over TLS or to localhost.
+func sendMail(c mailConfig, subject, body string) error {
+	var auth smtp.Auth
+	if c.User != "" {
+		host, _, err := net.SplitHostPort(c.Server)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", c.User, c.Password, host)
+	}
+	return smtp.SendMail(c.Server, auth, c.sender(), c.To, buildMail(c.sender(), c.To, subject, body, time.Now()))
+}
+
+func mailSubject(summary runSummary) string {
+	return msg("mail.subject", formatCount(int64(summary.DuplicateGroups)), summary.Root, humanReadableSize(summary.ReclaimableBytes))
+}
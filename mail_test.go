@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMail(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	message := string(buildMail("nas@example.com", []string{"a@example.com", "b@example.com"}, "Größe", "line 1\nline=2\n", date))
+
+	for _, expected := range []string{
+		"From: nas@example.com\r\n",
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: =?utf-8?q?Gr=C3=B6=C3=9Fe?=\r\n",
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n",
+		"Content-Transfer-Encoding: quoted-printable\r\n\r\nline 1\r\nline=3D2\r\n",
+	} {
+		if !strings.Contains(message, expected) {
+			t.Errorf("Expected %q in message:\n%s", expected, message)
+		}
+	}
+}
+
+func TestParseRecipients(t *testing.T) {
+	if to := parseRecipients("a@x, b@y,,"); !reflect.DeepEqual(to, []string{"a@x", "b@y"}) {
+		t.Errorf("Unexpected recipients: %q", to)
+	}
+	if to := parseRecipients(""); len(to) != 0 {
+		t.Errorf("Expected no recipients, Got: %q", to)
+	}
+}
+
+func TestMailSender(t *testing.T) {
+	testCases := []struct {
+		config   mailConfig
+		expected string
+	}{
+		{mailConfig{From: "from@example.com", User: "user@example.com"}, "from@example.com"},
+		{mailConfig{User: "user@example.com"}, "user@example.com"},
+		{mailConfig{User: "user"}, "duplicate_finder@localhost"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			if result := tc.config.sender(); result != tc.expected {
+				t.Errorf("Expected: %s, Got: %s", tc.expected, result)
+			}
+		})
+	}
+}
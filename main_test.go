@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 // Helper function to create a temporary directory for testing and return its path
@@ -42,17 +43,24 @@ func TestFormatPath(t *testing.T) {
 
 func TestHumanReadableSize(t *testing.T) {
 	testCases := []struct {
+		units    string
 		size     int64
 		expected string
 	}{
-		{1024, "1.00 KB"},
-		{1024 * 1024, "1.00 MB"},
-		{1024 * 1024 * 1024, "1.00 GB"},
-		{1024 * 1024 * 1024 * 1024, "1.00 TB"},
+		{"iec", 1024, "1.00 KiB"},
+		{"iec", 1024 * 1024, "1.00 MiB"},
+		{"iec", 1024 * 1024 * 1024, "1.00 GiB"},
+		{"iec", 1024 * 1024 * 1024 * 1024, "1.00 TiB"},
+		{"si", 1000, "1.00 KB"},
+		{"si", 1500 * 1000, "1.50 MB"},
+		{"si", 1024, "1.02 KB"},
+		{"si", 999, "999.00 B"},
 	}
 
+	defer func(units string) { sizeUnits = units }(sizeUnits)
 	for _, tc := range testCases {
-		t.Run(tc.expected, func(t *testing.T) {
+		t.Run(tc.units+" "+tc.expected, func(t *testing.T) {
+			sizeUnits = tc.units
 			result := humanReadableSize(tc.size)
 			if result != tc.expected {
 				t.Errorf("Expected: %s, Got: %s", tc.expected, result)
@@ -61,6 +69,44 @@ func TestHumanReadableSize(t *testing.T) {
 	}
 }
 
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		units    string
+		input    string
+		expected int64
+	}{
+		{"iec", "1500", 1500},
+		{"iec", "10b", 10},
+		{"iec", "100M", 100 << 20},
+		{"iec", "1.5 GiB", 3 << 29},
+		{"iec", "10kB", 10000},
+		{"iec", "2k", 2048},
+		{"si", "2k", 2000},
+		{"si", "2KiB", 2048},
+		{"si", "1T", 1000000000000},
+	}
+
+	defer func(units string) { sizeUnits = units }(sizeUnits)
+	for _, tc := range testCases {
+		t.Run(tc.units+" "+tc.input, func(t *testing.T) {
+			sizeUnits = tc.units
+			result, err := parseSize(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected: %d, Got: %d", tc.expected, result)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "M", "-1", "10x", "10kim", "10 mi b"} {
+		if _, err := parseSize(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
 func TestCopyFile(t *testing.T) {
 	// Create a temporary test directory
 	tempDir := createTempDirForTest(t)
@@ -179,7 +225,7 @@ func TestMoveFiles(t *testing.T) {
 	fileMap := make(map[string][]File)
 
 	for _, file := range testFiles {
-		fileMap["hash123"] = append(fileMap["hash123"], File{Path: file.sourcePath, Hash: "hash123", Size: int64(len(file.content))})
+		fileMap["hash123"] = append(fileMap["hash123"], File{path: indexPath(file.sourcePath), Hash: "hash123", Size: int64(len(file.content))})
 	}
 
 	moveFiles(fileMap, tempDir2)
@@ -222,7 +268,7 @@ func TestDeleteFiles(t *testing.T) {
 	fileMap := make(map[string][]File)
 
 	for _, file := range testFiles {
-		fileMap["hash123"] = append(fileMap["hash123"], File{Path: file.path, Hash: "hash123", Size: int64(len(file.content))})
+		fileMap["hash123"] = append(fileMap["hash123"], File{path: indexPath(file.path), Hash: "hash123", Size: int64(len(file.content))})
 	}
 
 	deleteFiles(fileMap, true)
@@ -239,6 +285,88 @@ func TestDeleteFiles(t *testing.T) {
 	}
 }
 
+func TestDeleteFilesCanceled(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	paths := []string{filepath.Join(tempDir, "keep.txt"), filepath.Join(tempDir, "dup.txt")}
+	fileMap := make(map[string][]File)
+	for _, path := range paths {
+		if err := ioutil.WriteFile(path, []byte("Test content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fileMap["hash123"] = append(fileMap["hash123"], File{path: indexPath(path), Hash: "hash123", Size: 12})
+	}
+
+	if stats := deleteFiles(fileMap, false); stats.Files != 0 {
+		t.Errorf("Expected no deletions, Got: %d", stats.Files)
+	}
+	if _, err := os.Stat(paths[1]); err != nil {
+		t.Errorf("Duplicate was deleted without confirmation: %v", err)
+	}
+
+	if stats := deleteFiles(fileMap, true); stats.Files != 1 || stats.Bytes != 12 {
+		t.Errorf("Unexpected delete stats: %+v", stats)
+	}
+}
+
+func TestActionsSkipFilesChangedSinceScan(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	archive := filepath.Join(tempDir, "archive")
+	os.Mkdir(archive, 0755)
+
+	scan := func(names ...string) []File {
+		var files []File
+		for _, name := range names {
+			path := filepath.Join(tempDir, name)
+			if err := ioutil.WriteFile(path, []byte("Test content"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			file, err := hashFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, file)
+		}
+		return files
+	}
+
+	files := scan("keep.txt", "dup1.txt", "dup2.txt")
+	ioutil.WriteFile(files[2].Path(), []byte("Edited while prompting"), 0644)
+	if stats := deleteFiles(map[string][]File{"hash": files}, true); stats != (actionStats{Files: 1, Bytes: 12, Stale: 1}) {
+		t.Errorf("Unexpected delete stats: %+v", stats)
+	}
+	if _, err := os.Stat(files[2].Path()); err != nil {
+		t.Errorf("Changed file was deleted: %v", err)
+	}
+
+	files = scan("keep.txt", "dup3.txt")
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(files[0].Path(), future, future)
+	if stats := moveFiles(map[string][]File{"hash": files}, archive); stats != (actionStats{Stale: 1}) {
+		t.Errorf("Unexpected move stats: %+v", stats)
+	}
+	if _, err := os.Stat(files[1].Path()); err != nil {
+		t.Errorf("Duplicate of a changed kept file was moved: %v", err)
+	}
+
+	// Vanished duplicates and kept copies make their group stale as well.
+	files = scan("keep.txt", "dup4.txt", "dup5.txt")
+	os.Remove(files[2].Path())
+	if stats := deleteFiles(map[string][]File{"hash": files}, true); stats != (actionStats{Files: 1, Bytes: 12, Stale: 1}) {
+		t.Errorf("Unexpected delete stats: %+v", stats)
+	}
+	files = scan("keep.txt", "dup6.txt")
+	os.Remove(files[0].Path())
+	if stats := deleteFiles(map[string][]File{"hash": files}, true); stats != (actionStats{Stale: 1}) {
+		t.Errorf("Unexpected delete stats: %+v", stats)
+	}
+	if _, err := os.Stat(files[1].Path()); err != nil {
+		t.Errorf("Duplicate of a vanished kept file was deleted: %v", err)
+	}
+}
+
 // Add more tests for other functions as needed
 
 func TestMain(m *testing.M) {
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// mergeResults combines saved scans into one set of results. Files are
+// regrouped by hash across all scans, so copies on different machines or
+// volumes form one group; a path that appears in several scans is kept once,
+// with the hash of the most recent scan. Groups that a matcher plugin split
+// stay split. The merged results have no root.
+func mergeResults(inputs []scanResults) scanResults {
+	var merged scanResults
+	type entry struct {
+		key       string // fileMap key of the file's group
+		file      File
+		scannedAt time.Time
+	}
+	entries := make(map[string]*entry)
+	var paths []string // in the order they were first seen
+	add := func(key string, file File, scannedAt time.Time) {
+		e, seen := entries[file.Path()]
+		if !seen {
+			entries[file.Path()] = &entry{key, file, scannedAt}
+			paths = append(paths, file.Path())
+			return
+		}
+		// The scans counted the file twice.
+		merged.FilesScanned--
+		if scannedAt.After(e.scannedAt) {
+			merged.TotalSize -= e.file.Size
+			*e = entry{key, file, scannedAt}
+		} else {
+			merged.TotalSize -= file.Size
+		}
+	}
+
+	keepUnique := false
+	for _, results := range inputs {
+		if results.ScannedAt.After(merged.ScannedAt) {
+			merged.ScannedAt = results.ScannedAt
+		}
+		merged.FilesScanned += results.FilesScanned
+		merged.TotalSize += results.TotalSize
+		keepUnique = keepUnique || len(results.Unique) > 0
+		for _, group := range results.Groups {
+			key := group.Hash
+			if dash := strings.IndexByte(group.ID, '-'); dash >= 0 {
+				key += group.ID[dash:]
+			}
+			for _, file := range group.Files {
+				add(key, file, results.ScannedAt)
+			}
+		}
+		for _, file := range results.Unique {
+			add(file.Hash, file, results.ScannedAt)
+		}
+	}
+
+	fileMap := make(map[string][]File)
+	for _, path := range paths {
+		e := entries[path]
+		fileMap[e.key] = append(fileMap[e.key], e.file)
+	}
+	merged.Groups = duplicateGroups(fileMap)
+	if keepUnique {
+		merged.Unique = uniqueFiles(fileMap)
+	}
+	return merged
+}
+
+// runMerge implements the merge command, which combines results files
+// written by --save into one.
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	out := fs.String("out", "", "write the merged results to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s merge [flags] results.json results.json...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var inputs []scanResults
+	for _, path := range fs.Args() {
+		results, err := loadResults(path)
+		if err != nil {
+			log.Fatalf("Error loading results from %s: %v", path, err)
+		}
+		if len(results.Unique) == 0 {
+			log.Printf("%s has no files without duplicates (saved without --save-all); duplicates of them in other scans are not found", path)
+		}
+		inputs = append(inputs, results)
+	}
+	merged := mergeResults(inputs)
+	var err error
+	if *out == "" {
+		err = writeResults(os.Stdout, merged)
+	} else {
+		err = saveResults(*out, merged)
+	}
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+}
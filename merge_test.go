@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeResults(t *testing.T) {
+	older := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	newer := older.Add(24 * time.Hour)
+	a := scanResults{
+		ScannedAt:    older,
+		FilesScanned: 4,
+		TotalSize:    10,
+		Groups: duplicateGroups(map[string][]File{
+			"aaaa":   {{path: indexPath("/a/1"), Size: 2, Hash: "aaaa"}, {path: indexPath("/a/2"), Size: 2, Hash: "aaaa"}},
+			"bbbb-1": {{path: indexPath("/a/3"), Size: 3, Hash: "bbbb"}, {path: indexPath("/a/4"), Size: 3, Hash: "bbbb"}},
+		}),
+	}
+	b := scanResults{
+		ScannedAt:    newer,
+		FilesScanned: 3,
+		TotalSize:    7,
+		Unique: []File{
+			{path: indexPath("/a/2"), Size: 2, Hash: "cccc"}, // changed since the older scan
+			{path: indexPath("/b/1"), Size: 2, Hash: "aaaa"},
+			{path: indexPath("/b/2"), Size: 3, Hash: "dddd"},
+		},
+	}
+
+	merged := mergeResults([]scanResults{a, b})
+	if merged.FilesScanned != 6 || merged.TotalSize != 15 || !merged.ScannedAt.Equal(newer) {
+		t.Errorf("Unexpected totals: %d files, %d bytes, scanned at %s", merged.FilesScanned, merged.TotalSize, merged.ScannedAt)
+	}
+	groups := make(map[string][]string)
+	for _, group := range merged.Groups {
+		for _, file := range group.Files {
+			groups[group.ID] = append(groups[group.ID], file.Path())
+		}
+	}
+	expected := map[string][]string{
+		"aaaa":   {"/a/1", "/b/1"},
+		"bbbb-1": {"/a/3", "/a/4"},
+	}
+	if len(groups) != len(expected) {
+		t.Fatalf("Expected: %v, Got: %v", expected, groups)
+	}
+	for id, paths := range expected {
+		if len(groups[id]) != len(paths) || groups[id][0] != paths[0] || groups[id][1] != paths[1] {
+			t.Errorf("Group %s: Expected: %v, Got: %v", id, paths, groups[id])
+		}
+	}
+	if len(merged.Unique) != 2 || merged.Unique[0].Path() != "/a/2" || merged.Unique[1].Path() != "/b/2" {
+		t.Errorf("Unexpected unique files: %+v", merged.Unique)
+	}
+}
+
+func TestMergeResultsWithoutUnique(t *testing.T) {
+	a := scanResults{FilesScanned: 2, Groups: duplicateGroups(map[string][]File{
+		"aaaa": {{path: indexPath("/a/1"), Hash: "aaaa"}, {path: indexPath("/a/2"), Hash: "aaaa"}},
+	})}
+	b := scanResults{FilesScanned: 2, Groups: duplicateGroups(map[string][]File{
+		"aaaa": {{path: indexPath("/b/1"), Hash: "aaaa"}, {path: indexPath("/b/2"), Hash: "aaaa"}},
+	})}
+
+	merged := mergeResults([]scanResults{a, b})
+	if len(merged.Groups) != 1 || len(merged.Groups[0].Files) != 4 {
+		t.Errorf("Expected one group of 4 files, Got: %+v", merged.Groups)
+	}
+	if merged.Unique != nil {
+		t.Errorf("Expected no unique files, Got: %+v", merged.Unique)
+	}
+}
@@ -0,0 +1,603 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// actionChoices lists the answers to the action prompt in every language.
+const actionChoices = "(l/f/s/v/o/r/m/d/i)"
+
+// messageLang is the language used by msg. It falls back to English for
+// languages or keys without a translation.
+var messageLang = "en"
+
+// messages holds the user-facing strings keyed by language and message id.
+// Log output stays in English so that it can be searched and reported upstream.
+var messages = map[string]map[string]string{
+	"en": {
+		"prompt.folder":            "Enter the folder path to search for duplicates: ",
+		"scan.started":             "Scanning files...",
+		"scan.completed":           "Scanning completed.",
+		"scan.progress":            "Files scanned: %s/%s | Total size: %s/%s (%s%%) | Goroutines: %d/%d",
+		"prompt.action":            "Do you want to list, filter, sort, preview, open, reveal, move, delete, or ignore the duplicates? " + actionChoices + ": ",
+		"action.ignored":           "Duplicates will be ignored.",
+		"action.invalid":           "Invalid choice.",
+		"answer.yes":               "yes",
+		"answer.no":                "no",
+		"confirm.move":             "Are you sure you want to move duplicated files? (%s/%s): ",
+		"move.canceled":            "Move operation canceled.",
+		"prompt.destination":       "Enter the destination path to move duplicated files: ",
+		"confirm.delete":           "Are you sure you want to delete duplicated files? (%s/%s): ",
+		"delete.canceled":          "Deletion canceled.",
+		"list.group":               "Duplicate group %s (hash %s):",
+		"move.done":                "Moved file %s to %s",
+		"delete.done":              "Deleted file: %s",
+		"report.by_extension":      "Wasted space by extension:",
+		"report.no_extension":      "(no extension)",
+		"report.files":             "%s files",
+		"report.by_directory":      "Wasted space by directory:",
+		"prompt.plugin_actions":    "Plugin actions available with p: %s",
+		"prompt.plugin":            "Enter the name of the plugin to run: ",
+		"plugin.done":              "Plugin %s processed %s",
+		"summary.root":             "Scan root: %s",
+		"summary.scanned":          "Files scanned: %s",
+		"summary.duplicates":       "Duplicate groups: %s (%s redundant files)",
+		"summary.reclaimable":      "Reclaimable space: %s",
+		"summary.cleanup":          "Cleanup (%s): %s files, %s reclaimed",
+		"summary.errors":           "Errors: %s",
+		"mail.subject":             "Duplicate finder: %s duplicate groups in %s (%s reclaimable)",
+		"chat.scan":                "Scan of %s finished: %s duplicate groups, %s reclaimable, %s errors",
+		"chat.cleanup":             "Cleanup of %s finished (%s): %s files, %s reclaimed, %s errors",
+		"desktop.title":            "Duplicate scan finished",
+		"service.written":          "Wrote %s",
+		"service.removed":          "Removed %s",
+		"gui.url":                  "Web interface running at %s",
+		"prompt.preview":           "Enter a group ID or file path to preview: ",
+		"preview.file":             "%s (%s, modified %s)",
+		"preview.image":            "Image: %dx%d %s",
+		"preview.camera":           "Camera: %s",
+		"preview.taken":            "Taken: %s",
+		"preview.media":            "Duration: %s | Streams: %s",
+		"preview.binary":           "Binary file (%s)",
+		"preview.not_found":        "No duplicate group or file named %s.",
+		"prompt.open":              "Enter a group ID or file path to open: ",
+		"prompt.reveal":            "Enter a group ID or file path to show in the file manager: ",
+		"report.treemap":           "Wasted space treemap:",
+		"simulate.keep":            "  keep    %s",
+		"simulate.remove":          "  remove  %s",
+		"simulate.total":           "Would keep %s files and remove %s files, reclaiming %s.",
+		"history.header":           "Date\tRoot\tEvent\tGroups\tWasted\tChange\t",
+		"history.empty":            "No scans have been recorded yet.",
+		"query.total":              "%s matching groups, %s reclaimable.",
+		"plan.written":             "Plan written to %s: %s actions, %s to reclaim.",
+		"apply.confirm":            "Apply %s actions reclaiming %s? (%s/%s): ",
+		"apply.canceled":           "Plan not applied.",
+		"apply.done":               "Applied %s actions, %s reclaimed, %s stale files skipped, %s errors.",
+		"scan.keys":                "Press p to pause, r to resume or s for a status report.",
+		"scan.paused":              "Scan paused; files being hashed are finished. Press r to resume.",
+		"scan.resumed":             "Scan resumed.",
+		"scan.state.running":       "running",
+		"scan.state.paused":        "paused",
+		"scan.status":              "Scan %s for %s",
+		"scan.status.files":        "Files hashed: %s of %s found, %s errors",
+		"scan.status.bytes":        "Data hashed: %s of %s (%s%%) at %s/s",
+		"scan.status.workers":      "Files being hashed: %d of %d workers busy",
+		"schedule.paused":          "Outside the time window %v: scan paused until %s.",
+		"schedule.resumed":         "Time window %v reached: scan resumed.",
+		"scan.state.canceled":      "canceled",
+		"prompt.filter":            "Enter a filter such as ext=jpg size>10M under=/old-backup path=holiday (empty for all groups): ",
+		"filter.active":            "Actions now apply to %s groups with %s of redundant copies.",
+		"simulate.score":           "(score %s)",
+		"apply.over":               "This plan touches %s files and %s, more than --confirm-over %s allows without confirmation.",
+		"delete.deferred":          "Delete limit reached: %s files left for a later run.",
+		"hardlinks.group":          "%s paths share one file of %s (%s links in total), saving %s:",
+		"hardlinks.total":          "%s hard-linked files with %s paths save %s.",
+		"hardlinks.none":           "No hard-linked files found.",
+		"cow.none":                 "No duplicates on a copy-on-write file system (btrfs, XFS or ZFS) found.",
+		"cow.fs":                   "%s: %s duplicates could share %s with their kept copy.",
+		"cow.total":                "Sharing the data of %s duplicates would save up to %s.",
+		"cow.done":                 "Shared the data of %s with %s",
+		"cow.applied":              "Shared the data of %s duplicates (%s), %s skipped (use --script for ZFS), %s errors.",
+		"scan.status.file":         "Hashing %s: %s of %s (%s%%) in %s",
+		"stream.group":             "Duplicates %s (%s each): %s and %s",
+		"stream.more":              "Duplicates %s: also %s",
+		"scan.quota":               "Stopped the scan early: %s duplicate groups wasting %s found so far.",
+		"scan.progress.eta":        " | %s/s | Time left: %v",
+		"scan.status.eta":          "Time left: about %v",
+		"move.progress":            "Copying %s: %s of %s (%s%%)",
+		"action.read_only":         "Files cannot be moved, deleted or processed by plugins in read-only mode (--read-only).",
+		"verify.ok":                "%s entries verified, the log is unchanged. Hash of the last entry: %s",
+		"verify.unchained":         "The first %s entries were written before the log was chained and cannot be verified.",
+		"verify.broken":            "%s was changed: line %s does not continue the chain of the entries before it.",
+		"restore.done":             "Restored %s to %s",
+		"restore.would":            "Would restore %s to %s",
+		"restore.none":             "No moved file matches %s.",
+		"purge.done":               "Purged %s",
+		"purge.would":              "Would purge %s (moved on %s)",
+		"purge.summary":            "Purged %s moved duplicates, %s reclaimed.",
+		"stats.dir":                "State directory: %s",
+		"stats.scans":              "Scans: %s of %s folders, the last on %s",
+		"stats.scans.none":         "Scans: none recorded",
+		"stats.wasted":             "Wasted space at the last scans: %s",
+		"stats.cleanups":           "Cleanups: %s, reclaiming %s to date",
+		"stats.actions":            "Audit log: %s actions, %s failed, %s deleted",
+		"stats.quarantine":         "Moved duplicates: %s files of %s, the oldest moved on %s",
+		"stats.quarantine.none":    "Moved duplicates: none",
+		"stats.quarantine.missing": "Moved duplicates that are gone: %s",
+		"stats.partial":            "Unfinished copies: %s of %s",
+		"photos.found":             "%s sets of photos look like duplicates by camera and capture time, but have different content:",
+		"photos.cluster":           "Likely duplicates: %s photos taken with %s at %s",
+		"likely.confirm":           "Treat them as duplicates of the first file? (%s/%s): ",
+		"heicjpeg.found":           "%s HEIC photos have a JPEG copy of the same image:",
+		"heicjpeg.pair":            "  %s (%s)\n  %s (%s)",
+		"heicjpeg.prompt":          "Keep only the HEIC, only the JPEG, or both? (h/j/b): ",
+		"screenshots.found":        "%s sets of screenshots are near-identical:",
+		"screenshots.cluster":      "Likely duplicates: %s screenshots, the newest first",
+		"git.checkouts":            "%s files in git working trees are duplicates of files in other checkouts and are left alone; pass --clean-checkouts to include them.",
+		"similar.found":            "%s groups of files in the same folder have similar names but different content, possibly versions of one document:",
+		"versions.found":           "%s sets of files look like versions of one document by their names and content:",
+		"versions.family":          "Likely versions: %s files named %s with at least %s%% of their content in common, the newest first",
+		"largest.files":            "Largest files:",
+		"largest.folders":          "Largest folders:",
+		"untouched.found":          "%s duplicate groups wasting %s were neither changed nor opened for %s, the safest to clean up:",
+		"untouched.group":          "%s in %s copies, last used %s: %s",
+		"sink.sent":                "Sent %s files to %s.",
+		"snapshot.identical":       "%s files (%s) are identical to the snapshot and safe to prune:",
+		"snapshot.changed":         "%s files (%s) differ from the snapshot:",
+		"snapshot.new":             "%s files (%s) are not in the snapshot:",
+		"snapshot.errors":          "%s files could not be compared.",
+		"report.omitted":           "%s smaller groups wasting %s in total are not listed (--report-min-size %s).",
+		"prompt.sort":              "Sort the listed groups by waste, count or path (empty for the default order): ",
+	},
+	"de": {
+		"prompt.folder":            "Ordnerpfad für die Duplikatsuche eingeben: ",
+		"scan.started":             "Dateien werden gescannt...",
+		"scan.completed":           "Scan abgeschlossen.",
+		"scan.progress":            "Gescannte Dateien: %s/%s | Gesamtgröße: %s/%s (%s %%) | Goroutinen: %d/%d",
+		"prompt.action":            "Duplikate auflisten, filtern, sortieren, ansehen, öffnen, im Dateimanager zeigen, verschieben, löschen oder ignorieren? " + actionChoices + ": ",
+		"action.ignored":           "Duplikate werden ignoriert.",
+		"action.invalid":           "Ungültige Auswahl.",
+		"answer.yes":               "ja",
+		"answer.no":                "nein",
+		"confirm.move":             "Sollen die doppelten Dateien wirklich verschoben werden? (%s/%s): ",
+		"move.canceled":            "Verschieben abgebrochen.",
+		"prompt.destination":       "Zielpfad für die doppelten Dateien eingeben: ",
+		"confirm.delete":           "Sollen die doppelten Dateien wirklich gelöscht werden? (%s/%s): ",
+		"delete.canceled":          "Löschen abgebrochen.",
+		"list.group":               "Duplikatgruppe %s (Hash %s):",
+		"move.done":                "Datei %s nach %s verschoben",
+		"delete.done":              "Datei gelöscht: %s",
+		"report.by_extension":      "Verschwendeter Speicher nach Dateiendung:",
+		"report.no_extension":      "(ohne Endung)",
+		"report.files":             "%s Dateien",
+		"report.by_directory":      "Verschwendeter Speicher nach Verzeichnis:",
+		"prompt.plugin_actions":    "Plugin-Aktionen mit p verfügbar: %s",
+		"prompt.plugin":            "Name des auszuführenden Plugins eingeben: ",
+		"plugin.done":              "Plugin %s hat %s verarbeitet",
+		"summary.root":             "Scan-Verzeichnis: %s",
+		"summary.scanned":          "Gescannte Dateien: %s",
+		"summary.duplicates":       "Duplikatgruppen: %s (%s überflüssige Dateien)",
+		"summary.reclaimable":      "Freizugebender Speicher: %s",
+		"summary.cleanup":          "Bereinigung (%s): %s Dateien, %s freigegeben",
+		"summary.errors":           "Fehler: %s",
+		"mail.subject":             "Duplicate finder: %s Duplikatgruppen in %s (%s freizugeben)",
+		"chat.scan":                "Scan von %s abgeschlossen: %s Duplikatgruppen, %s freizugeben, %s Fehler",
+		"chat.cleanup":             "Bereinigung von %s abgeschlossen (%s): %s Dateien, %s freigegeben, %s Fehler",
+		"desktop.title":            "Duplikatsuche abgeschlossen",
+		"service.written":          "%s geschrieben",
+		"service.removed":          "%s entfernt",
+		"gui.url":                  "Weboberfläche läuft unter %s",
+		"prompt.preview":           "Gruppen-ID oder Dateipfad zur Vorschau eingeben: ",
+		"preview.file":             "%s (%s, geändert %s)",
+		"preview.image":            "Bild: %dx%d %s",
+		"preview.camera":           "Kamera: %s",
+		"preview.taken":            "Aufgenommen: %s",
+		"preview.media":            "Dauer: %s | Streams: %s",
+		"preview.binary":           "Binärdatei (%s)",
+		"preview.not_found":        "Keine Duplikatgruppe oder Datei namens %s.",
+		"prompt.open":              "Gruppen-ID oder Dateipfad zum Öffnen eingeben: ",
+		"prompt.reveal":            "Gruppen-ID oder Dateipfad zur Anzeige im Dateimanager eingeben: ",
+		"report.treemap":           "Treemap des verschwendeten Speicherplatzes:",
+		"simulate.keep":            "  behalten  %s",
+		"simulate.remove":          "  entfernen %s",
+		"simulate.total":           "Es würden %s Dateien behalten und %s Dateien entfernt, %s würden frei.",
+		"history.header":           "Datum\tOrdner\tEreignis\tGruppen\tVerschwendet\tÄnderung\t",
+		"history.empty":            "Es wurden noch keine Scans aufgezeichnet.",
+		"query.total":              "%s passende Gruppen, %s freigebbar.",
+		"plan.written":             "Plan nach %s geschrieben: %s Aktionen, %s würden frei.",
+		"apply.confirm":            "%s Aktionen ausführen und %s freigeben? (%s/%s): ",
+		"apply.canceled":           "Plan nicht ausgeführt.",
+		"apply.done":               "%s Aktionen ausgeführt, %s freigegeben, %s veraltete Dateien übersprungen, %s Fehler.",
+		"scan.keys":                "p pausiert, r setzt fort, s zeigt einen Statusbericht.",
+		"scan.paused":              "Scan pausiert; Dateien in Bearbeitung werden noch fertig gehasht. r setzt fort.",
+		"scan.resumed":             "Scan fortgesetzt.",
+		"scan.state.running":       "läuft",
+		"scan.state.paused":        "pausiert",
+		"scan.status":              "Scan %s seit %s",
+		"scan.status.files":        "Gehashte Dateien: %s von %s gefundenen, %s Fehler",
+		"scan.status.bytes":        "Gehashte Daten: %s von %s (%s %%) mit %s/s",
+		"scan.status.workers":      "Dateien in Bearbeitung: %d von %d Workern belegt",
+		"schedule.paused":          "Außerhalb des Zeitfensters %v: Scan bis %s pausiert.",
+		"schedule.resumed":         "Zeitfenster %v erreicht: Scan fortgesetzt.",
+		"scan.state.canceled":      "abgebrochen",
+		"prompt.filter":            "Filter eingeben, z. B. ext=jpg size>10M under=/old-backup path=urlaub (leer für alle Gruppen): ",
+		"filter.active":            "Aktionen gelten jetzt für %s Gruppen mit %s redundanten Kopien.",
+		"simulate.score":           "(Punktzahl %s)",
+		"apply.over":               "Dieser Plan betrifft %s Dateien und %s, mehr als --confirm-over %s ohne Bestätigung erlaubt.",
+		"delete.deferred":          "Löschlimit erreicht: %s Dateien bleiben für einen späteren Lauf.",
+		"hardlinks.group":          "%s Pfade teilen sich eine Datei von %s (insgesamt %s Links) und sparen %s:",
+		"hardlinks.total":          "%s Dateien mit Hardlinks und %s Pfaden sparen %s.",
+		"hardlinks.none":           "Keine Dateien mit Hardlinks gefunden.",
+		"cow.none":                 "Keine Duplikate auf einem Copy-on-Write-Dateisystem (btrfs, XFS oder ZFS) gefunden.",
+		"cow.fs":                   "%s: %s Duplikate könnten %s mit ihrer behaltenen Kopie teilen.",
+		"cow.total":                "Das Teilen der Daten von %s Duplikaten würde bis zu %s sparen.",
+		"cow.done":                 "Daten von %s mit %s geteilt",
+		"cow.applied":              "Daten von %s Duplikaten geteilt (%s), %s übersprungen (--script für ZFS verwenden), %s Fehler.",
+		"scan.status.file":         "Hashe %s: %s von %s (%s%%) in %s",
+		"stream.group":             "Duplikate %s (je %s): %s und %s",
+		"stream.more":              "Duplikate %s: auch %s",
+		"scan.quota":               "Scan vorzeitig beendet: bisher %s Duplikatgruppen gefunden, die %s belegen.",
+		"scan.progress.eta":        " | %s/s | Verbleibend: %v",
+		"scan.status.eta":          "Verbleibende Zeit: etwa %v",
+		"move.progress":            "Kopiere %s: %s von %s (%s %%)",
+		"action.read_only":         "Im Nur-Lese-Modus (--read-only) können Dateien nicht verschoben, gelöscht oder von Plugins bearbeitet werden.",
+		"verify.ok":                "%s Einträge geprüft, das Protokoll ist unverändert. Hash des letzten Eintrags: %s",
+		"verify.unchained":         "Die ersten %s Einträge wurden vor der Verkettung des Protokolls geschrieben und können nicht geprüft werden.",
+		"verify.broken":            "%s wurde verändert: Zeile %s setzt die Kette der vorherigen Einträge nicht fort.",
+		"restore.done":             "%s nach %s wiederhergestellt",
+		"restore.would":            "Würde %s nach %s wiederherstellen",
+		"restore.none":             "Keine verschobene Datei passt zu %s.",
+		"purge.done":               "%s endgültig gelöscht",
+		"purge.would":              "Würde %s endgültig löschen (verschoben am %s)",
+		"purge.summary":            "%s verschobene Duplikate endgültig gelöscht, %s freigegeben.",
+		"stats.dir":                "Statusverzeichnis: %s",
+		"stats.scans":              "Scans: %s von %s Ordnern, der letzte am %s",
+		"stats.scans.none":         "Scans: keine aufgezeichnet",
+		"stats.wasted":             "Verschwendeter Platz bei den letzten Scans: %s",
+		"stats.cleanups":           "Bereinigungen: %s, bisher %s freigegeben",
+		"stats.actions":            "Audit-Protokoll: %s Aktionen, %s fehlgeschlagen, %s gelöscht",
+		"stats.quarantine":         "Verschobene Duplikate: %s Dateien mit %s, die älteste verschoben am %s",
+		"stats.quarantine.none":    "Verschobene Duplikate: keine",
+		"stats.quarantine.missing": "Verschobene Duplikate, die fehlen: %s",
+		"stats.partial":            "Unvollständige Kopien: %s mit %s",
+		"photos.found":             "%s Gruppen von Fotos sehen nach Kamera und Aufnahmezeit wie Duplikate aus, haben aber unterschiedlichen Inhalt:",
+		"photos.cluster":           "Wahrscheinliche Duplikate: %s Fotos, aufgenommen mit %s am %s",
+		"likely.confirm":           "Als Duplikate der ersten Datei behandeln? (%s/%s): ",
+		"heicjpeg.found":           "%s HEIC-Fotos haben eine JPEG-Kopie desselben Bildes:",
+		"heicjpeg.pair":            "  %s (%s)\n  %s (%s)",
+		"heicjpeg.prompt":          "Nur HEIC, nur JPEG oder beide behalten? (h/j/b): ",
+		"screenshots.found":        "%s Gruppen von Bildschirmfotos sind nahezu identisch:",
+		"screenshots.cluster":      "Wahrscheinliche Duplikate: %s Bildschirmfotos, das neueste zuerst",
+		"git.checkouts":            "%s Dateien in Git-Arbeitsverzeichnissen sind Duplikate von Dateien in anderen Checkouts und bleiben unangetastet; mit --clean-checkouts werden sie einbezogen.",
+		"similar.found":            "%s Gruppen von Dateien im selben Ordner haben ähnliche Namen, aber unterschiedlichen Inhalt, möglicherweise Versionen eines Dokuments:",
+		"versions.found":           "%s Gruppen von Dateien sehen nach Namen und Inhalt wie Versionen eines Dokuments aus:",
+		"versions.family":          "Wahrscheinliche Versionen: %s Dateien namens %s mit mindestens %s %% gemeinsamem Inhalt, die neueste zuerst",
+		"largest.files":            "Größte Dateien:",
+		"largest.folders":          "Größte Ordner:",
+		"untouched.found":          "%s Duplikatgruppen, die %s belegen, wurden seit %s weder geändert noch geöffnet und lassen sich am gefahrlosesten bereinigen:",
+		"untouched.group":          "%s in %s Kopien, zuletzt verwendet am %s: %s",
+		"sink.sent":                "%s Dateien an %s gesendet.",
+		"snapshot.identical":       "%s Dateien (%s) sind identisch mit dem Snapshot und können entfernt werden:",
+		"snapshot.changed":         "%s Dateien (%s) unterscheiden sich vom Snapshot:",
+		"snapshot.new":             "%s Dateien (%s) sind nicht im Snapshot:",
+		"snapshot.errors":          "%s Dateien konnten nicht verglichen werden.",
+		"report.omitted":           "%s kleinere Gruppen, die insgesamt %s verschwenden, sind nicht aufgeführt (--report-min-size %s).",
+		"prompt.sort":              "Aufgelistete Gruppen sortieren nach waste, count oder path (leer für die Standardreihenfolge): ",
+	},
+	"fr": {
+		"prompt.folder":            "Entrez le chemin du dossier à analyser : ",
+		"scan.started":             "Analyse des fichiers...",
+		"scan.completed":           "Analyse terminée.",
+		"scan.progress":            "Fichiers analysés : %s/%s | Taille totale : %s/%s (%s %%) | Goroutines : %d/%d",
+		"prompt.action":            "Lister, filtrer, trier, prévisualiser, ouvrir, afficher dans le dossier, déplacer, supprimer ou ignorer les doublons ? " + actionChoices + " : ",
+		"action.ignored":           "Les doublons seront ignorés.",
+		"action.invalid":           "Choix invalide.",
+		"answer.yes":               "oui",
+		"answer.no":                "non",
+		"confirm.move":             "Voulez-vous vraiment déplacer les fichiers en double ? (%s/%s) : ",
+		"move.canceled":            "Déplacement annulé.",
+		"prompt.destination":       "Entrez le dossier de destination des fichiers en double : ",
+		"confirm.delete":           "Voulez-vous vraiment supprimer les fichiers en double ? (%s/%s) : ",
+		"delete.canceled":          "Suppression annulée.",
+		"list.group":               "Groupe de doublons %s (hash %s) :",
+		"move.done":                "Fichier %s déplacé vers %s",
+		"delete.done":              "Fichier supprimé : %s",
+		"report.by_extension":      "Espace gaspillé par extension :",
+		"report.no_extension":      "(sans extension)",
+		"report.files":             "%s fichiers",
+		"report.by_directory":      "Espace gaspillé par dossier :",
+		"prompt.plugin_actions":    "Actions de plugin disponibles avec p : %s",
+		"prompt.plugin":            "Entrez le nom du plugin à exécuter : ",
+		"plugin.done":              "Le plugin %s a traité %s",
+		"summary.root":             "Dossier analysé : %s",
+		"summary.scanned":          "Fichiers analysés : %s",
+		"summary.duplicates":       "Groupes de doublons : %s (%s fichiers redondants)",
+		"summary.reclaimable":      "Espace récupérable : %s",
+		"summary.cleanup":          "Nettoyage (%s) : %s fichiers, %s récupérés",
+		"summary.errors":           "Erreurs : %s",
+		"mail.subject":             "Duplicate finder : %s groupes de doublons dans %s (%s récupérables)",
+		"chat.scan":                "Analyse de %s terminée : %s groupes de doublons, %s récupérables, %s erreurs",
+		"chat.cleanup":             "Nettoyage de %s terminé (%s) : %s fichiers, %s récupérés, %s erreurs",
+		"desktop.title":            "Recherche de doublons terminée",
+		"service.written":          "%s écrit",
+		"service.removed":          "%s supprimé",
+		"gui.url":                  "Interface web disponible sur %s",
+		"prompt.preview":           "Saisissez l'identifiant d'un groupe ou le chemin d'un fichier à prévisualiser : ",
+		"preview.file":             "%s (%s, modifié le %s)",
+		"preview.image":            "Image : %dx%d %s",
+		"preview.camera":           "Appareil : %s",
+		"preview.taken":            "Prise le : %s",
+		"preview.media":            "Durée : %s | Flux : %s",
+		"preview.binary":           "Fichier binaire (%s)",
+		"preview.not_found":        "Aucun groupe de doublons ni fichier nommé %s.",
+		"prompt.open":              "Saisissez l'identifiant d'un groupe ou le chemin d'un fichier à ouvrir : ",
+		"prompt.reveal":            "Saisissez l'identifiant d'un groupe ou le chemin d'un fichier à afficher dans le gestionnaire de fichiers : ",
+		"report.treemap":           "Carte proportionnelle de l'espace gaspillé :",
+		"simulate.keep":            "  garder     %s",
+		"simulate.remove":          "  supprimer  %s",
+		"simulate.total":           "%s fichiers seraient gardés et %s supprimés, libérant %s.",
+		"history.header":           "Date\tDossier\tÉvénement\tGroupes\tGaspillé\tÉvolution\t",
+		"history.empty":            "Aucune analyse n'a encore été enregistrée.",
+		"query.total":              "%s groupes correspondants, %s récupérables.",
+		"plan.written":             "Plan écrit dans %s : %s actions, %s à récupérer.",
+		"apply.confirm":            "Appliquer %s actions libérant %s ? (%s/%s) : ",
+		"apply.canceled":           "Plan non appliqué.",
+		"apply.done":               "%s actions appliquées, %s récupérés, %s fichiers obsolètes ignorés, %s erreurs.",
+		"scan.keys":                "Appuyez sur p pour mettre en pause, r pour reprendre ou s pour un état détaillé.",
+		"scan.paused":              "Analyse en pause ; les fichiers en cours sont terminés. Appuyez sur r pour reprendre.",
+		"scan.resumed":             "Analyse reprise.",
+		"scan.state.running":       "en cours",
+		"scan.state.paused":        "en pause",
+		"scan.status":              "Analyse %s depuis %s",
+		"scan.status.files":        "Fichiers hachés : %s sur %s trouvés, %s erreurs",
+		"scan.status.bytes":        "Données hachées : %s sur %s (%s %%) à %s/s",
+		"scan.status.workers":      "Fichiers en cours : %d travailleurs occupés sur %d",
+		"schedule.paused":          "Hors de la plage horaire %v : analyse en pause jusqu'à %s.",
+		"schedule.resumed":         "Plage horaire %v atteinte : analyse reprise.",
+		"scan.state.canceled":      "annulée",
+		"prompt.filter":            "Saisissez un filtre, par ex. ext=jpg size>10M under=/old-backup path=vacances (vide pour tous les groupes) : ",
+		"filter.active":            "Les actions s'appliquent désormais à %s groupes avec %s de copies redondantes.",
+		"simulate.score":           "(score %s)",
+		"apply.over":               "Ce plan touche %s fichiers et %s, plus que --confirm-over %s n'autorise sans confirmation.",
+		"delete.deferred":          "Limite de suppression atteinte : %s fichiers laissés pour une prochaine exécution.",
+		"hardlinks.group":          "%s chemins partagent un fichier de %s (%s liens au total), économisant %s :",
+		"hardlinks.total":          "%s fichiers liés en dur avec %s chemins économisent %s.",
+		"hardlinks.none":           "Aucun fichier lié en dur trouvé.",
+		"cow.none":                 "Aucun doublon trouvé sur un système de fichiers copy-on-write (btrfs, XFS ou ZFS).",
+		"cow.fs":                   "%s : %s doublons pourraient partager %s avec leur copie conservée.",
+		"cow.total":                "Partager les données de %s doublons économiserait jusqu'à %s.",
+		"cow.done":                 "Données de %s partagées avec %s",
+		"cow.applied":              "Données de %s doublons partagées (%s), %s ignorés (utilisez --script pour ZFS), %s erreurs.",
+		"scan.status.file":         "Hachage de %s : %s sur %s (%s %%) en %s",
+		"stream.group":             "Doublons %s (%s chacun) : %s et %s",
+		"stream.more":              "Doublons %s : aussi %s",
+		"scan.quota":               "Analyse arrêtée plus tôt : %s groupes de doublons occupant %s trouvés jusqu'ici.",
+		"scan.progress.eta":        " | %s/s | Temps restant : %v",
+		"scan.status.eta":          "Temps restant : environ %v",
+		"move.progress":            "Copie de %s : %s sur %s (%s %%)",
+		"action.read_only":         "Les fichiers ne peuvent pas être déplacés, supprimés ou traités par des plugins en mode lecture seule (--read-only).",
+		"verify.ok":                "%s entrées vérifiées, le journal est intact. Hachage de la dernière entrée : %s",
+		"verify.unchained":         "Les %s premières entrées ont été écrites avant le chaînage du journal et ne peuvent pas être vérifiées.",
+		"verify.broken":            "%s a été modifié : la ligne %s ne prolonge pas la chaîne des entrées précédentes.",
+		"restore.done":             "%s restauré vers %s",
+		"restore.would":            "Restaurerait %s vers %s",
+		"restore.none":             "Aucun fichier déplacé ne correspond à %s.",
+		"purge.done":               "%s purgé",
+		"purge.would":              "Purgerait %s (déplacé le %s)",
+		"purge.summary":            "%s doublons déplacés purgés, %s récupérés.",
+		"stats.dir":                "Répertoire d'état : %s",
+		"stats.scans":              "Analyses : %s de %s dossiers, la dernière le %s",
+		"stats.scans.none":         "Analyses : aucune enregistrée",
+		"stats.wasted":             "Espace gaspillé lors des dernières analyses : %s",
+		"stats.cleanups":           "Nettoyages : %s, %s récupérés à ce jour",
+		"stats.actions":            "Journal d'audit : %s actions, %s échouées, %s supprimés",
+		"stats.quarantine":         "Doublons déplacés : %s fichiers de %s, le plus ancien déplacé le %s",
+		"stats.quarantine.none":    "Doublons déplacés : aucun",
+		"stats.quarantine.missing": "Doublons déplacés introuvables : %s",
+		"stats.partial":            "Copies inachevées : %s de %s",
+		"photos.found":             "%s séries de photos semblent être des doublons d'après l'appareil et l'heure de prise de vue, mais leur contenu diffère :",
+		"photos.cluster":           "Doublons probables : %s photos prises avec %s le %s",
+		"likely.confirm":           "Les traiter comme des doublons du premier fichier ? (%s/%s) : ",
+		"heicjpeg.found":           "%s photos HEIC ont une copie JPEG de la même image :",
+		"heicjpeg.pair":            "  %s (%s)\n  %s (%s)",
+		"heicjpeg.prompt":          "Garder seulement le HEIC, seulement le JPEG, ou les deux ? (h/j/b) : ",
+		"screenshots.found":        "%s séries de captures d'écran sont presque identiques :",
+		"screenshots.cluster":      "Doublons probables : %s captures d'écran, la plus récente d'abord",
+		"git.checkouts":            "%s fichiers d'arbres de travail git sont des doublons de fichiers d'autres checkouts et sont laissés intacts ; utilisez --clean-checkouts pour les inclure.",
+		"similar.found":            "%s groupes de fichiers d'un même dossier ont des noms similaires mais un contenu différent, peut-être des versions d'un même document :",
+		"versions.found":           "%s séries de fichiers semblent être des versions d'un même document d'après leur nom et leur contenu :",
+		"versions.family":          "Versions probables : %s fichiers nommés %s ayant au moins %s %% de contenu en commun, le plus récent en premier",
+		"largest.files":            "Plus gros fichiers :",
+		"largest.folders":          "Plus gros dossiers :",
+		"untouched.found":          "%s groupes de doublons occupant %s n'ont été ni modifiés ni ouverts depuis %s ; ce sont les plus sûrs à nettoyer :",
+		"untouched.group":          "%s dans %s copies, dernière utilisation le %s : %s",
+		"sink.sent":                "%s fichiers envoyés à %s.",
+		"snapshot.identical":       "%s fichiers (%s) sont identiques à l'instantané et peuvent être supprimés :",
+		"snapshot.changed":         "%s fichiers (%s) diffèrent de l'instantané :",
+		"snapshot.new":             "%s fichiers (%s) ne sont pas dans l'instantané :",
+		"snapshot.errors":          "%s fichiers n'ont pas pu être comparés.",
+		"report.omitted":           "%s groupes plus petits gaspillant %s au total ne sont pas listés (--report-min-size %s).",
+		"prompt.sort":              "Trier les groupes listés par waste, count ou path (vide pour l'ordre par défaut) : ",
+	},
+	"es": {
+		"prompt.folder":            "Introduzca la ruta de la carpeta donde buscar duplicados: ",
+		"scan.started":             "Analizando archivos...",
+		"scan.completed":           "Análisis completado.",
+		"scan.progress":            "Archivos analizados: %s/%s | Tamaño total: %s/%s (%s %%) | Gorrutinas: %d/%d",
+		"prompt.action":            "¿Desea listar, filtrar, ordenar, previsualizar, abrir, mostrar en la carpeta, mover, eliminar o ignorar los duplicados? " + actionChoices + ": ",
+		"action.ignored":           "Se ignorarán los duplicados.",
+		"action.invalid":           "Opción no válida.",
+		"answer.yes":               "sí",
+		"answer.no":                "no",
+		"confirm.move":             "¿Seguro que desea mover los archivos duplicados? (%s/%s): ",
+		"move.canceled":            "Operación de mover cancelada.",
+		"prompt.destination":       "Introduzca la ruta de destino para los archivos duplicados: ",
+		"confirm.delete":           "¿Seguro que desea eliminar los archivos duplicados? (%s/%s): ",
+		"delete.canceled":          "Eliminación cancelada.",
+		"list.group":               "Grupo de duplicados %s (hash %s):",
+		"move.done":                "Archivo %s movido a %s",
+		"delete.done":              "Archivo eliminado: %s",
+		"report.by_extension":      "Espacio desperdiciado por extensión:",
+		"report.no_extension":      "(sin extensión)",
+		"report.files":             "%s archivos",
+		"report.by_directory":      "Espacio desperdiciado por directorio:",
+		"prompt.plugin_actions":    "Acciones de plugin disponibles con p: %s",
+		"prompt.plugin":            "Introduzca el nombre del plugin a ejecutar: ",
+		"plugin.done":              "El plugin %s procesó %s",
+		"summary.root":             "Carpeta analizada: %s",
+		"summary.scanned":          "Archivos analizados: %s",
+		"summary.duplicates":       "Grupos de duplicados: %s (%s archivos redundantes)",
+		"summary.reclaimable":      "Espacio recuperable: %s",
+		"summary.cleanup":          "Limpieza (%s): %s archivos, %s recuperados",
+		"summary.errors":           "Errores: %s",
+		"mail.subject":             "Duplicate finder: %s grupos de duplicados en %s (%s recuperables)",
+		"chat.scan":                "Análisis de %s terminado: %s grupos de duplicados, %s recuperables, %s errores",
+		"chat.cleanup":             "Limpieza de %s terminada (%s): %s archivos, %s recuperados, %s errores",
+		"desktop.title":            "Búsqueda de duplicados terminada",
+		"service.written":          "%s escrito",
+		"service.removed":          "%s eliminado",
+		"gui.url":                  "Interfaz web disponible en %s",
+		"prompt.preview":           "Introduzca el ID de un grupo o la ruta de un archivo para previsualizar: ",
+		"preview.file":             "%s (%s, modificado %s)",
+		"preview.image":            "Imagen: %dx%d %s",
+		"preview.camera":           "Cámara: %s",
+		"preview.taken":            "Tomada: %s",
+		"preview.media":            "Duración: %s | Flujos: %s",
+		"preview.binary":           "Archivo binario (%s)",
+		"preview.not_found":        "No hay ningún grupo de duplicados ni archivo llamado %s.",
+		"prompt.open":              "Introduzca el ID de un grupo o la ruta de un archivo para abrir: ",
+		"prompt.reveal":            "Introduzca el ID de un grupo o la ruta de un archivo para mostrar en el gestor de archivos: ",
+		"report.treemap":           "Mapa de árbol del espacio desperdiciado:",
+		"simulate.keep":            "  conservar  %s",
+		"simulate.remove":          "  eliminar   %s",
+		"simulate.total":           "Se conservarían %s archivos y se eliminarían %s, recuperando %s.",
+		"history.header":           "Fecha\tCarpeta\tEvento\tGrupos\tDesperdiciado\tCambio\t",
+		"history.empty":            "Todavía no se ha registrado ningún análisis.",
+		"query.total":              "%s grupos coincidentes, %s recuperables.",
+		"plan.written":             "Plan escrito en %s: %s acciones, %s a recuperar.",
+		"apply.confirm":            "¿Aplicar %s acciones recuperando %s? (%s/%s): ",
+		"apply.canceled":           "Plan no aplicado.",
+		"apply.done":               "%s acciones aplicadas, %s recuperados, %s archivos obsoletos omitidos, %s errores.",
+		"scan.keys":                "Pulse p para pausar, r para reanudar o s para ver el estado.",
+		"scan.paused":              "Análisis en pausa; los archivos en curso se terminan. Pulse r para reanudar.",
+		"scan.resumed":             "Análisis reanudado.",
+		"scan.state.running":       "en curso",
+		"scan.state.paused":        "en pausa",
+		"scan.status":              "Análisis %s desde hace %s",
+		"scan.status.files":        "Archivos procesados: %s de %s encontrados, %s errores",
+		"scan.status.bytes":        "Datos procesados: %s de %s (%s %%) a %s/s",
+		"scan.status.workers":      "Archivos en curso: %d de %d trabajadores ocupados",
+		"schedule.paused":          "Fuera de la franja horaria %v: análisis en pausa hasta las %s.",
+		"schedule.resumed":         "Franja horaria %v alcanzada: análisis reanudado.",
+		"scan.state.canceled":      "cancelado",
+		"prompt.filter":            "Introduzca un filtro, p. ej. ext=jpg size>10M under=/old-backup path=vacaciones (vacío para todos los grupos): ",
+		"filter.active":            "Las acciones se aplican ahora a %s grupos con %s de copias redundantes.",
+		"simulate.score":           "(puntuación %s)",
+		"apply.over":               "Este plan afecta a %s archivos y %s, más de lo que --confirm-over %s permite sin confirmación.",
+		"delete.deferred":          "Límite de eliminación alcanzado: quedan %s archivos para una ejecución posterior.",
+		"hardlinks.group":          "%s rutas comparten un archivo de %s (%s enlaces en total), ahorrando %s:",
+		"hardlinks.total":          "%s archivos con enlaces duros y %s rutas ahorran %s.",
+		"hardlinks.none":           "No se encontraron archivos con enlaces duros.",
+		"cow.none":                 "No se encontraron duplicados en un sistema de archivos copy-on-write (btrfs, XFS o ZFS).",
+		"cow.fs":                   "%s: %s duplicados podrían compartir %s con su copia conservada.",
+		"cow.total":                "Compartir los datos de %s duplicados ahorraría hasta %s.",
+		"cow.done":                 "Datos de %s compartidos con %s",
+		"cow.applied":              "Datos de %s duplicados compartidos (%s), %s omitidos (use --script para ZFS), %s errores.",
+		"scan.status.file":         "Calculando el hash de %s: %s de %s (%s%%) en %s",
+		"stream.group":             "Duplicados %s (%s cada uno): %s y %s",
+		"stream.more":              "Duplicados %s: también %s",
+		"scan.quota":               "Análisis detenido antes: %s grupos de duplicados que ocupan %s encontrados hasta ahora.",
+		"scan.progress.eta":        " | %s/s | Tiempo restante: %v",
+		"scan.status.eta":          "Tiempo restante: unos %v",
+		"move.progress":            "Copiando %s: %s de %s (%s %%)",
+		"action.read_only":         "En modo de solo lectura (--read-only) no se pueden mover, eliminar ni procesar archivos con plugins.",
+		"verify.ok":                "%s entradas verificadas, el registro no ha cambiado. Hash de la última entrada: %s",
+		"verify.unchained":         "Las primeras %s entradas se escribieron antes de encadenar el registro y no se pueden verificar.",
+		"verify.broken":            "%s fue modificado: la línea %s no continúa la cadena de las entradas anteriores.",
+		"restore.done":             "%s restaurado en %s",
+		"restore.would":            "Se restauraría %s en %s",
+		"restore.none":             "Ningún archivo movido coincide con %s.",
+		"purge.done":               "%s purgado",
+		"purge.would":              "Se purgaría %s (movido el %s)",
+		"purge.summary":            "%s duplicados movidos purgados, %s recuperados.",
+		"stats.dir":                "Directorio de estado: %s",
+		"stats.scans":              "Análisis: %s de %s carpetas, el último el %s",
+		"stats.scans.none":         "Análisis: ninguno registrado",
+		"stats.wasted":             "Espacio desperdiciado en los últimos análisis: %s",
+		"stats.cleanups":           "Limpiezas: %s, %s recuperados hasta la fecha",
+		"stats.actions":            "Registro de auditoría: %s acciones, %s fallidas, %s eliminados",
+		"stats.quarantine":         "Duplicados movidos: %s archivos de %s, el más antiguo movido el %s",
+		"stats.quarantine.none":    "Duplicados movidos: ninguno",
+		"stats.quarantine.missing": "Duplicados movidos que faltan: %s",
+		"stats.partial":            "Copias sin terminar: %s de %s",
+		"photos.found":             "%s conjuntos de fotos parecen duplicados por cámara y hora de captura, pero tienen distinto contenido:",
+		"photos.cluster":           "Duplicados probables: %s fotos tomadas con %s el %s",
+		"likely.confirm":           "¿Tratarlos como duplicados del primer archivo? (%s/%s): ",
+		"heicjpeg.found":           "%s fotos HEIC tienen una copia JPEG de la misma imagen:",
+		"heicjpeg.pair":            "  %s (%s)\n  %s (%s)",
+		"heicjpeg.prompt":          "¿Conservar solo el HEIC, solo el JPEG o ambos? (h/j/b): ",
+		"screenshots.found":        "%s conjuntos de capturas de pantalla son casi idénticos:",
+		"screenshots.cluster":      "Duplicados probables: %s capturas de pantalla, la más reciente primero",
+		"git.checkouts":            "%s archivos de árboles de trabajo de git son duplicados de archivos de otros checkouts y no se tocan; use --clean-checkouts para incluirlos.",
+		"similar.found":            "%s grupos de archivos de la misma carpeta tienen nombres similares pero distinto contenido, posiblemente versiones de un mismo documento:",
+		"versions.found":           "%s conjuntos de archivos parecen versiones de un mismo documento por su nombre y contenido:",
+		"versions.family":          "Versiones probables: %s archivos llamados %s con al menos un %s %% de contenido en común, el más reciente primero",
+		"largest.files":            "Archivos más grandes:",
+		"largest.folders":          "Carpetas más grandes:",
+		"untouched.found":          "%s grupos de duplicados que ocupan %s no se han modificado ni abierto en %s; son los más seguros de limpiar:",
+		"untouched.group":          "%s en %s copias, último uso el %s: %s",
+		"sink.sent":                "%s archivos enviados a %s.",
+		"snapshot.identical":       "%s archivos (%s) son idénticos a la instantánea y se pueden eliminar:",
+		"snapshot.changed":         "%s archivos (%s) difieren de la instantánea:",
+		"snapshot.new":             "%s archivos (%s) no están en la instantánea:",
+		"snapshot.errors":          "No se pudieron comparar %s archivos.",
+		"report.omitted":           "No se listan %s grupos más pequeños que desperdician %s en total (--report-min-size %s).",
+		"prompt.sort":              "Ordenar los grupos listados por waste, count o path (vacío para el orden predeterminado): ",
+	},
+}
+
+// detectMessageLang returns the language from the first non-empty locale
+// environment variable that has a translation, or English.
+func detectMessageLang() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if lang := messageLanguage(os.Getenv(name)); lang != "" {
+			if _, ok := messages[lang]; ok {
+				return lang
+			}
+			return "en"
+		}
+	}
+	return "en"
+}
+
+// messageLanguage reduces a locale name such as "de_CH.UTF-8" to the code of
+// its language ("de"). Unlike localeLanguage, it ignores the region, which
+// only matters for number formats.
+func messageLanguage(locale string) string {
+	if i := strings.IndexAny(locale, ".@_-"); i >= 0 {
+		locale = locale[:i]
+	}
+	return strings.ToLower(locale)
+}
+
+// msg returns the translation of key in messageLang, formatted with args.
+func msg(key string, args ...interface{}) string {
+	text, ok := messages[messageLang][key]
+	if !ok {
+		text = messages["en"][key]
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// isYes reports whether answer confirms a prompt. The English "yes" is always
+// accepted so that scripts piping answers keep working in every language.
+func isYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	yes := msg("answer.yes")
+	return answer == "yes" || answer == yes || (yes == "sí" && answer == "si")
+}
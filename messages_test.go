@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestMessagesTranslated(t *testing.T) {
+	for lang, catalog := range messages {
+		for key := range messages["en"] {
+			if _, ok := catalog[key]; !ok {
+				t.Errorf("Missing %s translation for %s", lang, key)
+			}
+		}
+	}
+}
+
+func TestMessageLanguage(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"de_CH.UTF-8", "de"},
+		{"es_ES.UTF-8", "es"},
+		{"fr-FR", "fr"},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		if result := messageLanguage(tc.input); result != tc.expected {
+			t.Errorf("%s: Expected: %s, Got: %s", tc.input, tc.expected, result)
+		}
+	}
+}
+
+func TestMsgFallback(t *testing.T) {
+	defer func(lang string) { messageLang = lang }(messageLang)
+
+	messageLang = "de"
+	if result := msg("move.done", "a", "b"); result != "Datei a nach b verschoben" {
+		t.Errorf("Expected German message, Got: %s", result)
+	}
+	messageLang = "xx"
+	if result := msg("delete.done", "a"); result != "Deleted file: a" {
+		t.Errorf("Expected English fallback, Got: %s", result)
+	}
+}
+
+func TestIsYes(t *testing.T) {
+	testCases := []struct {
+		lang     string
+		answer   string
+		expected bool
+	}{
+		{"en", "yes", true},
+		{"en", "YES ", true},
+		{"en", "y", false},
+		{"de", "ja", true},
+		{"de", "yes", true},
+		{"de", "nein", false},
+		{"fr", "oui", true},
+		{"es", "sí", true},
+		{"es", "si", true},
+		{"es", "no", false},
+	}
+
+	defer func(lang string) { messageLang = lang }(messageLang)
+	for _, tc := range testCases {
+		t.Run(tc.lang+" "+tc.answer, func(t *testing.T) {
+			messageLang = tc.lang
+			if result := isYes(tc.answer); result != tc.expected {
+				t.Errorf("Expected: %v, Got: %v", tc.expected, result)
+			}
+		})
+	}
+}
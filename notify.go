@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// runSummary describes a finished scan or cleanup for notifications.
+type runSummary struct {
+	Event            string    `json:"event"` // "scan" or "cleanup"
+	Root             string    `json:"root"`
+	Time             time.Time `json:"time"`
+	FilesScanned     int       `json:"files_scanned"`
+	DuplicateGroups  int       `json:"duplicate_groups"`
+	DuplicateFiles   int       `json:"duplicate_files"`
+	ReclaimableBytes int64     `json:"reclaimable_bytes"`
+	Errors           int       `json:"errors"`
+	Action           string    `json:"action,omitempty"` // "move" or "delete"
+	FilesProcessed   int       `json:"files_processed,omitempty"`
+	BytesReclaimed   int64     `json:"bytes_reclaimed,omitempty"`
+}
+
+// summarize builds the scan summary of fileMap.
+func summarize(root string, filesScanned int, fileMap map[string][]File, errors int) runSummary {
+	summary := runSummary{Event: "scan", Root: root, Time: time.Now(), FilesScanned: filesScanned, Errors: errors}
+	for _, group := range duplicateGroups(fileMap) {
+		summary.DuplicateGroups++
+		summary.DuplicateFiles += len(group.Files) - 1
+		summary.ReclaimableBytes += group.Waste()
+	}
+	return summary
+}
+
+// cleanupSummary turns the scan summary into the summary of a finished action.
+func cleanupSummary(summary runSummary, action string, stats actionStats) runSummary {
+	summary.Event = "cleanup"
+	summary.Time = time.Now()
+	summary.Action = action
+	summary.FilesProcessed = stats.Files
+	summary.BytesReclaimed = stats.Bytes
+	summary.Errors = stats.Errors
+	return summary
+}
+
+// notifier delivers run summaries to the configured targets.
+type notifier struct {
+	webhookURL     string
+	slackWebhook   string
+	discordWebhook string
+	telegramToken  string
+	telegramChat   string
+	mail           mailConfig
+}
+
+// telegramAPI is the base URL of the Telegram Bot API.
+var telegramAPI = "https://api.telegram.org"
+
+// chatSummary is the one-line summary posted to chat services.
+func chatSummary(summary runSummary) string {
+	if summary.Event == "cleanup" {
+		return msg("chat.cleanup", summary.Root, summary.Action, formatCount(int64(summary.FilesProcessed)), humanReadableSize(summary.BytesReclaimed), formatCount(int64(summary.Errors)))
+	}
+	return msg("chat.scan", summary.Root, formatCount(int64(summary.DuplicateGroups)), humanReadableSize(summary.ReclaimableBytes), formatCount(int64(summary.Errors)))
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+func postJSON(url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// notify sends summary to every configured target. report is the full text
+// report for targets that deliver one, and may be empty. Failures are logged
+// and never abort the run.
+func (n notifier) notify(summary runSummary, report string) {
+	if n.webhookURL != "" {
+		if err := postJSON(n.webhookURL, summary); err != nil {
+			log.Printf("Error posting summary to webhook %s: %v", n.webhookURL, err)
+		}
+	}
+	text := chatSummary(summary)
+	if n.slackWebhook != "" {
+		if err := postJSON(n.slackWebhook, map[string]string{"text": text}); err != nil {
+			log.Printf("Error posting summary to Slack: %v", err)
+		}
+	}
+	if n.discordWebhook != "" {
+		if err := postJSON(n.discordWebhook, map[string]string{"content": text}); err != nil {
+			log.Printf("Error posting summary to Discord: %v", err)
+		}
+	}
+	if n.telegramToken != "" && n.telegramChat != "" {
+		url := telegramAPI + "/bot" + n.telegramToken + "/sendMessage"
+		if err := postJSON(url, map[string]string{"chat_id": n.telegramChat, "text": text}); err != nil {
+			// The error contains the URL and therefore the bot token.
+			log.Printf("Error posting summary to Telegram chat %s: %v", n.telegramChat, strings.ReplaceAll(err.Error(), n.telegramToken, "***"))
+		}
+	}
+	if n.mail.enabled() {
+		if report == "" {
+			var buf bytes.Buffer
+			writeSummary(&buf, summary)
+			report = buf.String()
+		}
+		if err := sendMail(n.mail, mailSubject(summary), report); err != nil {
+			log.Printf("Error sending report to %s: %v", strings.Join(n.mail.To, ", "), err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	fileMap := map[string][]File{
+		"a": {{path: indexPath("a1"), Size: 100}, {path: indexPath("a2"), Size: 100}, {path: indexPath("a3"), Size: 100}},
+		"b": {{path: indexPath("b1"), Size: 5}, {path: indexPath("b2"), Size: 5}},
+		"c": {{path: indexPath("c1"), Size: 7}},
+	}
+
+	summary := summarize("/scan", 6, fileMap, 1)
+	if summary.Event != "scan" || summary.DuplicateGroups != 2 || summary.DuplicateFiles != 3 || summary.ReclaimableBytes != 205 || summary.Errors != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+
+	cleanup := cleanupSummary(summary, "delete", actionStats{Files: 2, Bytes: 105, Errors: 1})
+	if cleanup.Event != "cleanup" || cleanup.Action != "delete" || cleanup.FilesProcessed != 2 || cleanup.BytesReclaimed != 105 {
+		t.Errorf("Unexpected cleanup summary: %+v", cleanup)
+	}
+}
+
+func TestNotifyWebhook(t *testing.T) {
+	received := make(chan runSummary, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var summary runSummary
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&summary) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- summary
+	}))
+	defer server.Close()
+
+	notifier{webhookURL: server.URL}.notify(runSummary{Event: "scan", DuplicateGroups: 3}, "")
+	select {
+	case summary := <-received:
+		if summary.DuplicateGroups != 3 {
+			t.Errorf("Expected 3 duplicate groups, Got: %d", summary.DuplicateGroups)
+		}
+	default:
+		t.Error("Webhook was not called")
+	}
+
+	if err := postJSON(server.URL+"/missing", "not a summary"); err == nil {
+		t.Error("Expected error for rejected payload")
+	}
+}
+
+func TestNotifyChatServices(t *testing.T) {
+	received := make(chan map[string]string, 3)
+	paths := make(chan string, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		paths <- r.URL.Path
+		received <- payload
+	}))
+	defer server.Close()
+
+	defer func(api string) { telegramAPI = api }(telegramAPI)
+	telegramAPI = server.URL
+
+	n := notifier{
+		slackWebhook:   server.URL + "/slack",
+		discordWebhook: server.URL + "/discord",
+		telegramToken:  "123:abc",
+		telegramChat:   "42",
+	}
+	n.notify(runSummary{Event: "scan", Root: "/data", DuplicateGroups: 2, ReclaimableBytes: 2048}, "")
+
+	expected := "Scan of /data finished: 2 duplicate groups, 2.00 KiB reclaimable, 0 errors"
+	for _, want := range []struct{ path, key string }{{"/slack", "text"}, {"/discord", "content"}, {"/bot123:abc/sendMessage", "text"}} {
+		select {
+		case payload := <-received:
+			if path := <-paths; path != want.path {
+				t.Errorf("Expected request to %s, Got: %s", want.path, path)
+			}
+			if payload[want.key] != expected {
+				t.Errorf("Expected %s: %s, Got: %v", want.key, expected, payload)
+			}
+			if want.path != "/slack" && want.path != "/discord" && payload["chat_id"] != "42" {
+				t.Errorf("Expected chat_id 42, Got: %v", payload)
+			}
+		default:
+			t.Fatalf("No request to %s", want.path)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// launchCommand is a command that opens something in a desktop application.
+// Launchers such as open(1) return once the application is started and are
+// waited for so that a missing application is detected; browsers started
+// directly keep running and are not waited for.
+type launchCommand struct {
+	args []string
+	wait bool
+}
+
+// appWindowCommands returns the commands to try, in order, for opening url in
+// a chromeless browser window. The last command opens the default browser.
+func appWindowCommands(goos, url string) []launchCommand {
+	app := "--app=" + url
+	switch goos {
+	case "windows":
+		return []launchCommand{
+			{[]string{"cmd", "/c", "start", "", "msedge", app}, true},
+			{[]string{"cmd", "/c", "start", "", "chrome", app}, true},
+			{[]string{"rundll32", "url.dll,FileProtocolHandler", url}, true},
+		}
+	case "darwin":
+		return []launchCommand{
+			{[]string{"open", "-na", "Google Chrome", "--args", app}, true},
+			{[]string{"open", "-na", "Microsoft Edge", "--args", app}, true},
+			{[]string{"open", "-na", "Chromium", "--args", app}, true},
+			{[]string{"open", url}, true},
+		}
+	}
+	var commands []launchCommand
+	for _, browser := range []string{"microsoft-edge", "google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "brave-browser"} {
+		commands = append(commands, launchCommand{[]string{browser, app}, false})
+	}
+	return append(commands, launchCommand{[]string{"xdg-open", url}, true})
+}
+
+// launch runs the first of commands that can be started.
+func launch(commands []launchCommand) error {
+	err := errors.New("no launcher available")
+	for _, c := range commands {
+		path, lookErr := exec.LookPath(c.args[0])
+		if lookErr != nil {
+			err = lookErr
+			continue
+		}
+		cmd := exec.Command(path, c.args[1:]...)
+		if c.wait {
+			err = cmd.Run()
+		} else {
+			err = cmd.Start()
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// openAppWindow opens url in an application window of an installed
+// Chromium-based browser, or in the default browser.
+func openAppWindow(url string) error {
+	return launch(appWindowCommands(runtime.GOOS, url))
+}
+
+// openCommands returns the commands to try for opening path with the
+// default application.
+func openCommands(goos, path string) []launchCommand {
+	switch goos {
+	case "windows":
+		// explorer returns a non-zero status even on success.
+		return []launchCommand{{[]string{"explorer", path}, false}}
+	case "darwin":
+		return []launchCommand{{[]string{"open", path}, true}}
+	}
+	return []launchCommand{
+		{[]string{"xdg-open", path}, true},
+		{[]string{"gio", "open", path}, true},
+	}
+}
+
+// revealCommands returns the commands to try for showing path selected in
+// the file manager. On Linux the file manager is asked over D-Bus, falling
+// back to opening the containing folder.
+func revealCommands(goos, path string) []launchCommand {
+	switch goos {
+	case "windows":
+		return []launchCommand{{[]string{"explorer", "/select," + path}, false}}
+	case "darwin":
+		return []launchCommand{{[]string{"open", "-R", path}, true}}
+	}
+	uri := (&url.URL{Scheme: "file", Path: path}).String()
+	return []launchCommand{
+		{[]string{"dbus-send", "--session", "--print-reply", "--dest=org.freedesktop.FileManager1",
+			"--type=method_call", "/org/freedesktop/FileManager1", "org.freedesktop.FileManager1.ShowItems",
+			"array:string:" + uri, "string:"}, true},
+		{[]string{"xdg-open", filepath.Dir(path)}, true},
+	}
+}
+
+// openFile opens path with the default application.
+func openFile(path string) error {
+	return launch(openCommands(runtime.GOOS, absPath(path)))
+}
+
+// revealFile shows path in the file manager.
+func revealFile(path string) error {
+	return launch(revealCommands(runtime.GOOS, absPath(path)))
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
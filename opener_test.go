@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpenCommands(t *testing.T) {
+	testCases := []struct {
+		goos     string
+		open     []string
+		reveal   []string
+		revealed int
+	}{
+		{"darwin", []string{"open", "/data/a b.jpg"}, []string{"open", "-R", "/data/a b.jpg"}, 1},
+		{"windows", []string{"explorer", "/data/a b.jpg"}, []string{"explorer", "/select,/data/a b.jpg"}, 1},
+		{"linux", []string{"xdg-open", "/data/a b.jpg"}, []string{"xdg-open", "/data"}, 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.goos, func(t *testing.T) {
+			if got := openCommands(tc.goos, "/data/a b.jpg")[0].args; !reflect.DeepEqual(got, tc.open) {
+				t.Errorf("Expected: %q, Got: %q", tc.open, got)
+			}
+			reveal := revealCommands(tc.goos, "/data/a b.jpg")
+			if len(reveal) != tc.revealed {
+				t.Fatalf("Expected %d commands, Got: %d", tc.revealed, len(reveal))
+			}
+			if got := reveal[len(reveal)-1].args; !reflect.DeepEqual(got, tc.reveal) {
+				t.Errorf("Expected: %q, Got: %q", tc.reveal, got)
+			}
+		})
+	}
+
+	dbus := revealCommands("linux", "/data/a b.jpg")[0].args
+	if dbus[0] != "dbus-send" || dbus[len(dbus)-2] != "array:string:file:///data/a%20b.jpg" {
+		t.Errorf("Unexpected D-Bus command: %q", dbus)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// keepPageCache turns off the page cache hints given while hashing
+// (--keep-cache). By default files are read with a sequential read-ahead
+// hint and the pages read are dropped again, so a scan of a whole disk does
+// not push the data of other programs out of the page cache.
+var keepPageCache bool
+
+// dropCacheEvery is how much of a file is read before the pages read so far
+// are dropped from the page cache.
+const dropCacheEvery = 16 << 20
+
+// openForHashing opens a file that is read once from start to end. direct
+// reports whether it was opened for direct IO.
+func openForHashing(path string) (file *os.File, direct bool, err error) {
+	if directIO {
+		if info, err := os.Stat(path); err == nil && info.Size() >= directIOMinSize {
+			if file, err := openDirect(path); err == nil {
+				return file, true, nil
+			}
+		}
+	}
+	if keepPageCache {
+		file, err = os.Open(path)
+	} else {
+		file, err = openSequential(path)
+	}
+	return file, false, err
+}
+
+// uncachedReader reads a file opened by openForHashing and drops the pages
+// it has read from the page cache.
+type uncachedReader struct {
+	file    *os.File
+	read    int64
+	dropped int64
+}
+
+func (r *uncachedReader) Read(p []byte) (int, error) {
+	n, err := r.file.Read(p)
+	r.read += int64(n)
+	if r.read-r.dropped >= dropCacheEvery || (err == io.EOF && r.read > r.dropped) {
+		dropCache(r.file, r.dropped, r.read-r.dropped)
+		r.dropped = r.read
+	}
+	return n, err
+}
+
+// hashReader returns the reader hashFile reads file through.
+func hashReader(file *os.File, direct bool) io.Reader {
+	if ioUring {
+		if info, err := file.Stat(); err == nil && info.Size() >= uringMinSize {
+			if r, err := newUringReader(file, info.Size()); err == nil {
+				return r
+			}
+		}
+	}
+	if direct {
+		return newDirectReader(file)
+	}
+	if keepPageCache {
+		return file
+	}
+	return &uncachedReader{file: file}
+}
@@ -0,0 +1,34 @@
+//go:build linux && (amd64 || arm64 || riscv64 || ppc64 || ppc64le || s390x || mips64 || mips64le || loong64)
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	fadvSequential = 2 // POSIX_FADV_SEQUENTIAL
+	fadvDontNeed   = 4 // POSIX_FADV_DONTNEED
+)
+
+// fadvise calls posix_fadvise; the hints are best effort, so errors are
+// ignored.
+func fadvise(file *os.File, offset, length int64, advice int) {
+	syscall.Syscall6(syscall.SYS_FADVISE64, file.Fd(), uintptr(offset), uintptr(length), uintptr(advice), 0, 0)
+}
+
+// openSequential opens path and asks the kernel for a large read-ahead.
+func openSequential(path string) (*os.File, error) {
+	file, err := os.Open(path)
+	if err == nil {
+		fadvise(file, 0, 0, fadvSequential)
+	}
+	return file, err
+}
+
+// dropCache drops length bytes of file starting at offset from the page
+// cache.
+func dropCache(file *os.File, offset, length int64) {
+	fadvise(file, offset, length, fadvDontNeed)
+}
@@ -0,0 +1,13 @@
+//go:build !windows && !darwin && !(linux && (amd64 || arm64 || riscv64 || ppc64 || ppc64le || s390x || mips64 || mips64le || loong64))
+
+package main
+
+import "os"
+
+// openSequential opens path; there are no page cache hints on this system.
+func openSequential(path string) (*os.File, error) {
+	return os.Open(path)
+}
+
+// dropCache does nothing on this system.
+func dropCache(file *os.File, offset, length int64) {}
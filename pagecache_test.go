@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashReader(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "large.bin")
+	data := bytes.Repeat([]byte("0123456789abcdef"), dropCacheEvery/16+1000)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keep := range []bool{false, true} {
+		keepPageCache = keep
+		file, _, err := openForHashing(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := hashReader(file, false)
+		read, err := ioutil.ReadAll(r)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(read, data) {
+			t.Errorf("Expected %d bytes, Got: %d (keep cache %v)", len(data), len(read), keep)
+		}
+		if uncached, ok := r.(*uncachedReader); ok == keep {
+			t.Errorf("Expected an uncached reader: %v, Got: %v", !keep, ok)
+		} else if ok && uncached.dropped != int64(len(data)) {
+			t.Errorf("Expected: %d bytes dropped, Got: %d", len(data), uncached.dropped)
+		}
+	}
+	keepPageCache = false
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"os"
+)
+
+// The Parquet export writes the per-file records as an uncompressed,
+// PLAIN-encoded Parquet file, the subset of the format that every reader
+// supports. Files are split into row groups of parquetRowGroupSize rows with
+// one data page per column, so memory use stays bounded for large scans.
+const parquetRowGroupSize = 1 << 17
+
+// Values of the Parquet format's Thrift enums and compact protocol types.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetUTF8 = 0 // ConvertedType
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes Thrift structs with the compact protocol used by the
+// Parquet metadata.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // ID of the previous field of each open struct
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(uint64(uint16((id << 1) ^ (id >> 15))))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) begin()      { w.last = append(w.last, 0) }
+func (w *thriftWriter) end()        { w.buf.WriteByte(0); w.last = w.last[:len(w.last)-1] }
+func (w *thriftWriter) i64(v int64) { w.varint(uint64((v << 1) ^ (v >> 63))) }
+
+func (w *thriftWriter) list(typ byte, n int) {
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | typ)
+		return
+	}
+	w.buf.WriteByte(0xf0 | typ)
+	w.varint(uint64(n))
+}
+
+func (w *thriftWriter) binary(s string) {
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *thriftWriter) i32Field(id int16, v int32) { w.field(id, thriftI32); w.i64(int64(v)) }
+func (w *thriftWriter) i64Field(id int16, v int64) { w.field(id, thriftI64); w.i64(v) }
+func (w *thriftWriter) binaryField(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.binary(s)
+}
+
+// parquetColumn describes a column of the per-file export and how to append
+// the value of a file to its page.
+type parquetColumn struct {
+	name     string
+	typ      int32
+	optional bool
+	utf8     bool
+	value    func(f exportedFile) (interface{}, bool) // value, and whether it is set
+}
+
+var parquetColumns = []parquetColumn{
+	{"path", parquetByteArray, false, true, func(f exportedFile) (interface{}, bool) { return f.Path(), true }},
+	{"size", parquetInt64, false, false, func(f exportedFile) (interface{}, bool) { return f.Size, true }},
+	{"hash", parquetByteArray, false, true, func(f exportedFile) (interface{}, bool) { return f.Hash, true }},
+	{"group_id", parquetByteArray, true, true, func(f exportedFile) (interface{}, bool) { return f.Group, f.Group != "" }},
+	{"kept", parquetBoolean, false, false, func(f exportedFile) (interface{}, bool) { return f.Kept, true }},
+}
+
+// parquetColumnChunk is the metadata of a written column chunk.
+type parquetColumnChunk struct {
+	offset int64
+	size   int64
+	values int
+}
+
+// parquetPage encodes the data page of column for rows.
+func parquetPage(column parquetColumn, rows []exportedFile) []byte {
+	var levels, values bytes.Buffer
+	var bits, nbits byte
+	for _, row := range rows {
+		v, ok := column.value(row)
+		if column.optional {
+			levels.WriteByte(boolByte(ok))
+		}
+		if !ok {
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			binary.Write(&values, binary.LittleEndian, uint32(len(v)))
+			values.WriteString(v)
+		case int64:
+			binary.Write(&values, binary.LittleEndian, v)
+		case bool:
+			bits |= boolByte(v) << nbits
+			if nbits++; nbits == 8 {
+				values.WriteByte(bits)
+				bits, nbits = 0, 0
+			}
+		}
+	}
+	if nbits > 0 {
+		values.WriteByte(bits)
+	}
+
+	var page bytes.Buffer
+	if column.optional {
+		// Definition levels use the RLE/bit-packing hybrid encoding with a
+		// bit width of 1; every run of equal levels is one RLE run.
+		var runs bytes.Buffer
+		level := levels.Bytes()
+		for i := 0; i < len(level); {
+			j := i
+			for j < len(level) && level[j] == level[i] {
+				j++
+			}
+			var b [binary.MaxVarintLen64]byte
+			runs.Write(b[:binary.PutUvarint(b[:], uint64(j-i)<<1)])
+			runs.WriteByte(level[i])
+			i = j
+		}
+		binary.Write(&page, binary.LittleEndian, uint32(runs.Len()))
+		page.Write(runs.Bytes())
+	}
+	page.Write(values.Bytes())
+
+	var header thriftWriter
+	header.begin()
+	header.i32Field(1, 0) // DATA_PAGE
+	header.i32Field(2, int32(page.Len()))
+	header.i32Field(3, int32(page.Len()))
+	header.field(5, thriftStruct)
+	header.begin()
+	header.i32Field(1, int32(len(rows)))
+	header.i32Field(2, parquetPlain)
+	header.i32Field(3, parquetRLE)
+	header.i32Field(4, parquetRLE)
+	header.end()
+	header.end()
+	return append(header.buf.Bytes(), page.Bytes()...)
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// parquetFooter encodes the FileMetaData of the file.
+func parquetFooter(rows int, groups [][]parquetColumnChunk, groupRows []int) []byte {
+	var w thriftWriter
+	w.begin()
+	w.i32Field(1, 1)
+	w.field(2, thriftList)
+	w.list(thriftStruct, len(parquetColumns)+1)
+	w.begin()
+	w.binaryField(4, "schema")
+	w.i32Field(5, int32(len(parquetColumns)))
+	w.end()
+	for _, c := range parquetColumns {
+		w.begin()
+		w.i32Field(1, c.typ)
+		repetition := int32(parquetRequired)
+		if c.optional {
+			repetition = parquetOptional
+		}
+		w.i32Field(3, repetition)
+		w.binaryField(4, c.name)
+		if c.utf8 {
+			w.i32Field(6, parquetUTF8)
+		}
+		w.end()
+	}
+	w.i64Field(3, int64(rows))
+	w.field(4, thriftList)
+	w.list(thriftStruct, len(groups))
+	for g, chunks := range groups {
+		w.begin()
+		w.field(1, thriftList)
+		w.list(thriftStruct, len(chunks))
+		var total int64
+		for i, chunk := range chunks {
+			total += chunk.size
+			w.begin()
+			w.i64Field(2, chunk.offset)
+			w.field(3, thriftStruct)
+			w.begin()
+			w.i32Field(1, parquetColumns[i].typ)
+			w.field(2, thriftList)
+			w.list(thriftI32, 2)
+			w.i64(parquetPlain)
+			w.i64(parquetRLE)
+			w.field(3, thriftList)
+			w.list(thriftBinary, 1)
+			w.binary(parquetColumns[i].name)
+			w.i32Field(4, 0) // UNCOMPRESSED
+			w.i64Field(5, int64(chunk.values))
+			w.i64Field(6, chunk.size)
+			w.i64Field(7, chunk.size)
+			w.i64Field(9, chunk.offset)
+			w.end()
+			w.end()
+		}
+		w.i64Field(2, total)
+		w.i64Field(3, int64(groupRows[g]))
+		w.end()
+	}
+	w.binaryField(6, "duplicate_finder")
+	w.end()
+	return w.buf.Bytes()
+}
+
+// exportParquet writes one row per scanned file with its path, size, hash,
+// duplicate group (null for unique files) and whether it is the kept copy.
+func exportParquet(path string, data scanExport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	offset := int64(4)
+	bw.WriteString("PAR1")
+
+	files := exportedFiles(data.FileMap)
+	var groups [][]parquetColumnChunk
+	var groupRows []int
+	for start := 0; ; start += parquetRowGroupSize {
+		end := start + parquetRowGroupSize
+		if end > len(files) {
+			end = len(files)
+		}
+		rows := files[start:end]
+		var chunks []parquetColumnChunk
+		for _, column := range parquetColumns {
+			page := parquetPage(column, rows)
+			bw.Write(page)
+			chunks = append(chunks, parquetColumnChunk{offset: offset, size: int64(len(page)), values: len(rows)})
+			offset += int64(len(page))
+		}
+		groups = append(groups, chunks)
+		groupRows = append(groupRows, len(rows))
+		if end == len(files) {
+			break
+		}
+	}
+
+	footer := parquetFooter(len(files), groups, groupRows)
+	bw.Write(footer)
+	binary.Write(bw, binary.LittleEndian, uint32(len(footer)))
+	bw.WriteString("PAR1")
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// thriftReader decodes the compact protocol into maps of field ID to value,
+// enough to check the files written by exportParquet.
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := int(r.varint())
+		r.pos += n
+		return string(r.data[r.pos-n : r.pos])
+	case thriftList:
+		header := r.data[r.pos]
+		r.pos++
+		n := int(header >> 4)
+		if n == 15 {
+			n = int(r.varint())
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i] = r.value(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		fields := make(map[int16]interface{})
+		var last int16
+		for {
+			header := r.data[r.pos]
+			r.pos++
+			if header == 0 {
+				return fields
+			}
+			if delta := int16(header >> 4); delta != 0 {
+				last += delta
+			} else {
+				last = int16(r.zigzag())
+			}
+			fields[last] = r.value(header & 0x0f)
+		}
+	}
+	panic("unsupported thrift type")
+}
+
+func TestExportParquet(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "results.parquet")
+	if err := exportParquet(path, testScanExport()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatalf("Missing Parquet magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+	r := &thriftReader{data: data[:len(data)-8], pos: footerStart}
+	meta := r.value(thriftStruct).(map[int16]interface{})
+	if r.pos != len(data)-8 {
+		t.Errorf("Expected the footer to end at %d, Got: %d", len(data)-8, r.pos)
+	}
+	if meta[3] != int64(3) {
+		t.Errorf("Expected: 3 rows, Got: %v", meta[3])
+	}
+	schema := meta[2].([]interface{})
+	var names []string
+	for _, element := range schema[1:] {
+		names = append(names, element.(map[int16]interface{})[4].(string))
+	}
+	if len(schema) != 6 || names[3] != "group_id" || schema[4].(map[int16]interface{})[3] != int64(parquetOptional) {
+		t.Errorf("Unexpected schema: %v", schema)
+	}
+
+	rowGroup := meta[4].([]interface{})[0].(map[int16]interface{})
+	columns := rowGroup[1].([]interface{})
+	pages := make([][]byte, len(columns))
+	for i, column := range columns {
+		chunk := column.(map[int16]interface{})[3].(map[int16]interface{})
+		offset, size := int(chunk[9].(int64)), int(chunk[7].(int64))
+		page := &thriftReader{data: data, pos: offset}
+		header := page.value(thriftStruct).(map[int16]interface{})
+		if header[5].(map[int16]interface{})[1] != int64(3) {
+			t.Errorf("Expected: 3 values in column %s, Got: %v", names[i], header)
+		}
+		pages[i] = data[page.pos : offset+size]
+		if int(header[3].(int64)) != len(pages[i]) {
+			t.Errorf("Expected page size %d of column %s, Got: %v", len(pages[i]), names[i], header[3])
+		}
+	}
+
+	// Rows are ordered by path: copy.txt, it's.txt (kept), unique.txt.
+	expectedPath := "\x0e\x00\x00\x00/data/copy.txt\x0e\x00\x00\x00/data/it's.txt\x10\x00\x00\x00/data/unique.txt"
+	if string(pages[0]) != expectedPath {
+		t.Errorf("Expected: %q, Got: %q", expectedPath, pages[0])
+	}
+	// Definition levels 1,1,0 as two RLE runs, then the two group IDs.
+	expectedGroup := append([]byte{4, 0, 0, 0, 4, 1, 2, 0}, "\x0c\x00\x00\x00aaaaaaaaaaaa\x0c\x00\x00\x00aaaaaaaaaaaa"...)
+	if !bytes.Equal(pages[3], expectedGroup) {
+		t.Errorf("Expected: %q, Got: %q", expectedGroup, pages[3])
+	}
+	if !bytes.Equal(pages[4], []byte{0x02}) {
+		t.Errorf("Expected: %q, Got: %q", []byte{0x02}, pages[4])
+	}
+}
+
+func TestExportParquetRowGroups(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	fileMap := make(map[string][]File)
+	for i := 0; i < parquetRowGroupSize+1; i++ {
+		hash := string(rune('a'+i%26)) + string(rune(i))
+		fileMap[hash] = append(fileMap[hash], File{path: indexPath(hash), Hash: hash})
+	}
+	path := filepath.Join(tempDir, "results.parquet")
+	if err := exportParquet(path, scanExport{FileMap: fileMap}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{data: data, pos: len(data) - 8 - footerLen}
+	groups := r.value(thriftStruct).(map[int16]interface{})[4].([]interface{})
+	if len(groups) != 2 || groups[1].(map[int16]interface{})[3] != int64(1) {
+		t.Errorf("Unexpected row groups: %v", groups)
+	}
+}
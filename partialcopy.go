@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// partialCopiesFile is the name of the list of copies in progress in the
+// state directory. It holds one JSON encoded partialCopy per line, so the
+// partial files of a run that crashed can be found by the next run.
+const partialCopiesFile = "copies.jsonl"
+
+// partialCopy is a copy of a file to another volume that has not been
+// renamed to its final name yet.
+type partialCopy struct {
+	Source string `json:"source"`
+	Part   string `json:"part"` // the destination with partSuffix
+}
+
+func partialCopiesPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, partialCopiesFile), nil
+}
+
+// recordPartialCopy adds a copy that is about to start to the list.
+func recordPartialCopy(c partialCopy) error {
+	path, err := partialCopiesPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// forgetPartialCopy removes a copy that was completed from the list.
+func forgetPartialCopy(part string) {
+	copies, path := loadPartialCopies()
+	var left []partialCopy
+	for _, c := range copies {
+		if c.Part != part {
+			left = append(left, c)
+		}
+	}
+	if len(left) < len(copies) {
+		savePartialCopies(path, left)
+	}
+}
+
+// cleanPartialCopies removes the partial files that runs which crashed or
+// were killed left behind. A partial copy of a file that is still large
+// enough for copyResumable is kept, so moving the file again resumes it.
+func cleanPartialCopies() {
+	if readOnly {
+		return
+	}
+	copies, path := loadPartialCopies()
+	var left []partialCopy
+	for _, c := range copies {
+		if _, err := os.Stat(c.Part); err != nil {
+			continue
+		}
+		if info, err := os.Stat(c.Source); err == nil && info.Size() >= progressOver {
+			left = append(left, c)
+			continue
+		}
+		if err := os.Remove(c.Part); err != nil {
+			log.Printf("Error removing the partial copy %s: %v", c.Part, err)
+			left = append(left, c)
+			continue
+		}
+		log.Printf("Removed the partial copy %s left by an interrupted move", c.Part)
+	}
+	if len(left) < len(copies) {
+		savePartialCopies(path, left)
+	}
+}
+
+// loadPartialCopies reads the list of copies in progress and returns it with
+// its path. Lines that cannot be parsed are skipped.
+func loadPartialCopies() ([]partialCopy, string) {
+	path, err := partialCopiesPath()
+	if err != nil {
+		return nil, ""
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, path
+	}
+	defer f.Close()
+	var copies []partialCopy
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var c partialCopy
+		if err := json.Unmarshal(scanner.Bytes(), &c); err == nil && strings.HasSuffix(c.Part, partSuffix) {
+			copies = append(copies, c)
+		}
+	}
+	return copies, path
+}
+
+// savePartialCopies replaces the list of copies in progress, removing the
+// file when no copy is left.
+func savePartialCopies(path string, copies []partialCopy) {
+	if len(copies) == 0 {
+		os.Remove(path)
+		return
+	}
+	var data []byte
+	for _, c := range copies {
+		line, _ := json.Marshal(c)
+		data = append(append(data, line...), '\n')
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		log.Printf("Error writing %s: %v", path, err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanPartialCopies(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("STATE_DIRECTORY", os.Getenv("STATE_DIRECTORY"))
+	os.Setenv("STATE_DIRECTORY", filepath.Join(tempDir, "state"))
+	defer func(size int64) { progressOver = size }(progressOver)
+	progressOver = 10
+
+	small := filepath.Join(tempDir, "small")
+	large := filepath.Join(tempDir, "large")
+	ioutil.WriteFile(small, []byte("small"), 0644)
+	ioutil.WriteFile(large, []byte("a large file"), 0644)
+	copies := []partialCopy{
+		{small, filepath.Join(tempDir, "small.copy") + partSuffix},
+		{large, filepath.Join(tempDir, "large.copy") + partSuffix},
+		{small, filepath.Join(tempDir, "gone.copy") + partSuffix},
+	}
+	for i, c := range copies {
+		if i < 2 {
+			ioutil.WriteFile(c.Part, []byte("part"), 0644)
+		}
+		if err := recordPartialCopy(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cleanPartialCopies()
+	if _, err := os.Stat(copies[0].Part); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, Got: %v", copies[0].Part, err)
+	}
+	if _, err := os.Stat(copies[1].Part); err != nil {
+		t.Errorf("Expected %s to be kept for resuming, Got: %v", copies[1].Part, err)
+	}
+	if left, _ := loadPartialCopies(); len(left) != 1 || left[0] != copies[1] {
+		t.Errorf("Expected: %v, Got: %v", copies[1:2], left)
+	}
+
+	forgetPartialCopy(copies[1].Part)
+	path, _ := partialCopiesPath()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the empty list to be removed, Got: %v", err)
+	}
+}
+
+func TestCopyFileLeavesNoPartialFile(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	src := filepath.Join(tempDir, "src")
+	ioutil.WriteFile(src, []byte("content"), 0644)
+
+	dest := filepath.Join(tempDir, "dest")
+	if err := copyFile(src, dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dest + partSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected no partial file, Got: %v", err)
+	}
+
+	missing := filepath.Join(tempDir, "missing", "dest")
+	if err := copyFile(src, missing); err == nil {
+		t.Errorf("Expected an error for a missing destination folder")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("Expected no file under the final name, Got: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pathSeparators are the characters that end the folder of a path.
+const pathSeparators = "/" + string(os.PathSeparator)
+
+// pathDirs interns the folders of the paths of scanned files, so that all
+// files of a folder share one copy of its path instead of each holding it in
+// full. On deep trees with long paths the folders take up most of a path.
+var pathDirs = struct {
+	sync.Mutex
+	dirs map[string]*string
+}{dirs: make(map[string]*string)}
+
+// indexedPath is a path stored as its interned folder, with the trailing
+// separator, and its name.
+type indexedPath struct {
+	dir  *string // nil for a path without folder
+	name string
+}
+
+// indexPath splits path into its interned folder and its name.
+func indexPath(path string) indexedPath {
+	i := strings.LastIndexAny(path, pathSeparators)
+	if i < 0 {
+		return indexedPath{name: path}
+	}
+	pathDirs.Lock()
+	dir, ok := pathDirs.dirs[path[:i+1]]
+	if !ok {
+		shared := string([]byte(path[:i+1]))
+		dir = &shared
+		pathDirs.dirs[shared] = dir
+	}
+	pathDirs.Unlock()
+	// The name is copied so that the full path can be freed.
+	return indexedPath{dir: dir, name: string([]byte(path[i+1:]))}
+}
+
+func (p indexedPath) String() string {
+	if p.dir == nil {
+		return p.name
+	}
+	return *p.dir + p.name
+}
+
+// Path returns the path of the file.
+func (f File) Path() string {
+	return f.path.String()
+}
+
+// fileJSON is a File as written to and read from JSON. The modification time
+// is kept so that plans made from saved results detect files changed since
+// the scan.
+type fileJSON struct {
+	Path    string            `json:"path"`
+	Hash    string            `json:"hash"`
+	Size    int64             `json:"size"`
+	ModTime *time.Time        `json:"mtime,omitempty"`
+	Digests map[string]string `json:"digests,omitempty"`
+}
+
+func (f File) MarshalJSON() ([]byte, error) {
+	j := fileJSON{Path: f.Path(), Hash: f.Hash, Size: f.Size, Digests: f.Digests}
+	if !f.ModTime.IsZero() {
+		j.ModTime = &f.ModTime
+	}
+	return json.Marshal(j)
+}
+
+func (f *File) UnmarshalJSON(data []byte) error {
+	var j fileJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*f = File{path: indexPath(j.Path), Hash: j.Hash, Size: j.Size, Digests: j.Digests}
+	if j.ModTime != nil {
+		f.ModTime = *j.ModTime
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIndexPath(t *testing.T) {
+	paths := []string{
+		filepath.Join("photos", "2023", "summer", "beach.jpg"),
+		filepath.Join("photos", "2023", "summer", "dunes.jpg"),
+		"notes.txt",
+		filepath.Join("photos", "2023") + string(filepath.Separator),
+		"",
+	}
+	for _, path := range paths {
+		if got := indexPath(path).String(); got != path {
+			t.Errorf("Expected: %s, Got: %s", path, got)
+		}
+	}
+	a, b := indexPath(paths[0]), indexPath(paths[1])
+	if a.dir != b.dir {
+		t.Errorf("Expected the files of a folder to share it")
+	}
+	if a.name != "beach.jpg" || indexPath(paths[2]).dir != nil {
+		t.Errorf("Unexpected split: %q, %v", a.name, indexPath(paths[2]).dir)
+	}
+}
+
+func TestFileJSON(t *testing.T) {
+	file := File{path: indexPath(filepath.Join("a", "b.txt")), Hash: "0123", Size: 4, Digests: map[string]string{"sha256": "ab"}}
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"path":` + mustJSON(t, filepath.Join("a", "b.txt")) + `,"hash":"0123","size":4,"digests":{"sha256":"ab"}}`; string(data) != expected {
+		t.Errorf("Expected: %s, Got: %s", expected, data)
+	}
+	var decoded File
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, file) {
+		t.Errorf("Expected: %+v, Got: %+v", file, decoded)
+	}
+
+	file.ModTime = time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	if data, err = json.Marshal(file); err != nil {
+		t.Fatal(err)
+	}
+	decoded = File{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.ModTime.Equal(file.ModTime) {
+		t.Errorf("Expected: %v, Got: %v", file.ModTime, decoded.ModTime)
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
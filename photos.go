@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// photoWindow, set by --photo-window, is how far apart the capture times of
+// photos taken with the same camera can be for --photos to consider them
+// likely duplicates.
+var photoWindow = 2 * time.Second
+
+// photoCluster is a set of photos with different content that were taken
+// with the same camera within photoWindow of each other, such as a burst or
+// a photo that was imported twice with different compression. The photo to
+// keep, the largest, comes first.
+type photoCluster struct {
+	Camera string
+	Taken  time.Time
+	Files  []File
+}
+
+// isJPEG reports whether path has the extension of a JPEG file, the format
+// readExif understands.
+func isJPEG(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".jpe":
+		return true
+	}
+	return false
+}
+
+// photoCamera identifies the camera of a photo by its make, model and serial
+// number, as far as the EXIF data names them.
+func photoCamera(info exifInfo) string {
+	var parts []string
+	for _, part := range []string{info.Make, info.Model, info.SerialNumber} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// photoClusters groups the JPEG photos of fileMap whose EXIF data names the
+// same camera and capture times at most window apart. Exact duplicates
+// already share a group, so only the first file of each group is
+// considered. Photos without a camera or capture time are left out.
+func photoClusters(fileMap map[string][]File, window time.Duration) []photoCluster {
+	type shot struct {
+		file  File
+		taken time.Time
+	}
+	byCamera := make(map[string][]shot)
+	for _, files := range fileMap {
+		file := files[0]
+		if !isJPEG(file.Path()) {
+			continue
+		}
+		info, err := readExif(file.Path())
+		if err != nil {
+			continue
+		}
+		taken, err := time.Parse("2006:01:02 15:04:05", info.DateTimeOriginal)
+		camera := photoCamera(info)
+		if err != nil || camera == "" {
+			continue
+		}
+		byCamera[camera] = append(byCamera[camera], shot{file, taken})
+	}
+
+	var clusters []photoCluster
+	for camera, shots := range byCamera {
+		sort.Slice(shots, func(i, j int) bool {
+			if !shots[i].taken.Equal(shots[j].taken) {
+				return shots[i].taken.Before(shots[j].taken)
+			}
+			return shots[i].file.Path() < shots[j].file.Path()
+		})
+		start := 0
+		for i := 1; i <= len(shots); i++ {
+			if i < len(shots) && shots[i].taken.Sub(shots[i-1].taken) <= window {
+				continue
+			}
+			if i-start > 1 {
+				cluster := photoCluster{Camera: camera, Taken: shots[start].taken}
+				for _, s := range shots[start:i] {
+					cluster.Files = append(cluster.Files, s.file)
+				}
+				sort.SliceStable(cluster.Files, func(a, b int) bool { return cluster.Files[a].Size > cluster.Files[b].Size })
+				clusters = append(clusters, cluster)
+			}
+			start = i
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if !clusters[i].Taken.Equal(clusters[j].Taken) {
+			return clusters[i].Taken.Before(clusters[j].Taken)
+		}
+		return clusters[i].Camera < clusters[j].Camera
+	})
+	return clusters
+}
+
+// key returns the fileMap key of a confirmed cluster.
+func (c photoCluster) key() string {
+	return pathsKey("photo", c.Files)
+}
+
+// pathsKey returns a fileMap key for a group of files with different
+// content, derived from their paths since they share no hash.
+func pathsKey(kind string, files []File) string {
+	h := sha256.New()
+	for _, file := range files {
+		io.WriteString(h, file.Path()+"\x00")
+	}
+	return fmt.Sprintf("%s:%x", kind, h.Sum(nil))
+}
+
+// confirmPhotoClusters shows every cluster as likely duplicates and adds the
+// ones the user confirms to fileMap, so the actions treat them like the
+// other duplicate groups. Clusters are left out when there is no answer.
+func confirmPhotoClusters(w io.Writer, scanner *bufio.Scanner, fileMap map[string][]File, clusters []photoCluster) int {
+	if len(clusters) == 0 {
+		return 0
+	}
+	fmt.Fprintln(w, msg("photos.found", formatCount(int64(len(clusters)))))
+	titles := make([]string, len(clusters))
+	groups := make([][]File, len(clusters))
+	for i, cluster := range clusters {
+		titles[i] = msg("photos.cluster", formatCount(int64(len(cluster.Files))), cluster.Camera, cluster.Taken.Format("2006-01-02 15:04:05"))
+		groups[i] = cluster.Files
+	}
+	return confirmLikelyDuplicates(w, scanner, fileMap, "photo", titles, groups)
+}
+
+// confirmLikelyDuplicates shows every group under its title and asks whether
+// its files are duplicates of the first one. The confirmed groups are added
+// to fileMap under a key of the given kind.
+func confirmLikelyDuplicates(w io.Writer, scanner *bufio.Scanner, fileMap map[string][]File, kind string, titles []string, groups [][]File) int {
+	confirmed := 0
+	for i, files := range groups {
+		fmt.Fprintln(w, titles[i])
+		for _, file := range files {
+			fmt.Fprintf(w, "  %s (%s)\n", file.Path(), humanReadableSize(file.Size))
+		}
+		fmt.Fprint(w, msg("likely.confirm", msg("answer.yes"), msg("answer.no")))
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			break
+		}
+		if isYes(scanner.Text()) {
+			fileMap[pathsKey(kind, files)] = files
+			confirmed++
+		}
+	}
+	return confirmed
+}
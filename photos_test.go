@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhotoClusters(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	photos := []struct {
+		name, camera, taken, extra string
+	}{
+		{"burst1.jpg", "Canon", "2024:05:01 10:00:00", ""},
+		{"burst2.jpg", "Canon", "2024:05:01 10:00:01", ""},
+		{"burst3.JPG", "Canon", "2024:05:01 10:00:03", ""},
+		{"later.jpg", "Canon", "2024:05:01 10:00:06", ""},
+		{"other.jpg", "Nikon", "2024:05:01 10:00:01", ""},
+		{"import.jpeg", "Nikon", "2024:06:01 08:00:00", ""},
+		{"import-copy.jpeg", "Nikon", "2024:06:01 08:00:00", "larger"},
+		{"notes.txt", "Nikon", "2024:06:01 08:00:00", ""},
+	}
+	fileMap := make(map[string][]File)
+	for i, p := range photos {
+		path := filepath.Join(tempDir, p.name)
+		data := append(testExifJPEG(t, binary.LittleEndian, p.camera, p.taken), p.extra...)
+		ioutil.WriteFile(path, data, 0644)
+		fileMap[string(rune('a'+i))] = []File{{path: indexPath(path), Size: int64(len(data))}}
+	}
+
+	clusters := photoClusters(fileMap, 2*time.Second)
+	if len(clusters) != 2 {
+		t.Fatalf("Expected 2 clusters, Got: %+v", clusters)
+	}
+	var names [][]string
+	for _, c := range clusters {
+		var cluster []string
+		for _, file := range c.Files {
+			cluster = append(cluster, filepath.Base(file.Path()))
+		}
+		names = append(names, cluster)
+	}
+	if strings.Join(names[0], " ") != "burst1.jpg burst2.jpg burst3.JPG" || clusters[0].Camera != "Canon" {
+		t.Errorf("Expected the Canon burst first, Got: %v", names)
+	}
+	if strings.Join(names[1], " ") != "import-copy.jpeg import.jpeg" {
+		t.Errorf("Expected the larger import first, Got: %v", names)
+	}
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(strings.NewReader("no\nyes\n"))
+	if n := confirmPhotoClusters(&out, scanner, fileMap, clusters); n != 1 {
+		t.Errorf("Expected: 1 confirmed cluster, Got: %d", n)
+	}
+	if _, ok := fileMap[clusters[0].key()]; ok {
+		t.Errorf("Expected the rejected cluster not to be added")
+	}
+	if files := fileMap[clusters[1].key()]; len(files) != 2 || files[0].Path() != clusters[1].Files[0].Path() {
+		t.Errorf("Expected the confirmed cluster to be added, Got: %v", files)
+	}
+	if !strings.Contains(out.String(), "Likely duplicates: 3 photos taken with Canon") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// burstName matches the names Android cameras give to the photos of a
+// burst, e.g. IMG_20240501_100000_BURST001_COVER.jpg; the first group names
+// the burst.
+var burstName = regexp.MustCompile(`(?i)^(.+)_BURST\d+(_COVER)?\.(jpe?g|heic|dng)$`)
+
+// isLivePhoto reports whether path has the extension of the still image of a
+// Live Photo, and isMotion whether it has that of its motion file.
+func isLivePhoto(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".heic", ".heif", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+func isMotion(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".mov"
+}
+
+// photoUnits returns the unit of every file of fileMap that belongs to a
+// burst or a Live Photo pair, i.e. an IMG_1234.HEIC with an IMG_1234.MOV in
+// the same folder. Units are named without their folder, so the copies of
+// a burst in different folders share a name. Only folders with duplicates
+// are looked at.
+func photoUnits(fileMap map[string][]File) map[string]string {
+	dirs := make(map[string]bool)
+	for _, files := range fileMap {
+		if len(files) > 1 {
+			for _, file := range files {
+				dirs[filepath.Dir(file.Path())] = true
+			}
+		}
+	}
+	units := make(map[string]string)
+	type pair struct{ photos, motions []string }
+	pairs := make(map[string]*pair) // by folder and lower-case base name
+	for _, files := range fileMap {
+		for _, file := range files {
+			if !dirs[filepath.Dir(file.Path())] {
+				continue
+			}
+			name := filepath.Base(file.Path())
+			if m := burstName.FindStringSubmatch(name); m != nil {
+				units[file.Path()] = "burst:" + strings.ToLower(m[1])
+				continue
+			}
+			if isJPEG(file.Path()) {
+				if info, err := readExif(file.Path()); err == nil && info.BurstID != "" {
+					units[file.Path()] = "burst:" + info.BurstID
+					continue
+				}
+			}
+			if !isLivePhoto(file.Path()) && !isMotion(file.Path()) {
+				continue
+			}
+			base := strings.ToLower(strings.TrimSuffix(file.Path(), filepath.Ext(file.Path())))
+			p := pairs[base]
+			if p == nil {
+				p = &pair{}
+				pairs[base] = p
+			}
+			if isMotion(file.Path()) {
+				p.motions = append(p.motions, file.Path())
+			} else {
+				p.photos = append(p.photos, file.Path())
+			}
+		}
+	}
+	for base, p := range pairs {
+		if len(p.photos) == 0 || len(p.motions) == 0 {
+			continue
+		}
+		for _, path := range append(p.photos, p.motions...) {
+			units[path] = "live:" + filepath.Base(base)
+		}
+	}
+	return units
+}
+
+// keepUnitsTogether makes sure cleanups never take bursts and Live Photos
+// apart. Of the copies of a unit, the one in the folder with the most of its
+// files is kept: it becomes the kept copy of every group with one of its
+// files. A copy in another folder is only removed as a whole. If some of its
+// files have no duplicate, it is kept as well, except that the motion file
+// of a Live Photo whose still image is removed is removed with it, provided
+// the kept Live Photo has a motion file too.
+func keepUnitsTogether(fileMap map[string][]File) map[string][]File {
+	units := photoUnits(fileMap)
+	if len(units) == 0 {
+		return fileMap
+	}
+	keys := make([]string, 0, len(fileMap))
+	for key := range fileMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// The folders each unit has copies in, with their files.
+	instances := make(map[string]map[string][]string)
+	kept := make(map[string]map[string]bool) // folders that hold a kept copy of a unit
+	for _, key := range keys {
+		for i, file := range fileMap[key] {
+			unit, ok := units[file.Path()]
+			if !ok {
+				continue
+			}
+			dir := filepath.Dir(file.Path())
+			if instances[unit] == nil {
+				instances[unit], kept[unit] = make(map[string][]string), make(map[string]bool)
+			}
+			instances[unit][dir] = append(instances[unit][dir], file.Path())
+			if i == 0 && len(fileMap[key]) > 1 {
+				kept[unit][dir] = true
+			}
+		}
+	}
+	keptDir := make(map[string]string)
+	for unit, dirs := range instances {
+		best := ""
+		for dir, paths := range dirs {
+			if best == "" || len(paths) > len(dirs[best]) ||
+				len(paths) == len(dirs[best]) && (kept[unit][dir] && !kept[unit][best] || kept[unit][dir] == kept[unit][best] && dir < best) {
+				best = dir
+			}
+		}
+		keptDir[unit] = best
+	}
+
+	together := make(map[string][]File, len(fileMap))
+	type position struct {
+		key   string
+		index int
+	}
+	positions := make(map[string]position)
+	for _, key := range keys {
+		files := append([]File(nil), fileMap[key]...)
+		for i, file := range files {
+			if unit, ok := units[file.Path()]; ok && i > 0 && filepath.Dir(file.Path()) == keptDir[unit] {
+				copy(files[1:i+1], files[:i])
+				files[0] = file
+				break
+			}
+		}
+		together[key] = files
+		for i, file := range files {
+			positions[file.Path()] = position{key, i}
+		}
+	}
+	removable := func(path string) bool {
+		pos := positions[path]
+		return pos.index > 0 && len(together[pos.key]) > 1
+	}
+
+	drop := make(map[string]bool)
+	var motionPairs [][]File
+	for unit, dirs := range instances {
+		for dir, paths := range dirs {
+			if dir == keptDir[unit] {
+				continue
+			}
+			var left []string
+			for _, path := range paths {
+				if !removable(path) {
+					left = append(left, path)
+				}
+			}
+			if len(left) == 0 {
+				continue
+			}
+			if strings.HasPrefix(unit, "live:") && len(left) == 1 && len(paths) > 1 && isMotion(left[0]) {
+				if keptMotion := motionFile(instances[unit][keptDir[unit]]); keptMotion != "" {
+					pos, keptPos := positions[left[0]], positions[keptMotion]
+					motionPairs = append(motionPairs, []File{together[keptPos.key][keptPos.index], together[pos.key][pos.index]})
+					continue
+				}
+			}
+			for _, path := range paths {
+				if removable(path) {
+					drop[path] = true
+				}
+			}
+		}
+	}
+	for key, files := range together {
+		var left []File
+		for _, file := range files {
+			if !drop[file.Path()] {
+				left = append(left, file)
+			}
+		}
+		together[key] = left
+	}
+	for _, pair := range motionPairs {
+		together[pathsKey("live", pair)] = pair
+	}
+	return together
+}
+
+// motionFile returns the motion file among the paths of a Live Photo.
+func motionFile(paths []string) string {
+	for _, path := range paths {
+		if isMotion(path) {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestKeepUnitsTogether(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := func(name string) string {
+		p := filepath.Join(tempDir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		ioutil.WriteFile(p, []byte(name), 0644)
+		return p
+	}
+	file := func(name string) File { return File{path: indexPath(path(name)), Size: 1} }
+
+	fileMap := map[string][]File{
+		// A Live Photo in a and b whose motion files differ.
+		"heic1": {file("b/IMG_1.HEIC"), file("a/IMG_1.HEIC")},
+		"mov1a": {file("a/IMG_1.MOV")},
+		"mov1b": {file("b/IMG_1.MOV")},
+		// A Live Photo in a of which c only holds the still image.
+		"heic2": {file("c/IMG_2.HEIC"), file("a/IMG_2.HEIC")},
+		"mov2":  {file("a/IMG_2.MOV")},
+		// A burst of which b holds a frame the burst in a lacks.
+		"x1": {file("b/X_BURST001.jpg"), file("a/X_BURST001.jpg")},
+		"x2": {file("b/X_BURST002.jpg")},
+		"x3": {file("a/X_BURST003.jpg")},
+		// A burst of which c holds a part.
+		"y1": {file("c/Y_BURST001_COVER.jpg"), file("a/Y_BURST001_COVER.jpg")},
+		"y2": {file("c/Y_BURST002.jpg"), file("a/Y_BURST002.jpg")},
+		"y3": {file("a/Y_BURST003.jpg")},
+	}
+	together := keepUnitsTogether(fileMap)
+
+	names := func(files []File) string {
+		var names []string
+		for _, file := range files {
+			rel, _ := filepath.Rel(tempDir, file.Path())
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return strings.Join(names, " ")
+	}
+	tests := map[string]string{
+		"heic1": "b/IMG_1.HEIC a/IMG_1.HEIC",
+		"heic2": "a/IMG_2.HEIC c/IMG_2.HEIC",
+		"x1":    "b/X_BURST001.jpg",
+		"y1":    "a/Y_BURST001_COVER.jpg c/Y_BURST001_COVER.jpg",
+		"y2":    "a/Y_BURST002.jpg c/Y_BURST002.jpg",
+	}
+	for key, expected := range tests {
+		if got := names(together[key]); got != expected {
+			t.Errorf("%s: Expected: %s, Got: %s", key, expected, got)
+		}
+	}
+	var pairs []string
+	for key, files := range together {
+		if strings.HasPrefix(key, "live:") {
+			pairs = append(pairs, names(files))
+		}
+	}
+	sort.Strings(pairs)
+	if strings.Join(pairs, ", ") != "b/IMG_1.MOV a/IMG_1.MOV" {
+		t.Errorf("Expected the motion file to be removed with its photo, Got: %v", pairs)
+	}
+}
+
+func TestAppleBurstID(t *testing.T) {
+	uuid := "6F74B4C2-1F0A-4E1E-9C9B-2A3C4D5E6F70\x00"
+	var note bytes.Buffer
+	note.WriteString(appleMakerNote)
+	binary.Write(&note, binary.BigEndian, uint16(2))
+	for _, entry := range [][4]uint32{{0x0001, 9, 1, 0}, {appleTagBurstUUID, 2, uint32(len(uuid)), uint32(len(appleMakerNote) + 2 + 2*12)}} {
+		binary.Write(&note, binary.BigEndian, uint16(entry[0]))
+		binary.Write(&note, binary.BigEndian, uint16(entry[1]))
+		binary.Write(&note, binary.BigEndian, entry[2])
+		binary.Write(&note, binary.BigEndian, entry[3])
+	}
+	note.WriteString(uuid)
+
+	if id := appleBurstID(note.Bytes()); id != strings.TrimRight(uuid, "\x00") {
+		t.Errorf("Expected: %s, Got: %q", uuid, id)
+	}
+	if id := appleBurstID([]byte("Nikon\x00\x02\x10\x00\x00")); id != "" {
+		t.Errorf("Expected no burst in another maker note, Got: %q", id)
+	}
+}
@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// planVersion is incremented whenever the plan file format changes
+// incompatibly.
+const planVersion = 2
+
+// cleanupPlan is a reviewable list of the actions a cleanup will take,
+// written by the plan command and executed by apply.
+type cleanupPlan struct {
+	Version     int         `json:"version"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Root        string      `json:"root,omitempty"`
+	Keep        string      `json:"keep"`
+	KeepExpr    string      `json:"keep_expr,omitempty"`
+	KeepWeights string      `json:"keep_weights,omitempty"`
+	Protect     []string    `json:"protect,omitempty"`
+	Groups      []planGroup `json:"groups"`
+}
+
+// planGroup lists the files of a duplicate group that are kept and the
+// actions for the others.
+type planGroup struct {
+	ID      string       `json:"id"`
+	Hash    string       `json:"hash"`
+	Keep    []planFile   `json:"keep"`
+	Actions []planAction `json:"actions"`
+}
+
+// planFile is a kept file with the size and modification time it had when
+// the plan was made.
+type planFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// planAction deletes Path, or moves it to To. Size and ModTime are recorded
+// when the plan is made so apply can skip files that changed since.
+type planAction struct {
+	Action  string    `json:"action"` // "delete" or "move"
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	To      string    `json:"to,omitempty"`
+}
+
+// makePlan applies policy to groups. Redundant copies are deleted, or moved
+// into moveTo if it is set; files with the same name get distinct names there.
+func makePlan(groups []Group, policy keepPolicy, moveTo string) cleanupPlan {
+	plan := cleanupPlan{Version: planVersion, CreatedAt: time.Now(), Keep: policy.keep, Protect: policy.protect, Groups: []planGroup{}}
+	if policy.expr != nil {
+		plan.KeepExpr = policy.expr.source
+	}
+	if policy.weights != nil {
+		plan.KeepWeights = policy.weights.source
+	}
+	used := make(map[string]bool)
+	for _, group := range groups {
+		keep, remove := policy.apply(group)
+		g := planGroup{ID: group.ID, Hash: group.Hash}
+		for _, file := range keep {
+			g.Keep = append(g.Keep, planFile{Path: file.Path(), Size: file.Size, ModTime: scannedModTime(file)})
+		}
+		for _, file := range remove {
+			action := planAction{Action: "delete", Path: file.Path(), Size: file.Size, ModTime: scannedModTime(file)}
+			if moveTo != "" {
+				action.Action = "move"
+				action.To = uniqueDestination(moveTo, filepath.Base(file.Path()), used)
+			}
+			g.Actions = append(g.Actions, action)
+		}
+		plan.Groups = append(plan.Groups, g)
+	}
+	return plan
+}
+
+// scannedModTime returns the modification time the scan saw of file, or, for
+// results saved without it, the one the file has now.
+func scannedModTime(file File) time.Time {
+	if file.ModTime.IsZero() {
+		return modTime(file.Path())
+	}
+	return file.ModTime
+}
+
+// uniqueDestination returns a path for name in dir that is neither in used
+// nor an existing file, adding " (2)", " (3)", ... before the extension.
+func uniqueDestination(dir, name string, used map[string]bool) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for n := 1; ; n++ {
+		candidate := filepath.Join(dir, name)
+		if n > 1 {
+			candidate = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, n, ext))
+		}
+		if _, err := os.Lstat(candidate); !used[candidate] && os.IsNotExist(err) {
+			used[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// planFormats lists the formats plan files can be written in.
+var planFormats = []string{"json", "text"}
+
+func savePlan(path string, plan cleanupPlan, format string) error {
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	case "text":
+		if err := writePlanText(&buf, plan); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown plan format %q", format)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// loadPlan reads a plan file in either format.
+func loadPlan(path string) (cleanupPlan, error) {
+	var plan cleanupPlan
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return plan, err
+	}
+	if isPlanJSON(data) {
+		err = json.Unmarshal(data, &plan)
+	} else {
+		plan, err = parsePlanText(bytes.NewReader(data))
+	}
+	if err != nil {
+		return plan, err
+	}
+	if plan.Version != planVersion {
+		return plan, fmt.Errorf("unsupported plan version %d", plan.Version)
+	}
+	return plan, nil
+}
+
+// validatePlan checks a possibly edited plan before it is applied and returns
+// every problem found. Every group must keep an existing file, every file may
+// appear only once, and moves need a destination that does not exist yet. A
+// destination that ends in a path separator or names an existing folder is
+// replaced by the file's path inside it.
+func validatePlan(plan *cleanupPlan) []error {
+	var problems []error
+	seen := make(map[string]bool)
+	claim := func(path string) {
+		if seen[path] {
+			problems = append(problems, fmt.Errorf("%s appears more than once", path))
+		}
+		seen[path] = true
+	}
+	for g := range plan.Groups {
+		group := &plan.Groups[g]
+		if len(group.Keep) == 0 && len(group.Actions) > 0 {
+			problems = append(problems, fmt.Errorf("group %s keeps no file", group.ID))
+		}
+		for _, file := range group.Keep {
+			claim(file.Path)
+			if isRemotePath(file.Path) {
+				continue
+			}
+			if _, err := os.Stat(file.Path); err != nil && len(group.Actions) > 0 {
+				problems = append(problems, fmt.Errorf("group %s: kept file %v", group.ID, err))
+			}
+		}
+		for i := range group.Actions {
+			action := &group.Actions[i]
+			claim(action.Path)
+			switch action.Action {
+			case "delete":
+				if action.To != "" {
+					problems = append(problems, fmt.Errorf("delete of %s has a destination", action.Path))
+				}
+			case "move":
+				if action.To == "" {
+					problems = append(problems, fmt.Errorf("move of %s has no destination", action.Path))
+					continue
+				}
+				if info, err := os.Stat(action.To); os.IsPathSeparator(action.To[len(action.To)-1]) || (err == nil && info.IsDir()) {
+					action.To = filepath.Join(action.To, filepath.Base(action.Path))
+				}
+				claim(action.To)
+				if _, err := os.Lstat(action.To); err == nil {
+					problems = append(problems, fmt.Errorf("destination %s of %s already exists", action.To, action.Path))
+				}
+			default:
+				problems = append(problems, fmt.Errorf("unknown action %q for %s", action.Action, action.Path))
+			}
+		}
+	}
+	return problems
+}
+
+// totals returns the number of actions of the plan and the bytes they free.
+func (p cleanupPlan) totals() (actions int, bytes int64) {
+	for _, group := range p.Groups {
+		for _, action := range group.Actions {
+			actions++
+			bytes += action.Size
+		}
+	}
+	return actions, bytes
+}
+
+// applyPlan executes the actions of plan in order. Files whose size or
+// modification time changed since the plan was made, or whose content no
+// longer matches the group's hash, are skipped as stale. The content is
+// checked for the files to delete and the kept files of their groups, since
+// an edited plan can name any file with any size and time, and with verify
+// for all files. Kept files on rclone remotes must still have the size and
+// hash of the group. A group is skipped entirely if none of its kept files is
+// unchanged.
+func applyPlan(plan cleanupPlan, verify bool) actionStats {
+	var stats actionStats
+	defer stopRclone() // started to check kept copies on remotes
+	unchanged := func(path string, size int64, modTime time.Time, hash string, rehash bool) error {
+		if isRemotePath(path) {
+			return checkRemoteUnchanged(path, size, hash)
+		}
+		if err := checkUnchanged(path, size, modTime); err != nil {
+			return err
+		}
+		if rehash {
+			return checkHash(path, hash)
+		}
+		return nil
+	}
+	var moves []pendingMove
+	var sources []string
+	for _, group := range plan.Groups {
+		for _, action := range group.Actions {
+			if action.Action == "move" && !isRemotePath(action.Path) {
+				moves = append(moves, pendingMove{action.Path, action.To, action.Size})
+				sources = append(sources, action.Path)
+			}
+		}
+	}
+	if err := checkFreeSpace(moves); err != nil {
+		log.Printf("Error: %v; the plan was not applied", err)
+		stats.Errors = len(moves)
+		return stats
+	}
+	links := newLinkMover(sources)
+	for _, group := range plan.Groups {
+		deletes := false
+		for _, action := range group.Actions {
+			deletes = deletes || action.Action == "delete"
+		}
+		var keepErr error
+		var kept string
+		for _, file := range group.Keep {
+			if keepErr = unchanged(file.Path, file.Size, file.ModTime, group.Hash, verify || deletes); keepErr == nil {
+				kept = file.Path
+				break
+			}
+			log.Printf("Kept file %s changed since the plan was made: %v", file.Path, keepErr)
+		}
+		for _, action := range group.Actions {
+			if keepErr != nil {
+				log.Printf("Skipping stale file %s: no kept copy of group %s is unchanged", action.Path, group.ID)
+				stats.Stale++
+				continue
+			}
+			if skipRemote(action.Path, &stats) {
+				continue
+			}
+			if err := unchanged(action.Path, action.Size, action.ModTime, group.Hash, verify || action.Action == "delete"); err != nil {
+				log.Printf("Skipping stale file %s: %v", action.Path, err)
+				stats.Stale++
+				continue
+			}
+			if err := checkSampledContent(group.Hash, kept, action.Path); err != nil {
+				log.Printf("Skipping %s: %v", action.Path, err)
+				stats.Stale++
+				continue
+			}
+			var err error
+			switch action.Action {
+			case "delete":
+				if !runDeleteLimit.allows(action.Size) {
+					deferDelete(action.Path, &stats)
+					continue
+				}
+				err = withFileLock(action.Path, func() error {
+					if err := checkWritable("delete", action.Path); err != nil {
+						return err
+					}
+					return os.Remove(action.Path)
+				})
+				if err == nil {
+					fmt.Println(msg("delete.done", action.Path))
+					runDeleteLimit.deleted(action.Size)
+				}
+			case "move":
+				if err = os.MkdirAll(filepath.Dir(action.To), 0755); err == nil {
+					err = withFileLock(action.Path, func() error { return links.move(action.Path, action.To) })
+				}
+				if err == nil {
+					fmt.Println(msg("move.done", action.Path, action.To))
+				}
+			default:
+				err = fmt.Errorf("unknown action %q", action.Action)
+			}
+			if skipLocked(action.Path, err, &stats) {
+				continue
+			}
+			audit.record(auditEntry{Action: action.Action, Hash: group.Hash, Path: action.Path, To: action.To, Keep: kept, Size: action.Size}, err)
+			if err != nil {
+				log.Printf("Error applying %s of %s: %v", action.Action, action.Path, err)
+				stats.Errors++
+				continue
+			}
+			stats.Files++
+			stats.Bytes += action.Size
+		}
+	}
+	if stats.Deferred > 0 {
+		fmt.Println(msg("delete.deferred", formatCount(int64(stats.Deferred))))
+	}
+	return stats
+}
+
+// runPlan implements the plan command, which writes the actions a cleanup
+// would take to a file for review instead of executing them.
+func runPlan(args []string) {
+	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+	keep := fs.String("keep", "first", "copy to keep: "+strings.Join(keepPolicies, ", "))
+	keepExpr := fs.String("keep-expr", "", "keep the copy with the highest score of this expression instead, e.g. 'path contains \"/master/\" ? 100 : mtime'")
+	keepWeights := fs.String("keep-weights", "", "keep the copy with the highest weighted score of these criteria instead, e.g. 'path:/master=10,age=1,name=2'")
+	var protect stringList
+	fs.Var(&protect, "protect", "never remove files below this folder (repeatable)")
+	moveTo := fs.String("move-to", "", "move redundant copies into this folder instead of deleting them")
+	resultsPath := fs.String("results", "", "plan from a results file written by --save instead of scanning")
+	out := fs.String("out", "plan.json", "plan file to write")
+	format := fs.String("format", "json", "plan file format: "+strings.Join(planFormats, ", "))
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s plan [flags] (--results file | folder)\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if (*resultsPath == "") == (fs.NArg() == 0) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	policy, err := newKeepPolicy(*keep, protect)
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	if *keepExpr != "" && *keepWeights != "" {
+		log.Fatal("Error: --keep-expr and --keep-weights cannot be combined")
+	}
+	if *keepExpr != "" {
+		if policy.expr, err = parseKeepExpr(*keepExpr); err != nil {
+			log.Fatal("Error:", err)
+		}
+	}
+	if *keepWeights != "" {
+		if policy.weights, err = parseKeepWeights(*keepWeights); err != nil {
+			log.Fatal("Error:", err)
+		}
+	}
+	destination := ""
+	if *moveTo != "" {
+		if destination, err = filepath.Abs(*moveTo); err != nil {
+			log.Fatal("Error:", err)
+		}
+	}
+
+	var root string
+	var groups []Group
+	if *resultsPath != "" {
+		results, err := loadResults(*resultsPath)
+		if err != nil {
+			log.Fatalf("Error loading results from %s: %v", *resultsPath, err)
+		}
+		root, groups = results.Root, results.Groups
+	} else {
+		root = formatPath(fs.Arg(0))
+		fileMap, _, err := scanFolder(root, nil, nil)
+		if err != nil {
+			log.Fatal("Error:", err)
+		}
+		groups = duplicateGroups(fileMap)
+	}
+
+	plan := makePlan(groups, policy, destination)
+	plan.Root = root
+	if err := savePlan(*out, plan, *format); err != nil {
+		log.Fatal("Error:", err)
+	}
+	actions, bytes := plan.totals()
+	fmt.Println(msg("plan.written", *out, formatCount(int64(actions)), humanReadableSize(bytes)))
+}
+
+// runApply implements the apply command, which executes a plan file.
+func runApply(args []string) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "apply the plan without asking for confirmation")
+	verify := fs.Bool("verify", false, "re-hash every file before acting on it, also the moved ones and their kept copies (files to delete are always re-hashed)")
+	force := fs.Bool("force", false, "apply the plan even if another run is working on its folder")
+	maxDeleteFiles := fs.Int("max-delete-files", 0, "delete at most this many files and leave the rest of the plan for later runs (0 means no limit)")
+	maxDeleteBytes := fs.String("max-delete-bytes", "", "delete at most this much data, e.g. 10G, and leave the rest of the plan for later runs")
+	auditPath := fs.String("audit-log", "", "append every move and deletion to this JSONL file (default: audit.jsonl in the state directory)")
+	fs.StringVar(&rcloneRC, "rclone-rc", "", "URL of a running 'rclone rcd --rc-serve' to check kept copies on rclone: remotes through (default: start one)")
+	preserveList := fs.String("preserve", "", "metadata to keep when moved files are copied to another volume: a comma-separated list of owner, acl and xattr")
+	fs.BoolVar(&readOnly, "read-only", false, "never move or delete files; only report which actions would fail")
+	var confirmOver confirmThreshold
+	fs.Var(&confirmOver, "confirm-over", "ask for interactive confirmation, even with --yes, if the plan touches more than this many files or bytes (e.g. 1000, 50G or 1000,50G)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s apply [flags] plan.json\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+	cleanPartialCopies()
+
+	var err error
+	if preserve, err = parsePreserve(*preserveList); err != nil {
+		log.Fatalf("Error: invalid --preserve: %v", err)
+	}
+	if audit, err = openAuditLog(*auditPath); err != nil {
+		log.Fatal("Error:", err)
+	}
+	if runDeleteLimit, err = parseDeleteLimit(*maxDeleteFiles, *maxDeleteBytes); err != nil {
+		log.Fatal("Error:", err)
+	}
+	plan, err := loadPlan(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error loading plan from %s: %v", fs.Arg(0), err)
+	}
+	if problems := validatePlan(&plan); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("Error in plan %s: %v", fs.Arg(0), problem)
+		}
+		os.Exit(1)
+	}
+	if plan.Root != "" && !*force {
+		defer acquireRootLock(plan.Root)()
+	}
+	if actions, bytes := plan.totals(); confirmOver.over(actions, bytes) {
+		fmt.Println(msg("apply.over", formatCount(int64(actions)), humanReadableSize(bytes), confirmOver.String()))
+		if !isTerminal(os.Stdin) {
+			log.Fatal("Error: the plan exceeds --confirm-over and can only be confirmed interactively")
+		}
+		*yes = false
+	}
+	if !*yes && !confirmApply(os.Stdin, os.Stdout, plan) {
+		fmt.Println(msg("apply.canceled"))
+		return
+	}
+	stats := applyPlan(plan, *verify)
+	recordHistory(runSummary{Event: "cleanup", Root: plan.Root, Time: time.Now(), Action: "apply",
+		FilesProcessed: stats.Files, BytesReclaimed: stats.Bytes, Errors: stats.Errors})
+	fmt.Println(msg("apply.done", formatCount(int64(stats.Files)), humanReadableSize(stats.Bytes), formatCount(int64(stats.Stale)), formatCount(int64(stats.Errors))))
+	if stats.Errors > 0 || stats.Stale > 0 || stats.Locked > 0 {
+		os.Exit(1)
+	}
+}
+
+func confirmApply(in io.Reader, out io.Writer, plan cleanupPlan) bool {
+	actions, bytes := plan.totals()
+	fmt.Fprint(out, msg("apply.confirm", formatCount(int64(actions)), humanReadableSize(bytes), msg("answer.yes"), msg("answer.no")))
+	scanner := bufio.NewScanner(in)
+	return scanner.Scan() && isYes(scanner.Text())
+}
+
+// confirmThreshold is the value of --confirm-over: a number of files, a
+// size, or both separated by a comma. Zero disables a limit.
+type confirmThreshold struct {
+	files int
+	bytes int64
+	text  string
+}
+
+func (t *confirmThreshold) String() string { return t.text }
+
+func (t *confirmThreshold) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if n, err := strconv.Atoi(part); err == nil && n >= 0 {
+			t.files = n
+			continue
+		}
+		size, err := parseSize(part)
+		if err != nil {
+			return err
+		}
+		t.bytes = size
+	}
+	t.text = value
+	return nil
+}
+
+// over reports whether a cleanup of files and bytes exceeds the threshold.
+func (t confirmThreshold) over(files int, bytes int64) bool {
+	return (t.files > 0 && files > t.files) || (t.bytes > 0 && bytes > t.bytes)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMakePlan(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	ioutil.WriteFile(filepath.Join(tempDir, "a.txt"), nil, 0644)
+
+	groups := duplicateGroups(map[string][]File{
+		"aaaaaaaaaaaaaaaa": {{path: indexPath("/x/a.txt"), Size: 5}, {path: indexPath("/y/a.txt"), Size: 5}, {path: indexPath("/z/a.txt"), Size: 5}},
+		"bbbbbbbbbbbbbbbb": {{path: indexPath("/x/b"), Size: 1}, {path: indexPath("/y/b"), Size: 1}},
+	})
+	policy, _ := newKeepPolicy("first", nil)
+
+	plan := makePlan(groups, policy, "")
+	if len(plan.Groups) != 2 || plan.Groups[0].Keep[0].Path != "/x/a.txt" || plan.Groups[0].Actions[1] != (planAction{Action: "delete", Path: "/z/a.txt", Size: 5}) {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+	if actions, bytes := plan.totals(); actions != 3 || bytes != 11 {
+		t.Errorf("Expected: 3 actions, 11 bytes, Got: %d, %d", actions, bytes)
+	}
+
+	moves := makePlan(groups, policy, tempDir)
+	expected := []string{filepath.Join(tempDir, "a (2).txt"), filepath.Join(tempDir, "a (3).txt"), filepath.Join(tempDir, "b")}
+	var got []string
+	for _, group := range moves.Groups {
+		for _, action := range group.Actions {
+			if action.Action != "move" {
+				t.Errorf("Expected a move, Got: %+v", action)
+			}
+			got = append(got, action.To)
+		}
+	}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected: %v, Got: %v", expected, got)
+	}
+}
+
+func TestApplyPlan(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	write := func(name string) string {
+		path := filepath.Join(tempDir, name)
+		ioutil.WriteFile(path, []byte("same"), 0644)
+		return path
+	}
+	keep, del, move := write("keep"), write("del"), write("move")
+	to := filepath.Join(tempDir, "archive", "move")
+	hashed, _ := hashFile(keep)
+	plan := cleanupPlan{Version: planVersion, Groups: []planGroup{{
+		ID:   "g",
+		Hash: hashed.Hash,
+		Keep: []planFile{{Path: keep, Size: 4, ModTime: modTime(keep)}},
+		Actions: []planAction{
+			{Action: "delete", Path: del, Size: 4, ModTime: modTime(del)},
+			{Action: "move", Path: move, Size: 4, ModTime: modTime(move), To: to},
+			{Action: "delete", Path: filepath.Join(tempDir, "missing"), Size: 4},
+		},
+	}}}
+
+	path := filepath.Join(tempDir, "plan.json")
+	if err := savePlan(path, plan, "json"); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadPlan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := applyPlan(loaded, false)
+	if stats != (actionStats{Files: 2, Bytes: 8, Stale: 1}) {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if _, err := os.Stat(del); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be deleted", del)
+	}
+	if _, err := os.Stat(to); err != nil {
+		t.Errorf("Expected %s to be moved: %v", move, err)
+	}
+	if _, err := os.Stat(keep); err != nil {
+		t.Errorf("Expected %s to be kept: %v", keep, err)
+	}
+}
+
+func TestApplyPlanStale(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	write := func(name, content string) (string, time.Time) {
+		path := filepath.Join(tempDir, name)
+		ioutil.WriteFile(path, []byte(content), 0644)
+		stamp := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		os.Chtimes(path, stamp, stamp)
+		return path, stamp
+	}
+	same, _ := write("same", "same")
+	hashed, _ := hashFile(same)
+	other := "b2d6c1b9f5a413f0d3d3d2e4d8a0a6b4" // not the hash of "same"
+
+	testCases := []struct {
+		name     string
+		change   func(keep, dup string)
+		action   string
+		hash     string
+		verify   bool
+		expected actionStats
+	}{
+		{"unchanged", func(keep, dup string) {}, "delete", hashed.Hash, false, actionStats{Files: 1, Bytes: 4}},
+		{"duplicate resized", func(keep, dup string) { ioutil.WriteFile(dup, []byte("changed"), 0644) }, "delete", hashed.Hash, false, actionStats{Stale: 1}},
+		{"duplicate touched", func(keep, dup string) { os.Chtimes(dup, time.Now(), time.Now()) }, "delete", hashed.Hash, false, actionStats{Stale: 1}},
+		{"kept copy removed", func(keep, dup string) { os.Remove(keep) }, "delete", hashed.Hash, false, actionStats{Stale: 1}},
+		{"deleted file differs from hash", func(keep, dup string) {}, "delete", other, false, actionStats{Stale: 1}},
+		{"moved file differs from hash", func(keep, dup string) {}, "move", other, false, actionStats{Files: 1, Bytes: 4}},
+		{"moved file verified", func(keep, dup string) {}, "move", other, true, actionStats{Stale: 1}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keep, stamp := write("keep", "same")
+			dup, _ := write("dup", "same")
+			action := planAction{Action: tc.action, Path: dup, Size: 4, ModTime: stamp}
+			if tc.action == "move" {
+				action.To = filepath.Join(tempDir, "archive", "dup")
+				defer os.RemoveAll(filepath.Dir(action.To))
+			}
+			plan := cleanupPlan{Groups: []planGroup{{
+				ID:      "g",
+				Hash:    tc.hash,
+				Keep:    []planFile{{Path: keep, Size: 4, ModTime: stamp}},
+				Actions: []planAction{action},
+			}}}
+			tc.change(keep, dup)
+			if stats := applyPlan(plan, tc.verify); stats != tc.expected {
+				t.Errorf("Expected: %+v, Got: %+v", tc.expected, stats)
+			}
+		})
+	}
+
+	keep, stamp := write("keep", "same")
+	dup, _ := write("dup", "same")
+	plan := cleanupPlan{Groups: []planGroup{{
+		ID:      "g",
+		Hash:    hashed.Hash + "-2",
+		Keep:    []planFile{{Path: keep, Size: 4, ModTime: stamp}},
+		Actions: []planAction{{Action: "delete", Path: dup, Size: 4, ModTime: stamp}},
+	}}}
+	if stats := applyPlan(plan, true); stats != (actionStats{Files: 1, Bytes: 4}) {
+		t.Errorf("Expected the verified duplicate to be deleted, Got: %+v", stats)
+	}
+}
+
+func TestConfirmApply(t *testing.T) {
+	plan := cleanupPlan{Groups: []planGroup{{Actions: []planAction{{Action: "delete", Path: "a", Size: 2048}}}}}
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{"yes\n", true},
+		{"no\n", false},
+		{"", false},
+	}
+	for _, tc := range testCases {
+		var out strings.Builder
+		if got := confirmApply(strings.NewReader(tc.input), &out, plan); got != tc.expected {
+			t.Errorf("Expected: %v, Got: %v for %q", tc.expected, got, tc.input)
+		}
+		if !strings.HasPrefix(out.String(), "Apply 1 actions reclaiming 2.00 KiB?") {
+			t.Errorf("Unexpected prompt: %s", out.String())
+		}
+	}
+}
+
+func TestValidatePlan(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	keep := filepath.Join(tempDir, "keep")
+	existing := filepath.Join(tempDir, "existing")
+	ioutil.WriteFile(keep, nil, 0644)
+	ioutil.WriteFile(existing, nil, 0644)
+
+	testCases := []struct {
+		name     string
+		group    planGroup
+		expected string
+	}{
+		{"valid", planGroup{ID: "g", Keep: []planFile{{Path: keep}}, Actions: []planAction{{Action: "delete", Path: "/x/b"}}}, ""},
+		{"nothing kept", planGroup{ID: "g", Actions: []planAction{{Action: "delete", Path: "/x/b"}}}, "group g keeps no file"},
+		{"kept twice", planGroup{ID: "g", Keep: []planFile{{Path: keep}}, Actions: []planAction{{Action: "delete", Path: keep}}}, keep + " appears more than once"},
+		{"move without destination", planGroup{ID: "g", Keep: []planFile{{Path: keep}}, Actions: []planAction{{Action: "move", Path: "/x/b"}}}, "move of /x/b has no destination"},
+		{"existing destination", planGroup{ID: "g", Keep: []planFile{{Path: keep}}, Actions: []planAction{{Action: "move", Path: "/x/b", To: existing}}}, "destination " + existing + " of /x/b already exists"},
+		{"unknown action", planGroup{ID: "g", Keep: []planFile{{Path: keep}}, Actions: []planAction{{Action: "shred", Path: "/x/b"}}}, `unknown action "shred" for /x/b`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := cleanupPlan{Groups: []planGroup{tc.group}}
+			var got []string
+			for _, problem := range validatePlan(&plan) {
+				got = append(got, problem.Error())
+			}
+			if strings.Join(got, "; ") != tc.expected {
+				t.Errorf("Expected: %s, Got: %s", tc.expected, strings.Join(got, "; "))
+			}
+		})
+	}
+
+	plan := cleanupPlan{Groups: []planGroup{{ID: "g", Keep: []planFile{{Path: keep}}, Actions: []planAction{{Action: "move", Path: "/x/b", To: tempDir}}}}}
+	if problems := validatePlan(&plan); len(problems) != 0 || plan.Groups[0].Actions[0].To != filepath.Join(tempDir, "b") {
+		t.Errorf("Expected the move into %s to be resolved, Got: %v %v", tempDir, plan.Groups[0].Actions[0].To, problems)
+	}
+}
+
+func TestLoadPlanFormats(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	plan := cleanupPlan{Version: planVersion, Keep: "first", Groups: []planGroup{{
+		ID: "g", Hash: "h", Keep: []planFile{{Path: "/a", Size: 3}}, Actions: []planAction{{Action: "delete", Path: "/b", Size: 3}},
+	}}}
+	for _, format := range planFormats {
+		path := filepath.Join(tempDir, "plan."+format)
+		if err := savePlan(path, plan, format); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := loadPlan(path)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if actions, bytes := loaded.totals(); actions != 1 || bytes != 3 || loaded.Groups[0].Keep[0].Path != "/a" {
+			t.Errorf("Unexpected %s plan: %+v", format, loaded)
+		}
+	}
+	ioutil.WriteFile(filepath.Join(tempDir, "old.txt"), []byte("version 0\n"), 0644)
+	if _, err := loadPlan(filepath.Join(tempDir, "old.txt")); err == nil || err.Error() != "unsupported plan version 0" {
+		t.Errorf("Expected a version error, Got: %v", err)
+	}
+}
+
+func TestConfirmThreshold(t *testing.T) {
+	testCases := []struct {
+		value    string
+		files    int
+		bytes    int64
+		expected bool
+	}{
+		{"1000", 1000, 1 << 40, false},
+		{"1000", 1001, 0, true},
+		{"50G", 100000, 50 << 30, false},
+		{"50G", 1, 50<<30 + 1, true},
+		{"1000,50G", 10, 60 << 30, true},
+		{"1000, 50G", 1001, 10, true},
+		{"0", 1 << 20, 1 << 40, false},
+	}
+	for _, tc := range testCases {
+		var threshold confirmThreshold
+		if err := threshold.Set(tc.value); err != nil {
+			t.Fatal(err)
+		}
+		if got := threshold.over(tc.files, tc.bytes); got != tc.expected {
+			t.Errorf("%s with %d files and %d bytes: Expected: %v, Got: %v", tc.value, tc.files, tc.bytes, tc.expected, got)
+		}
+	}
+	var threshold confirmThreshold
+	if err := threshold.Set("lots"); err == nil || err.Error() != `invalid size "lots"` {
+		t.Errorf("Expected: invalid size \"lots\", Got: %v", err)
+	}
+}
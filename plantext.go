@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The text plan format lists one action per line so plans can be reviewed
+// and edited in any editor:
+//
+//	version 2
+//	group 0123456789ab <hash> <size>
+//	keep 2026-10-14T09:30:00Z "/photos/a.jpg"
+//	delete 2026-10-14T09:30:00Z "/backup/a.jpg"
+//	move 2026-10-14T09:30:00Z "/old/a.jpg" "/archive/a.jpg"
+//
+// The time is the modification time of the file when the plan was made.
+// Paths are Go-quoted strings. Lines starting with # are comments.
+
+const planTextHeader = `# duplicate_finder cleanup plan
+# Delete an action line to keep that file, or change "delete TIME PATH" to
+# "move TIME PATH DESTINATION". Run "duplicate_finder apply" on this file to
+# execute it.
+`
+
+// planTextArgs is the number of arguments of each keyword of the text format.
+var planTextArgs = map[string]int{
+	"version": 1, "created": 1, "root": 1, "policy": 1, "keep-expr": 1, "keep-weights": 1, "protect": 1,
+	"group": 3, "keep": 2, "delete": 2, "move": 3,
+}
+
+// writePlanText writes plan in the text plan format.
+func writePlanText(w io.Writer, plan cleanupPlan) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, planTextHeader)
+	fmt.Fprintf(bw, "version %d\n", plan.Version)
+	fmt.Fprintf(bw, "created %s\n", plan.CreatedAt.Format(time.RFC3339))
+	if plan.Root != "" {
+		fmt.Fprintf(bw, "root %q\n", plan.Root)
+	}
+	fmt.Fprintf(bw, "policy %s\n", plan.Keep)
+	if plan.KeepExpr != "" {
+		fmt.Fprintf(bw, "keep-expr %q\n", plan.KeepExpr)
+	}
+	if plan.KeepWeights != "" {
+		fmt.Fprintf(bw, "keep-weights %q\n", plan.KeepWeights)
+	}
+	for _, dir := range plan.Protect {
+		fmt.Fprintf(bw, "protect %q\n", dir)
+	}
+	for _, group := range plan.Groups {
+		var size int64
+		if len(group.Keep) > 0 {
+			size = group.Keep[0].Size
+		}
+		fmt.Fprintf(bw, "\ngroup %s %s %d\n", group.ID, group.Hash, size)
+		for _, file := range group.Keep {
+			fmt.Fprintf(bw, "keep %s %q\n", file.ModTime.Format(time.RFC3339Nano), file.Path)
+		}
+		for _, action := range group.Actions {
+			if action.Action == "move" {
+				fmt.Fprintf(bw, "move %s %q %q\n", action.ModTime.Format(time.RFC3339Nano), action.Path, action.To)
+			} else {
+				fmt.Fprintf(bw, "%s %s %q\n", action.Action, action.ModTime.Format(time.RFC3339Nano), action.Path)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// parsePlanText reads a plan in the text plan format.
+func parsePlanText(r io.Reader) (cleanupPlan, error) {
+	plan := cleanupPlan{Groups: []planGroup{}}
+	var group *planGroup
+	var size int64
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields, err := splitQuoted(line)
+		if err != nil {
+			return plan, fmt.Errorf("line %d: %v", n, err)
+		}
+		args := fields[1:]
+		count, known := planTextArgs[fields[0]]
+		if !known {
+			return plan, fmt.Errorf("line %d: unknown keyword %q", n, fields[0])
+		}
+		if len(args) != count {
+			return plan, fmt.Errorf("line %d: %s needs %d arguments, got %d", n, fields[0], count, len(args))
+		}
+		if group == nil && (fields[0] == "keep" || fields[0] == "delete" || fields[0] == "move") {
+			return plan, fmt.Errorf("line %d: %s outside of a group", n, fields[0])
+		}
+		var modTime time.Time
+		if fields[0] == "keep" || fields[0] == "delete" || fields[0] == "move" {
+			if modTime, err = time.Parse(time.RFC3339Nano, args[0]); err != nil {
+				return plan, fmt.Errorf("line %d: invalid time %q", n, args[0])
+			}
+		}
+		switch fields[0] {
+		case "version":
+			if plan.Version, err = strconv.Atoi(args[0]); err != nil {
+				return plan, fmt.Errorf("line %d: invalid version %q", n, args[0])
+			}
+		case "created":
+			if plan.CreatedAt, err = time.Parse(time.RFC3339, args[0]); err != nil {
+				return plan, fmt.Errorf("line %d: %v", n, err)
+			}
+		case "root":
+			plan.Root = args[0]
+		case "policy":
+			plan.Keep = args[0]
+		case "keep-expr":
+			plan.KeepExpr = args[0]
+		case "keep-weights":
+			plan.KeepWeights = args[0]
+		case "protect":
+			plan.Protect = append(plan.Protect, args[0])
+		case "group":
+			if size, err = strconv.ParseInt(args[2], 10, 64); err != nil {
+				return plan, fmt.Errorf("line %d: invalid size %q", n, args[2])
+			}
+			plan.Groups = append(plan.Groups, planGroup{ID: args[0], Hash: args[1]})
+			group = &plan.Groups[len(plan.Groups)-1]
+		case "keep":
+			group.Keep = append(group.Keep, planFile{Path: args[1], Size: size, ModTime: modTime})
+		case "delete":
+			group.Actions = append(group.Actions, planAction{Action: "delete", Path: args[1], Size: size, ModTime: modTime})
+		case "move":
+			group.Actions = append(group.Actions, planAction{Action: "move", Path: args[1], Size: size, ModTime: modTime, To: args[2]})
+		}
+	}
+	return plan, scanner.Err()
+}
+
+// splitQuoted splits a line such as a line of a text plan into its fields,
+// which are separated by spaces and may be Go-quoted strings.
+func splitQuoted(line string) ([]string, error) {
+	var fields []string
+	for line != "" {
+		if line[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted string %s", line)
+			}
+			field, _ := strconv.Unquote(quoted)
+			fields = append(fields, field)
+			line = line[len(quoted):]
+			if line != "" && line[0] != ' ' && line[0] != '\t' {
+				return nil, fmt.Errorf("missing space after %s", quoted)
+			}
+		} else {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			fields = append(fields, line[:end])
+			line = line[end:]
+		}
+		line = strings.TrimLeft(line, " \t")
+	}
+	return fields, nil
+}
+
+// isPlanJSON reports whether the plan data is JSON rather than text.
+func isPlanJSON(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanTextRoundTrip(t *testing.T) {
+	plan := cleanupPlan{
+		Version:   planVersion,
+		CreatedAt: time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC),
+		Root:      "/data",
+		Keep:      "newest",
+		KeepExpr:  `path contains "/master/" ? 100 : mtime`,
+		Protect:   []string{"/data/master"},
+		Groups: []planGroup{{
+			ID:   "0123456789ab",
+			Hash: "0123456789abcdef",
+			Keep: []planFile{{Path: "/data/master/a b.jpg", Size: 42, ModTime: time.Date(2025, 3, 4, 5, 6, 7, 890, time.UTC)}},
+			Actions: []planAction{
+				{Action: "delete", Path: "/data/copy/a b.jpg", Size: 42, ModTime: time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)},
+				{Action: "move", Path: "/data/\"quoted\".jpg", Size: 42, ModTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: "/archive/a.jpg"},
+			},
+		}},
+	}
+	var out strings.Builder
+	if err := writePlanText(&out, plan); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "\nkeep 2025-03-04T05:06:07.00000089Z \"/data/master/a b.jpg\"\ndelete 2025-03-04T05:06:07Z \"/data/copy/a b.jpg\"\n") {
+		t.Errorf("Unexpected text plan:\n%s", out.String())
+	}
+	got, err := parsePlanText(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, plan) {
+		t.Errorf("Expected: %+v, Got: %+v", plan, got)
+	}
+}
+
+func TestParsePlanTextErrors(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"version 2\ndelete 2025-01-01T00:00:00Z \"/a\"\n", "line 2: delete outside of a group"},
+		{"version 2\ngroup id hash 1\nmove 2025-01-01T00:00:00Z \"/a\"\n", "line 3: move needs 3 arguments, got 2"},
+		{"version 2\nerase \"/a\"\n", "line 2: unknown keyword \"erase\""},
+		{"version 2\ngroup id hash 1\nkeep 2025-01-01T00:00:00Z \"/a\n", "line 3: invalid quoted string \"/a"},
+		{"version 2\ngroup id hash x\n", "line 2: invalid size \"x\""},
+		{"version 2\ngroup id hash 1\ndelete \"/a\" \"/b\"\n", "line 3: invalid time \"/a\""},
+	}
+	for _, tc := range testCases {
+		_, err := parsePlanText(strings.NewReader(tc.input))
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("Expected: %s, Got: %v", tc.expected, err)
+		}
+	}
+}
+
+func TestSplitQuoted(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected []string
+	}{
+		{`keep "/a b"`, []string{"keep", "/a b"}},
+		{`move  "/a"	"/b\\c"`, []string{"move", "/a", `/b\c`}},
+		{`group id hash 10`, []string{"group", "id", "hash", "10"}},
+	}
+	for _, tc := range testCases {
+		got, err := splitQuoted(tc.line)
+		if err != nil || !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Expected: %q, Got: %q (%v)", tc.expected, got, err)
+		}
+	}
+	if _, err := splitQuoted(`keep "/a"x`); err == nil {
+		t.Errorf("Expected an error for a missing space")
+	}
+}
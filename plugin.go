@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// pluginProtocol is the version of the JSON protocol spoken with plugins.
+const pluginProtocol = 1
+
+// A plugin is an executable that receives one JSON request on stdin and
+// answers with one JSON response on stdout. Matcher plugins refine duplicate
+// groups, action plugins perform a custom action on a group.
+type plugin struct {
+	Path string
+	Name string
+	Kind string // "matcher" or "action"
+}
+
+type pluginRequest struct {
+	Type     string `json:"type"` // "describe", "match" or "action"
+	Protocol int    `json:"protocol"`
+	Group    *Group `json:"group,omitempty"`
+}
+
+type pluginResult struct {
+	Path  string `json:"path"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type pluginResponse struct {
+	Name    string         `json:"name,omitempty"`
+	Kind    string         `json:"kind,omitempty"`
+	Groups  [][]string     `json:"groups,omitempty"`
+	Results []pluginResult `json:"results,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+func (p plugin) call(req pluginRequest) (pluginResponse, error) {
+	var resp pluginResponse
+	req.Protocol = pluginProtocol
+	input, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(p.Path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return resp, fmt.Errorf("plugin %s: %v", p.Path, err)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return resp, fmt.Errorf("plugin %s: invalid response: %v", p.Path, err)
+	}
+	if resp.Error != "" {
+		return resp, fmt.Errorf("plugin %s: %s", p.Path, resp.Error)
+	}
+	return resp, nil
+}
+
+// loadPlugin asks the executable at path to describe itself.
+func loadPlugin(path string) (plugin, error) {
+	p := plugin{Path: path}
+	resp, err := p.call(pluginRequest{Type: "describe"})
+	if err != nil {
+		return p, err
+	}
+	if resp.Kind != "matcher" && resp.Kind != "action" {
+		return p, fmt.Errorf("plugin %s: unknown kind %q", path, resp.Kind)
+	}
+	p.Name, p.Kind = resp.Name, resp.Kind
+	if p.Name == "" {
+		p.Name = path
+	}
+	return p, nil
+}
+
+// match sends a group to a matcher plugin and returns the sub-groups it
+// considers duplicates of each other. Files the plugin leaves out are returned
+// as sub-groups of their own; a file it lists twice, within a sub-group or in two of them, is an error,
+// since it would be kept and removed at once.
+func (p plugin) match(group Group) ([][]File, error) {
+	resp, err := p.call(pluginRequest{Type: "match", Group: &group})
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]File, len(group.Files))
+	for _, file := range group.Files {
+		byPath[file.Path()] = file
+	}
+	claimed := make(map[string]bool, len(group.Files))
+	var subGroups [][]File
+	for _, paths := range resp.Groups {
+		var files []File
+		for _, path := range paths {
+			file, ok := byPath[path]
+			if !ok {
+				return nil, fmt.Errorf("plugin %s: unknown path %s", p.Path, path)
+			}
+			if claimed[path] {
+				return nil, fmt.Errorf("plugin %s: path %s listed more than once", p.Path, path)
+			}
+			claimed[path] = true
+			files = append(files, file)
+		}
+		subGroups = append(subGroups, files)
+	}
+	// Files the plugin left out have no duplicates, but are still part of
+	// the scan.
+	for _, file := range group.Files {
+		if !claimed[file.Path()] {
+			subGroups = append(subGroups, []File{file})
+		}
+	}
+	return subGroups, nil
+}
+
+// act runs an action plugin on a group and returns the per-file results.
+func (p plugin) act(group Group) ([]pluginResult, error) {
+	resp, err := p.call(pluginRequest{Type: "action", Group: &group})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// applyMatchers lets every matcher plugin refine the duplicate groups of
+// fileMap in turn. A group that a plugin splits keeps its key for the first
+// part; further parts get a free "-N" suffix so their group IDs stay distinct.
+// Files a plugin leaves out become groups of their own, and groups a plugin
+// fails on are kept unchanged.
+func applyMatchers(fileMap map[string][]File, plugins []plugin) map[string][]File {
+	for _, p := range plugins {
+		if p.Kind != "matcher" {
+			continue
+		}
+		refined := make(map[string][]File, len(fileMap))
+		for key, files := range fileMap {
+			if len(files) < 2 {
+				refined[key] = files
+				continue
+			}
+			subGroups, err := p.match(Group{ID: groupID(key), Hash: files[0].Hash, Files: files})
+			if err != nil {
+				log.Printf("Error matching group %s: %v", groupID(key), err)
+				refined[key] = files
+				continue
+			}
+			for i, sub := range subGroups {
+				subKey := key
+				if i > 0 {
+					subKey = splitKey(key, fileMap, refined)
+				}
+				refined[subKey] = sub
+			}
+		}
+		fileMap = refined
+	}
+	return fileMap
+}
+
+// splitKey returns a key for a part split off the group at key: its hash
+// with the lowest "-N" suffix, N >= 2, that is used neither in fileMap nor in
+// refined, so groups split by several plugins never replace each other.
+func splitKey(key string, fileMap, refined map[string][]File) string {
+	hash := key
+	if i := strings.IndexByte(key, '-'); i >= 0 {
+		hash = key[:i]
+	}
+	for n := 2; ; n++ {
+		subKey := hash + "-" + strconv.Itoa(n)
+		_, old := fileMap[subKey]
+		_, taken := refined[subKey]
+		if !old && !taken {
+			return subKey
+		}
+	}
+}
+
+// actionPlugins returns the plugins of kind "action".
+func actionPlugins(plugins []plugin) []plugin {
+	var actions []plugin
+	for _, p := range plugins {
+		if p.Kind == "action" {
+			actions = append(actions, p)
+		}
+	}
+	return actions
+}
+
+// runActionPlugin runs p on every duplicate group, reports the results and
+// returns the files the plugin processed.
+func runActionPlugin(p plugin, fileMap map[string][]File) actionStats {
+	var stats actionStats
+	if err := checkWritable("run plugin "+p.Name+" on", "the duplicates"); err != nil {
+		log.Printf("Error: %v", err)
+		return stats
+	}
+	for _, group := range duplicateGroups(fileMap) {
+		results, err := p.act(group)
+		if err != nil {
+			log.Printf("Error running plugin %s on group %s: %v", p.Name, group.ID, err)
+			stats.Errors++
+			continue
+		}
+		sizes := make(map[string]int64, len(group.Files))
+		for _, file := range group.Files {
+			sizes[file.Path()] = file.Size
+		}
+		for _, result := range results {
+			var resultErr error
+			if !result.OK {
+				resultErr = errors.New(result.Error)
+			}
+			audit.record(auditEntry{Action: "plugin:" + p.Name, Hash: group.Hash, Path: result.Path, Size: sizes[result.Path]}, resultErr)
+			if result.OK {
+				fmt.Println(msg("plugin.done", p.Name, result.Path))
+				stats.Files++
+				stats.Bytes += sizes[result.Path]
+			} else {
+				log.Printf("Plugin %s failed on %s: %s", p.Name, result.Path, result.Error)
+				stats.Errors++
+			}
+		}
+	}
+	return stats
+}
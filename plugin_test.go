@@ -0,0 +1,186 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeTestPlugin creates an executable shell script plugin and returns its path.
+func writeTestPlugin(t *testing.T, dir, name, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMatcherPlugin(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	// The matcher splits every group into {a, b} and {c}.
+	path := writeTestPlugin(t, tempDir, "split", `
+read req
+case "$req" in
+*describe*) echo '{"name":"split","kind":"matcher"}' ;;
+*) echo '{"groups":[["a","b"],["c"]]}' ;;
+esac
+`)
+	p, err := loadPlugin(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "split" || p.Kind != "matcher" {
+		t.Errorf("Unexpected plugin description: %+v", p)
+	}
+
+	fileMap := map[string][]File{
+		"9c192053ffbc363705b13508c36566f6": {{path: indexPath("a"), Hash: "9c192053ffbc363705b13508c36566f6"}, {path: indexPath("b")}, {path: indexPath("c")}},
+		"unique":                           {{path: indexPath("u")}},
+	}
+	fileMap = applyMatchers(fileMap, []plugin{p})
+	groups := duplicateGroups(fileMap)
+	if len(groups) != 1 || groups[0].ID != "9c192053ffbc" || len(groups[0].Files) != 2 {
+		t.Errorf("Unexpected refined groups: %+v", groups)
+	}
+	if files := fileMap["9c192053ffbc363705b13508c36566f6-2"]; len(files) != 1 || files[0].Path() != "c" {
+		t.Errorf("Expected split-off group with c, Got: %+v", files)
+	}
+}
+
+func TestMatcherPluginOmittedPath(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	path := writeTestPlugin(t, tempDir, "omit", `
+read req
+case "$req" in
+*describe*) echo '{"name":"omit","kind":"matcher"}' ;;
+*) echo '{"groups":[["a","b"]]}' ;;
+esac
+`)
+	p, err := loadPlugin(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileMap := map[string][]File{
+		"hash": {{path: indexPath("a"), Hash: "hash"}, {path: indexPath("b"), Hash: "hash"}, {path: indexPath("c"), Hash: "hash"}},
+	}
+	fileMap = applyMatchers(fileMap, []plugin{p})
+	if len(fileMap["hash"]) != 2 {
+		t.Errorf("Expected a and b to stay a group, Got: %+v", fileMap)
+	}
+	if files := fileMap["hash-2"]; len(files) != 1 || files[0].Path() != "c" {
+		t.Errorf("Expected c to be kept on its own, Got: %+v", fileMap)
+	}
+}
+
+func TestTwoMatcherPlugins(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	// The first matcher splits {a, b, c, d} into {a, b} and {c, d}, the
+	// second splits off the first file of every group.
+	first := writeTestPlugin(t, tempDir, "halves", `
+read req
+case "$req" in
+*describe*) echo '{"name":"halves","kind":"matcher"}' ;;
+*) echo '{"groups":[["a","b"],["c","d"]]}' ;;
+esac
+`)
+	second := writeTestPlugin(t, tempDir, "first", `
+read req
+case "$req" in
+*describe*) echo '{"name":"first","kind":"matcher"}' ;;
+*'"a"'*) echo '{"groups":[["a"],["b"]]}' ;;
+*) echo '{"groups":[["c"],["d"]]}' ;;
+esac
+`)
+	var plugins []plugin
+	for _, path := range []string{first, second} {
+		p, err := loadPlugin(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plugins = append(plugins, p)
+	}
+	var files []File
+	for _, name := range []string{"a", "b", "c", "d"} {
+		files = append(files, File{path: indexPath(name), Hash: "hash"})
+	}
+	fileMap := applyMatchers(map[string][]File{"hash": files}, plugins)
+	seen := make(map[string]bool)
+	for key, files := range fileMap {
+		if strings.Count(key, "-") > 1 {
+			t.Errorf("Unexpected key %s", key)
+		}
+		for _, file := range files {
+			seen[file.Path()] = true
+		}
+	}
+	if len(fileMap) != 4 || len(seen) != 4 {
+		t.Errorf("Expected every file in a group of its own, Got: %+v", fileMap)
+	}
+}
+
+func TestMatcherPluginRepeatedPath(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	group := Group{ID: "aaaaaaaaaaaa", Hash: "aaaaaaaaaaaaaaaa", Files: []File{{path: indexPath("a")}, {path: indexPath("b")}}}
+	for name, reply := range map[string]string{
+		"same": `[["a","a"]]`,
+		"both": `[["a","b"],["b","a"]]`,
+	} {
+		path := writeTestPlugin(t, tempDir, name, `
+read req
+case "$req" in
+*describe*) echo '{"name":"repeat","kind":"matcher"}' ;;
+*) echo '{"groups":`+reply+`}' ;;
+esac
+`)
+		p, err := loadPlugin(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.match(group); err == nil || !strings.Contains(err.Error(), "listed more than once") {
+			t.Errorf("%s: Expected an error for a repeated path, Got: %v", reply, err)
+		}
+	}
+}
+
+func TestActionPlugin(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	path := writeTestPlugin(t, tempDir, "archive", `
+read req
+case "$req" in
+*describe*) echo '{"name":"archive","kind":"action"}' ;;
+*) echo '{"results":[{"path":"b","ok":true},{"path":"c","ok":false,"error":"read-only"}]}' ;;
+esac
+`)
+	p, err := loadPlugin(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := p.act(Group{ID: "g", Files: []File{{path: indexPath("a")}, {path: indexPath("b")}, {path: indexPath("c")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].OK || results[1].OK || results[1].Error != "read-only" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+
+	bad := writeTestPlugin(t, tempDir, "bad", `echo '{"kind":"unknown"}'`)
+	if _, err := loadPlugin(bad); err == nil {
+		t.Error("Expected error for unknown plugin kind")
+	}
+}
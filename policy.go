@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// keepPolicies are the names accepted by --keep, describing which copy of a
+// duplicate group survives a cleanup.
+var keepPolicies = []string{"first", "newest", "oldest", "shortest-path", "longest-path"}
+
+// keepPolicy decides which files of a duplicate group are kept. Files below
+// one of the protected folders are always kept; otherwise the policy keeps a
+// single file, the one with the highest score if expr or weights is set.
+type keepPolicy struct {
+	keep    string
+	expr    *keepExpr
+	weights *keepWeights
+	protect []string
+}
+
+func newKeepPolicy(keep string, protect []string) (keepPolicy, error) {
+	valid := false
+	for _, name := range keepPolicies {
+		valid = valid || name == keep
+	}
+	if !valid {
+		return keepPolicy{}, fmt.Errorf("invalid keep policy %q (expected one of %s)", keep, strings.Join(keepPolicies, ", "))
+	}
+	p := keepPolicy{keep: keep}
+	for _, dir := range protect {
+		abs, err := absOrRemote(dir)
+		if err != nil {
+			return keepPolicy{}, err
+		}
+		p.protect = append(p.protect, abs)
+	}
+	return p, nil
+}
+
+// absOrRemote returns the absolute path of path; paths on rclone remotes
+// are left as they are.
+func absOrRemote(path string) (string, error) {
+	if isRemotePath(path) {
+		return path, nil
+	}
+	return filepath.Abs(path)
+}
+
+// protected reports whether path lies inside one of the protected folders.
+func (p keepPolicy) protected(path string) bool {
+	abs, err := absOrRemote(path)
+	if err != nil {
+		return false
+	}
+	for _, dir := range p.protect {
+		if rel, err := filepath.Rel(dir, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply splits the files of a group into the ones that are kept and the
+// ones that would be removed, both in the order of the group.
+func (p keepPolicy) apply(group Group) (keep, remove []File) {
+	for _, file := range group.Files {
+		if p.protected(file.Path()) {
+			keep = append(keep, file)
+		} else {
+			remove = append(remove, file)
+		}
+	}
+	if len(keep) > 0 {
+		return keep, remove
+	}
+
+	best := p.best(group.Files)
+	keep = []File{group.Files[best]}
+	remove = append(append([]File(nil), group.Files[:best]...), group.Files[best+1:]...)
+	return keep, remove
+}
+
+// best returns the index of the file the policy keeps.
+func (p keepPolicy) best(files []File) int {
+	if scores := p.scores(files); scores != nil {
+		best := 0
+		for i, score := range scores {
+			if score > scores[best] {
+				best = i
+			}
+		}
+		return best
+	}
+	// A file whose name looks like a copy, such as "report (1).pdf", is only
+	// kept if all names do.
+	var candidates []int
+	for i, file := range files {
+		if !isCopyName(file.Path()) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range files {
+			candidates = append(candidates, i)
+		}
+	}
+	best := candidates[0]
+	var bestTime int64
+	for n, i := range candidates {
+		file := files[i]
+		var better bool
+		switch p.keep {
+		case "newest", "oldest":
+			// Files that cannot be read count as the oldest.
+			var t int64
+			if info, err := os.Stat(file.Path()); err == nil {
+				t = info.ModTime().UnixNano()
+			}
+			better = n == 0 || (p.keep == "newest" && t > bestTime) || (p.keep == "oldest" && t < bestTime)
+			if better {
+				bestTime = t
+			}
+		case "shortest-path":
+			better = len(file.Path()) < len(files[best].Path())
+		case "longest-path":
+			better = len(file.Path()) > len(files[best].Path())
+		}
+		if better {
+			best = i
+		}
+	}
+	return best
+}
+
+// scores returns the score of every file if the policy keeps the file with
+// the highest score, and nil otherwise.
+func (p keepPolicy) scores(files []File) []float64 {
+	switch {
+	case p.expr != nil:
+		return p.expr.scores(files)
+	case p.weights != nil:
+		return p.weights.scores(files)
+	}
+	return nil
+}
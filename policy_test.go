@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeepPolicy(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	master := filepath.Join(tempDir, "master")
+	os.Mkdir(master, 0755)
+	paths := []string{
+		filepath.Join(tempDir, "old.txt"),
+		filepath.Join(tempDir, "a-very-long-name.txt"),
+		filepath.Join(master, "new.txt"),
+	}
+	now := time.Now()
+	for i, path := range paths {
+		ioutil.WriteFile(path, []byte("same"), 0644)
+		mtime := now.Add(time.Duration(i-len(paths)) * time.Hour)
+		os.Chtimes(path, mtime, mtime)
+	}
+	group := Group{ID: "g", Files: []File{{path: indexPath(paths[0])}, {path: indexPath(paths[1])}, {path: indexPath(paths[2])}}}
+
+	testCases := []struct {
+		keep     string
+		protect  []string
+		expected []string
+	}{
+		{"first", nil, []string{paths[0]}},
+		{"newest", nil, []string{paths[2]}},
+		{"oldest", nil, []string{paths[0]}},
+		{"shortest-path", nil, []string{paths[0]}},
+		{"longest-path", nil, []string{paths[1]}},
+		{"oldest", []string{master}, []string{paths[2]}},
+		{"first", []string{tempDir}, paths},
+		{"first", []string{master + "x"}, []string{paths[0]}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.keep, func(t *testing.T) {
+			policy, err := newKeepPolicy(tc.keep, tc.protect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keep, remove := policy.apply(group)
+			if len(keep) != len(tc.expected) || len(keep)+len(remove) != len(group.Files) {
+				t.Fatalf("Expected: %v, Got: keep %v remove %v", tc.expected, keep, remove)
+			}
+			for i, file := range keep {
+				if file.Path() != tc.expected[i] {
+					t.Errorf("Expected: %s, Got: %s", tc.expected[i], file.Path())
+				}
+			}
+		})
+	}
+
+	if _, err := newKeepPolicy("largest", nil); err == nil {
+		t.Errorf("Expected an error for an unknown policy")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// preserveAttributes are the metadata --preserve can keep when a moved file
+// is copied to another volume. Renamed files keep all of them anyway.
+var preserveAttributes = []string{"owner", "acl", "xattr"}
+
+// preserve holds the attributes of --preserve.
+var preserve map[string]bool
+
+// errNotPreservable is returned for attributes this system cannot copy.
+var errNotPreservable = errors.New("not supported on this system")
+
+// parsePreserve parses a comma-separated list of preserveAttributes.
+func parsePreserve(list string) (map[string]bool, error) {
+	attributes := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, attribute := range preserveAttributes {
+			known = known || name == attribute
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown attribute %q (expected %s)", name, strings.Join(preserveAttributes, ", "))
+		}
+		attributes[name] = true
+	}
+	return attributes, nil
+}
+
+// preserveMetadata copies the attributes of --preserve from src to its copy
+// dest. An attribute that cannot be copied fails the move, so the file stays
+// in place rather than losing its permissions.
+func preserveMetadata(src, dest string) error {
+	for _, attribute := range preserveAttributes {
+		if !preserve[attribute] {
+			continue
+		}
+		var err error
+		switch attribute {
+		case "owner":
+			err = copyOwner(src, dest)
+		case "acl":
+			err = copyACL(src, dest)
+		case "xattr":
+			err = copyXattrs(src, dest)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot preserve the %s of %s: %v", attribute, src, err)
+		}
+	}
+	return nil
+}
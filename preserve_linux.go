@@ -0,0 +1,91 @@
+//go:build linux
+
+package main
+
+import (
+	"strings"
+	"syscall"
+)
+
+// aclXattrs are the extended attributes that hold the POSIX ACLs of a file.
+var aclXattrs = []string{"system.posix_acl_access", "system.posix_acl_default"}
+
+// copyACL copies the POSIX ACLs of src to dest.
+func copyACL(src, dest string) error {
+	for _, name := range aclXattrs {
+		value, err := getXattr(src, name)
+		if err == syscall.ENODATA {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := syscall.Setxattr(dest, name, value, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyXattrs copies the extended attributes of src to dest, except for the
+// system attributes such as ACLs.
+func copyXattrs(src, dest string) error {
+	names, err := listXattrs(src)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, "system.") {
+			continue
+		}
+		value, err := getXattr(src, name)
+		if err != nil {
+			return err
+		}
+		if err := syscall.Setxattr(dest, name, value, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listXattrs returns the names of the extended attributes of path.
+func listXattrs(path string) ([]string, error) {
+	for {
+		size, err := syscall.Listxattr(path, nil)
+		if err != nil || size == 0 {
+			return nil, err
+		}
+		buf := make([]byte, size)
+		n, err := syscall.Listxattr(path, buf)
+		if err == syscall.ERANGE {
+			continue // attributes were added in between
+		}
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, name := range strings.Split(string(buf[:n]), "\x00") {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+}
+
+// getXattr returns the value of an extended attribute of path.
+func getXattr(path, name string) ([]byte, error) {
+	for {
+		size, err := syscall.Getxattr(path, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size)
+		n, err := syscall.Getxattr(path, name, buf)
+		if err == syscall.ERANGE {
+			continue
+		}
+		return buf[:n], err
+	}
+}
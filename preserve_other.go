@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+
+package main
+
+// copyACL cannot copy ACLs on this system.
+func copyACL(src, dest string) error {
+	return errNotPreservable
+}
+
+// copyXattrs cannot copy extended attributes on this system.
+func copyXattrs(src, dest string) error {
+	return errNotPreservable
+}
@@ -0,0 +1,7 @@
+package main
+
+// copyOwner cannot copy owners on Plan 9, which names them by strings that
+// os.Lchown does not accept.
+func copyOwner(src, dest string) error {
+	return errNotPreservable
+}
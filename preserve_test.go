@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParsePreserve(t *testing.T) {
+	tests := []struct {
+		list     string
+		expected string // sorted attributes, or "error"
+	}{
+		{"", ""},
+		{"owner", "owner"},
+		{"xattr, owner,acl", "acl owner xattr"},
+		{"owner,mode", "error"},
+	}
+	for _, test := range tests {
+		attributes, err := parsePreserve(test.list)
+		got := "error"
+		if err == nil {
+			var names []string
+			for _, name := range []string{"acl", "owner", "xattr"} {
+				if attributes[name] {
+					names = append(names, name)
+				}
+			}
+			got = strings.Join(names, " ")
+		}
+		if got != test.expected {
+			t.Errorf("Expected: %s, Got: %s for %q", test.expected, got, test.list)
+		}
+	}
+}
+
+func TestPreserveMetadata(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	src := filepath.Join(tempDir, "src")
+	dest := filepath.Join(tempDir, "dest")
+	ioutil.WriteFile(src, []byte("content"), 0644)
+	ioutil.WriteFile(dest, []byte("content"), 0644)
+	defer func() { preserve = nil }()
+
+	// Files of the running user can be given their own owner.
+	preserve = map[string]bool{"owner": true}
+	if err := preserveMetadata(src, dest); err != nil {
+		t.Errorf("Expected the owner to be preserved, Got: %v", err)
+	}
+
+	preserve = map[string]bool{"acl": true, "xattr": true}
+	err := preserveMetadata(src, dest)
+	if runtime.GOOS == "linux" {
+		if err != nil && !strings.Contains(err.Error(), "not supported") {
+			t.Errorf("Expected the ACL and attributes to be preserved, Got: %v", err)
+		}
+	} else if runtime.GOOS != "windows" && (err == nil || !strings.Contains(err.Error(), "cannot preserve the acl")) {
+		t.Errorf("Expected the ACL not to be preservable, Got: %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner gives dest the owner and group of src, which usually requires
+// root privileges.
+func copyOwner(src, dest string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return errNotPreservable
+	}
+	return os.Lchown(dest, int(st.Uid), int(st.Gid))
+}
@@ -0,0 +1,70 @@
+//go:build windows
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procGetNamedSecurityInfo = advapi32.NewProc("GetNamedSecurityInfoW")
+	procSetNamedSecurityInfo = advapi32.NewProc("SetNamedSecurityInfoW")
+	procLocalFree            = kernel32.NewProc("LocalFree")
+)
+
+// Security information flags and the object type of files for the named
+// security info functions.
+const (
+	seFileObject             = 1
+	ownerSecurityInformation = 0x1
+	groupSecurityInformation = 0x2
+	daclSecurityInformation  = 0x4
+	protectedDaclInformation = 0x80000000
+)
+
+// copyOwner gives dest the owner and primary group of src. Setting another
+// user as owner requires the restore privilege of administrators.
+func copyOwner(src, dest string) error {
+	return copySecurity(src, dest, ownerSecurityInformation|groupSecurityInformation)
+}
+
+// copyACL copies the access control list of src to dest.
+func copyACL(src, dest string) error {
+	return copySecurity(src, dest, daclSecurityInformation)
+}
+
+// copyXattrs cannot copy extended attributes on Windows.
+func copyXattrs(src, dest string) error {
+	return errNotPreservable
+}
+
+// copySecurity copies parts of the security descriptor of src to dest.
+func copySecurity(src, dest string, info uint32) error {
+	srcName, err := syscall.UTF16PtrFromString(src)
+	if err != nil {
+		return err
+	}
+	destName, err := syscall.UTF16PtrFromString(dest)
+	if err != nil {
+		return err
+	}
+	var owner, group, dacl, descriptor uintptr
+	ret, _, _ := procGetNamedSecurityInfo.Call(uintptr(unsafe.Pointer(srcName)), seFileObject, uintptr(info),
+		uintptr(unsafe.Pointer(&owner)), uintptr(unsafe.Pointer(&group)), uintptr(unsafe.Pointer(&dacl)), 0,
+		uintptr(unsafe.Pointer(&descriptor)))
+	if ret != 0 {
+		return syscall.Errno(ret)
+	}
+	defer procLocalFree.Call(descriptor)
+	if info&daclSecurityInformation != 0 {
+		// Keep the ACL as it was instead of merging it with the permissions
+		// the new folder would pass on.
+		info |= protectedDaclInformation
+	}
+	ret, _, _ = procSetNamedSecurityInfo.Call(uintptr(unsafe.Pointer(destName)), seFileObject, uintptr(info), owner, group, dacl, 0)
+	if ret != 0 {
+		return syscall.Errno(ret)
+	}
+	return nil
+}
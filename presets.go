@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// usePresets are the cleanup scenarios of --preset, each a set of flag
+// values. Flags given on the command line take precedence.
+var usePresets = map[string]map[string]string{
+	// Imports of the same card tend to be repeated, so the oldest copy is the
+	// original; moving keeps mistaken confirmations of likely duplicates
+	// recoverable.
+	"photos": {
+		"ext":            "jpg,jpeg,heic,heif,png,gif,tif,tiff,webp,dng,cr2,cr3,nef,arw,orf,rw2,raf,mp4,mov,m4v",
+		"photos":         "true",
+		"screenshots":    "true",
+		"heic-jpeg":      "ask",
+		"keep":           "oldest",
+		"default-action": "m",
+	},
+	// Libraries keep their tracks in a shallow structure, while copies end up
+	// in nested backup and device folders.
+	"music": {
+		"ext":            "mp3,flac,m4a,aac,ogg,opus,wav,aif,aiff,wma,alac,ape",
+		"keep":           "shortest-path",
+		"default-action": "m",
+	},
+	// The copy edited last is the one its owner works with.
+	"documents": {
+		"ext":            "pdf,doc,docx,odt,rtf,txt,md,xls,xlsx,ods,csv,ppt,pptx,odp,pages,numbers,key,epub",
+		"keep":           "newest",
+		"default-action": "m",
+	},
+	// Repeated downloads of the same file are newer than the first, and can
+	// be downloaded again, so they are deleted.
+	"downloads": {
+		"keep":           "oldest",
+		"default-action": "d",
+	},
+}
+
+func usePresetNames() []string {
+	var names []string
+	for name := range usePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyUsePreset sets the flags of fs that the preset name configures, unless
+// they were given on the command line.
+func applyUsePreset(fs *flag.FlagSet, name string) error {
+	preset, ok := usePresets[name]
+	if !ok {
+		return fmt.Errorf("unknown preset %q (expected one of %s)", name, strings.Join(usePresetNames(), ", "))
+	}
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for flagName, value := range preset {
+		if given[flagName] {
+			continue
+		}
+		if err := fs.Set(flagName, value); err != nil {
+			return fmt.Errorf("preset %s: -%s: %v", name, flagName, err)
+		}
+	}
+	return nil
+}
+
+// defaultActions are the answers --default-action accepts: the ones that
+// act on all duplicates without asking for more input.
+var defaultActions = []string{"l", "m", "d", "i"}
+
+// withDefaultAction marks the default answer in the action prompt.
+func withDefaultAction(prompt, action string) string {
+	if action == "" {
+		return prompt
+	}
+	return strings.Replace(prompt, actionChoices, actionChoices+" ["+action+"]", 1)
+}
+
+// keepFileMap moves the file that policy keeps to the front of every group.
+func keepFileMap(fileMap map[string][]File, policy keepPolicy) map[string][]File {
+	kept := make(map[string][]File, len(fileMap))
+	for key, files := range fileMap {
+		if len(files) < 2 {
+			kept[key] = files
+			continue
+		}
+		best := policy.best(files)
+		ordered := append([]File{files[best]}, files[:best]...)
+		kept[key] = append(ordered, files[best+1:]...)
+	}
+	return kept
+}
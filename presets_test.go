@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyUsePreset(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var exts stringList
+	fs.Var(&exts, "ext", "")
+	photos := fs.Bool("photos", false, "")
+	fs.Bool("screenshots", false, "")
+	fs.String("heic-jpeg", "", "")
+	keep := fs.String("keep", "first", "")
+	defaultAction := fs.String("default-action", "", "")
+	if err := fs.Parse([]string{"-keep", "newest"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := applyUsePreset(fs, "photos"); err != nil {
+		t.Fatal(err)
+	}
+	if !*photos || *defaultAction != "m" || len(exts) != 1 {
+		t.Errorf("Expected the preset to set the flags, Got: photos=%v default-action=%q ext=%v", *photos, *defaultAction, exts)
+	}
+	if *keep != "newest" {
+		t.Errorf("Expected: newest from the command line, Got: %s", *keep)
+	}
+	if err := applyUsePreset(fs, "videos"); err == nil {
+		t.Error("Expected an error for an unknown preset")
+	}
+	// Every preset must only set flags that exist.
+	for name := range usePresets {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.Var(&stringList{}, "ext", "")
+		fs.Bool("photos", false, "")
+		fs.Bool("screenshots", false, "")
+		fs.String("heic-jpeg", "", "")
+		fs.String("keep", "first", "")
+		fs.String("default-action", "", "")
+		if err := applyUsePreset(fs, name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestWithDefaultAction(t *testing.T) {
+	for lang, texts := range messages {
+		prompt := texts["prompt.action"]
+		if got := withDefaultAction(prompt, "d"); !strings.Contains(got, actionChoices+" [d]") {
+			t.Errorf("%s: Unexpected prompt: %q", lang, got)
+		}
+		if got := withDefaultAction(prompt, ""); got != prompt {
+			t.Errorf("%s: Unexpected prompt: %q", lang, got)
+		}
+	}
+}
+
+func TestKeepFileMap(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	var files []File
+	for i, name := range []string{"a", "b", "c"} {
+		path := filepath.Join(tempDir, name)
+		ioutil.WriteFile(path, []byte("x"), 0644)
+		modTime := time.Now().Add(time.Duration(i-1) * time.Hour)
+		if name == "b" {
+			modTime = time.Now().Add(-24 * time.Hour)
+		}
+		os.Chtimes(path, modTime, modTime)
+		files = append(files, File{path: indexPath(path)})
+	}
+	policy, _ := newKeepPolicy("oldest", nil)
+	kept := keepFileMap(map[string][]File{"x": files}, policy)["x"]
+	if len(kept) != 3 || kept[0].Path() != files[1].Path() || kept[1].Path() != files[0].Path() || kept[2].Path() != files[2].Path() {
+		t.Errorf("Expected b first, then a and c, Got: %v", kept)
+	}
+}
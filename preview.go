@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	previewTextLines = 10
+	previewLineWidth = 100
+)
+
+// contentType sniffs the MIME type of a file from its first bytes, falling
+// back to the extension for formats the sniffer does not know.
+func contentType(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	sniffed := http.DetectContentType(head[:n])
+	if sniffed == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(path))); byExt != "" {
+			return byExt, nil
+		}
+	}
+	return sniffed, nil
+}
+
+// writePreview prints a short description of a file: the first lines of text
+// files, dimensions and camera details of images, and duration and codecs of
+// audio and video files when ffprobe is installed.
+func writePreview(w io.Writer, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	kind, err := contentType(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, msg("preview.file", path, humanReadableSize(info.Size()), info.ModTime().Format("2006-01-02 15:04")))
+
+	switch {
+	case strings.HasPrefix(kind, "text/"):
+		lines, err := textHead(path, previewTextLines)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			fmt.Fprintf(w, "    | %s\n", line)
+		}
+	case strings.HasPrefix(kind, "image/"):
+		writeImagePreview(w, path)
+	case strings.HasPrefix(kind, "video/") || strings.HasPrefix(kind, "audio/"):
+		if media, err := probeMedia(path); err == nil {
+			fmt.Fprintln(w, "    "+media)
+		} else {
+			fmt.Fprintln(w, "    "+msg("preview.binary", kind))
+		}
+	default:
+		fmt.Fprintln(w, "    "+msg("preview.binary", kind))
+	}
+	return nil
+}
+
+// textHead returns up to n lines from the start of a text file, each
+// shortened to previewLineWidth characters.
+func textHead(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for len(lines) < n && scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if utf8.RuneCountInString(line) > previewLineWidth {
+			line = string([]rune(line)[:previewLineWidth-1]) + "…"
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil && err != bufio.ErrTooLong {
+		return nil, err
+	}
+	return lines, nil
+}
+
+func writeImagePreview(w io.Writer, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	config, format, err := image.DecodeConfig(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintln(w, "    "+msg("preview.binary", "image"))
+		return
+	}
+	fmt.Fprintln(w, "    "+msg("preview.image", config.Width, config.Height, strings.ToUpper(format)))
+	exif, err := readExif(path)
+	if err != nil {
+		return
+	}
+	if camera := strings.TrimSpace(exif.Make + " " + exif.Model); camera != "" {
+		fmt.Fprintln(w, "    "+msg("preview.camera", camera))
+	}
+	if exif.DateTimeOriginal != "" {
+		fmt.Fprintln(w, "    "+msg("preview.taken", exif.DateTimeOriginal))
+	}
+}
+
+// probeMedia describes an audio or video file using ffprobe.
+func probeMedia(path string) (string, error) {
+	out, err := exec.Command("ffprobe", "-v", "error",
+		"-show_entries", "format=duration:stream=codec_type,codec_name",
+		"-of", "json", path).Output()
+	if err != nil {
+		return "", err
+	}
+	return parseProbe(out)
+}
+
+// parseProbe formats the JSON output of ffprobe as a duration and a stream
+// list such as "video h264, audio aac".
+func parseProbe(data []byte) (string, error) {
+	var probe struct {
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return "", err
+	}
+	duration := "?"
+	if seconds, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		duration = time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
+	}
+	streams := make([]string, 0, len(probe.Streams))
+	for _, s := range probe.Streams {
+		streams = append(streams, strings.TrimSpace(s.CodecType+" "+s.CodecName))
+	}
+	return msg("preview.media", duration, strings.Join(streams, ", ")), nil
+}
+
+// selectedFiles returns the files of the duplicate group with the given ID,
+// or the file at the given path. It returns nil if neither exists.
+func selectedFiles(fileMap map[string][]File, selection string) []string {
+	for _, group := range duplicateGroups(fileMap) {
+		if group.ID == selection {
+			paths := make([]string, len(group.Files))
+			for i, file := range group.Files {
+				paths[i] = file.Path()
+			}
+			return paths
+		}
+	}
+	if _, err := os.Stat(selection); err != nil {
+		return nil
+	}
+	return []string{selection}
+}
+
+// previewSelection previews every file of the duplicate group with the given
+// ID, or the file at the given path.
+func previewSelection(w io.Writer, fileMap map[string][]File, selection string) {
+	paths := selectedFiles(fileMap, selection)
+	if paths == nil {
+		fmt.Fprintln(w, msg("preview.not_found", selection))
+		return
+	}
+	for _, path := range paths {
+		if err := writePreview(w, path); err != nil {
+			log.Printf("Error previewing file %s: %v", path, err)
+		}
+	}
+}
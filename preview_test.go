@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWritePreview(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	var lines []string
+	for i := 0; i < 15; i++ {
+		lines = append(lines, strings.Repeat("x", i))
+	}
+	lines[3] = strings.Repeat("é", 150)
+	textFile := filepath.Join(tempDir, "notes.txt")
+	ioutil.WriteFile(textFile, []byte(strings.Join(lines, "\n")), 0644)
+
+	var pngData bytes.Buffer
+	png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 64, 48)))
+	pngFile := filepath.Join(tempDir, "image.png")
+	ioutil.WriteFile(pngFile, pngData.Bytes(), 0644)
+
+	jpegFile := filepath.Join(tempDir, "photo.jpg")
+	ioutil.WriteFile(jpegFile, testExifJPEG(t, binary.LittleEndian, "Canon", "2021:07:04 10:30:00"), 0644)
+
+	binFile := filepath.Join(tempDir, "data.bin")
+	ioutil.WriteFile(binFile, []byte{0, 1, 2, 3}, 0644)
+
+	testCases := []struct {
+		name     string
+		path     string
+		contains []string
+		excludes []string
+	}{
+		{"text", textFile, []string{"notes.txt", "    | xxxxxxxxx\n", "…"}, []string{"xxxxxxxxxx\n"}},
+		{"image", pngFile, []string{"Image: 64x48 PNG"}, nil},
+		{"photo", jpegFile, []string{"Image: 4x3 JPEG", "Camera: Canon", "Taken: 2021:07:04 10:30:00"}, nil},
+		{"binary", binFile, []string{"Binary file (application/octet-stream)"}, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writePreview(&out, tc.path); err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("Expected preview to contain %q, Got: %s", s, out.String())
+				}
+			}
+			for _, s := range tc.excludes {
+				if strings.Contains(out.String(), s) {
+					t.Errorf("Expected preview not to contain %q, Got: %s", s, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestParseProbe(t *testing.T) {
+	data := []byte(`{"streams":[{"codec_name":"h264","codec_type":"video"},{"codec_name":"aac","codec_type":"audio"}],"format":{"duration":"94.600000"}}`)
+	got, err := parseProbe(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Duration: 1m35s | Streams: video h264, audio aac"
+	if got != expected {
+		t.Errorf("Expected: %s, Got: %s", expected, got)
+	}
+}
+
+func TestPreviewSelection(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	a := filepath.Join(tempDir, "a.txt")
+	b := filepath.Join(tempDir, "b.txt")
+	ioutil.WriteFile(a, []byte("same\n"), 0644)
+	ioutil.WriteFile(b, []byte("same\n"), 0644)
+	hash := "0123456789abcdef0123"
+	fileMap := map[string][]File{hash: {{path: indexPath(a), Hash: hash, Size: 5}, {path: indexPath(b), Hash: hash, Size: 5}}}
+
+	var out bytes.Buffer
+	previewSelection(&out, fileMap, hash[:groupIDLength])
+	if strings.Count(out.String(), "| same") != 2 {
+		t.Errorf("Expected both files of the group, Got: %s", out.String())
+	}
+
+	out.Reset()
+	previewSelection(&out, fileMap, "missing")
+	if !strings.Contains(out.String(), "No duplicate group or file named missing.") {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+}
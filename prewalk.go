@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// preWalk, set by --pre-walk, totals the files and sizes of all roots before
+// hashing begins. The progress then knows the size of the whole scan and can
+// show the throughput and the time left from the start of the hashing.
+var preWalk bool
+
+// sizeRoots walks roots without hashing anything and returns the number and
+// the total size of their files.
+func sizeRoots(roots []string, control *scanControl) (files int, size int64, err error) {
+	for _, root := range roots {
+		filter := newWalkFilter(root)
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if control.isCanceled() {
+				return errScanCanceled
+			}
+			if filter.skip(path, info) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir() {
+				files++
+				size += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return files, size, err
+		}
+	}
+	return files, size, nil
+}
+
+// estimate returns the hashing rate of a scan and the time left until all
+// the bytes found are hashed. ok is false unless the total size of the scan
+// is known and some data was hashed, so a partial walk does not make the
+// scan look shorter than it is.
+func estimate(p scanProgress, hashing time.Duration) (rate int64, remaining time.Duration, ok bool) {
+	if !p.Sized || p.TotalSize == 0 || hashing <= 0 {
+		return 0, 0, false
+	}
+	perSecond := float64(p.TotalSize) / hashing.Seconds()
+	left := p.FoundSize - p.TotalSize
+	if left < 0 { // files that grew since the pre-walk
+		left = 0
+	}
+	remaining = time.Duration(float64(left) / perSecond * float64(time.Second))
+	return int64(perSecond), remaining.Round(time.Second), true
+}
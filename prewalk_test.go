@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSizeRoots(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	os.Mkdir(filepath.Join(tempDir, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("abc"), 0644)
+	ioutil.WriteFile(filepath.Join(tempDir, "sub", "b.txt"), []byte("defgh"), 0644)
+
+	files, size, err := sizeRoots([]string{tempDir, filepath.Join(tempDir, "sub")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != 3 || size != 13 {
+		t.Errorf("Expected: 3 files of 13 bytes, Got: %d files of %d bytes", files, size)
+	}
+
+	control := newScanControl()
+	control.cancel()
+	if _, _, err := sizeRoots([]string{tempDir}, control); err != errScanCanceled {
+		t.Errorf("Expected: %v, Got: %v", errScanCanceled, err)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		progress  scanProgress
+		hashing   time.Duration
+		ok        bool
+		rate      int64
+		remaining time.Duration
+	}{
+		{scanProgress{TotalSize: 10, FoundSize: 40}, time.Second, false, 0, 0},
+		{scanProgress{FoundSize: 40, Sized: true}, time.Second, false, 0, 0},
+		{scanProgress{TotalSize: 10, FoundSize: 40, Sized: true}, 0, false, 0, 0},
+		{scanProgress{TotalSize: 10, FoundSize: 40, Sized: true}, 2 * time.Second, true, 5, 6 * time.Second},
+		{scanProgress{TotalSize: 50, FoundSize: 40, Sized: true}, 10 * time.Second, true, 5, 0},
+	}
+	for _, test := range tests {
+		rate, remaining, ok := estimate(test.progress, test.hashing)
+		if ok != test.ok || rate != test.rate || remaining != test.remaining {
+			t.Errorf("Expected: %v %d %v, Got: %v %d %v for %+v", test.ok, test.rate, test.remaining, ok, rate, remaining, test.progress)
+		}
+	}
+}
+
+func TestScanRootsPreWalk(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	var roots []string
+	for _, name := range []string{"a", "b"} {
+		root := filepath.Join(tempDir, name)
+		os.Mkdir(root, 0755)
+		ioutil.WriteFile(filepath.Join(root, "file.txt"), []byte("content"), 0644)
+		roots = append(roots, root)
+	}
+
+	preWalk = true
+	defer func() { preWalk = false }()
+	var first *scanProgress
+	_, progress, err := scanRoots(roots, func(p scanProgress) {
+		if first == nil {
+			first = &p
+		}
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == nil || !first.Sized || first.Files != 2 || first.FoundSize != 14 {
+		t.Errorf("Expected the first progress to know the whole scan, Got: %+v", first)
+	}
+	if !progress.Sized || progress.Files != 2 || progress.FoundSize != 14 || progress.TotalSize != 14 {
+		t.Errorf("Unexpected progress: %+v", progress)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// quarantineRetention, set by --quarantine-retention, is how long moved
+// duplicates are kept before a run purges them; 0 keeps them.
+var quarantineRetention time.Duration
+
+// parseRetention parses a period such as 30d, 2w, 1y or 36h.
+func parseRetention(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour, "y": 365 * 24 * time.Hour} {
+		if number := strings.TrimSuffix(s, suffix); number != s {
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid period %q", s)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid period %q (expected e.g. 30d, 2w, 1y or 36h)", s)
+	}
+	return d, nil
+}
+
+// expiredFiles returns the moved files that were moved longer than
+// retention before now.
+func expiredFiles(moved []movedFile, retention time.Duration, now time.Time) []movedFile {
+	var expired []movedFile
+	for _, m := range moved {
+		if !m.Moved.IsZero() && now.Sub(m.Moved) > retention {
+			expired = append(expired, m)
+		}
+	}
+	return expired
+}
+
+// purgeFile deletes a moved file after making sure it is still a duplicate:
+// it and the copy kept instead of it must both still have the hash they had
+// when the file was moved.
+func purgeFile(m movedFile) error {
+	if m.Lost {
+		return fmt.Errorf("%s was overwritten by a later move", m.Current)
+	}
+	if m.Keep == "" {
+		return errors.New("the audit log does not name its kept copy")
+	}
+	if err := checkHash(m.Current, m.Hash); err != nil {
+		return fmt.Errorf("it changed since it was moved: %v", err)
+	}
+	if err := checkHash(m.Keep, m.Hash); err != nil {
+		return fmt.Errorf("its kept copy %s changed or is gone: %v", m.Keep, err)
+	}
+	return withFileLock(m.Current, func() error {
+		if err := checkWritable("purge", m.Current); err != nil {
+			return err
+		}
+		return os.Remove(m.Current)
+	})
+}
+
+// purgeExpired purges the files of the audit log that were moved longer
+// than retention ago, or only lists them with dryRun.
+func purgeExpired(retention time.Duration, dryRun bool) (actionStats, error) {
+	var stats actionStats
+	moved, err := movedFiles(audit.path)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+	for _, m := range expiredFiles(moved, retention, time.Now()) {
+		if dryRun {
+			fmt.Println(msg("purge.would", m.Current, m.Moved.Local().Format("2006-01-02")))
+			stats.Files++
+			stats.Bytes += m.Size
+			continue
+		}
+		err := purgeFile(m)
+		if skipLocked(m.Current, err, &stats) {
+			continue
+		}
+		audit.record(auditEntry{Action: "purge", Hash: m.Hash, Path: m.Current, Keep: m.Keep, Size: m.Size}, err)
+		if err != nil {
+			log.Printf("Error purging %s: %v", m.Current, err)
+			stats.Errors++
+			continue
+		}
+		fmt.Println(msg("purge.done", m.Current))
+		stats.Files++
+		stats.Bytes += m.Size
+	}
+	return stats, nil
+}
+
+// runPurge implements the purge command, which deletes the duplicates that
+// earlier runs moved once they are older than the retention period.
+func runPurge(args []string) {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	olderThan := fs.String("older-than", "30d", "purge the files moved longer ago than this, e.g. 30d, 2w or 36h")
+	auditPath := fs.String("audit-log", "", "audit log that recorded the moves (default: audit.jsonl in the state directory)")
+	dryRun := fs.Bool("dry-run", false, "only list the files that would be purged")
+	fs.BoolVar(&readOnly, "read-only", false, "never delete files; only list them")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s purge [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	retention, err := parseRetention(*olderThan)
+	if err != nil {
+		log.Fatalf("Error: invalid --older-than: %v", err)
+	}
+	if audit, err = openAuditLog(*auditPath); err != nil {
+		log.Fatal("Error:", err)
+	}
+	stats, err := purgeExpired(retention, *dryRun)
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	if !*dryRun {
+		fmt.Println(msg("purge.summary", formatCount(int64(stats.Files)), humanReadableSize(stats.Bytes)))
+	}
+	if stats.Errors > 0 || stats.Locked > 0 {
+		os.Exit(1)
+	}
+}
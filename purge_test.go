@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"30d", 30 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"36h", 36 * time.Hour, true},
+		{"2y", 2 * 365 * 24 * time.Hour, true},
+		{"1.5d", 36 * time.Hour, true},
+		{"-1d", 0, false},
+		{"month", 0, false},
+	}
+	for _, test := range tests {
+		got, err := parseRetention(test.input)
+		if (err == nil) != test.valid || got != test.expected {
+			t.Errorf("Expected: %v (valid %v), Got: %v (%v) for %q", test.expected, test.valid, got, err, test.input)
+		}
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	keep := filepath.Join(tempDir, "keep")
+	ioutil.WriteFile(keep, []byte("content"), 0644)
+	file, _ := hashFile(keep)
+	hash := file.Hash
+	path := filepath.Join(tempDir, auditFile)
+	audit, _ = openAuditLog(path)
+	defer func() { audit = nil }()
+
+	// Write moves of different ages to the log directly.
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	f, _ := os.Create(path)
+	enc := json.NewEncoder(f)
+	for _, m := range []struct {
+		name  string
+		moved time.Time
+		keep  string
+		data  string
+	}{
+		{"old", old, keep, "content"},
+		{"recent", time.Now(), keep, "content"},
+		{"changed", old, keep, "changed"},
+		{"unknown", old, "", "content"},
+	} {
+		current := filepath.Join(tempDir, m.name)
+		ioutil.WriteFile(current, []byte(m.data), 0644)
+		enc.Encode(auditEntry{Time: m.moved, Action: "move", Hash: hash, Path: "/original/" + m.name, To: current, Keep: m.keep, Size: 7, Result: "ok"})
+	}
+	f.Close()
+
+	stats, err := purgeExpired(30*24*time.Hour, true)
+	if err != nil || stats.Files != 3 {
+		t.Errorf("Expected 3 files to be listed, Got: %+v, %v", stats, err)
+	}
+	stats, err = purgeExpired(30*24*time.Hour, false)
+	if err != nil || stats.Files != 1 || stats.Errors != 2 {
+		t.Errorf("Expected 1 file to be purged and 2 errors, Got: %+v, %v", stats, err)
+	}
+	for name, exists := range map[string]bool{"old": false, "recent": true, "changed": true, "unknown": true, "keep": true} {
+		if _, err := os.Stat(filepath.Join(tempDir, name)); (err == nil) != exists {
+			t.Errorf("Expected %s to exist: %v, Got: %v", name, exists, err)
+		}
+	}
+	if moved, _ := movedFiles(path); len(moved) != 3 {
+		t.Errorf("Expected the purged file to be left out, Got: %+v", moved)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// groupQuery selects duplicate groups from saved results. Zero values match
+// every group.
+type groupQuery struct {
+	minWaste int64
+	maxWaste int64 // 0 for no limit
+	minSize  int64
+	maxSize  int64 // 0 for no limit
+	// minCopies and maxCopies bound the number of files of the group; 0 for
+	// no limit.
+	minCopies int
+	maxCopies int
+	under     []string // the group has a copy below one of these folders
+	exts      []string // lower-case extensions with leading dot
+	paths     []string // lower-case substrings, each in the path of a file
+}
+
+func (q groupQuery) matches(group Group) bool {
+	if group.Waste() < q.minWaste || group.Files[0].Size < q.minSize {
+		return false
+	}
+	if (q.maxWaste > 0 && group.Waste() > q.maxWaste) || (q.maxSize > 0 && group.Files[0].Size > q.maxSize) {
+		return false
+	}
+	if len(group.Files) < q.minCopies || (q.maxCopies > 0 && len(group.Files) > q.maxCopies) {
+		return false
+	}
+	if len(q.exts) > 0 {
+		found := false
+		for _, file := range group.Files {
+			ext := strings.ToLower(filepath.Ext(file.Path()))
+			for _, e := range q.exts {
+				found = found || ext == e
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, substring := range q.paths {
+		found := false
+		for _, file := range group.Files {
+			found = found || strings.Contains(strings.ToLower(file.Path()), substring)
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(q.under) > 0 {
+		found := false
+		for _, file := range group.Files {
+			path := filepath.Clean(filepath.FromSlash(file.Path()))
+			for _, dir := range q.under {
+				found = found || path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// addUnder adds a folder to the folders one of whose copies must be below.
+func (q *groupQuery) addUnder(dir string) {
+	q.under = append(q.under, filepath.Clean(filepath.FromSlash(formatPath(dir))))
+}
+
+// addExts adds a comma-separated list of extensions such as "jpg,.png".
+func (q *groupQuery) addExts(list string) {
+	for _, e := range strings.Split(list, ",") {
+		q.exts = append(q.exts, "."+strings.TrimPrefix(strings.ToLower(strings.TrimSpace(e)), "."))
+	}
+}
+
+// parseFilter parses a filter such as `ext=jpg size>10M under=/old-backup`.
+// All terms must match. size and waste, the size of a file of the group and
+// of its redundant copies, and copies, its number of files, accept =, <, <=,
+// > and >=; ext, under and path, a part of the path of a file in any case,
+// accept = and may be repeated. Terms that contain spaces can be quoted as a
+// whole.
+func parseFilter(expr string) (groupQuery, error) {
+	var q groupQuery
+	terms, err := splitQuoted(strings.TrimSpace(expr))
+	if err != nil {
+		return q, err
+	}
+	for _, term := range terms {
+		i := strings.IndexAny(term, "<>=")
+		if i <= 0 {
+			return q, fmt.Errorf("invalid filter term %q (expected e.g. ext=jpg or size>10M)", term)
+		}
+		key, op, value := term[:i], term[i:i+1], term[i+1:]
+		if strings.HasPrefix(value, "=") && op != "=" {
+			op, value = op+"=", value[1:]
+		}
+		switch key {
+		case "ext", "under", "path":
+			if op != "=" {
+				return q, fmt.Errorf("invalid filter term %q: %s only supports =", term, key)
+			}
+			switch key {
+			case "ext":
+				q.addExts(value)
+			case "under":
+				q.addUnder(value)
+			case "path":
+				q.paths = append(q.paths, strings.ToLower(value))
+			}
+		case "copies":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return q, fmt.Errorf("invalid filter term %q: invalid number of copies %q", term, value)
+			}
+			switch op {
+			case "=":
+				q.minCopies, q.maxCopies = n, n
+			case ">":
+				q.minCopies = n + 1
+			case ">=":
+				q.minCopies = n
+			case "<":
+				q.maxCopies = n - 1
+			case "<=":
+				q.maxCopies = n
+			}
+		case "size", "waste":
+			size, err := parseSize(value)
+			if err != nil {
+				return q, fmt.Errorf("invalid filter term %q: %v", term, err)
+			}
+			lower, upper := &q.minSize, &q.maxSize
+			if key == "waste" {
+				lower, upper = &q.minWaste, &q.maxWaste
+			}
+			switch op {
+			case "=":
+				*lower, *upper = size, size
+			case ">":
+				*lower = size + 1
+			case ">=":
+				*lower = size
+			case "<":
+				*upper = size - 1
+			case "<=":
+				*upper = size
+			}
+		default:
+			return q, fmt.Errorf("unknown filter key %q (expected ext, under, path, size, waste or copies)", key)
+		}
+	}
+	return q, nil
+}
+
+// filterFileMap returns the duplicate groups of fileMap that match q, keyed
+// like fileMap.
+func filterFileMap(fileMap map[string][]File, q groupQuery) map[string][]File {
+	filtered := make(map[string][]File)
+	for key, files := range fileMap {
+		if len(files) > 1 && q.matches(Group{ID: groupID(key), Hash: files[0].Hash, Files: files}) {
+			filtered[key] = files
+		}
+	}
+	return filtered
+}
+
+// groupOrders are the orders the groups can be listed in: by waste and by
+// number of files, largest first, or by the path of the kept file.
+var groupOrders = []string{"waste", "count", "path"}
+
+// sortGroups sorts groups in the order by, one of groupOrders; groups that
+// tie stay in the order of their IDs.
+func sortGroups(groups []Group, by string) error {
+	var less func(a, b Group) bool
+	switch by {
+	case "waste":
+		less = func(a, b Group) bool { return a.Waste() > b.Waste() }
+	case "count":
+		less = func(a, b Group) bool { return len(a.Files) > len(b.Files) }
+	case "path":
+		less = func(a, b Group) bool { return a.Files[0].Path() < b.Files[0].Path() }
+	default:
+		return fmt.Errorf("unknown order %q (expected %s)", by, strings.Join(groupOrders, ", "))
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return less(groups[i], groups[j]) })
+	return nil
+}
+
+// queryGroups returns the groups that match q.
+func queryGroups(groups []Group, q groupQuery) []Group {
+	var matched []Group
+	for _, group := range groups {
+		if q.matches(group) {
+			matched = append(matched, group)
+		}
+	}
+	return matched
+}
+
+// writeQueryResult lists the matched groups followed by their totals.
+func writeQueryResult(w io.Writer, groups []Group) {
+	var waste int64
+	for _, group := range groups {
+		fmt.Fprintln(w, msg("list.group", group.ID, group.Hash))
+		for _, file := range group.Files {
+			fmt.Fprintln(w, file.Path())
+		}
+		fmt.Fprintln(w)
+		waste += group.Waste()
+	}
+	fmt.Fprintln(w, msg("query.total", formatCount(int64(len(groups))), humanReadableSize(waste)))
+}
+
+// runQuery implements the query command, which answers questions about a
+// results file written by --save without scanning again.
+func runQuery(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	fs.StringVar(&sizeUnits, "units", sizeUnits, "size units: iec (1024-based, KiB/MiB) or si (1000-based, KB/MB)")
+	minWaste := fs.String("min-waste", "0", "only groups whose redundant copies take at least this much space, e.g. 100M")
+	minSize := fs.String("min-size", "0", "only groups of files of at least this size")
+	minCopies := fs.Int("min-copies", 0, "only groups with at least this many copies")
+	var under, exts stringList
+	fs.Var(&under, "under", "only groups with a copy below this folder (repeatable)")
+	fs.Var(&exts, "ext", "only groups with a file of this extension, e.g. mp4 (repeatable)")
+	asJSON := fs.Bool("json", false, "print the matching groups as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s query [flags] results.json\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	var q groupQuery
+	var err error
+	if q.minWaste, err = parseSize(*minWaste); err != nil {
+		log.Fatal("Error:", err)
+	}
+	if q.minSize, err = parseSize(*minSize); err != nil {
+		log.Fatal("Error:", err)
+	}
+	q.minCopies = *minCopies
+	for _, dir := range under {
+		q.addUnder(dir)
+	}
+	for _, ext := range exts {
+		q.addExts(ext)
+	}
+
+	results, err := loadResults(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error loading results from %s: %v", fs.Arg(0), err)
+	}
+	groups := queryGroups(results.Groups, q)
+	if *asJSON {
+		if groups == nil {
+			groups = []Group{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(groups)
+		return
+	}
+	writeQueryResult(os.Stdout, groups)
+}
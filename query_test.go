@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQueryGroups(t *testing.T) {
+	p := filepath.FromSlash
+	groups := duplicateGroups(map[string][]File{
+		"aaaaaaaaaaaaaaaa": {{path: indexPath(p("/videos/a.mp4")), Size: 200 << 20}, {path: indexPath(p("/backup/a.MP4")), Size: 200 << 20}},
+		"bbbbbbbbbbbbbbbb": {{path: indexPath(p("/videos-old/b.mkv")), Size: 50 << 20}, {path: indexPath(p("/backup/b.mkv")), Size: 50 << 20}, {path: indexPath(p("/tmp/b.mkv")), Size: 50 << 20}},
+		"cccccccccccccccc": {{path: indexPath(p("/docs/c.txt")), Size: 10}, {path: indexPath(p("/videos/c.txt")), Size: 10}},
+	})
+
+	testCases := []struct {
+		name     string
+		query    groupQuery
+		expected []string
+	}{
+		{"all", groupQuery{}, []string{"aaaaaaaaaaaa", "bbbbbbbbbbbb", "cccccccccccc"}},
+		{"min waste", groupQuery{minWaste: 150 << 20}, []string{"aaaaaaaaaaaa"}},
+		{"min size", groupQuery{minSize: 50 << 20}, []string{"aaaaaaaaaaaa", "bbbbbbbbbbbb"}},
+		{"under", groupQuery{under: []string{p("/videos")}}, []string{"aaaaaaaaaaaa", "cccccccccccc"}},
+		{"under with slash", groupQuery{under: []string{p("/videos-old/")}}, []string{"bbbbbbbbbbbb"}},
+		{"ext", groupQuery{exts: []string{".mp4", ".mkv"}}, []string{"aaaaaaaaaaaa", "bbbbbbbbbbbb"}},
+		{"combined", groupQuery{minWaste: 1 << 20, under: []string{p("/videos")}, exts: []string{".mp4"}}, []string{"aaaaaaaaaaaa"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ids []string
+			for _, group := range queryGroups(groups, tc.query) {
+				ids = append(ids, group.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected: %v, Got: %v", tc.expected, ids)
+			}
+		})
+	}
+}
+
+func TestWriteQueryResult(t *testing.T) {
+	groups := duplicateGroups(map[string][]File{
+		"aaaaaaaaaaaaaaaa": {{path: indexPath("a1"), Size: 1024}, {path: indexPath("a2"), Size: 1024}, {path: indexPath("a3"), Size: 1024}},
+	})
+	var out bytes.Buffer
+	writeQueryResult(&out, groups)
+	if !strings.Contains(out.String(), "a1\na2\na3\n") || !strings.HasSuffix(out.String(), "1 matching groups, 2.00 KiB reclaimable.\n") {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected groupQuery
+		err      string
+	}{
+		{"ext=jpg,PNG size>10M", groupQuery{exts: []string{".jpg", ".png"}, minSize: 10<<20 + 1}, ""},
+		{`size<=1K waste>=1M "under=/old backup"`, groupQuery{maxSize: 1024, minWaste: 1 << 20, under: []string{filepath.Clean(filepath.FromSlash("/old backup"))}}, ""},
+		{"size=5 waste<100", groupQuery{minSize: 5, maxSize: 5, maxWaste: 99}, ""},
+		{"", groupQuery{}, ""},
+		{"copies>=3", groupQuery{minCopies: 3}, ""},
+		{"path=Holiday ext=jpg", groupQuery{exts: []string{".jpg"}, paths: []string{"holiday"}}, ""},
+		{"copies<5 copies>2", groupQuery{minCopies: 3, maxCopies: 4}, ""},
+		{"copies=x", groupQuery{}, `invalid filter term "copies=x": invalid number of copies "x"`},
+		{"color=red", groupQuery{}, `unknown filter key "color" (expected ext, under, path, size, waste or copies)`},
+		{"ext>jpg", groupQuery{}, `invalid filter term "ext>jpg": ext only supports =`},
+		{"size>big", groupQuery{}, `invalid filter term "size>big": invalid size "big"`},
+		{"jpg", groupQuery{}, `invalid filter term "jpg" (expected e.g. ext=jpg or size>10M)`},
+	}
+	for _, tc := range testCases {
+		got, err := parseFilter(tc.expr)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s: Expected: %s, Got: %v", tc.expr, tc.err, err)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(tc.expected) {
+			t.Errorf("%s: Expected: %+v, Got: %+v (%v)", tc.expr, tc.expected, got, err)
+		}
+	}
+}
+
+func TestFilterFileMap(t *testing.T) {
+	fileMap := map[string][]File{
+		"aaaaaaaaaaaaaaaa":   {{path: indexPath("/old-backup/a.jpg"), Size: 20 << 20}, {path: indexPath("/photos/a.jpg"), Size: 20 << 20}},
+		"bbbbbbbbbbbbbbbb":   {{path: indexPath("/old-backup/b.jpg"), Size: 1 << 20}, {path: indexPath("/photos/b.jpg"), Size: 1 << 20}},
+		"cccccccccccccccc-2": {{path: indexPath("/old-backup/c.png"), Size: 20 << 20}, {path: indexPath("/photos/c.png"), Size: 20 << 20}},
+		"dddddddddddddddd":   {{path: indexPath("/old-backup/d.jpg"), Size: 20 << 20}},
+	}
+	q, err := parseFilter("ext=jpg size>10M under=" + filepath.FromSlash("/old-backup"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered := filterFileMap(fileMap, q)
+	if len(filtered) != 1 || len(filtered["aaaaaaaaaaaaaaaa"]) != 2 {
+		t.Errorf("Expected only group aaaaaaaaaaaa, Got: %v", filtered)
+	}
+	if filtered = filterFileMap(fileMap, groupQuery{exts: []string{".png"}}); len(filtered["cccccccccccccccc-2"]) != 2 {
+		t.Errorf("Expected the split group to keep its key, Got: %v", filtered)
+	}
+
+	fileMap["eeeeeeeeeeeeeeee"] = []File{{path: indexPath("/e1"), Size: 4 << 20}, {path: indexPath("/e2"), Size: 4 << 20}, {path: indexPath("/e3"), Size: 4 << 20}}
+	if filtered = filterFileMap(fileMap, groupQuery{minCopies: 3}); len(filtered) != 1 || filtered["eeeeeeeeeeeeeeee"] == nil {
+		t.Errorf("Expected only the group with 3 copies, Got: %v", filtered)
+	}
+	if filtered = filterFileMap(fileMap, groupQuery{minWaste: 8 << 20}); len(filtered) != 3 || filtered["bbbbbbbbbbbbbbbb"] != nil {
+		t.Errorf("Expected the groups wasting at least 8 MiB, Got: %v", filtered)
+	}
+}
+
+func TestSortGroups(t *testing.T) {
+	groups := []Group{
+		{ID: "a", Files: []File{{path: indexPath("/b/x"), Size: 10}, {path: indexPath("/c/x"), Size: 10}}},
+		{ID: "b", Files: []File{{path: indexPath("/a/y"), Size: 1}, {path: indexPath("/b/y"), Size: 1}, {path: indexPath("/c/y"), Size: 1}}},
+		{ID: "c", Files: []File{{path: indexPath("/c/z"), Size: 100}, {path: indexPath("/d/z"), Size: 100}}},
+	}
+	testCases := []struct {
+		by       string
+		expected string
+	}{
+		{"waste", "c,a,b"},
+		{"count", "b,a,c"},
+		{"path", "b,a,c"},
+	}
+	for _, tc := range testCases {
+		sorted := append([]Group(nil), groups...)
+		if err := sortGroups(sorted, tc.by); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, group := range sorted {
+			ids = append(ids, group.ID)
+		}
+		if got := strings.Join(ids, ","); got != tc.expected {
+			t.Errorf("%s: Expected: %s, Got: %s", tc.by, tc.expected, got)
+		}
+	}
+	if err := sortGroups(groups, "age"); err == nil {
+		t.Error("Expected an error for an unknown order")
+	}
+}
+
+func TestFilterByPath(t *testing.T) {
+	fileMap := map[string][]File{
+		"aaaaaaaaaaaaaaaa": {{path: indexPath("/photos/Holiday 2020/a.jpg"), Size: 1}, {path: indexPath("/backup/a.jpg"), Size: 1}},
+		"bbbbbbbbbbbbbbbb": {{path: indexPath("/photos/work/b.jpg"), Size: 1}, {path: indexPath("/backup/b.jpg"), Size: 1}},
+	}
+	q, err := parseFilter("path=holiday")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filtered := filterFileMap(fileMap, q); len(filtered) != 1 || filtered["aaaaaaaaaaaaaaaa"] == nil {
+		t.Errorf("Expected only the holiday group, Got: %v", filtered)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Limits of the hashing, for scans of network storage such as NFS, SMB or
+// rclone mounts that should not trip provider throttling or saturate the
+// uplink. They apply to every file read of the run.
+var (
+	scanWorkers int          // concurrent hashes; 0 to pick them by storage type
+	openLimiter *rateLimiter // files opened per second
+	readLimiter *rateLimiter // bytes read per second
+)
+
+// rateLimiter lets at most rate units pass per second, with bursts of up to
+// one second's worth. Units taken beyond that are paid back by waiting. A
+// nil *rateLimiter never waits.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter for rate units per second, or nil if rate
+// is not positive.
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// wait takes n units, blocking until the rate allows them.
+func (l *rateLimiter) wait(n int64) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// limitedReader reads from r at the rate of limiter.
+type limitedReader struct {
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (r limitedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.limiter.wait(int64(n))
+	return n, err
+}
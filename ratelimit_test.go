@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var unlimited *rateLimiter
+	unlimited.wait(1 << 30)
+	if newRateLimiter(0) != nil {
+		t.Errorf("Expected no limiter for a rate of 0")
+	}
+
+	l := newRateLimiter(100)
+	start := time.Now()
+	l.wait(100) // the initial burst
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Expected the burst to pass right away, took %s", elapsed)
+	}
+	l.wait(20)
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected to wait about 200ms beyond the burst, took %s", elapsed)
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 3000)
+	start := time.Now()
+	got, err := ioutil.ReadAll(limitedReader{bytes.NewReader(data), newRateLimiter(10000)})
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Unexpected read: %d bytes, %v", len(got), err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the data to fit in the burst, took %s", elapsed)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rclonePrefix marks scan roots and reference folders on a remote of rclone,
+// such as rclone:gdrive:Photos, which are listed and read through the remote
+// control API of rclone. Their files are never moved or deleted.
+const rclonePrefix = "rclone:"
+
+// isRemotePath reports whether path is on an rclone remote.
+func isRemotePath(path string) bool {
+	return strings.HasPrefix(path, rclonePrefix)
+}
+
+// skipRemote logs a file that is not moved or deleted because it is on an
+// rclone remote and counts it in stats. It reports whether it skipped path.
+func skipRemote(path string, stats *actionStats) bool {
+	if !isRemotePath(path) {
+		return false
+	}
+	log.Printf("Skipping %s, files on rclone remotes are never moved or deleted", path)
+	stats.Remote++
+	return true
+}
+
+// rcloneRC, set by --rclone-rc, is the URL of the remote control API of a
+// running "rclone rcd --rc-serve"; user and password go into the URL. If it
+// is empty, an rclone rcd is started for the scan.
+var rcloneRC string
+
+// rcloneStartTimeout is how long an rclone rcd started for the scan has to
+// answer.
+var rcloneStartTimeout = 10 * time.Second
+
+// rcloneServer is the remote control API the remotes are used through.
+type rcloneServer struct {
+	url string    // without trailing slash
+	cmd *exec.Cmd // the rclone rcd started for the scan, if any
+}
+
+// rclone is the server of the running scan, started with its first remote.
+var rclone struct {
+	sync.Mutex
+	server *rcloneServer
+}
+
+// rcloneClient returns the remote control API of rclone, starting rclone rcd
+// unless --rclone-rc names a running one.
+func rcloneClient() (*rcloneServer, error) {
+	rclone.Lock()
+	defer rclone.Unlock()
+	if rclone.server != nil {
+		return rclone.server, nil
+	}
+	if rcloneRC != "" {
+		rclone.server = &rcloneServer{url: strings.TrimSuffix(rcloneRC, "/")}
+		return rclone.server, nil
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	addr := l.Addr().String()
+	l.Close()
+	secret := make([]byte, 16)
+	rand.Read(secret)
+	user, pass := "duplicate_finder", hex.EncodeToString(secret)
+	cmd := exec.Command("rclone", "rcd", "--rc-addr", addr, "--rc-user", user, "--rc-pass", pass, "--rc-serve")
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting rclone: %v", err)
+	}
+	server := &rcloneServer{url: "http://" + url.UserPassword(user, pass).String() + "@" + addr, cmd: cmd}
+	for deadline := time.Now().Add(rcloneStartTimeout); ; time.Sleep(100 * time.Millisecond) {
+		if err = server.call("rc/noop", struct{}{}, nil); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return nil, fmt.Errorf("rclone rcd did not start: %v", err)
+		}
+	}
+	rclone.server = server
+	return server, nil
+}
+
+// stopRclone stops the rclone rcd started for the scan, once no remote is
+// read anymore.
+func stopRclone() {
+	rclone.Lock()
+	defer rclone.Unlock()
+	if rclone.server != nil && rclone.server.cmd != nil {
+		rclone.server.cmd.Process.Kill()
+		rclone.server.cmd.Wait()
+	}
+	rclone.server = nil
+}
+
+// call calls method of the remote control API with in and decodes the reply
+// into out, if not nil.
+func (s *rcloneServer) call(method string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(s.url+"/"+method, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var reply struct {
+			Error string `json:"error"`
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(data, &reply) == nil && reply.Error != "" {
+			return fmt.Errorf("rclone %s: %s", method, reply.Error)
+		}
+		return fmt.Errorf("rclone %s: unexpected status %s", method, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// open reads the file at path below the remote fs, such as gdrive:Photos.
+func (s *rcloneServer) open(fs, path string) (io.ReadCloser, error) {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	resp, err := http.Get(s.url + "/" + url.PathEscape("["+fs+"]") + "/" + strings.Join(segments, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("reading %s from rclone: unexpected status %s", path, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// rcloneItem is a file of the listing of a remote.
+type rcloneItem struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Hashes  map[string]string
+}
+
+// remoteHash returns the hash of scanHash and the digests of extraHashes
+// from the hashes the remote keeps of item, such as the MD5 of S3 objects
+// that were not uploaded in parts or the SHA-1 of OneDrive. ok is false if
+// the remote does not have all of them, and the file is to be read.
+func remoteHash(item rcloneItem) (hash string, digests map[string]string, ok bool) {
+	sum, err := hex.DecodeString(item.Hashes[scanHash.name])
+	if err != nil || len(sum) == 0 {
+		return "", nil, false
+	}
+	for _, extra := range extraHashes {
+		digest := item.Hashes[extra.name]
+		if digest == "" {
+			return "", nil, false
+		}
+		if digests == nil {
+			digests = make(map[string]string)
+		}
+		digests[extra.name] = strings.ToLower(digest)
+	}
+	return scanHash.format(sum), digests, true
+}
+
+// checkRemoteUnchanged checks that the file at path on its remote still has
+// size and hash, since it may have been deleted or replaced after the listing.
+// The hash the remote keeps is compared, or, if it has none, the file is read.
+func checkRemoteUnchanged(path string, size int64, hash string) error {
+	server, err := rcloneClient()
+	if err != nil {
+		return err
+	}
+	fs := strings.TrimPrefix(path, rclonePrefix)
+	remote := ""
+	if colon := strings.IndexByte(fs, ':'); colon >= 0 {
+		fs, remote = fs[:colon+1], fs[colon+1:]
+	}
+	var reply struct {
+		Item *rcloneItem `json:"item"`
+	}
+	opt := map[string]interface{}{"showHash": true}
+	if err := server.call("operations/stat", map[string]interface{}{"fs": fs, "remote": remote, "opt": opt}, &reply); err != nil {
+		return err
+	}
+	if reply.Item == nil {
+		return errors.New("it is gone from the remote")
+	}
+	if reply.Item.Size != size {
+		return fmt.Errorf("size changed from %d to %d", size, reply.Item.Size)
+	}
+	algorithm := hashAlgorithmOf(hash)
+	got := ""
+	if sum, err := hex.DecodeString(reply.Item.Hashes[algorithm.name]); err == nil && len(sum) > 0 {
+		got = algorithm.format(sum)
+	} else {
+		var file File
+		if err := hashRemoteFile(server, fs, remote, &file, algorithm, nil); err != nil {
+			return err
+		}
+		got = file.Hash
+	}
+	if got != hash {
+		return errors.New("content changed")
+	}
+	return nil
+}
+
+// scanRemote scans the rclone remote root like scanFolder. The files are
+// listed with the hashes the remote keeps of them; only the files whose
+// remote lacks the hash of --hash are read, one after the other.
+func scanRemote(root string, onProgress func(scanProgress), control *scanControl) (map[string][]File, scanProgress, error) {
+	fileMap := make(map[string][]File)
+	progress := scanProgress{Workers: 1}
+	server, err := rcloneClient()
+	if err != nil {
+		return fileMap, progress, err
+	}
+	fs := strings.TrimPrefix(root, rclonePrefix)
+	var list struct {
+		List []rcloneItem `json:"list"`
+	}
+	opt := map[string]interface{}{"recurse": true, "filesOnly": true, "showHash": true}
+	if err := server.call("operations/list", map[string]interface{}{"fs": fs, "remote": "", "opt": opt}, &list); err != nil {
+		return fileMap, progress, err
+	}
+	for _, item := range list.List {
+		progress.Files++
+		progress.FoundSize += item.Size
+	}
+	prefix := root
+	if !strings.HasSuffix(prefix, ":") && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	for _, item := range list.List {
+		if !control.wait() {
+			return fileMap, progress, errScanCanceled
+		}
+		path := prefix + item.Path
+		file := File{path: indexPath(path), Size: item.Size, ModTime: item.ModTime}
+		var ok bool
+		if file.Hash, file.Digests, ok = remoteHash(item); !ok {
+			if err := hashRemoteFile(server, fs, item.Path, &file, scanHash, extraHashes); err != nil {
+				log.Printf("Error processing %s: %v", path, err)
+				progress.Errors++
+				progress.Failures = append(progress.Failures, HashError{Path: path, Err: err})
+				continue
+			}
+		}
+		addScannedFile(fileMap, file)
+		progress.Scanned++
+		progress.TotalSize += file.Size
+		control.update(progress)
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+	return fileMap, progress, nil
+}
+
+// hashRemoteFile reads the file at path below the remote fs to set the hash
+// of algorithm and the digests of extra of file.
+func hashRemoteFile(server *rcloneServer, fs, path string, file *File, algorithm hashAlgorithm, extra []hashAlgorithm) error {
+	openLimiter.wait(1)
+	body, err := server.open(fs, path)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	hash := newMultiHash(algorithm, extra)
+	var r io.Reader = body
+	if readLimiter != nil {
+		r = limitedReader{r, readLimiter}
+	}
+	if err := copyChunks(hash, r); err != nil {
+		return err
+	}
+	file.Hash, file.Digests = hash.sum(), hash.digests()
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScanRemote(t *testing.T) {
+	content := "remote content"
+	listed := md5.Sum([]byte("listed"))
+	var gotFS string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/operations/list", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			FS string `json:"fs"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		gotFS = req.FS
+		writeJSON(w, map[string]interface{}{"list": []map[string]interface{}{
+			{"Path": "a.txt", "Size": 6, "ModTime": "2024-05-01T10:00:00Z", "Hashes": map[string]string{"md5": hex.EncodeToString(listed[:])}},
+			{"Path": "sub/b c.txt", "Size": len(content), "ModTime": "2024-05-01T10:00:00Z"},
+		}})
+	})
+	mux.HandleFunc("/[remote:dir]/sub/b c.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer func(rc string) { rcloneRC = rc }(rcloneRC)
+	rcloneRC = server.URL
+	defer stopRclone()
+
+	fileMap, progress, err := scanRemote("rclone:remote:dir", nil, newScanControl())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotFS != "remote:dir" {
+		t.Errorf("Expected: remote:dir, Got: %s", gotFS)
+	}
+	if progress.Scanned != 2 || progress.Files != 2 {
+		t.Errorf("Unexpected progress: %+v", progress)
+	}
+	read := md5.Sum([]byte(content))
+	expected := map[string]string{
+		hex.EncodeToString(listed[:]): "rclone:remote:dir/a.txt",
+		hex.EncodeToString(read[:]):   "rclone:remote:dir/sub/b c.txt",
+	}
+	for hash, path := range expected {
+		if files := fileMap[hash]; len(files) != 1 || files[0].Path() != path {
+			t.Errorf("Expected: %s for %s, Got: %v", path, hash, files)
+		}
+	}
+	if modTime := fileMap[hex.EncodeToString(listed[:])][0].ModTime; !modTime.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected modification time: %v", modTime)
+	}
+}
+
+func TestRemoteHash(t *testing.T) {
+	defer func(a hashAlgorithm, extra []hashAlgorithm) { scanHash, extraHashes = a, extra }(scanHash, extraHashes)
+	var err error
+	if scanHash, extraHashes, err = parseHashList("sha1,sha256"); err != nil {
+		t.Fatal(err)
+	}
+	item := rcloneItem{Hashes: map[string]string{"sha1": "0a0b", "md5": "ffff"}}
+	if _, _, ok := remoteHash(item); ok {
+		t.Errorf("Expected a remote without SHA-256 to need reading")
+	}
+	item.Hashes["sha256"] = "ABCD"
+	if hash, digests, ok := remoteHash(item); !ok || hash != "sha1:0a0b" || digests["sha256"] != "abcd" {
+		t.Errorf("Unexpected hash: %s, %v, %v", hash, digests, ok)
+	}
+}
+
+func TestRemoteReference(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	local := filepath.Join(tempDir, "a.jpg")
+	ioutil.WriteFile(local, []byte("photo"), 0644)
+	info, _ := os.Stat(local)
+
+	reference, err := newKeepPolicy("first", []string{"rclone:gdrive:Photos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reference.protected("rclone:gdrive:Photos/2023/a.jpg") || reference.protected(local) || reference.protected("rclone:gdrive:Other/a.jpg") {
+		t.Errorf("Unexpected protection of %v", reference.protect)
+	}
+	sum := md5.Sum([]byte("photo"))
+	var stat struct {
+		FS     string `json:"fs"`
+		Remote string `json:"remote"`
+	}
+	item := map[string]interface{}{"Path": "Photos/2023/a.jpg", "Size": 5, "Hashes": map[string]string{"md5": hex.EncodeToString(sum[:])}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/operations/stat", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&stat)
+		writeJSON(w, map[string]interface{}{"item": item})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer func(rc string) { rcloneRC = rc }(rcloneRC)
+	rcloneRC = server.URL
+	defer stopRclone()
+
+	kept := File{path: indexPath("rclone:gdrive:Photos/2023/a.jpg"), Hash: hex.EncodeToString(sum[:]), Size: 5}
+	if err := changedSinceScan(kept, File{path: indexPath(local), Size: 5, ModTime: info.ModTime()}); err != nil {
+		t.Errorf("Expected an unchanged remote kept copy, Got: %v", err)
+	}
+	if stat.FS != "gdrive:" || stat.Remote != "Photos/2023/a.jpg" {
+		t.Errorf("Unexpected stat of %s %s", stat.FS, stat.Remote)
+	}
+	item["Size"] = 6
+	if err := changedSinceScan(kept, File{path: indexPath(local), Size: 5, ModTime: info.ModTime()}); err == nil {
+		t.Errorf("Expected a remote kept copy of another size to be detected")
+	}
+	item["Size"], item["Hashes"] = 5, map[string]string{"md5": "00ff"}
+	if err := changedSinceScan(kept, File{path: indexPath(local), Size: 5, ModTime: info.ModTime()}); err == nil {
+		t.Errorf("Expected a replaced remote kept copy to be detected")
+	}
+	item = nil
+	if err := changedSinceScan(kept, File{path: indexPath(local), Size: 5, ModTime: info.ModTime()}); err == nil {
+		t.Errorf("Expected a deleted remote kept copy to be detected")
+	}
+	item = map[string]interface{}{"Path": "Photos/2023/a.jpg", "Size": 5, "Hashes": map[string]string{"md5": hex.EncodeToString(sum[:])}}
+	ioutil.WriteFile(local, []byte("edit!"), 0644)
+	os.Chtimes(local, info.ModTime().Add(time.Minute), info.ModTime().Add(time.Minute))
+	if err := changedSinceScan(kept, File{path: indexPath(local), Size: 5, ModTime: info.ModTime()}); err == nil {
+		t.Errorf("Expected a local duplicate edited after the scan to be detected")
+	}
+	if root := make(gitCheckouts).root(kept.Path()); root != "" {
+		t.Errorf("Expected a remote file to be outside of checkouts, Got: %s", root)
+	}
+}
+
+func TestRemoteRoot(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	sum := md5.Sum([]byte("photo"))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/operations/list", func(w http.ResponseWriter, r *http.Request) {
+		hashes := map[string]string{"md5": hex.EncodeToString(sum[:])}
+		writeJSON(w, map[string]interface{}{"list": []map[string]interface{}{
+			{"Path": "2023/a.jpg", "Size": 5, "ModTime": "2024-05-01T10:00:00Z", "Hashes": hashes},
+			{"Path": "backup/a.jpg", "Size": 5, "ModTime": "2024-05-01T10:00:00Z", "Hashes": hashes},
+		}})
+	})
+	mux.HandleFunc("/operations/stat", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"item": map[string]interface{}{"Size": 5, "Hashes": map[string]string{"md5": hex.EncodeToString(sum[:])}}})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	defer func(rc string) { rcloneRC = rc }(rcloneRC)
+	rcloneRC = server.URL
+	defer stopRclone()
+
+	root := "rclone:gdrive:Photos"
+	roots, err := rootsWithReferences(root, keepPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileMap, _, err := scanRoots(roots, nil, newScanControl())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reference, err := newKeepPolicy("first", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileMap = referenceFileMap(fileMap, reference)
+	groups := duplicateGroups(fileMap)
+	if len(groups) != 1 || len(groups[0].Files) != 2 {
+		t.Fatalf("Expected one group of the two remote copies, Got: %+v", groups)
+	}
+
+	if stats := deleteFiles(fileMap, true); stats != (actionStats{Remote: 1}) {
+		t.Errorf("Unexpected delete stats: %+v", stats)
+	}
+	if stats := moveFiles(fileMap, tempDir); stats != (actionStats{Remote: 1}) {
+		t.Errorf("Unexpected move stats: %+v", stats)
+	}
+	if stats := applyPlan(makePlan(groups, reference, ""), false); stats != (actionStats{Remote: 1}) {
+		t.Errorf("Unexpected apply stats: %+v", stats)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// readOnly, set by --read-only, disables everything that can change the
+// scanned files: moving, deleting and linking duplicates, sharing their data,
+// restoring and purging moved files, action plugins, --exec-per-group and the
+// cleanup of partial copies. The scan and the apply, reflink, restore and
+// purge commands accept it. The checks sit in the
+// functions that touch the files, not only in the menu, so no path around
+// them is left. Results, reports and the state directory are still written.
+var readOnly bool
+
+// errReadOnly is returned by every action while --read-only is set.
+var errReadOnly = errors.New("disabled by --read-only")
+
+// checkWritable returns an error for an action that would change path while
+// --read-only is set.
+func checkWritable(action, path string) error {
+	if readOnly {
+		return fmt.Errorf("cannot %s %s: %w", action, path, errReadOnly)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	files := []File{}
+	for _, name := range []string{"keep", "dup1", "dup2"} {
+		path := filepath.Join(tempDir, name)
+		ioutil.WriteFile(path, []byte("content"), 0644)
+		info, _ := os.Stat(path)
+		files = append(files, File{path: indexPath(path), Size: 7, ModTime: info.ModTime()})
+	}
+	archive := filepath.Join(tempDir, "archive")
+	os.Mkdir(archive, 0755)
+	fileMap := map[string][]File{"hash": files}
+
+	readOnly = true
+	defer func() { readOnly = false }()
+	if err := moveFile(files[1].Path(), filepath.Join(archive, "dup1")); !errors.Is(err, errReadOnly) {
+		t.Errorf("Expected: %v, Got: %v", errReadOnly, err)
+	}
+	if err := copyFile(files[1].Path(), filepath.Join(archive, "dup1")); !errors.Is(err, errReadOnly) {
+		t.Errorf("Expected: %v, Got: %v", errReadOnly, err)
+	}
+	if err := runGroupCommand("rm {dups...}", Group{ID: "hash", Files: files}); !errors.Is(err, errReadOnly) {
+		t.Errorf("Expected: %v, Got: %v", errReadOnly, err)
+	}
+	if stats := moveFiles(fileMap, archive); stats.Files != 0 || stats.Errors != 2 {
+		t.Errorf("Expected both moves to fail, Got: %+v", stats)
+	}
+	if stats := deleteFiles(fileMap, true); stats.Files != 0 || stats.Errors != 2 {
+		t.Errorf("Expected both deletions to fail, Got: %+v", stats)
+	}
+	for _, file := range files {
+		if _, err := os.Stat(file.Path()); err != nil {
+			t.Errorf("Expected %s to be left alone, Got: %v", file.Path(), err)
+		}
+	}
+	if err := restoreFile(movedFile{Original: files[1].Path(), Current: files[2].Path()}); !errors.Is(err, errReadOnly) {
+		t.Errorf("Expected: %v, Got: %v", errReadOnly, err)
+	}
+	if stats, _ := applyDedupe([]cowCandidate{{Keep: files[0].Path(), Dup: files[1].Path(), Size: 7, FS: "btrfs"}}); stats.Files != 0 || stats.Errors != 1 {
+		t.Errorf("Expected the dedupe to fail, Got: %+v", stats)
+	}
+	if entries, _ := ioutil.ReadDir(archive); len(entries) != 0 {
+		t.Errorf("Expected nothing in %s, Got: %d files", archive, len(entries))
+	}
+}
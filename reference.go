@@ -0,0 +1,57 @@
+package main
+
+// rootsWithReferences returns the folders a scan of root covers: root and the
+// reference folders (--reference) that are not below it or below another
+// reference folder, so that copies of reference files elsewhere are found and
+// no file is scanned twice.
+func rootsWithReferences(root string, reference keepPolicy) ([]string, error) {
+	covered, err := newKeepPolicy("first", []string{root})
+	if err != nil {
+		return nil, err
+	}
+	roots := []string{root}
+	for _, dir := range reference.protect {
+		if covered.protected(dir) {
+			continue
+		}
+		// A reference folder listed before one that contains it is dropped
+		// in favour of the outer one.
+		outer := keepPolicy{protect: []string{dir}}
+		kept := roots[:1]
+		for _, other := range roots[1:] {
+			if !outer.protected(other) {
+				kept = append(kept, other)
+			}
+		}
+		roots = append(kept, dir)
+		covered.protect = append([]string{covered.protect[0]}, roots[1:]...)
+	}
+	return roots, nil
+}
+
+// referenceFileMap makes a file below a reference folder the kept copy of
+// every group that has one. Further reference copies are left out of the
+// group, so that only copies outside the reference folders are ever listed
+// as removable; groups of reference files only are no duplicates anymore.
+func referenceFileMap(fileMap map[string][]File, reference keepPolicy) map[string][]File {
+	if len(reference.protect) == 0 {
+		return fileMap
+	}
+	referenced := make(map[string][]File, len(fileMap))
+	for key, files := range fileMap {
+		var kept *File
+		var others []File
+		for i, file := range files {
+			if !reference.protected(file.Path()) {
+				others = append(others, file)
+			} else if kept == nil {
+				kept = &files[i]
+			}
+		}
+		if kept != nil {
+			others = append([]File{*kept}, others...)
+		}
+		referenced[key] = others
+	}
+	return referenced
+}
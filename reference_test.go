@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReferenceFileMap(t *testing.T) {
+	reference, err := newKeepPolicy("first", []string{filepath.FromSlash("/originals")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := func(path string) File { return File{path: indexPath(filepath.FromSlash(path))} }
+	fileMap := map[string][]File{
+		"a": {file("/copies/a"), file("/originals/a"), file("/more/a"), file("/originals/sub/a")},
+		"b": {file("/copies/b"), file("/more/b")},
+		"c": {file("/originals/c"), file("/originals/sub/c")},
+	}
+
+	referenced := referenceFileMap(fileMap, reference)
+	expected := map[string][]string{
+		"a": {"/originals/a", "/copies/a", "/more/a"},
+		"b": {"/copies/b", "/more/b"},
+		"c": {"/originals/c"},
+	}
+	for key, paths := range expected {
+		files := referenced[key]
+		if len(files) != len(paths) {
+			t.Errorf("%s: Expected: %v, Got: %v", key, paths, files)
+			continue
+		}
+		for i, path := range paths {
+			if files[i].Path() != filepath.FromSlash(path) {
+				t.Errorf("%s: Expected: %v, Got: %v", key, paths, files)
+				break
+			}
+		}
+	}
+}
+
+func TestRootsWithReferences(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	root := filepath.Join(tempDir, "root")
+	inside := filepath.Join(root, "inside")
+	outside := filepath.Join(tempDir, "originals")
+	for _, dir := range []string{inside, outside} {
+		os.MkdirAll(dir, 0755)
+	}
+	ioutil.WriteFile(filepath.Join(inside, "a.txt"), []byte("same"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "b.txt"), []byte("same"), 0644)
+	ioutil.WriteFile(filepath.Join(outside, "c.txt"), []byte("same"), 0644)
+
+	reference, _ := newKeepPolicy("first", []string{inside, outside})
+	roots, err := rootsWithReferences(root, reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 2 || roots[0] != root || roots[1] != reference.protect[1] {
+		t.Fatalf("Expected the root and the outside reference folder, Got: %v", roots)
+	}
+	fileMap, progress, err := scanRoots(roots, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Scanned != 3 {
+		t.Errorf("Expected 3 scanned files, Got: %d", progress.Scanned)
+	}
+	groups := duplicateGroups(referenceFileMap(fileMap, reference))
+	if len(groups) != 1 || len(groups[0].Files) != 2 || !reference.protected(groups[0].Files[0].Path()) || groups[0].Files[1].Path() != filepath.Join(root, "b.txt") {
+		t.Errorf("Expected a reference copy and b.txt, Got: %+v", groups)
+	}
+}
+
+func TestRootsWithNestedReferences(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	root := filepath.Join(tempDir, "root")
+	outer := filepath.Join(tempDir, "originals")
+	nested := filepath.Join(outer, "2023")
+	for _, dir := range []string{root, nested} {
+		os.MkdirAll(dir, 0755)
+	}
+	ioutil.WriteFile(filepath.Join(root, "a.txt"), []byte("same"), 0644)
+	ioutil.WriteFile(filepath.Join(nested, "b.txt"), []byte("same"), 0644)
+
+	reference, _ := newKeepPolicy("first", []string{nested, outer, outer})
+	roots, err := rootsWithReferences(root, reference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 2 || roots[0] != root || roots[1] != reference.protect[1] {
+		t.Fatalf("Expected the root and the outer reference folder, Got: %v", roots)
+	}
+	fileMap, progress, err := scanRoots(roots, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Scanned != 2 {
+		t.Errorf("Expected 2 scanned files, Got: %d", progress.Scanned)
+	}
+	groups := duplicateGroups(referenceFileMap(fileMap, reference))
+	if len(groups) != 1 || len(groups[0].Files) != 2 {
+		t.Errorf("Expected a reference copy and a.txt, Got: %+v", groups)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// reportMinSize, set by --report-min-size, leaves the duplicate groups that
+// waste less than this out of the listings of the text and HTML reports;
+// their summary and statistics still count them.
+var reportMinSize int64
+
+// reportedGroups returns the groups that waste at least reportMinSize, and
+// the number and waste of the others.
+func reportedGroups(groups []Group) (reported []Group, omitted int, omittedWaste int64) {
+	for _, group := range groups {
+		if group.Waste() < reportMinSize {
+			omitted++
+			omittedWaste += group.Waste()
+			continue
+		}
+		reported = append(reported, group)
+	}
+	return reported, omitted, omittedWaste
+}
+
+// writeGroups writes every duplicate group with its files, kept file first.
+func writeGroups(w io.Writer, fileMap map[string][]File) {
+	writeGroupList(w, copiesFirst(duplicateGroups(fileMap)))
+}
+
+func writeGroupList(w io.Writer, groups []Group) {
+	writeScoredGroupList(w, groups, keepPolicy{})
+}
+
+// writeScoredGroupList writes groups like writeGroupList, with the score of
+// every file next to it if policy keeps the file with the highest score.
+func writeScoredGroupList(w io.Writer, groups []Group, policy keepPolicy) {
+	for _, group := range groups {
+		fmt.Fprintln(w, msg("list.group", group.ID, group.Hash))
+		scores := policy.scores(group.Files)
+		for i, file := range group.Files {
+			if scores != nil {
+				fmt.Fprintln(w, file.Path()+"  "+msg("simulate.score", formatDecimal(scores[i], 2)))
+				continue
+			}
+			fmt.Fprintln(w, file.Path())
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// writeSummary writes the headline numbers of a scan or cleanup.
+func writeSummary(w io.Writer, summary runSummary) {
+	fmt.Fprintln(w, msg("summary.root", summary.Root))
+	fmt.Fprintln(w, msg("summary.scanned", formatCount(int64(summary.FilesScanned))))
+	fmt.Fprintln(w, msg("summary.duplicates", formatCount(int64(summary.DuplicateGroups)), formatCount(int64(summary.DuplicateFiles))))
+	fmt.Fprintln(w, msg("summary.reclaimable", humanReadableSize(summary.ReclaimableBytes)))
+	if summary.Event == "cleanup" {
+		fmt.Fprintln(w, msg("summary.cleanup", summary.Action, formatCount(int64(summary.FilesProcessed)), humanReadableSize(summary.BytesReclaimed)))
+	}
+	fmt.Fprintln(w, msg("summary.errors", formatCount(int64(summary.Errors))))
+	fmt.Fprintln(w)
+}
+
+// writeTextReport writes the complete plain text report of a scan.
+func writeTextReport(w io.Writer, summary runSummary, fileMap map[string][]File, roots []string, depth int) {
+	writeSummary(w, summary)
+	printExtensionStats(w, fileMap)
+	printDirectoryStats(w, fileMap, roots, depth)
+	groups, omitted, omittedWaste := reportedGroups(copiesFirst(duplicateGroups(fileMap)))
+	writeGroupList(w, groups)
+	if omitted > 0 {
+		fmt.Fprintln(w, msg("report.omitted", formatCount(int64(omitted)), humanReadableSize(omittedWaste), humanReadableSize(reportMinSize)))
+	}
+}
+
+// wasteStat aggregates the redundant copies that fall under one report key.
+type wasteStat struct {
+	Key   string
+	Files int
+	Bytes int64
+}
+
+// wasteBy groups the redundant copies of every duplicate group by key(file)
+// and returns the stats sorted by wasted bytes, largest first. The first file
+// of each group is the one that is kept and does not count as waste.
+func wasteBy(fileMap map[string][]File, key func(File) string) ([]wasteStat, int64) {
+	byKey := make(map[string]*wasteStat)
+	var total int64
+	for _, files := range fileMap {
+		if len(files) < 2 {
+			continue
+		}
+		for _, file := range files[1:] {
+			k := key(file)
+			stat, ok := byKey[k]
+			if !ok {
+				stat = &wasteStat{Key: k}
+				byKey[k] = stat
+			}
+			stat.Files++
+			stat.Bytes += file.Size
+			total += file.Size
+		}
+	}
+
+	stats := make([]wasteStat, 0, len(byKey))
+	for _, stat := range byKey {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	return stats, total
+}
+
+func fileExtension(file File) string {
+	ext := strings.ToLower(filepath.Ext(file.Path()))
+	if ext == "" {
+		return msg("report.no_extension")
+	}
+	return ext
+}
+
+// printWasteStats writes a report section with one line per key showing the
+// number of redundant files, their size and their share of the total waste.
+func printWasteStats(w io.Writer, title string, stats []wasteStat, total int64) {
+	if len(stats) == 0 {
+		return
+	}
+	fmt.Fprintln(w, title)
+	for _, stat := range stats {
+		share := 0.0
+		if total > 0 {
+			share = float64(stat.Bytes) * 100 / float64(total)
+		}
+		fmt.Fprintf(w, "  %-24s %12s %8s%% (%s)\n", stat.Key, humanReadableSize(stat.Bytes), formatDecimal(share, 1), msg("report.files", formatCount(int64(stat.Files))))
+	}
+	fmt.Fprintln(w)
+}
+
+// directoryKey returns the directory of file relative to the first of roots
+// that contains it, truncated to at most depth path components, or to any
+// depth if depth is 0. Files directly inside the scan root, roots[0], map to
+// "."; the directories of further roots, such as reference folders, are
+// prefixed with the root.
+func directoryKey(roots []string, depth int) func(File) string {
+	return func(file File) string {
+		dir := filepath.Dir(file.Path())
+		for i, root := range roots {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if depth > 0 && len(parts) > depth {
+				parts = parts[:depth]
+			}
+			key := strings.Join(parts, "/")
+			if i > 0 {
+				key = path.Join(filepath.ToSlash(root), key)
+			}
+			return key
+		}
+		return filepath.ToSlash(dir)
+	}
+}
+
+func printExtensionStats(w io.Writer, fileMap map[string][]File) {
+	stats, total := wasteBy(fileMap, fileExtension)
+	printWasteStats(w, msg("report.by_extension"), stats, total)
+}
+
+func printDirectoryStats(w io.Writer, fileMap map[string][]File, roots []string, depth int) {
+	stats, total := wasteBy(fileMap, directoryKey(roots, depth))
+	printWasteStats(w, msg("report.by_directory"), stats, total)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWasteByExtension(t *testing.T) {
+	fileMap := map[string][]File{
+		"a": {{path: indexPath("/k/a.jpg"), Size: 100}, {path: indexPath("/x/a.JPG"), Size: 100}, {path: indexPath("/y/a.jpg"), Size: 100}},
+		"b": {{path: indexPath("/k/b.mp4"), Size: 50}, {path: indexPath("/x/b.mp4"), Size: 50}},
+		"c": {{path: indexPath("/k/README"), Size: 10}, {path: indexPath("/x/README"), Size: 10}},
+		"d": {{path: indexPath("/k/unique.txt"), Size: 1000}},
+	}
+
+	stats, total := wasteBy(fileMap, fileExtension)
+	if total != 260 {
+		t.Errorf("Expected total: 260, Got: %d", total)
+	}
+	expected := []wasteStat{
+		{Key: ".jpg", Files: 2, Bytes: 200},
+		{Key: ".mp4", Files: 1, Bytes: 50},
+		{Key: "(no extension)", Files: 1, Bytes: 10},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected %d stats, Got: %v", len(expected), stats)
+	}
+	for i := range expected {
+		if stats[i] != expected[i] {
+			t.Errorf("Expected: %v, Got: %v", expected[i], stats[i])
+		}
+	}
+}
+
+func TestPrintExtensionStats(t *testing.T) {
+	fileMap := map[string][]File{
+		"a": {{path: indexPath("a.jpg"), Size: 300}, {path: indexPath("b.jpg"), Size: 300}},
+		"b": {{path: indexPath("a.mp4"), Size: 100}, {path: indexPath("b.mp4"), Size: 100}},
+	}
+
+	var buf bytes.Buffer
+	printExtensionStats(&buf, fileMap)
+	out := buf.String()
+	if !strings.Contains(out, "Wasted space by extension:") || !strings.Contains(out, "75.0%") || !strings.Contains(out, "25.0%") {
+		t.Errorf("Unexpected report: %s", out)
+	}
+
+	buf.Reset()
+	printExtensionStats(&buf, map[string][]File{"a": {{path: indexPath("a.jpg"), Size: 1}}})
+	if buf.Len() != 0 {
+		t.Errorf("Expected empty section without duplicates, Got: %s", buf.String())
+	}
+}
+
+func TestDirectoryKey(t *testing.T) {
+	testCases := []struct {
+		path     string
+		depth    int
+		expected string
+	}{
+		{"/scan/photos/2020/a.jpg", 1, "photos"},
+		{"/scan/photos/2020/a.jpg", 2, "photos/2020"},
+		{"/scan/photos/2020/a.jpg", 0, "photos/2020"},
+		{"/scan/a.jpg", 1, "."},
+		{"/ref/photos/2020/a.jpg", 1, "/ref/photos"},
+		{"/ref/a.jpg", 1, "/ref"},
+		{"/elsewhere/a.jpg", 1, "/elsewhere"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			result := directoryKey([]string{"/scan", "/ref"}, tc.depth)(File{path: indexPath(tc.path)})
+			if result != tc.expected {
+				t.Errorf("Expected: %s, Got: %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestWriteScoredGroupList(t *testing.T) {
+	weights, err := parseKeepWeights("depth=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := []Group{{ID: "g1", Hash: "h", Files: []File{{path: indexPath("/a/b/x.txt")}, {path: indexPath("/x.txt")}}}}
+	var out bytes.Buffer
+	writeScoredGroupList(&out, groups, keepPolicy{weights: weights})
+	if !strings.Contains(out.String(), "/x.txt  (score 1.00)") || !strings.Contains(out.String(), "/a/b/x.txt  (score 0.00)") {
+		t.Errorf("Expected the scores next to the files, Got: %s", out.String())
+	}
+	out.Reset()
+	writeGroupList(&out, groups)
+	if strings.Contains(out.String(), "score") {
+		t.Errorf("Expected no scores without a scoring policy, Got: %s", out.String())
+	}
+}
+
+func TestReportMinSize(t *testing.T) {
+	defer func() { reportMinSize = 0 }()
+	reportMinSize = 100
+	fileMap := map[string][]File{
+		"big":   {{path: indexPath("/k/big.iso"), Hash: "big", Size: 500}, {path: indexPath("/x/big.iso"), Hash: "big", Size: 500}},
+		"small": {{path: indexPath("/k/a.txt"), Hash: "small", Size: 10}, {path: indexPath("/x/a.txt"), Hash: "small", Size: 10}},
+		"tiny":  {{path: indexPath("/k/b.txt"), Hash: "tiny", Size: 2}, {path: indexPath("/x/b.txt"), Hash: "tiny", Size: 2}, {path: indexPath("/y/b.txt"), Hash: "tiny", Size: 2}},
+	}
+	summary := summarize("/", 7, fileMap, 0)
+
+	var text bytes.Buffer
+	writeTextReport(&text, summary, fileMap, []string{"/"}, 1)
+	var html bytes.Buffer
+	if err := writeHTMLReport(&html, summary, fileMap, []string{"/"}, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]string{"text": text.String(), "html": html.String()} {
+		if !strings.Contains(out, "/x/big.iso") {
+			t.Errorf("%s: Expected the large group to be listed, Got: %s", name, out)
+		}
+		if strings.Contains(out, "/x/a.txt") || strings.Contains(out, "/y/b.txt") {
+			t.Errorf("%s: Expected the small groups to be left out, Got: %s", name, out)
+		}
+		if !strings.Contains(out, "2 smaller groups wasting 14.00 B in total are not listed") {
+			t.Errorf("%s: Expected a note on the omitted groups, Got: %s", name, out)
+		}
+		if !strings.Contains(out, "Duplicate groups: 3") {
+			t.Errorf("%s: Expected the summary to count every group, Got: %s", name, out)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// movedFile is a file that a move took out of its original place and that
+// has not been restored yet, as recorded by the audit log.
+type movedFile struct {
+	Original string // where the file was
+	Current  string // where the move put it
+	Hash     string
+	Keep     string // the copy that was kept, if recorded
+	Size     int64
+	Moved    time.Time // when it was moved first
+	// Lost is set if a later move put another file at Current, so the data
+	// of this one is gone.
+	Lost bool
+}
+
+// movedFiles replays the audit log and returns the files that are still
+// moved, that is neither restored nor purged, in the order they were moved.
+// A file that was moved more than once is returned with its last location.
+func movedFiles(path string) ([]movedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	byCurrent := make(map[string]int) // index in moved of the file at a path
+	var moved []movedFile
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry auditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.Result != "ok" {
+			continue
+		}
+		i, seen := byCurrent[entry.Path]
+		if j, held := byCurrent[entry.To]; entry.Action == "move" && held && (!seen || j != i) {
+			log.Printf("Warning: %s, moved from %s, was overwritten by the move of %s", entry.To, moved[j].Original, entry.Path)
+			moved[j].Lost = true
+		}
+		switch {
+		case entry.Action == "move" && seen: // moved on from where it was moved to
+			delete(byCurrent, entry.Path)
+			moved[i].Current = entry.To
+			byCurrent[entry.To] = i
+		case entry.Action == "move":
+			byCurrent[entry.To] = len(moved)
+			moved = append(moved, movedFile{Original: entry.Path, Current: entry.To, Hash: entry.Hash, Keep: entry.Keep, Size: entry.Size, Moved: entry.Time})
+		case (entry.Action == "restore" || entry.Action == "purge") && seen:
+			delete(byCurrent, entry.Path)
+			moved[i].Current = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	var left []movedFile
+	for _, m := range moved {
+		if m.Current != "" {
+			left = append(left, m)
+		}
+	}
+	return left, nil
+}
+
+// matches reports whether a moved file is selected by a group ID, by its
+// original or current path, or by a folder containing either.
+func (m movedFile) matches(selector string) bool {
+	id := selector
+	if dash := strings.IndexByte(id, '-'); dash >= 0 {
+		id = id[:dash]
+	}
+	if m.Hash != "" && groupID(m.Hash) == id {
+		return true
+	}
+	abs, err := filepath.Abs(selector)
+	if err != nil {
+		return false
+	}
+	below := keepPolicy{protect: []string{abs}}
+	return below.protected(m.Original) || below.protected(m.Current)
+}
+
+// restoreFile moves a moved file back to its original place, creating its
+// folder if needed. A file that is now at the original place is only
+// replaced if it is older than the moved file.
+func restoreFile(m movedFile) error {
+	if m.Lost {
+		return fmt.Errorf("%s was overwritten by a later move", m.Current)
+	}
+	if err := checkWritable("restore", m.Current); err != nil {
+		return err
+	}
+	info, err := os.Stat(m.Current)
+	if err != nil {
+		return err
+	}
+	if existing, err := os.Stat(m.Original); err == nil {
+		if existing.ModTime().After(info.ModTime()) {
+			return fmt.Errorf("%s is newer than the moved file, not overwriting it", m.Original)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(m.Original), 0755); err != nil {
+		return err
+	}
+	return withFileLock(m.Current, func() error { return moveFile(m.Current, m.Original) })
+}
+
+// runRestore implements the restore command, which puts files moved by
+// earlier runs back where they were found.
+func runRestore(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	auditPath := fs.String("audit-log", "", "audit log that recorded the moves (default: audit.jsonl in the state directory)")
+	dryRun := fs.Bool("dry-run", false, "only list the files that would be restored")
+	fs.BoolVar(&readOnly, "read-only", false, "never move files back; only list them")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s restore [flags] group-id|path...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	var err error
+	if audit, err = openAuditLog(*auditPath); err != nil {
+		log.Fatal("Error:", err)
+	}
+	moved, err := movedFiles(audit.path)
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	failed := false
+	restored := make(map[int]bool) // by several selectors
+	for _, selector := range fs.Args() {
+		found := false
+		for i, m := range moved {
+			if !m.matches(selector) {
+				continue
+			}
+			found = true
+			if restored[i] {
+				continue
+			}
+			restored[i] = true
+			if *dryRun {
+				fmt.Println(msg("restore.would", m.Current, m.Original))
+				continue
+			}
+			err := restoreFile(m)
+			audit.record(auditEntry{Action: "restore", Hash: m.Hash, Path: m.Current, To: m.Original}, err)
+			if err != nil {
+				log.Printf("Error restoring %s: %v", m.Original, err)
+				failed = true
+				continue
+			}
+			fmt.Println(msg("restore.done", m.Current, m.Original))
+		}
+		if !found {
+			fmt.Println(msg("restore.none", selector))
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestore(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	var files []File
+	for _, name := range []string{"keep", "dup", "other"} {
+		path := filepath.Join(tempDir, "photos", name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte("content"), 0644)
+		info, _ := os.Stat(path)
+		files = append(files, File{path: indexPath(path), Hash: "0123456789abcdef0123456789abcdef", Size: 7, ModTime: info.ModTime()})
+	}
+	quarantine := filepath.Join(tempDir, "quarantine")
+	os.Mkdir(quarantine, 0755)
+	path := filepath.Join(tempDir, auditFile)
+	audit, _ = openAuditLog(path)
+	defer func() { audit = nil }()
+	moveFiles(map[string][]File{"hash": files}, quarantine)
+	os.Remove(filepath.Join(tempDir, "photos", "keep"))
+	os.Remove(filepath.Join(tempDir, "photos"))
+
+	moved, err := movedFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 2 || moved[0].Original != files[1].Path() || moved[0].Current != filepath.Join(quarantine, "dup") {
+		t.Fatalf("Unexpected moved files: %+v", moved)
+	}
+	for _, selector := range []string{"0123456789ab", "0123456789ab-2", files[1].Path(), quarantine, filepath.Join(tempDir, "photos")} {
+		if !moved[0].matches(selector) {
+			t.Errorf("Expected %s to select %+v", selector, moved[0])
+		}
+	}
+	if moved[0].matches("ba9876543210") || moved[0].matches(files[0].Path()) {
+		t.Errorf("Expected %+v not to be selected", moved[0])
+	}
+
+	if err := restoreFile(moved[0]); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile(files[1].Path()); err != nil || string(data) != "content" {
+		t.Errorf("Expected %s to be restored, Got: %q, %v", files[1].Path(), data, err)
+	}
+	audit.record(auditEntry{Action: "restore", Path: moved[0].Current, To: moved[0].Original}, nil)
+
+	// A newer file at the original place is not overwritten.
+	ioutil.WriteFile(files[2].Path(), []byte("newer"), 0644)
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(files[2].Path(), future, future)
+	if err := restoreFile(moved[1]); err == nil {
+		t.Errorf("Expected the newer %s not to be overwritten", files[2].Path())
+	}
+	if moved, _ := movedFiles(path); len(moved) != 1 || moved[0].Original != files[2].Path() {
+		t.Errorf("Expected only %s to be left moved, Got: %+v", files[2].Path(), moved)
+	}
+}
+
+func TestRestoreSameName(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	fileMap := make(map[string][]File)
+	for _, group := range []string{"a", "b"} {
+		for _, dir := range []string{"keep", "dup"} {
+			path := filepath.Join(tempDir, group, dir, "photo.jpg")
+			os.MkdirAll(filepath.Dir(path), 0755)
+			ioutil.WriteFile(path, []byte(group), 0644)
+			info, _ := os.Stat(path)
+			fileMap[group] = append(fileMap[group], File{path: indexPath(path), Hash: group, Size: 1, ModTime: info.ModTime()})
+		}
+	}
+	quarantine := filepath.Join(tempDir, "quarantine")
+	os.Mkdir(quarantine, 0755)
+	path := filepath.Join(tempDir, auditFile)
+	audit, _ = openAuditLog(path)
+	defer func() { audit = nil }()
+	if stats := moveFiles(fileMap, quarantine); stats.Files != 2 {
+		t.Fatalf("Expected both duplicates to be moved, Got: %+v", stats)
+	}
+
+	moved, err := movedFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 2 || moved[0].Current == moved[1].Current || moved[0].Lost || moved[1].Lost {
+		t.Fatalf("Expected distinct places in the quarantine, Got: %+v", moved)
+	}
+	for _, m := range moved {
+		if err := restoreFile(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, group := range []string{"a", "b"} {
+		dup := filepath.Join(tempDir, group, "dup", "photo.jpg")
+		if data, err := ioutil.ReadFile(dup); err != nil || string(data) != group {
+			t.Errorf("Expected %s to be restored with its own content, Got: %q, %v", dup, data, err)
+		}
+	}
+}
+
+func TestMovedFilesOverwritten(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, auditFile)
+	audit, _ = openAuditLog(path)
+	defer func() { audit = nil }()
+	dest := filepath.Join(tempDir, "quarantine", "photo.jpg")
+	audit.record(auditEntry{Action: "move", Hash: "a", Path: "/a/photo.jpg", To: dest, Keep: "/a/keep.jpg", Size: 1}, nil)
+	audit.record(auditEntry{Action: "move", Hash: "b", Path: "/b/photo.jpg", To: dest, Keep: "/b/keep.jpg", Size: 1}, nil)
+
+	moved, err := movedFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 2 || !moved[0].Lost || moved[1].Lost {
+		t.Fatalf("Expected the first move to be lost, Got: %+v", moved)
+	}
+	if err := restoreFile(moved[0]); err == nil {
+		t.Errorf("Expected the overwritten file not to be restored")
+	}
+	if err := purgeFile(moved[0]); err == nil {
+		t.Errorf("Expected the overwritten file not to be purged")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// resultsVersion is incremented whenever the results file format changes
+// incompatibly.
+const resultsVersion = 1
+
+// scanResults is the JSON document written by --save.
+type scanResults struct {
+	Version      int       `json:"version"`
+	Root         string    `json:"root"`
+	ScannedAt    time.Time `json:"scanned_at"`
+	FilesScanned int       `json:"files_scanned"`
+	TotalSize    int64     `json:"total_size"`
+	Groups       []Group   `json:"groups"`
+	Unique       []File    `json:"unique,omitempty"` // files without duplicates, with --save-all
+}
+
+func saveResults(path string, results scanResults) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeResults(f, results); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeResults writes results as the JSON document of --save.
+func writeResults(w io.Writer, results scanResults) error {
+	results.Version = resultsVersion
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// loadResults reads a results file written by --save.
+func loadResults(path string) (scanResults, error) {
+	var results scanResults
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return results, err
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return results, err
+	}
+	if results.Version != resultsVersion {
+		return results, fmt.Errorf("unsupported results version %d", results.Version)
+	}
+	return results, nil
+}
+
+// uniqueFiles returns the files of fileMap that have no duplicate, ordered by
+// path.
+func uniqueFiles(fileMap map[string][]File) []File {
+	var unique []File
+	for _, files := range fileMap {
+		if len(files) == 1 {
+			unique = append(unique, files[0])
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i].Path() < unique[j].Path() })
+	return unique
+}
+
+// fileMap returns the groups of the results keyed like the map returned by
+// scanFolder.
+func (r scanResults) fileMap() map[string][]File {
+	fileMap := make(map[string][]File, len(r.Groups))
+	for _, group := range r.Groups {
+		fileMap[group.ID] = group.Files
+	}
+	return fileMap
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveResults(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "results.json")
+	groups := duplicateGroups(map[string][]File{
+		"9c192053ffbc363705b13508c36566f6": {{path: indexPath("a"), Size: 1}, {path: indexPath("b"), Size: 1}},
+	})
+	if err := saveResults(path, scanResults{Root: tempDir, FilesScanned: 2, Groups: groups}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved scanResults
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Version != resultsVersion || len(saved.Groups) != 1 || saved.Groups[0].ID != "9c192053ffbc" {
+		t.Errorf("Unexpected saved results: %+v", saved)
+	}
+}
+
+func TestLoadResults(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "results.json")
+	fileMap := map[string][]File{
+		"9c192053ffbc363705b13508c36566f6": {{path: indexPath("a"), Size: 1}, {path: indexPath("b"), Size: 1}},
+		"0123456789abcdef-1":               {{path: indexPath("c"), Size: 2}, {path: indexPath("d"), Size: 2}},
+	}
+	if err := saveResults(path, scanResults{Root: tempDir, Groups: duplicateGroups(fileMap)}); err != nil {
+		t.Fatal(err)
+	}
+	results, err := loadResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := duplicateGroups(results.fileMap())
+	if len(loaded) != 2 || loaded[0].ID != "0123456789ab-1" || loaded[1].ID != "9c192053ffbc" || loaded[1].Files[1].Path() != "b" {
+		t.Errorf("Unexpected loaded groups: %+v", loaded)
+	}
+
+	ioutil.WriteFile(path, []byte(`{"version": 99}`), 0644)
+	if _, err := loadResults(path); err == nil {
+		t.Errorf("Expected an error for an unsupported version")
+	}
+}
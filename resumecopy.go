@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// partSuffix is appended to the name of a large file that is being copied
+// to another volume until the copy is complete and verified.
+const partSuffix = ".duplicate_finder-part"
+
+// resumeCheck is how much of the end of a partial copy is compared with the
+// source before the copy is resumed after it.
+const resumeCheck = 1 << 20
+
+// copyResumable copies a large file to another volume. The data goes to a
+// partial file next to dest first, so a copy that was interrupted resumes
+// where it stopped, and the partial file is only renamed to dest once it
+// compares equal to src and has the metadata of --preserve. The progress is
+// printed while the copy runs.
+func copyResumable(src, dest string, size int64) error {
+	if err := checkWritable("copy to", dest); err != nil {
+		return err
+	}
+	part := dest + partSuffix
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	offset, err := resumeOffset(in, out, size)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		log.Printf("Resuming the copy of %s to %s after %s", src, dest, humanReadableSize(offset))
+	}
+	if err := out.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := in.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := out.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	progress := &copyProgress{path: src, size: size, done: offset, shown: time.Now()}
+	_, err = io.CopyBuffer(out, io.TeeReader(in, progress), make([]byte, 1<<20))
+	progress.finish()
+	if err != nil {
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	same, err := sameContent(File{path: indexPath(src)}, File{path: indexPath(part)})
+	if err != nil {
+		return err
+	}
+	if !same {
+		os.Remove(part)
+		return fmt.Errorf("the copy %s differs from the source", part)
+	}
+	if err := preserveMetadata(src, part); err != nil {
+		return err
+	}
+	return os.Rename(part, dest)
+}
+
+// resumeOffset returns how much of a partial copy can be kept: all of it if
+// its last resumeCheck bytes match the source, otherwise nothing.
+func resumeOffset(in, out *os.File, size int64) (int64, error) {
+	info, err := out.Stat()
+	if err != nil {
+		return 0, err
+	}
+	done := info.Size()
+	if done == 0 || done > size {
+		return 0, nil
+	}
+	start := done - resumeCheck
+	if start < 0 {
+		start = 0
+	}
+	source, copied := make([]byte, done-start), make([]byte, done-start)
+	if _, err := in.ReadAt(source, start); err != nil {
+		return 0, err
+	}
+	if _, err := out.ReadAt(copied, start); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(source, copied) {
+		return 0, nil
+	}
+	return done, nil
+}
+
+// copyProgress counts the bytes of a copy and prints its progress at most
+// once a second.
+type copyProgress struct {
+	path        string
+	size, done  int64
+	shown       time.Time
+	printedLine bool
+}
+
+func (p *copyProgress) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if time.Since(p.shown) >= time.Second {
+		p.shown = time.Now()
+		percent := float64(p.done) * 100 / float64(p.size)
+		fmt.Print("\r" + msg("move.progress", p.path, humanReadableSize(p.done), humanReadableSize(p.size), formatDecimal(percent, 1)))
+		p.printedLine = true
+	}
+	return len(b), nil
+}
+
+// finish ends the progress line, if one was printed.
+func (p *copyProgress) finish() {
+	if p.printedLine {
+		fmt.Println()
+	}
+}
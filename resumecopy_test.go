@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyResumable(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	content := bytes.Repeat([]byte("0123456789abcdef"), 256<<10) // 4 MiB
+	src := filepath.Join(tempDir, "src")
+	ioutil.WriteFile(src, content, 0644)
+
+	tests := []struct {
+		name    string
+		partial []byte // left by an interrupted copy
+	}{
+		{"fresh", nil},
+		{"resumed", content[:3<<20]},
+		{"mismatch", append(append([]byte{}, content[:2<<20]...), bytes.Repeat([]byte("x"), 1<<20)...)},
+		{"too long", append(append([]byte{}, content...), 'x')},
+	}
+	for _, test := range tests {
+		dest := filepath.Join(tempDir, test.name)
+		if test.partial != nil {
+			ioutil.WriteFile(dest+partSuffix, test.partial, 0644)
+		}
+		if err := copyResumable(src, dest, int64(len(content))); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got, _ := ioutil.ReadFile(dest); !bytes.Equal(got, content) {
+			t.Errorf("%s: Expected the content of the source, Got: %d bytes", test.name, len(got))
+		}
+		if _, err := os.Stat(dest + partSuffix); !os.IsNotExist(err) {
+			t.Errorf("%s: Expected the partial file to be renamed, Got: %v", test.name, err)
+		}
+	}
+}
+
+func TestResumeOffset(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	src := filepath.Join(tempDir, "src")
+	ioutil.WriteFile(src, []byte("0123456789"), 0644)
+
+	tests := []struct {
+		partial  string
+		expected int64
+	}{
+		{"", 0},
+		{"0123", 4},
+		{"01x3", 0},
+		{"0123456789", 10},
+		{"0123456789x", 0},
+	}
+	for _, test := range tests {
+		part := filepath.Join(tempDir, "part")
+		ioutil.WriteFile(part, []byte(test.partial), 0644)
+		in, _ := os.Open(src)
+		out, _ := os.Open(part)
+		got, err := resumeOffset(in, out, 10)
+		in.Close()
+		out.Close()
+		if err != nil || got != test.expected {
+			t.Errorf("Expected: %d, Got: %d, %v for %q", test.expected, got, err, test.partial)
+		}
+	}
+}
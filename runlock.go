@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// runLockDir is the folder in the state directory that holds the locks taken
+// by runs on a scan root.
+const runLockDir = "locks"
+
+// rootKey returns a name for the state kept about root: a hash of the
+// absolute root, so different spellings of the same folder share it.
+func rootKey(root string) (string, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		abs = strings.ToLower(abs) // case-insensitive file systems
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(abs)))[:16], nil
+}
+
+// rootLockPath returns the lock file for root in the state directory dir.
+func rootLockPath(dir, root string) (string, error) {
+	key, err := rootKey(root)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, runLockDir, key+".lock"), nil
+}
+
+// rootLockedError is returned by lockRoot when another run holds the lock.
+type rootLockedError struct {
+	root, path string
+}
+
+func (e rootLockedError) Error() string {
+	return fmt.Sprintf("another run is already working on %s (lock file %s); use --force to run anyway", e.root, e.path)
+}
+
+// lockRoot takes the lock of root in dir, which is held until unlock is
+// called or the process exits, so overlapping runs such as cron jobs cannot
+// act on the same tree at once.
+func lockRoot(dir, root string) (unlock func(), err error) {
+	path, err := rootLockPath(dir, root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	unlock, err = lockFile(path)
+	if err == errFileLocked {
+		return nil, rootLockedError{root, path}
+	}
+	return unlock, err
+}
+
+// acquireRootLock takes the lock of root in the state directory and exits if
+// another run holds it. Other failures are logged and the run continues
+// without the lock.
+func acquireRootLock(root string) (unlock func()) {
+	dir, err := stateDir()
+	if err == nil {
+		if unlock, err = lockRoot(dir, root); err == nil {
+			return unlock
+		}
+		if _, locked := err.(rootLockedError); locked {
+			log.Fatal("Error:", err)
+		}
+	}
+	log.Printf("Error locking %s against concurrent runs: %v", root, err)
+	return func() {}
+}
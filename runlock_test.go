@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRootLockPath(t *testing.T) {
+	a, err := rootLockPath("state", "photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := rootLockPath("state", "./photos/")
+	c, _ := rootLockPath("state", "videos")
+	if a != b || a == c {
+		t.Errorf("Expected the same lock for both spellings of photos and another for videos, Got: %s, %s, %s", a, b, c)
+	}
+	if filepath.Dir(a) != filepath.Join("state", runLockDir) || len(filepath.Base(a)) != len("0123456789abcdef.lock") {
+		t.Errorf("Unexpected lock path: %s", a)
+	}
+}
+
+func TestLockRoot(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	unlock, err := lockRoot(tempDir, "photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockRoot(tempDir, "photos"); err == nil {
+		t.Errorf("Expected a second lock of the same root to fail")
+	} else if _, locked := err.(rootLockedError); !locked {
+		t.Errorf("Expected a rootLockedError, Got: %v", err)
+	}
+
+	other, err := lockRoot(tempDir, "videos")
+	if err != nil {
+		t.Errorf("Expected another root to be lockable, Got: %v", err)
+	} else {
+		other()
+	}
+
+	unlock()
+	unlock, err = lockRoot(tempDir, "photos")
+	if err != nil {
+		t.Errorf("Expected the released lock to be available, Got: %v", err)
+	} else {
+		unlock()
+	}
+}
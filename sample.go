@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// sampleOver makes files of at least this size be hashed from samples
+// (--sample-over); 0 hashes every file fully. A sampled hash only covers the
+// size and sampleBlocks blocks of the file, so groups of such files are
+// verified in full when an action is applied to them.
+var sampleOver int64
+
+const (
+	sampleBlock  = 1 << 20
+	sampleBlocks = 18 // the first, the last and 16 evenly spaced in between
+	// sampledPrefix marks sampled hashes, e.g. "sample:9a0364b9e99bb480...".
+	sampledPrefix = "sample:"
+)
+
+// isSampled reports whether hash was computed from samples.
+func isSampled(hash string) bool {
+	return strings.HasPrefix(hash, sampledPrefix)
+}
+
+// sampleHash hashes the size of a file and sampleBlocks blocks of it.
+func sampleHash(r io.ReaderAt, size int64, algorithm hashAlgorithm) (string, error) {
+	h := algorithm.new()
+	var sizeBytes [8]byte
+	binary.BigEndian.PutUint64(sizeBytes[:], uint64(size))
+	h.Write(sizeBytes[:])
+	buf := make([]byte, sampleBlock)
+	last := size - sampleBlock
+	if last < 0 {
+		last = 0
+	}
+	for i := int64(0); i < sampleBlocks; i++ {
+		n, err := r.ReadAt(buf, last*i/(sampleBlocks-1))
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		readLimiter.wait(int64(n))
+		h.Write(buf[:n])
+	}
+	return sampledPrefix + algorithm.format(h.Sum(nil)), nil
+}
+
+// checkSampledContent compares a file of a group with a sampled hash with
+// the kept copy, since the samples may have missed a difference.
+func checkSampledContent(hash, keptPath, path string) error {
+	if !isSampled(hash) {
+		return nil
+	}
+	same, err := sameContent(File{path: indexPath(keptPath)}, File{path: indexPath(path)})
+	if err == nil && !same {
+		err = errors.New("content differs from the kept copy outside the sampled blocks")
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSampleHash(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 3*sampleBlock)
+	hash, err := sampleHash(bytes.NewReader(data), int64(len(data)), hashAlgorithms[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isSampled(hash) || hashAlgorithmOf(hash).name != "md5" {
+		t.Errorf("Expected a sampled MD5 hash, Got: %s", hash)
+	}
+
+	// A change between the samples is missed; one in a sample is not.
+	between := append([]byte(nil), data...)
+	between[sampleBlock+10] ^= 1
+	if other, _ := sampleHash(bytes.NewReader(between), int64(len(between)), hashAlgorithms[0]); other != hash {
+		t.Errorf("Expected: %s, Got: %s", hash, other)
+	}
+	inSample := append([]byte(nil), data...)
+	inSample[len(inSample)-1] ^= 1
+	if other, _ := sampleHash(bytes.NewReader(inSample), int64(len(inSample)), hashAlgorithms[0]); other == hash {
+		t.Errorf("Expected a change in the last block to change the hash")
+	}
+
+	small := []byte("small")
+	if _, err := sampleHash(bytes.NewReader(small), int64(len(small)), hashAlgorithms[0]); err != nil {
+		t.Errorf("Expected files smaller than a block to be sampled, Got: %v", err)
+	}
+}
+
+func TestSampledActionsVerifyContent(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	data := bytes.Repeat([]byte("0123456789abcdef"), 3*sampleBlock)
+	between := append([]byte(nil), data...)
+	between[sampleBlock+10] ^= 1
+	keep, same, differs := filepath.Join(dir, "keep"), filepath.Join(dir, "same"), filepath.Join(dir, "differs")
+	ioutil.WriteFile(keep, data, 0644)
+	ioutil.WriteFile(same, data, 0644)
+	ioutil.WriteFile(differs, between, 0644)
+
+	sampleOver = sampleBlock
+	defer func() { sampleOver = 0 }()
+	var files []File
+	for _, path := range []string{keep, same, differs} {
+		file, err := hashFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	if files[0].Hash != files[2].Hash {
+		t.Fatalf("Expected the samples to match, Got: %s and %s", files[0].Hash, files[2].Hash)
+	}
+	if err := changedSinceScan(files[0], files[1]); err != nil {
+		t.Errorf("Expected an identical copy to pass, Got: %v", err)
+	}
+	if err := changedSinceScan(files[0], files[2]); err == nil {
+		t.Errorf("Expected a copy that differs between the samples to be refused")
+	}
+	if err := checkHash(differs, files[0].Hash); err != nil {
+		t.Errorf("Expected the sampled hash to be checked by its samples, Got: %v", err)
+	}
+}
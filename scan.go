@@ -0,0 +1,337 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// scanProgress is a snapshot of a running or finished scan.
+type scanProgress struct {
+	Files     int   `json:"files"`      // files found by the walk
+	Scanned   int   `json:"scanned"`    // files hashed so far
+	Errors    int   `json:"errors"`     // files that could not be read
+	TotalSize int64 `json:"total_size"` // size of the hashed files
+	FoundSize int64 `json:"found_size"` // size of the files found by the walk
+	Sized     bool  `json:"sized"`      // the size found is that of the whole scan (--pre-walk)
+	Active    int   `json:"active"`     // files being hashed right now
+	Workers   int   `json:"workers"`    // maximum number of concurrent hashes
+
+	Failures []HashError `json:"-"` // files that could not be read, with the reason
+}
+
+// percentHashed returns the share of the bytes found that were hashed, as
+// a formatted percentage. While the walk is running, the bytes found grow
+// with it, so the percentage can go down.
+func (p scanProgress) percentHashed() string {
+	percent := 0.0
+	if p.FoundSize > 0 {
+		percent = float64(p.TotalSize) * 100 / float64(p.FoundSize)
+	}
+	if percent > 100 { // files that grew while they were hashed
+		percent = 100
+	}
+	return formatDecimal(percent, 1)
+}
+
+// scanFolder walks folderPath, hashes every file and groups the files by
+// hash. onProgress, if not nil, is called after every hashed file. Files that
+// cannot be read are logged and counted as errors; only a failing walk
+// aborts the scan. control, if not nil, can pause the hashing or cancel the
+// scan, in which case errScanCanceled is returned with the files hashed so far.
+func scanFolder(folderPath string, onProgress func(scanProgress), control *scanControl) (map[string][]File, scanProgress, error) {
+	fileMap := make(map[string][]File)
+	var wg sync.WaitGroup
+	hashCh := make(chan File)
+	errCh := make(chan HashError)
+	goroutineCh := make(chan struct{}, hashWorkers(folderPath)) // Limit the number of concurrently running goroutines
+	progress := scanProgress{Workers: cap(goroutineCh)}
+	var inUse []string // files another process had open, retried at the end
+
+	hash := func(path string) {
+		wg.Add(1)
+		go func() {
+			if !control.wait() {
+				wg.Done()
+				return
+			}
+			calculateHash(path, &wg, hashCh, errCh, goroutineCh)
+		}()
+	}
+	// The walk runs while the files it found are hashed; it counts the files
+	// and bytes found for the progress.
+	var found struct{ files, bytes int64 }
+	refresh := func() {
+		progress.Files = int(atomic.LoadInt64(&found.files))
+		progress.FoundSize = atomic.LoadInt64(&found.bytes)
+	}
+	var err error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var walked []walkedFile // with --screen, hashed after the walk
+		filter := newWalkFilter(folderPath)
+		// Originals of files named as copies, hashed together with the copy
+		// before the walk reaches them.
+		early := make(map[string]bool)
+		err = filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if control.isCanceled() {
+				return errScanCanceled
+			}
+			if filter.skip(path, info) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir() {
+				if screenFiles {
+					walked = append(walked, walkedFile{path, info})
+				} else if early[path] {
+					delete(early, path)
+				} else {
+					hash(path)
+					if original, ok := copyOriginal(path); ok && filepath.Base(original) > info.Name() {
+						// The walk visits the names of a folder in order.
+						if o, err := os.Lstat(original); err == nil && o.Mode().IsRegular() && o.Size() == info.Size() && !filter.skip(original, o) {
+							early[original] = true
+							hash(original)
+						}
+					}
+				}
+				atomic.AddInt64(&found.files, 1)
+				atomic.AddInt64(&found.bytes, info.Size())
+			}
+			return nil
+		})
+		if screenFiles && err == nil {
+			candidates, unique := screen(walked, cap(goroutineCh), control)
+			for _, file := range unique {
+				hashCh <- file // without hash
+			}
+			for _, path := range prioritizeCopies(candidates) {
+				hash(path)
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(hashCh)
+		close(errCh)
+	}()
+
+	for {
+		select {
+		case file, ok := <-hashCh:
+			if !ok {
+				hashCh = nil // Set to nil to exit the loop when both channels are closed
+			} else {
+				addScannedFile(fileMap, file)
+				progress.Scanned++
+				progress.TotalSize += file.Size
+				progress.Active = len(goroutineCh)
+				refresh()
+				control.update(progress)
+				if onProgress != nil {
+					onProgress(progress)
+				}
+			}
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil // Set to nil to exit the loop when both channels are closed
+			} else {
+				if isSharingViolation(err.Err) {
+					inUse = append(inUse, err.Path)
+					continue
+				}
+				log.Printf("Error processing %s: %v", err.Path, err.Err)
+				progress.Errors++
+				progress.Failures = append(progress.Failures, err)
+			}
+		}
+
+		if hashCh == nil && errCh == nil {
+			break // Both channels are closed, exit the loop
+		}
+	}
+	if len(inUse) > 0 && control.wait() {
+		time.Sleep(sharingRetryDelay)
+		retryInUse(inUse, fileMap, &progress, onProgress)
+	}
+	progress.Active = 0
+	refresh()
+	control.update(progress)
+	if err == nil && control.isCanceled() {
+		err = errScanCanceled
+	}
+	return fileMap, progress, err
+}
+
+// addScannedFile adds a file the scan hashed, or found unique without
+// hashing it, to fileMap and reports it to the stream, quota and sink.
+func addScannedFile(fileMap map[string][]File, file File) {
+	if resultSink != nil {
+		resultSink.add(file) // not kept for this run
+	} else if file.Hash == "" {
+		fileMap[unhashedKey+file.Path()] = []File{file}
+	} else {
+		fileMap[file.Hash] = append(fileMap[file.Hash], file)
+	}
+	if file.Hash != "" {
+		duplicateStream.add(file)
+		scanQuota.add(file)
+	}
+}
+
+// scanRoots scans several folders like scanFolder and combines their files.
+// Folders on different devices are scanned at the same time, each with a
+// worker pool of its own, so a slow drive does not hold up the hashing on a
+// fast one; folders on the same device are scanned one after the other.
+// onProgress receives the combined progress of all folders, one call at a
+// time.
+func scanRoots(roots []string, onProgress func(scanProgress), control *scanControl) (map[string][]File, scanProgress, error) {
+	var devices [][]string
+	index := make(map[fileID]int)
+	for _, root := range roots {
+		id := fileID{dev: ^uint64(0)} // unknown devices share one pool
+		if info, err := os.Stat(root); err == nil {
+			if rootID, _, ok := fileIdentity(root, info); ok {
+				id = fileID{dev: rootID.dev}
+			}
+		}
+		i, seen := index[id]
+		if !seen {
+			i = len(devices)
+			index[id] = i
+			devices = append(devices, nil)
+		}
+		devices[i] = append(devices[i], root)
+	}
+
+	var planned scanProgress // with --pre-walk, the files and size of all roots
+	if preWalk {
+		control.setPhase("pre-walk")
+		files, size, err := sizeRoots(roots, control)
+		control.setPhase("scanning")
+		if err != nil {
+			return nil, scanProgress{}, err
+		}
+		planned = scanProgress{Files: files, FoundSize: size}
+	}
+
+	var mu sync.Mutex
+	fileMap := make(map[string][]File)
+	total := scanProgress{Sized: preWalk}
+	current := make([]scanProgress, len(devices)) // of the folder being scanned
+	combined := func() scanProgress {
+		p := total
+		for _, c := range current {
+			p.Files += c.Files
+			p.Scanned += c.Scanned
+			p.Errors += c.Errors
+			p.TotalSize += c.TotalSize
+			p.FoundSize += c.FoundSize
+			p.Active += c.Active
+			p.Workers += c.Workers
+		}
+		// Files added since the pre-walk can make the walks find more.
+		if p.Files < planned.Files {
+			p.Files = planned.Files
+		}
+		if p.FoundSize < planned.FoundSize {
+			p.FoundSize = planned.FoundSize
+		}
+		return p
+	}
+	var firstErr error
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		wg.Add(1)
+		go func(i int, device []string) {
+			defer wg.Done()
+			for _, root := range device {
+				scan := scanFolder
+				if isRemotePath(root) {
+					scan = scanRemote
+				}
+				rootMap, progress, err := scan(root, func(p scanProgress) {
+					mu.Lock()
+					defer mu.Unlock()
+					current[i] = p
+					p = combined()
+					control.update(p)
+					if onProgress != nil {
+						onProgress(p)
+					}
+				}, control)
+				mu.Lock()
+				for key, files := range rootMap {
+					fileMap[key] = append(fileMap[key], files...)
+				}
+				current[i] = scanProgress{}
+				total.Files += progress.Files
+				total.Scanned += progress.Scanned
+				total.Errors += progress.Errors
+				total.TotalSize += progress.TotalSize
+				total.FoundSize += progress.FoundSize
+				total.Failures = append(total.Failures, progress.Failures...)
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}(i, device)
+	}
+	wg.Wait()
+	planned = scanProgress{} // the walks found what is there now
+	progress := combined()
+	for _, device := range devices {
+		progress.Workers += hashWorkers(device[0])
+	}
+	control.update(progress)
+	return fileMap, progress, firstErr
+}
+
+// sharingRetryDelay is how long the scan waits before retrying files that
+// were in use by other processes.
+var sharingRetryDelay = 2 * time.Second
+
+// retryInUse hashes the files that could not be opened because another
+// process had them open on Windows, once the rest of the scan is done. Files
+// that are still in use are reported together and counted as errors.
+func retryInUse(paths []string, fileMap map[string][]File, progress *scanProgress, onProgress func(scanProgress)) {
+	var stillInUse []string
+	for _, path := range paths {
+		file, err := hashFile(path)
+		if err != nil {
+			if isSharingViolation(err) {
+				stillInUse = append(stillInUse, path)
+			} else {
+				log.Printf("Error processing %s: %v", path, err)
+			}
+			progress.Errors++
+			progress.Failures = append(progress.Failures, HashError{Path: path, Err: err})
+			continue
+		}
+		addScannedFile(fileMap, file)
+		progress.Scanned++
+		progress.TotalSize += file.Size
+		if onProgress != nil {
+			onProgress(*progress)
+		}
+	}
+	if len(stillInUse) > 0 {
+		log.Printf("Skipped %d files that are in use by other processes: %s", len(stillInUse), strings.Join(stillInUse, ", "))
+	}
+}
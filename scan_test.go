@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRetryInUse(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	released := filepath.Join(tempDir, "released")
+	ioutil.WriteFile(released, []byte("content"), 0644)
+	missing := filepath.Join(tempDir, "missing")
+
+	fileMap := make(map[string][]File)
+	progress := scanProgress{Files: 2}
+	calls := 0
+	retryInUse([]string{released, missing}, fileMap, &progress, func(scanProgress) { calls++ })
+
+	if progress.Scanned != 1 || progress.TotalSize != 7 || progress.Errors != 1 || calls != 1 {
+		t.Errorf("Unexpected progress: %+v (%d callbacks)", progress, calls)
+	}
+	if len(progress.Failures) != 1 || progress.Failures[0].Path != missing {
+		t.Errorf("Expected %s to fail, Got: %+v", missing, progress.Failures)
+	}
+	if files := fileMap["9a0364b9e99bb480dd25e1f0284c8555"]; len(files) != 1 || files[0].Path() != released {
+		t.Errorf("Expected %s to be hashed, Got: %+v", released, fileMap)
+	}
+}
+
+// recordingSinkTarget keeps the batches sent to it.
+type recordingSinkTarget struct{ batches []sinkBatch }
+
+func (r *recordingSinkTarget) send(batch sinkBatch) error {
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func (r *recordingSinkTarget) close() error { return nil }
+
+func TestRetryInUseSink(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	released := filepath.Join(tempDir, "released")
+	ioutil.WriteFile(released, []byte("content"), 0644)
+
+	target := &recordingSinkTarget{}
+	resultSink = newSink(target, tempDir, nil)
+	defer func() { resultSink = nil }()
+	fileMap := make(map[string][]File)
+	retryInUse([]string{released}, fileMap, &scanProgress{Files: 1}, nil)
+	if sent, err := resultSink.finish(scanProgress{Scanned: 1}, true); err != nil || sent != 1 {
+		t.Fatalf("Expected: 1 file sent, Got: %d (%v)", sent, err)
+	}
+	if len(target.batches) == 0 || len(target.batches[0].Files) != 1 || target.batches[0].Files[0].Path != released {
+		t.Errorf("Expected %s to be sent, Got: %+v", released, target.batches)
+	}
+	if len(fileMap) != 0 {
+		t.Errorf("Expected the sent file not to be kept, Got: %+v", fileMap)
+	}
+}
+
+func TestScanRoots(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	var roots []string
+	for _, name := range []string{"a", "b", "c"} {
+		root := filepath.Join(tempDir, name)
+		os.Mkdir(root, 0755)
+		ioutil.WriteFile(filepath.Join(root, "same.txt"), []byte("same"), 0644)
+		ioutil.WriteFile(filepath.Join(root, "own.txt"), []byte(name), 0644)
+		roots = append(roots, root)
+	}
+
+	var last scanProgress
+	control := newScanControl()
+	fileMap, progress, err := scanRoots(roots, func(p scanProgress) { last = p }, control)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Files != 6 || progress.Scanned != 6 || progress.TotalSize != 15 || progress.FoundSize != 15 || progress.Active != 0 {
+		t.Errorf("Unexpected progress: %+v", progress)
+	}
+	if last.Scanned != 6 || control.status().Scanned != 6 {
+		t.Errorf("Expected the combined progress to be reported, Got: %+v and %+v", last, control.status().scanProgress)
+	}
+	if groups := duplicateGroups(fileMap); len(groups) != 1 || len(groups[0].Files) != 3 {
+		t.Errorf("Expected one group across the roots, Got: %+v", groups)
+	}
+
+	if _, _, err := scanRoots([]string{roots[0], filepath.Join(tempDir, "missing")}, nil, nil); err == nil {
+		t.Errorf("Expected an error for a missing root")
+	}
+}
+
+func TestPercentHashed(t *testing.T) {
+	tests := []struct {
+		progress scanProgress
+		expected string
+	}{
+		{scanProgress{}, "0.0"},
+		{scanProgress{TotalSize: 1, FoundSize: 4}, "25.0"},
+		{scanProgress{TotalSize: 5, FoundSize: 4}, "100.0"},
+	}
+	for _, test := range tests {
+		if got := test.progress.percentHashed(); got != test.expected {
+			t.Errorf("Expected: %s, Got: %s", test.expected, got)
+		}
+	}
+}
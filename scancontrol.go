@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// errScanCanceled is returned by scanFolder when its scan was canceled.
+var errScanCanceled = errors.New("scan canceled")
+
+// scanControl lets a running scan be paused, resumed and canceled from
+// other goroutines, and keeps the latest progress for status reports. A nil
+// *scanControl never pauses.
+type scanControl struct {
+	mu       sync.Mutex
+	resumed  *sync.Cond
+	paused   bool
+	canceled bool
+	phase    string // "pre-walk", "scanning" or "done"
+	started  time.Time
+	pausedAt time.Time
+	idle     time.Duration // total time spent paused
+	progress scanProgress
+}
+
+func newScanControl() *scanControl {
+	c := &scanControl{phase: "scanning", started: time.Now()}
+	c.resumed = sync.NewCond(&c.mu)
+	return c
+}
+
+// pause stops new files from being hashed; files being hashed are finished.
+// It reports whether the scan was running.
+func (c *scanControl) pause() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused || c.canceled {
+		return false
+	}
+	c.paused = true
+	c.pausedAt = time.Now()
+	return true
+}
+
+// resume continues a paused scan. It reports whether the scan was paused.
+func (c *scanControl) resume() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		return false
+	}
+	c.paused = false
+	c.idle += time.Since(c.pausedAt)
+	c.resumed.Broadcast()
+	return true
+}
+
+// cancel stops the scan; files that are not being hashed yet are skipped.
+func (c *scanControl) cancel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.canceled = true
+	c.resumed.Broadcast()
+}
+
+// wait blocks while the scan is paused. It returns false if the scan was
+// canceled.
+func (c *scanControl) wait() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.paused && !c.canceled {
+		c.resumed.Wait()
+	}
+	return !c.canceled
+}
+
+func (c *scanControl) isCanceled() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.canceled
+}
+
+func (c *scanControl) update(p scanProgress) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.progress = p
+	c.mu.Unlock()
+}
+
+// setPhase records what the scan is doing.
+func (c *scanControl) setPhase(phase string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.phase = phase
+	c.mu.Unlock()
+}
+
+// scanStatus is a snapshot of a controlled scan.
+type scanStatus struct {
+	scanProgress
+	Phase    string
+	Paused   bool
+	Canceled bool
+	Elapsed  time.Duration // since the scan started
+	Hashing  time.Duration // elapsed time minus pauses
+	Large    []fileProgress
+}
+
+func (c *scanControl) status() scanStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := scanStatus{scanProgress: c.progress, Phase: c.phase, Paused: c.paused, Canceled: c.canceled, Elapsed: time.Since(c.started), Large: activeLargeFiles()}
+	s.Hashing = s.Elapsed - c.idle
+	if c.paused {
+		s.Hashing -= time.Since(c.pausedAt)
+	}
+	return s
+}
+
+// rate returns the bytes hashed per second while the scan was not paused.
+func (s scanStatus) rate() int64 {
+	if seconds := s.Hashing.Seconds(); seconds > 0 {
+		return int64(float64(s.TotalSize) / seconds)
+	}
+	return 0
+}
+
+// writeScanStatus prints a detailed status report of a controlled scan.
+func writeScanStatus(w io.Writer, s scanStatus) {
+	state := msg("scan.state.running")
+	if s.Canceled {
+		state = msg("scan.state.canceled")
+	} else if s.Paused {
+		state = msg("scan.state.paused")
+	}
+	rate := s.rate()
+	fmt.Fprintln(w, msg("scan.status", state, s.Elapsed.Round(time.Second)))
+	fmt.Fprintln(w, "  "+msg("scan.status.files", formatCount(int64(s.Scanned)), formatCount(int64(s.Files)), formatCount(int64(s.Errors))))
+	fmt.Fprintln(w, "  "+msg("scan.status.bytes", humanReadableSize(s.TotalSize), humanReadableSize(s.FoundSize), s.percentHashed(), humanReadableSize(rate)))
+	if _, remaining, ok := estimate(s.scanProgress, s.Hashing); ok {
+		fmt.Fprintln(w, "  "+msg("scan.status.eta", remaining))
+	}
+	fmt.Fprintln(w, "  "+msg("scan.status.workers", s.Active, s.Workers))
+	for _, f := range s.Large {
+		percent := 0.0
+		if f.Size > 0 {
+			percent = float64(f.Read) * 100 / float64(f.Size)
+		}
+		fmt.Fprintln(w, "  "+msg("scan.status.file", f.Path, humanReadableSize(f.Read), humanReadableSize(f.Size), formatDecimal(percent, 1), time.Since(f.Started).Round(time.Second)))
+	}
+}
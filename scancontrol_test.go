@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanControlPauseResume(t *testing.T) {
+	control := newScanControl()
+	if !control.pause() || control.pause() {
+		t.Fatalf("Expected only the first pause to take effect")
+	}
+	waited := make(chan bool)
+	go func() { waited <- control.wait() }()
+	select {
+	case <-waited:
+		t.Fatal("Expected wait to block while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if !control.status().Paused {
+		t.Errorf("Expected the status to report the pause")
+	}
+	if !control.resume() || control.resume() {
+		t.Errorf("Expected only the first resume to take effect")
+	}
+	if ok := <-waited; !ok {
+		t.Errorf("Expected wait to return true after resuming")
+	}
+	if s := control.status(); s.Hashing >= s.Elapsed-15*time.Millisecond {
+		t.Errorf("Expected the hashing time to exclude the pause, Got: %v of %v", s.Hashing, s.Elapsed)
+	}
+
+	control.pause()
+	go func() { waited <- control.wait() }()
+	control.cancel()
+	if ok := <-waited; ok {
+		t.Errorf("Expected wait to return false after canceling")
+	}
+
+	var none *scanControl
+	if !none.wait() || none.isCanceled() {
+		t.Errorf("Expected a nil control to never pause")
+	}
+}
+
+func TestScanFolderControl(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	for _, name := range []string{"a", "b", "c"} {
+		ioutil.WriteFile(filepath.Join(tempDir, name), []byte(name), 0644)
+	}
+
+	control := newScanControl()
+	control.pause()
+	done := make(chan scanProgress)
+	go func() {
+		_, progress, _ := scanFolder(tempDir, nil, control)
+		done <- progress
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if s := control.status(); s.Scanned != 0 {
+		t.Errorf("Expected no files to be hashed while paused, Got: %d", s.Scanned)
+	}
+	control.resume()
+	if progress := <-done; progress.Scanned != 3 {
+		t.Errorf("Expected: 3 files hashed after resuming, Got: %d", progress.Scanned)
+	}
+
+	canceled := newScanControl()
+	canceled.cancel()
+	if _, progress, err := scanFolder(tempDir, nil, canceled); err != errScanCanceled || progress.Scanned != 0 {
+		t.Errorf("Expected: %v with no files, Got: %v with %d", errScanCanceled, err, progress.Scanned)
+	}
+}
+
+func TestWriteScanStatus(t *testing.T) {
+	var out strings.Builder
+	writeScanStatus(&out, scanStatus{
+		scanProgress: scanProgress{Files: 10, Scanned: 4, Errors: 1, TotalSize: 4 << 20, FoundSize: 16 << 20, Active: 2, Workers: 8},
+		Paused:       true,
+		Elapsed:      90 * time.Second,
+		Hashing:      2 * time.Second,
+	})
+	expected := "Scan paused for 1m30s\n" +
+		"  Files hashed: 4 of 10 found, 1 errors\n" +
+		"  Data hashed: 4.00 MiB of 16.00 MiB (25.0%) at 2.00 MiB/s\n" +
+		"  Files being hashed: 2 of 8 workers busy\n"
+	if out.String() != expected {
+		t.Errorf("Expected: %s, Got: %s", expected, out.String())
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// stdin is the input of the interactive prompts. It is a consoleInput when
+// key presses are read during the scan.
+var stdin io.Reader = os.Stdin
+
+// isTerminal reports whether f is a terminal or console.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// consoleInput reads a reader in a background goroutine. The keys pressed
+// during a scan and the answers to the prompts afterwards both come from it,
+// so no input is lost to a read that is still pending when the scan ends.
+type consoleInput struct {
+	chunks chan []byte
+	rest   []byte
+}
+
+func newConsoleInput(r io.Reader) *consoleInput {
+	in := &consoleInput{chunks: make(chan []byte)}
+	go func() {
+		for {
+			buf := make([]byte, 256)
+			n, err := r.Read(buf)
+			if n > 0 {
+				in.chunks <- buf[:n]
+			}
+			if err != nil {
+				close(in.chunks)
+				return
+			}
+		}
+	}()
+	return in
+}
+
+func (in *consoleInput) Read(p []byte) (int, error) {
+	if len(in.rest) == 0 {
+		chunk, ok := <-in.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+		in.rest = chunk
+	}
+	n := copy(p, in.rest)
+	in.rest = in.rest[n:]
+	return n, nil
+}
+
+// runScanKeys reads single key presses from in while a scan runs: p pauses
+// the hashing, r resumes it and s prints a status report. It returns a
+// function that stops reading keys and restores the terminal. Nothing
+// happens if the terminal cannot be switched to single key input.
+func runScanKeys(in *consoleInput, control *scanControl, out io.Writer) (stop func()) {
+	restore, err := rawTerminal()
+	if err != nil {
+		return func() {}
+	}
+	fmt.Fprintln(out, msg("scan.keys"))
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case chunk, ok := <-in.chunks:
+				if !ok {
+					return
+				}
+				for _, key := range chunk {
+					handleScanKey(key, control, out)
+				}
+			case <-signals:
+				restore()
+				os.Exit(130)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+		signal.Stop(signals)
+		restore()
+	}
+}
+
+func handleScanKey(key byte, control *scanControl, out io.Writer) {
+	switch key {
+	case 'p', 'P':
+		if control.pause() {
+			fmt.Fprintln(out, "\n"+msg("scan.paused"))
+		}
+	case 'r', 'R':
+		if control.resume() {
+			fmt.Fprintln(out, "\n"+msg("scan.resumed"))
+		}
+	case 's', 'S':
+		fmt.Fprintln(out)
+		writeScanStatus(out, control.status())
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestConsoleInput(t *testing.T) {
+	in := newConsoleInput(strings.NewReader("first line\nsecond\n"))
+	scanner := bufio.NewScanner(in)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if strings.Join(lines, "|") != "first line|second" {
+		t.Errorf("Expected: first line|second, Got: %s", strings.Join(lines, "|"))
+	}
+}
+
+func TestHandleScanKey(t *testing.T) {
+	control := newScanControl()
+	var out strings.Builder
+	for _, key := range []byte("ppsrrx") {
+		handleScanKey(key, control, &out)
+	}
+	got := out.String()
+	if strings.Count(got, "Scan paused;") != 1 || strings.Count(got, "Scan resumed.") != 1 {
+		t.Errorf("Expected one pause and one resume, Got: %s", got)
+	}
+	if !strings.Contains(got, "Scan paused for ") {
+		t.Errorf("Expected a status report of the paused scan, Got: %s", got)
+	}
+	if control.status().Paused {
+		t.Errorf("Expected the scan to be running again")
+	}
+}
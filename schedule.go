@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// timeWindow is a daily period of local time such as 01:00-06:00. A window
+// whose end is before its start spans midnight.
+type timeWindow struct {
+	start, end time.Duration // since midnight
+	spec       string
+}
+
+func parseTimeWindow(s string) (timeWindow, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return timeWindow{}, fmt.Errorf("invalid time window %q (expected HH:MM-HH:MM)", s)
+	}
+	w := timeWindow{spec: s}
+	for _, part := range []struct {
+		text string
+		dst  *time.Duration
+	}{{from, &w.start}, {to, &w.end}} {
+		t, err := time.Parse("15:04", strings.TrimSpace(part.text))
+		if err != nil {
+			return timeWindow{}, fmt.Errorf("invalid time window %q (expected HH:MM-HH:MM)", s)
+		}
+		*part.dst = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if w.start == w.end {
+		return timeWindow{}, fmt.Errorf("invalid time window %q: start and end are equal", s)
+	}
+	return w, nil
+}
+
+func (w timeWindow) String() string { return w.spec }
+
+// contains reports whether t lies inside the window.
+func (w timeWindow) contains(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	if w.start < w.end {
+		return offset >= w.start && offset < w.end
+	}
+	return offset >= w.start || offset < w.end
+}
+
+// nextChange returns the next time after t at which the window opens or
+// closes.
+func (w timeWindow) nextChange(t time.Time) time.Time {
+	boundary := w.start
+	if w.contains(t) {
+		boundary = w.end
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	next := day.Add(boundary)
+	if !next.After(t) {
+		next = day.AddDate(0, 0, 1).Add(boundary)
+	}
+	return next
+}
+
+// scheduleScan pauses the scan of control whenever the time is outside the
+// window and resumes it when the window opens again. A pause from a key press
+// is left alone. It returns a function that stops the schedule.
+func scheduleScan(w timeWindow, control *scanControl, out io.Writer) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		scheduled := false // whether the schedule paused the scan
+		for {
+			now := time.Now()
+			next := w.nextChange(now)
+			if !w.contains(now) {
+				if control.pause() {
+					scheduled = true
+					fmt.Fprintln(out, "\n"+msg("schedule.paused", w, next.Format("15:04")))
+				}
+			} else if scheduled {
+				scheduled = false
+				if control.resume() {
+					fmt.Fprintln(out, "\n"+msg("schedule.resumed", w))
+				}
+			}
+			select {
+			case <-time.After(time.Until(next)):
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
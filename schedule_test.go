@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeWindow(t *testing.T) {
+	testCases := []struct {
+		spec       string
+		start, end time.Duration
+		err        bool
+	}{
+		{"01:00-06:00", time.Hour, 6 * time.Hour, false},
+		{"22:30 - 5:15", 22*time.Hour + 30*time.Minute, 5*time.Hour + 15*time.Minute, false},
+		{"01:00", 0, 0, true},
+		{"25:00-06:00", 0, 0, true},
+		{"06:00-06:00", 0, 0, true},
+	}
+	for _, tc := range testCases {
+		w, err := parseTimeWindow(tc.spec)
+		if (err != nil) != tc.err || w.start != tc.start || w.end != tc.end {
+			t.Errorf("%s: Expected: %v-%v (error %v), Got: %v-%v (%v)", tc.spec, tc.start, tc.end, tc.err, w.start, w.end, err)
+		}
+	}
+}
+
+func TestTimeWindow(t *testing.T) {
+	at := func(hour, minute int) time.Time { return time.Date(2026, 10, 14, hour, minute, 0, 0, time.UTC) }
+	night, _ := parseTimeWindow("22:00-06:00")
+	day, _ := parseTimeWindow("09:00-17:30")
+
+	testCases := []struct {
+		name     string
+		window   timeWindow
+		now      time.Time
+		contains bool
+		next     time.Time
+	}{
+		{"before a day window", day, at(8, 0), false, at(9, 0)},
+		{"inside a day window", day, at(12, 0), true, at(17, 30)},
+		{"at the end of a day window", day, at(17, 30), false, at(9, 0).AddDate(0, 0, 1)},
+		{"before midnight in a night window", night, at(23, 0), true, at(6, 0).AddDate(0, 0, 1)},
+		{"after midnight in a night window", night, at(2, 0), true, at(6, 0)},
+		{"outside a night window", night, at(12, 0), false, at(22, 0)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.window.contains(tc.now); got != tc.contains {
+				t.Errorf("Expected: %v, Got: %v", tc.contains, got)
+			}
+			if got := tc.window.nextChange(tc.now); !got.Equal(tc.next) {
+				t.Errorf("Expected: %v, Got: %v", tc.next, got)
+			}
+		})
+	}
+}
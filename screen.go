@@ -0,0 +1,110 @@
+package main
+
+import (
+	"hash/crc32"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// screenFiles turns on the screening stage before the full hashes
+// (--screen): only files that share their size and a CRC32C of their first
+// and last 64 KiB with another file are hashed fully. The standard library
+// computes CRC32C with the SSE 4.2 and ARMv8 CRC instructions.
+var screenFiles bool
+
+// screenBlock is how much of the start and the end of a file the screening
+// CRC covers.
+const screenBlock = 64 << 10
+
+// unhashedKey is the fileMap key prefix of files the screening found to have
+// no duplicates, which have no hash.
+const unhashedKey = "unhashed:"
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// walkedFile is a file found by the walk of a scan.
+type walkedFile struct {
+	path string
+	info os.FileInfo
+}
+
+// screen splits off the files that cannot have duplicates: files of a size
+// no other file has, and files whose screening CRC no other file of their
+// size has. It returns the paths that still need a full hash, and the other
+// files with their size and modification time but without hash. Files whose
+// CRC cannot be computed are hashed, so the full hash reports the error.
+func screen(files []walkedFile, workers int, control *scanControl) ([]string, []File) {
+	bySize := make(map[int64][]int)
+	for i, file := range files {
+		bySize[file.info.Size()] = append(bySize[file.info.Size()], i)
+	}
+
+	keys := make([]string, len(files))
+	failed := make([]bool, len(files))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, workers)
+	for i, file := range files {
+		size := file.info.Size()
+		if len(bySize[size]) < 2 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			if !control.wait() {
+				failed[i] = true // not hashed either
+				return
+			}
+			sum, err := screenCRC(path, size)
+			keys[i] = strconv.FormatInt(size, 10) + ":" + strconv.FormatUint(uint64(sum), 16)
+			failed[i] = err != nil
+		}(i, file.path)
+	}
+	wg.Wait()
+
+	count := make(map[string]int)
+	for _, key := range keys {
+		count[key]++
+	}
+	var candidates []string
+	var unique []File
+	for i, file := range files {
+		if failed[i] || (keys[i] != "" && count[keys[i]] > 1) {
+			candidates = append(candidates, file.path)
+		} else {
+			unique = append(unique, File{path: indexPath(file.path), Size: file.info.Size(), ModTime: file.info.ModTime()})
+		}
+	}
+	return candidates, unique
+}
+
+// screenCRC returns the CRC32C of the first and the last screenBlock bytes
+// of a file, which cover the whole file if it is small.
+func screenCRC(path string, size int64) (uint32, error) {
+	openLimiter.wait(1)
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	buf := make([]byte, screenBlock)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, err
+	}
+	sum := crc32.Update(0, castagnoli, buf[:n])
+	read := n
+	if size > int64(n) {
+		if n, err = f.ReadAt(buf, size-screenBlock); err != nil && err != io.EOF {
+			return 0, err
+		}
+		sum = crc32.Update(sum, castagnoli, buf[:n])
+		read += n
+	}
+	readLimiter.wait(int64(read))
+	return sum, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestScreen(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	large := bytes.Repeat([]byte("x"), 3*screenBlock)
+	middle := append([]byte(nil), large...)
+	middle[len(middle)/2] = 'y' // outside the screened blocks
+	contents := map[string][]byte{
+		"a":      []byte("same"),
+		"b":      []byte("same"),
+		"c":      []byte("diff"),
+		"d":      []byte("unique size"),
+		"large":  large,
+		"middle": middle,
+	}
+	var files []walkedFile
+	for name, content := range contents {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, content, 0644)
+		info, _ := os.Stat(path)
+		files = append(files, walkedFile{path, info})
+	}
+
+	candidates, unique := screen(files, 2, nil)
+	sort.Strings(candidates)
+	expected := []string{"a", "b", "large", "middle"}
+	if len(candidates) != len(expected) {
+		t.Fatalf("Expected: %v, Got: %v", expected, candidates)
+	}
+	for i, name := range expected {
+		if candidates[i] != filepath.Join(dir, name) {
+			t.Errorf("Expected: %s, Got: %s", name, candidates[i])
+		}
+	}
+	if len(unique) != 2 {
+		t.Errorf("Expected c and d without duplicates, Got: %v", unique)
+	}
+	for _, file := range unique {
+		if file.Hash != "" || file.Size != int64(len(contents[filepath.Base(file.Path())])) {
+			t.Errorf("Unexpected unhashed file: %+v", file)
+		}
+	}
+}
+
+func TestScanFolderScreen(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{"a": "same", "b": "same", "c": "diff", "d": "unique size"} {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	screenFiles = true
+	defer func() { screenFiles = false }()
+	fileMap, progress, err := scanFolder(dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Files != 4 || progress.Scanned != 4 || progress.TotalSize != 23 {
+		t.Errorf("Unexpected progress: %+v", progress)
+	}
+	if groups := duplicateGroups(fileMap); len(groups) != 1 || len(groups[0].Files) != 2 {
+		t.Errorf("Expected a and b as duplicates, Got: %+v", groups)
+	}
+	if files := fileMap[unhashedKey+filepath.Join(dir, "d")]; len(files) != 1 {
+		t.Errorf("Expected d without hash, Got: %v", fileMap)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// screenshotName matches the names screenshot tools of the common systems
+// and languages give their files, e.g. "Screenshot 2024-05-01 at 10.00.00.png",
+// "Screenshot_20240501-100000.png" or macOS's "SCR-20240501-abcd.png".
+var screenshotName = regexp.MustCompile(`(?i)^(screenshot|screen shot|scr-\d{8}|bildschirmfoto|capture d.écran|captura de pantalla|schermafbeelding|cleanshot)`)
+
+// screenSizes are the resolutions of common monitors, laptops and phones,
+// in landscape orientation.
+var screenSizes = map[[2]int]bool{
+	{1280, 720}: true, {1280, 800}: true, {1366, 768}: true, {1440, 900}: true,
+	{1536, 864}: true, {1600, 900}: true, {1680, 1050}: true, {1920, 1080}: true,
+	{1920, 1200}: true, {2560, 1080}: true, {2560, 1440}: true, {2560, 1600}: true,
+	{2880, 1800}: true, {3024, 1964}: true, {3440, 1440}: true, {3456, 2234}: true,
+	{3840, 2160}: true, {5120, 2880}: true,
+	{1334, 750}: true, {1792, 828}: true, {2208, 1242}: true, {2436, 1125}: true,
+	{2532, 1170}: true, {2556, 1179}: true, {2688, 1242}: true, {2778, 1284}: true,
+	{2796, 1290}: true, {2340, 1080}: true, {2400, 1080}: true, {3120, 1440}: true,
+	{3200, 1440}: true, {2048, 1536}: true, {2388, 1668}: true, {2732, 2048}: true,
+}
+
+// screenshotDistance is the largest number of differing bits of the
+// perceptual hashes of two screenshots that are considered near-identical.
+// It is lower than for photos, since screenshots of one app differ little.
+const screenshotDistance = 3
+
+// isScreenshot reports whether an image looks like a screenshot: by its name,
+// by metadata naming it a screenshot, as macOS and several tools write it
+// into PNG files, or by being a PNG file of the size of a common screen.
+func isScreenshot(path string, width, height int) bool {
+	if screenshotName.MatchString(filepath.Base(path)) {
+		return true
+	}
+	if strings.ToLower(filepath.Ext(path)) != ".png" {
+		return false
+	}
+	if width < height {
+		width, height = height, width
+	}
+	return screenSizes[[2]int{width, height}] || pngMentionsScreenshot(path)
+}
+
+// pngMentionsScreenshot reports whether a text chunk of the PNG file at path,
+// such as its XMP data or description, mentions a screenshot.
+func pngMentionsScreenshot(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var signature [8]byte
+	if _, err := io.ReadFull(r, signature[:]); err != nil || string(signature[:]) != "\x89PNG\r\n\x1a\n" {
+		return false
+	}
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return false
+		}
+		length, typ := binary.BigEndian.Uint32(header[:4]), string(header[4:])
+		if typ == "IDAT" || typ == "IEND" || length > 1<<20 {
+			return false
+		}
+		data := make([]byte, length+4) // with the CRC
+		if _, err := io.ReadFull(r, data); err != nil {
+			return false
+		}
+		if (typ == "tEXt" || typ == "iTXt") && bytes.Contains(bytes.ToLower(data), []byte("screenshot")) {
+			return true
+		}
+	}
+}
+
+// screenshotClusters groups the screenshots of fileMap that have the same
+// size and near-identical content. Exact duplicates already share a group,
+// so only the first file of each group is considered. The newest
+// screenshot of a cluster comes first, to be kept.
+func screenshotClusters(fileMap map[string][]File) [][]File {
+	type shot struct {
+		file File
+		hash uint64
+	}
+	bySize := make(map[image.Point][]shot)
+	for _, files := range fileMap {
+		file := files[0]
+		switch strings.ToLower(filepath.Ext(file.Path())) {
+		case ".png", ".jpg", ".jpeg":
+		default:
+			continue
+		}
+		config, err := decodeImageConfig(file.Path())
+		if err != nil || !isScreenshot(file.Path(), config.Width, config.Height) {
+			continue
+		}
+		img, err := decodeImage(file.Path())
+		if err != nil {
+			continue
+		}
+		size := image.Pt(config.Width, config.Height)
+		bySize[size] = append(bySize[size], shot{file, gridOf(img).dhash()})
+	}
+
+	var clusters [][]File
+	for _, shots := range bySize {
+		sort.Slice(shots, func(i, j int) bool {
+			if !shots[i].file.ModTime.Equal(shots[j].file.ModTime) {
+				return shots[i].file.ModTime.After(shots[j].file.ModTime)
+			}
+			return shots[i].file.Path() < shots[j].file.Path()
+		})
+		var leaders []shot
+		var members [][]File
+	next:
+		for _, s := range shots {
+			for i, leader := range leaders {
+				if bits.OnesCount64(s.hash^leader.hash) <= screenshotDistance {
+					members[i] = append(members[i], s.file)
+					continue next
+				}
+			}
+			leaders = append(leaders, s)
+			members = append(members, []File{s.file})
+		}
+		for _, files := range members {
+			if len(files) > 1 {
+				clusters = append(clusters, files)
+			}
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i][0].Path() < clusters[j][0].Path() })
+	return clusters
+}
+
+// decodeImageConfig returns the size of the image at path.
+func decodeImageConfig(path string) (image.Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(bufio.NewReader(f))
+	return config, err
+}
+
+// confirmScreenshotClusters shows every cluster as likely duplicates and adds
+// the ones the user confirms to fileMap.
+func confirmScreenshotClusters(w io.Writer, scanner *bufio.Scanner, fileMap map[string][]File, clusters [][]File) int {
+	if len(clusters) == 0 {
+		return 0
+	}
+	fmt.Fprintln(w, msg("screenshots.found", formatCount(int64(len(clusters)))))
+	titles := make([]string, len(clusters))
+	for i, files := range clusters {
+		titles[i] = msg("screenshots.cluster", formatCount(int64(len(files))))
+	}
+	return confirmLikelyDuplicates(w, scanner, fileMap, "screenshot", titles, clusters)
+}
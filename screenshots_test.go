@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPNG encodes img as PNG, with a tEXt chunk holding text if it is set.
+func testPNG(t *testing.T, img image.Image, text string) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		t.Fatal(err)
+	}
+	data := encoded.Bytes()
+	if text == "" {
+		return data
+	}
+	const ihdrEnd = 8 + 8 + 13 + 4
+	chunk := append([]byte("tEXt"), "Description\x00"+text...)
+	var out bytes.Buffer
+	out.Write(data[:ihdrEnd])
+	binary.Write(&out, binary.BigEndian, uint32(len(chunk)-4))
+	out.Write(chunk)
+	binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	out.Write(data[ihdrEnd:])
+	return out.Bytes()
+}
+
+func TestIsScreenshot(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	tagged := filepath.Join(tempDir, "image.png")
+	ioutil.WriteFile(tagged, testPNG(t, image.NewGray(image.Rect(0, 0, 20, 10)), "Screenshot"), 0644)
+	plain := filepath.Join(tempDir, "plain.png")
+	ioutil.WriteFile(plain, testPNG(t, image.NewGray(image.Rect(0, 0, 20, 10)), ""), 0644)
+
+	tests := []struct {
+		path          string
+		width, height int
+		expected      bool
+	}{
+		{"/a/Screenshot 2024-05-01 at 10.00.00.png", 20, 10, true},
+		{"/a/Screenshot_20240501-100000_Chrome.jpg", 20, 10, true},
+		{"/a/SCR-20240501-abcd.png", 20, 10, true},
+		{"/a/Bildschirmfoto vom 2024-05-01.png", 20, 10, true},
+		{"/a/IMG_0001.PNG", 1170, 2532, true},
+		{"/a/IMG_0001.jpg", 1170, 2532, false},
+		{"/a/diagram.png", 800, 600, false},
+		{tagged, 20, 10, true},
+		{plain, 20, 10, false},
+	}
+	for _, test := range tests {
+		if got := isScreenshot(test.path, test.width, test.height); got != test.expected {
+			t.Errorf("%s: Expected: %v, Got: %v", test.path, test.expected, got)
+		}
+	}
+}
+
+func TestScreenshotClusters(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	pattern := testPattern(false)
+	changed := image.NewGray(pattern.Bounds())
+	copy(changed.Pix, pattern.Pix)
+	for x := 80; x < 90; x++ {
+		changed.SetGray(x, 0, color.Gray{255}) // e.g. the clock
+	}
+	smaller := image.NewGray(image.Rect(0, 0, 80, 72))
+	for y := 0; y < 72; y++ {
+		copy(smaller.Pix[y*80:], pattern.Pix[y*90:y*90+80])
+	}
+
+	now := time.Now()
+	images := []struct {
+		name string
+		img  image.Image
+		age  time.Duration
+	}{
+		{"Screenshot 1.png", pattern, 2 * time.Hour},
+		{"Screenshot 2.png", changed, time.Hour},
+		{"Screenshot 3.png", testPattern(true), 0},
+		{"Screenshot 4.png", smaller, 0},
+		{"diagram.png", pattern, 0},
+	}
+	fileMap := make(map[string][]File)
+	for _, i := range images {
+		path := filepath.Join(tempDir, i.name)
+		data := testPNG(t, i.img, "")
+		ioutil.WriteFile(path, data, 0644)
+		fileMap[i.name] = []File{{path: indexPath(path), Size: int64(len(data)), ModTime: now.Add(-i.age)}}
+	}
+
+	clusters := screenshotClusters(fileMap)
+	if len(clusters) != 1 || len(clusters[0]) != 2 || filepath.Base(clusters[0][0].Path()) != "Screenshot 2.png" || filepath.Base(clusters[0][1].Path()) != "Screenshot 1.png" {
+		t.Fatalf("Unexpected clusters: %v", clusters)
+	}
+
+	var out bytes.Buffer
+	if n := confirmScreenshotClusters(&out, bufio.NewScanner(strings.NewReader("yes\n")), fileMap, clusters); n != 1 {
+		t.Errorf("Expected: 1 confirmed cluster, Got: %d", n)
+	}
+	if files := fileMap[pathsKey("screenshot", clusters[0])]; len(files) != 2 {
+		t.Errorf("Expected the confirmed cluster to be added, Got: %v", files)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// errNoServerCopy is returned by copyOnServer on systems that cannot ask a
+// file server to copy a file itself.
+var errNoServerCopy = errors.New("server-side copies are not supported on this system")
+
+// serverCopyFile copies src to dest on a network file system with a copy
+// that the server makes itself, such as an SMB copy offload or an NFS 4.2
+// server-side copy, instead of reading the data over the network and
+// writing it back. Like copyFile, it writes a temporary file next to dest
+// that is renamed once it is complete. copied is false if src or dest is
+// not on a network file system or the server could not copy the file, which
+// is then copied the usual way.
+func serverCopyFile(src, dest string) (copied bool, err error) {
+	if storageType(src) != "network" || storageType(filepath.Dir(dest)) != "network" {
+		return false, nil
+	}
+	if err := checkWritable("copy to", dest); err != nil {
+		return true, err
+	}
+	part := dest + partSuffix
+	if err := copyOnServer(src, part); err != nil {
+		os.Remove(part)
+		if err != errNoServerCopy {
+			log.Printf("Error copying %s on the server, copying it through this computer instead: %v", src, err)
+		}
+		return false, nil
+	}
+	// The server made the copy, so it is only checked for its size instead
+	// of being read back.
+	source, err := os.Stat(src)
+	if err == nil {
+		var copy os.FileInfo
+		if copy, err = os.Stat(part); err == nil && copy.Size() != source.Size() {
+			err = fmt.Errorf("the copy %s has %d bytes instead of %d", part, copy.Size(), source.Size())
+		}
+	}
+	if err == nil {
+		err = preserveMetadata(src, part)
+	}
+	if err == nil {
+		err = os.Rename(part, dest)
+	}
+	if err != nil {
+		os.Remove(part)
+	}
+	return true, err
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package main
+
+import "os"
+
+// copyOnServer copies src to dest with copy_file_range, which the NFS 4.2
+// and SMB clients of the kernel turn into a copy on the server when both
+// files are on the same one. Elsewhere the kernel, or Go for kernels without
+// copy_file_range, copies the data itself.
+func copyOnServer(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	_, err = out.ReadFrom(in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !windows
+
+package main
+
+func copyOnServer(src, dest string) error {
+	return errNoServerCopy
+}
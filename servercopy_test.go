@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServerCopyFile(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	src := filepath.Join(tempDir, "src.bin")
+	ioutil.WriteFile(src, []byte("server-side"), 0644)
+
+	// The test folder is not on a network file system.
+	if copied, err := serverCopyFile(src, filepath.Join(tempDir, "dest.bin")); copied || err != nil {
+		t.Errorf("Expected a local file not to be copied on a server, Got: %v (%v)", copied, err)
+	}
+
+	dest := filepath.Join(tempDir, "copy.bin")
+	err := copyOnServer(src, dest)
+	if err == errNoServerCopy {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(dest); string(data) != "server-side" {
+		t.Errorf("Expected: server-side, Got: %s", data)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procCopyFileW = kernel32.NewProc("CopyFileW")
+
+// copyOnServer copies src to dest with CopyFileW, which has an SMB server
+// copy a file within the server (SMB copy offload), and storage that
+// supports ODX copy it within the storage.
+func copyOnServer(src, dest string) error {
+	from, err := syscall.UTF16PtrFromString(src)
+	if err != nil {
+		return err
+	}
+	to, err := syscall.UTF16PtrFromString(dest)
+	if err != nil {
+		return err
+	}
+	if ok, _, err := procCopyFileW.Call(uintptr(unsafe.Pointer(from)), uintptr(unsafe.Pointer(to)), 0); ok == 0 {
+		return err
+	}
+	return nil
+}
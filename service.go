@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// serviceEnv is set for scans started by a service host so that they log
+// like unattended runs.
+const serviceEnv = "DUPLICATE_FINDER_SERVICE"
+
+// serviceOptions configures the periodic scan installed by install-service.
+type serviceOptions struct {
+	scanArgs   []string
+	onCalendar string        // systemd timer expression
+	interval   time.Duration // scan interval of the Windows service and macOS launch agent
+	user       bool
+	printOnly  bool
+	notify     bool
+}
+
+// runningAsService reports whether the process runs unattended under a
+// service manager, where progress lines and log timestamps only add noise.
+func runningAsService() bool {
+	return underSystemd() || os.Getenv(serviceEnv) != ""
+}
+
+// runInstallService implements the install-service command, which installs a
+// periodic scan with the arguments after "--" using the platform's service manager.
+func runInstallService(args []string) {
+	fs := flag.NewFlagSet("install-service", flag.ExitOnError)
+	var opts serviceOptions
+	fs.StringVar(&opts.onCalendar, "on-calendar", "weekly", "systemd OnCalendar expression for the timer")
+	fs.DurationVar(&opts.interval, "interval", 24*time.Hour, "time between scans of the Windows service or macOS launch agent")
+	fs.BoolVar(&opts.notify, "notify", false, "show a desktop notification after each scan of the macOS launch agent")
+	fs.BoolVar(&opts.user, "user", false, "install user units in ~/.config/systemd/user instead of system units")
+	fs.BoolVar(&opts.printOnly, "print", false, "print the service definition instead of installing it")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s install-service [flags] -- [scan flags] folder\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts.scanArgs = fs.Args()
+	root, err := filepath.Abs(opts.scanArgs[len(opts.scanArgs)-1])
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	opts.scanArgs[len(opts.scanArgs)-1] = root
+	installService(opts)
+}
+
+// runUninstallService implements the uninstall-service command.
+func runUninstallService(args []string) {
+	fs := flag.NewFlagSet("uninstall-service", flag.ExitOnError)
+	var opts serviceOptions
+	fs.BoolVar(&opts.user, "user", false, "remove user units instead of system units")
+	fs.Parse(args)
+	uninstallService(opts)
+}
@@ -0,0 +1,76 @@
+//go:build darwin
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+func launchAgentPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+}
+
+func runLaunchctl(args ...string) error {
+	cmd := exec.Command("launchctl", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// installService installs a per-user launch agent that scans every interval
+// and drops its reports into ~/Library/Application Support/duplicate_finder/reports.
+func installService(opts serviceOptions) {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	support, err := os.UserConfigDir()
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	reportDir := filepath.Join(support, serviceName, "reports")
+	plist := launchdPlist(executable, opts.scanArgs, opts.interval, reportDir, opts.notify)
+	if opts.printOnly {
+		fmt.Print(plist)
+		return
+	}
+
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		log.Fatal("Error:", err)
+	}
+	path := launchAgentPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Fatal("Error:", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(plist), 0644); err != nil {
+		log.Fatal("Error:", err)
+	}
+	fmt.Println(msg("service.written", path))
+	if err := runLaunchctl("load", "-w", path); err != nil {
+		log.Fatal("Error loading launch agent:", err)
+	}
+}
+
+func uninstallService(opts serviceOptions) {
+	path := launchAgentPath()
+	if err := runLaunchctl("unload", "-w", path); err != nil {
+		log.Printf("Error unloading launch agent: %v", err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Fatal("Error:", err)
+	}
+	fmt.Println(msg("service.removed", path))
+}
+
+func runServiceHost(args []string) {
+	log.Fatal("Error: service-run is only available on Windows")
+}
@@ -0,0 +1,17 @@
+//go:build !windows && !darwin
+
+package main
+
+import "log"
+
+func installService(opts serviceOptions) {
+	installSystemdUnits(opts)
+}
+
+func uninstallService(opts serviceOptions) {
+	uninstallSystemdUnits(opts)
+}
+
+func runServiceHost(args []string) {
+	log.Fatal("Error: service-run is only available on Windows")
+}
@@ -0,0 +1,265 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	advapi32                         = syscall.NewLazyDLL("advapi32.dll")
+	procStartServiceCtrlDispatcherW  = advapi32.NewProc("StartServiceCtrlDispatcherW")
+	procRegisterServiceCtrlHandlerEx = advapi32.NewProc("RegisterServiceCtrlHandlerExW")
+	procSetServiceStatus             = advapi32.NewProc("SetServiceStatus")
+	procRegisterEventSourceW         = advapi32.NewProc("RegisterEventSourceW")
+	procDeregisterEventSource        = advapi32.NewProc("DeregisterEventSource")
+	procReportEventW                 = advapi32.NewProc("ReportEventW")
+)
+
+const (
+	serviceWin32OwnProcess = 0x10
+
+	serviceStopped      = 1
+	serviceStartPending = 2
+	serviceStopPending  = 3
+	serviceRunning      = 4
+
+	serviceAcceptStop     = 1
+	serviceAcceptShutdown = 4
+
+	serviceControlStop        = 1
+	serviceControlInterrogate = 4
+	serviceControlShutdown    = 5
+
+	eventlogErrorType       = 1
+	eventlogWarningType     = 2
+	eventlogInformationType = 4
+)
+
+type serviceStatus struct {
+	ServiceType             uint32
+	CurrentState            uint32
+	ControlsAccepted        uint32
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	CheckPoint              uint32
+	WaitHint                uint32
+}
+
+type serviceTableEntry struct {
+	ServiceName *uint16
+	ServiceProc uintptr
+}
+
+// eventLog writes messages to the Windows Application event log.
+type eventLog struct {
+	handle uintptr
+}
+
+func openEventLog(source string) (*eventLog, error) {
+	name, err := syscall.UTF16PtrFromString(source)
+	if err != nil {
+		return nil, err
+	}
+	h, _, err := procRegisterEventSourceW.Call(0, uintptr(unsafe.Pointer(name)))
+	if h == 0 {
+		return nil, err
+	}
+	return &eventLog{handle: h}, nil
+}
+
+func (l *eventLog) report(eventType uint16, text string) {
+	if l == nil {
+		return
+	}
+	s, err := syscall.UTF16PtrFromString(strings.ReplaceAll(text, "\x00", ""))
+	if err != nil {
+		return
+	}
+	strs := []*uint16{s}
+	procReportEventW.Call(l.handle, uintptr(eventType), 0, 1, 0, 1, 0, uintptr(unsafe.Pointer(&strs[0])), 0)
+}
+
+func (l *eventLog) close() {
+	if l != nil {
+		procDeregisterEventSource.Call(l.handle)
+	}
+}
+
+// windowsService runs the scan as a child process every interval until the
+// service control manager asks it to stop.
+type windowsService struct {
+	interval time.Duration
+	scanArgs []string
+	events   *eventLog
+
+	statusHandle uintptr
+	stop         chan struct{}
+	stopOnce     sync.Once
+}
+
+var activeService *windowsService
+
+func (s *windowsService) setStatus(state, accepts uint32) {
+	status := serviceStatus{ServiceType: serviceWin32OwnProcess, CurrentState: state, ControlsAccepted: accepts}
+	if state == serviceStartPending || state == serviceStopPending {
+		status.WaitHint = 30000
+	}
+	procSetServiceStatus.Call(s.statusHandle, uintptr(unsafe.Pointer(&status)))
+}
+
+func (s *windowsService) handler(control, eventType uint32, eventData, context uintptr) uintptr {
+	switch control {
+	case serviceControlStop, serviceControlShutdown:
+		s.setStatus(serviceStopPending, 0)
+		s.stopOnce.Do(func() { close(s.stop) })
+	case serviceControlInterrogate:
+	}
+	return 0
+}
+
+func (s *windowsService) main(argc uint32, argv **uint16) uintptr {
+	name, _ := syscall.UTF16PtrFromString(serviceName)
+	h, _, _ := procRegisterServiceCtrlHandlerEx.Call(uintptr(unsafe.Pointer(name)), syscall.NewCallback(s.handler), 0)
+	if h == 0 {
+		return 0
+	}
+	s.statusHandle = h
+	s.setStatus(serviceStartPending, 0)
+	s.setStatus(serviceRunning, serviceAcceptStop|serviceAcceptShutdown)
+	s.events.report(eventlogInformationType, fmt.Sprintf("Service started, scanning every %s: %s", s.interval, strings.Join(s.scanArgs, " ")))
+
+	for {
+		s.runScan()
+		select {
+		case <-s.stop:
+			s.events.report(eventlogInformationType, "Service stopped")
+			s.setStatus(serviceStopped, 0)
+			return 0
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// forward copies each line of r to the event log with the given type.
+func (s *windowsService) forward(r io.Reader, eventType uint16, wg *sync.WaitGroup) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			s.events.report(eventType, line)
+		}
+	}
+}
+
+// runScan runs one non-interactive scan and forwards its output to the event
+// log. A stop request kills the running scan.
+func (s *windowsService) runScan() {
+	executable, err := os.Executable()
+	if err != nil {
+		s.events.report(eventlogErrorType, err.Error())
+		return
+	}
+	cmd := exec.Command(executable, s.scanArgs...)
+	cmd.Env = append(os.Environ(), serviceEnv+"=1")
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
+	if err := cmd.Start(); err != nil {
+		s.events.report(eventlogErrorType, fmt.Sprintf("Error starting scan: %v", err))
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go s.forward(stdout, eventlogInformationType, &wg)
+	go s.forward(stderr, eventlogWarningType, &wg)
+
+	done := make(chan error, 1)
+	go func() {
+		wg.Wait()
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			s.events.report(eventlogErrorType, fmt.Sprintf("Scan failed: %v", err))
+		}
+	case <-s.stop:
+		cmd.Process.Kill()
+		<-done
+	}
+}
+
+// runServiceHost implements the hidden service-run command that the service
+// control manager starts.
+func runServiceHost(args []string) {
+	fs := flag.NewFlagSet("service-run", flag.ExitOnError)
+	interval := fs.Duration("interval", 24*time.Hour, "time between scans")
+	fs.Parse(args)
+
+	events, err := openEventLog(serviceName)
+	if err != nil {
+		log.Printf("Error opening event log: %v", err)
+	}
+	defer events.close()
+
+	activeService = &windowsService{interval: *interval, scanArgs: fs.Args(), events: events, stop: make(chan struct{})}
+	name, _ := syscall.UTF16PtrFromString(serviceName)
+	table := []serviceTableEntry{{ServiceName: name, ServiceProc: syscall.NewCallback(activeService.main)}, {}}
+	if r, _, err := procStartServiceCtrlDispatcherW.Call(uintptr(unsafe.Pointer(&table[0]))); r == 0 {
+		log.Fatalf("Error connecting to the service control manager (service-run must be started as a service): %v", err)
+	}
+}
+
+func runSC(args ...string) error {
+	cmd := exec.Command("sc.exe", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// installService registers a Windows service that runs the scan every interval.
+func installService(opts serviceOptions) {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	stateDir := filepath.Join(os.Getenv("ProgramData"), serviceName)
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		log.Fatal("Error:", err)
+	}
+	binPath := []string{syscall.EscapeArg(executable), "service-run", "--interval", opts.interval.String(), "--",
+		"--save", syscall.EscapeArg(filepath.Join(stateDir, "results.json"))}
+	for _, arg := range opts.scanArgs {
+		binPath = append(binPath, syscall.EscapeArg(arg))
+	}
+	if opts.printOnly {
+		fmt.Printf("sc.exe create %s binPath= %s start= auto\n", serviceName, syscall.EscapeArg(strings.Join(binPath, " ")))
+		return
+	}
+	if err := runSC("create", serviceName, "binPath=", strings.Join(binPath, " "), "start=", "auto", "DisplayName=", "Duplicate Finder"); err != nil {
+		log.Fatal("Error creating service:", err)
+	}
+	runSC("description", serviceName, "Periodically scans for duplicate files")
+	if err := runSC("start", serviceName); err != nil {
+		log.Fatal("Error starting service:", err)
+	}
+}
+
+func uninstallService(opts serviceOptions) {
+	runSC("stop", serviceName)
+	if err := runSC("delete", serviceName); err != nil {
+		log.Fatal("Error deleting service:", err)
+	}
+}
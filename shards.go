@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// shardDepth, set by --shard-depth, makes the scan process every folder this
+// many levels below the root as a shard of its own, and the files above
+// them as one more. Each shard is checkpointed once it is hashed, so that
+// only the files with duplicates are held in memory for the whole tree, and
+// an interrupted scan resumes after the last shard done.
+var shardDepth int
+
+// shardDirs are the folders scanned as shards; the scan of the root leaves
+// them out.
+var shardDirs map[string]bool
+
+// shardStateDir is the folder in the state directory that holds the shard
+// checkpoints, one folder for every root.
+const shardStateDir = "shards"
+
+// shardsComplete marks a checkpoint folder whose scan finished.
+const shardsComplete = "complete"
+
+// shardEntry is a file in the checkpoint of a shard.
+type shardEntry struct {
+	Path       string            `json:"path"`
+	Hash       string            `json:"hash"`
+	Size       int64             `json:"size"`
+	ModTime    time.Time         `json:"mtime"`
+	AccessTime time.Time         `json:"atime"`
+	Digests    map[string]string `json:"digests,omitempty"`
+}
+
+func (e shardEntry) file() File {
+	return File{path: indexPath(e.Path), Hash: e.Hash, Size: e.Size, ModTime: e.ModTime, AccessTime: e.AccessTime, Digests: e.Digests}
+}
+
+// fileHashCache holds the hashes of files from an earlier scan, by path.
+type fileHashCache map[string]File
+
+// hashCache is set while a shard is scanned to the files of its checkpoint
+// from the last complete scan.
+var hashCache fileHashCache
+
+// lookup returns the cached file at path if it still has the size and
+// modification time it was hashed with.
+func (c fileHashCache) lookup(path string) (File, bool) {
+	cached, ok := c[path]
+	if !ok {
+		return File{}, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() != cached.Size || !info.ModTime().Equal(cached.ModTime) {
+		return File{}, false
+	}
+	cached.AccessTime = accessTime(info)
+	return cached, true
+}
+
+// shardStore keeps the checkpoints of the shards of a root: those of the
+// running scan in current, and those of the last complete scan, which serve
+// as hash cache, in previous.
+type shardStore struct {
+	dir               string
+	current, previous string
+}
+
+// shardConfig describes what the hashes of a checkpoint depend on; the
+// checkpoints of scans with other settings are discarded.
+func shardConfig() string {
+	names := []string{scanHash.name}
+	for _, extra := range extraHashes {
+		names = append(names, extra.name)
+	}
+	return fmt.Sprintf("hash=%s sample-over=%d screen=%v\n", strings.Join(names, ","), sampleOver, screenFiles)
+}
+
+// openShardStore opens the checkpoints of root in the state directory. The
+// checkpoints of an interrupted scan are resumed; those of a complete one
+// become the hash cache of the new scan.
+func openShardStore(root string) (shardStore, error) {
+	state, err := stateDir()
+	if err != nil {
+		return shardStore{}, err
+	}
+	key, err := rootKey(root)
+	if err != nil {
+		return shardStore{}, err
+	}
+	dir := filepath.Join(state, shardStateDir, key)
+	store := shardStore{dir: dir, current: filepath.Join(dir, "current"), previous: filepath.Join(dir, "previous")}
+	configPath := filepath.Join(dir, "config")
+	if config, err := ioutil.ReadFile(configPath); err == nil && string(config) != shardConfig() {
+		if err := os.RemoveAll(dir); err != nil {
+			return shardStore{}, err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(store.current, shardsComplete)); err == nil {
+		if err := os.RemoveAll(store.previous); err != nil {
+			return shardStore{}, err
+		}
+		if err := os.Rename(store.current, store.previous); err != nil {
+			return shardStore{}, err
+		}
+	}
+	if err := os.MkdirAll(store.current, 0755); err != nil {
+		return shardStore{}, err
+	}
+	return store, ioutil.WriteFile(configPath, []byte(shardConfig()), 0644)
+}
+
+// shardName returns the file name of the checkpoint of the shard at dir.
+func shardName(dir string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(filepath.Clean(dir))))[:16] + ".jsonl"
+}
+
+// done reports whether the running scan has checkpointed the shard at dir.
+func (s shardStore) done(dir string) bool {
+	_, err := os.Stat(filepath.Join(s.current, shardName(dir)))
+	return err == nil
+}
+
+// cache returns the files of the shard at dir from the last complete scan.
+func (s shardStore) cache(dir string) fileHashCache {
+	cache := make(fileHashCache)
+	readShard(filepath.Join(s.previous, shardName(dir)), func(e shardEntry) { cache[e.Path] = e.file() })
+	return cache
+}
+
+// save writes the checkpoint of the shard at dir with the files of fileMap.
+func (s shardStore) save(dir string, fileMap map[string][]File) error {
+	f, err := ioutil.TempFile(s.current, "shard-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, files := range fileMap {
+		for _, file := range files {
+			if err := enc.Encode(shardEntry{file.Path(), file.Hash, file.Size, file.ModTime, file.AccessTime, file.Digests}); err != nil {
+				f.Close()
+				return err
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(s.current, shardName(dir)))
+}
+
+// complete marks the scan finished and drops the cache it was made with.
+func (s shardStore) complete() error {
+	if err := ioutil.WriteFile(filepath.Join(s.current, shardsComplete), nil, 0644); err != nil {
+		return err
+	}
+	return os.RemoveAll(s.previous)
+}
+
+// readShard calls fn with every file of the checkpoint at path; a missing
+// checkpoint has no files.
+func readShard(path string, fn func(shardEntry)) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var e shardEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		fn(e)
+	}
+}
+
+// shardFolders returns the folders depth levels below root, leaving out the
+// ones the walk skips.
+func shardFolders(root string, depth int) ([]string, error) {
+	root = filepath.Clean(root)
+	filter := newWalkFilter(root)
+	var shards []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		if filter.skip(path, info) {
+			return filepath.SkipDir
+		}
+		if rel, _ := filepath.Rel(root, path); rel != "." && strings.Count(filepath.ToSlash(rel), "/")+1 == depth {
+			shards = append(shards, filepath.Clean(path))
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return shards, err
+}
+
+// scanShards scans the roots like scanRoots, but shard by shard, one after
+// the other (see shardDepth). The result only holds the files that have
+// duplicates; its progress counts the files of all shards, also of the ones
+// checkpointed by an interrupted earlier scan.
+func scanShards(roots []string, onProgress func(scanProgress), control *scanControl) (map[string][]File, scanProgress, error) {
+	var total scanProgress
+	var checkpoints []string
+	var partial map[string][]File // of a shard the scan stopped in
+	var stores []shardStore
+	var scanErr error
+scan:
+	for _, root := range roots {
+		store, err := openShardStore(root)
+		if err != nil {
+			return nil, scanProgress{}, err
+		}
+		stores = append(stores, store)
+		shards, err := shardFolders(root, shardDepth)
+		if err != nil {
+			return nil, scanProgress{}, err
+		}
+		shardDirs = make(map[string]bool, len(shards))
+		for _, dir := range shards {
+			shardDirs[dir] = true
+		}
+		for _, dir := range append(shards, filepath.Clean(root)) {
+			checkpoint := filepath.Join(store.current, shardName(dir))
+			if store.done(dir) {
+				checkpoints = append(checkpoints, checkpoint)
+				continue
+			}
+			hashCache = store.cache(dir)
+			shardMap, progress, err := scanFolder(dir, func(p scanProgress) {
+				p.Scanned += total.Scanned
+				p.Files += total.Files
+				p.Errors += total.Errors
+				p.TotalSize += total.TotalSize
+				p.FoundSize += total.FoundSize
+				control.update(p)
+				if onProgress != nil {
+					onProgress(p)
+				}
+			}, control)
+			hashCache = nil
+			total.Files += progress.Files
+			total.Errors += progress.Errors
+			total.FoundSize += progress.FoundSize
+			total.Failures = append(total.Failures, progress.Failures...)
+			total.Workers = progress.Workers
+			if err != nil {
+				partial, scanErr = shardMap, err
+				break scan
+			}
+			if err := store.save(dir, shardMap); err != nil {
+				return nil, scanProgress{}, err
+			}
+			checkpoints = append(checkpoints, checkpoint)
+			total.Scanned += progress.Scanned
+			total.TotalSize += progress.TotalSize
+		}
+	}
+	shardDirs = nil
+
+	sortDir, err := ioutil.TempDir(stores[0].dir, "sort-")
+	if err != nil {
+		return nil, scanProgress{}, err
+	}
+	defer os.RemoveAll(sortDir)
+	fileMap, scanned, size, err := mergeShards(checkpoints, partial, sortDir)
+	if err != nil {
+		return nil, scanProgress{}, err
+	}
+	total.Scanned, total.TotalSize = scanned, size
+	if total.Files < scanned {
+		total.Files = scanned
+	}
+	if scanErr == nil {
+		for _, store := range stores {
+			if err := store.complete(); err != nil {
+				return nil, scanProgress{}, err
+			}
+		}
+	}
+	control.update(total)
+	return fileMap, total, scanErr
+}
+
+// mergeShards groups the files of the checkpoints and of partial by hash
+// with an external sort in dir, so that only the files with duplicates are
+// held in memory. It returns the number and size of all files.
+func mergeShards(checkpoints []string, partial map[string][]File, dir string) (fileMap map[string][]File, scanned int, size int64, err error) {
+	sorter := newExternalSorter(dir)
+	defer sorter.close()
+	add := func(e shardEntry) {
+		scanned++
+		size += e.Size
+		if err == nil {
+			err = sorter.add(e)
+		}
+	}
+	for _, files := range partial {
+		for _, file := range files {
+			add(shardEntry{file.Path(), file.Hash, file.Size, file.ModTime, file.AccessTime, file.Digests})
+		}
+	}
+	for _, path := range checkpoints {
+		if readErr := readShard(path, add); readErr != nil {
+			return nil, 0, 0, readErr
+		}
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	fileMap = make(map[string][]File)
+	if err := sorter.groups(func(files []File) { fileMap[files[0].Hash] = files }); err != nil {
+		return nil, 0, 0, err
+	}
+	return fileMap, scanned, size, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestTree creates the files under dir, by slash-separated name.
+func writeTestTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestShardFolders(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	writeTestTree(t, tempDir, map[string]string{"a/b/f": "", "a/c/f": "", "d/f": "", "e": "", "node_modules/x/f": ""})
+	tests := []struct {
+		depth    int
+		expected string
+	}{
+		{1, "a d"},
+		{2, "a/b a/c"},
+	}
+	for _, test := range tests {
+		shards, err := shardFolders(tempDir, test.depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, dir := range shards {
+			rel, _ := filepath.Rel(tempDir, dir)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		if got := strings.Join(names, " "); got != test.expected {
+			t.Errorf("Depth %d: Expected: %s, Got: %s", test.depth, test.expected, got)
+		}
+	}
+}
+
+func TestScanShards(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("STATE_DIRECTORY", os.Getenv("STATE_DIRECTORY"))
+	defer func(depth int) { shardDepth = depth }(shardDepth)
+	os.Setenv("STATE_DIRECTORY", filepath.Join(tempDir, "state"))
+	root := filepath.Join(tempDir, "root")
+	writeTestTree(t, root, map[string]string{
+		"a/one":   "same",
+		"b/two":   "same",
+		"three":   "same",
+		"a/other": "unique",
+		"b/x/y":   "also unique",
+	})
+	shardDepth = 1
+
+	groups := func(fileMap map[string][]File) string {
+		var lines []string
+		for _, group := range duplicateGroups(fileMap) {
+			var names []string
+			for _, file := range group.Files {
+				rel, _ := filepath.Rel(root, file.Path())
+				names = append(names, filepath.ToSlash(rel))
+			}
+			sort.Strings(names)
+			lines = append(lines, strings.Join(names, " "))
+		}
+		return strings.Join(lines, "; ")
+	}
+	fileMap, progress, err := scanShards([]string{root}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := groups(fileMap); got != "a/one b/two three" || len(fileMap) != 1 {
+		t.Errorf("Expected one group across the shards, Got: %s (%d entries)", got, len(fileMap))
+	}
+	if progress.Scanned != 5 || progress.TotalSize != 4*3+6+11 {
+		t.Errorf("Expected all files to be counted, Got: %d files, %d bytes", progress.Scanned, progress.TotalSize)
+	}
+
+	// An interrupted scan resumes after the shards it checkpointed.
+	store, err := openShardStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(store.previous, shardName(filepath.Join(root, "a")))); err != nil {
+		t.Errorf("Expected the complete scan to become the cache: %v", err)
+	}
+	checkpointed := map[string][]File{"fake": {{path: indexPath(filepath.Join(root, "a", "one")), Hash: "fake"}, {path: indexPath(filepath.Join(root, "a", "other")), Hash: "fake"}}}
+	if err := store.save(filepath.Join(root, "a"), checkpointed); err != nil {
+		t.Fatal(err)
+	}
+	fileMap, _, err = scanShards([]string{root}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := groups(fileMap); got != "b/two three; a/one a/other" && got != "a/one a/other; b/two three" {
+		t.Errorf("Expected the checkpoint of a to be used, Got: %s", got)
+	}
+}
+
+func TestFileHashCache(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "file")
+	ioutil.WriteFile(path, []byte("data"), 0644)
+	info, _ := os.Stat(path)
+	cache := fileHashCache{path: {path: indexPath(path), Hash: "cached", Size: info.Size(), ModTime: info.ModTime()}}
+	if file, ok := cache.lookup(path); !ok || file.Hash != "cached" {
+		t.Errorf("Expected the cached hash, Got: %v", file)
+	}
+	later := info.ModTime().Add(time.Second)
+	os.Chtimes(path, later, later)
+	if _, ok := cache.lookup(path); ok {
+		t.Error("Expected a changed file to be hashed again")
+	}
+	if _, ok := cache.lookup(filepath.Join(tempDir, "missing")); ok {
+		t.Error("Expected no hash for an unknown file")
+	}
+}
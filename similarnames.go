@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// versionToken matches the words of a file name that tell versions of a
+// document apart, such as "v2", "rev3", "final" or "draft".
+var versionToken = regexp.MustCompile(`^(v\d+|ver\d*|version|rev\d*|revised|final|finale|draft|new|old|copy|updated|latest|kopie|copie|entwurf|endgültig|neu|alt|borrador|brouillon)$`)
+
+// similarNameDistance is the largest number of letters in which the names
+// of two versions of a document can differ, besides their version words.
+const similarNameDistance = 2
+
+// nameTokens splits the name of the file at path, without its extension and
+// copy suffix, into lower-case words and numbers.
+func nameTokens(path string) []string {
+	if original, ok := copyOriginal(path); ok {
+		path = original
+	}
+	name := filepath.Base(path)
+	name = strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	return strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+// nameKey returns the words of a file name that are not version words, and
+// its numbers. Names with different numbers, such as IMG_0001 and IMG_0002
+// or budget 2023 and budget 2024, are different documents.
+func nameKey(path string) (words, numbers string) {
+	var w, n []string
+	for _, token := range nameTokens(path) {
+		switch {
+		case versionToken.MatchString(token):
+		case strings.IndexFunc(token, func(r rune) bool { return !unicode.IsDigit(r) }) < 0:
+			n = append(n, token)
+		default:
+			w = append(w, token)
+		}
+	}
+	return strings.Join(w, " "), strings.Join(n, " ")
+}
+
+// editDistance returns the Levenshtein distance of a and b in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// similarNames reports whether the names of the files at a and b look like
+// versions of one document: the same words once version words are left out,
+// or words that differ in at most similarNameDistance letters, and the same
+// numbers.
+func similarNames(a, b string) bool {
+	wordsA, numbersA := nameKey(a)
+	wordsB, numbersB := nameKey(b)
+	if numbersA != numbersB || wordsA == "" || wordsB == "" {
+		return false
+	}
+	if wordsA == wordsB {
+		return true
+	}
+	// Short names differ in a letter or two without being related.
+	shorter := len([]rune(wordsA))
+	if n := len([]rune(wordsB)); n < shorter {
+		shorter = n
+	}
+	return shorter >= 3*similarNameDistance && editDistance(wordsA, wordsB) <= similarNameDistance
+}
+
+// similarNameClusters groups the files of fileMap that have different
+// content but similar names, in the same folder and with the same extension,
+// so that versions of a document that content hashing cannot group can be
+// reviewed. Each group of duplicates takes part with its first file. Files
+// of a cluster are sorted by name.
+func similarNameClusters(fileMap map[string][]File) [][]File {
+	byFolder := make(map[[2]string][]File)
+	for _, files := range fileMap {
+		file := files[0]
+		key := [2]string{filepath.Dir(file.Path()), strings.ToLower(filepath.Ext(file.Path()))}
+		byFolder[key] = append(byFolder[key], file)
+	}
+
+	var clusters [][]File
+	for _, files := range byFolder {
+		if len(files) < 2 {
+			continue
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Path() < files[j].Path() })
+		// Union-find over the files of the folder.
+		parent := make([]int, len(files))
+		for i := range parent {
+			parent[i] = i
+		}
+		var find func(int) int
+		find = func(i int) int {
+			if parent[i] != i {
+				parent[i] = find(parent[i])
+			}
+			return parent[i]
+		}
+		for i := range files {
+			for j := i + 1; j < len(files); j++ {
+				if similarNames(files[i].Path(), files[j].Path()) {
+					parent[find(j)] = find(i)
+				}
+			}
+		}
+		members := make(map[int][]File)
+		for i, file := range files {
+			root := find(i)
+			members[root] = append(members[root], file)
+		}
+		for _, cluster := range members {
+			if len(cluster) > 1 {
+				clusters = append(clusters, cluster)
+			}
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i][0].Path() < clusters[j][0].Path() })
+	return clusters
+}
+
+// writeSimilarNames writes the clusters of similarNameClusters with the size
+// and modification time of every file.
+func writeSimilarNames(w io.Writer, clusters [][]File) {
+	if len(clusters) == 0 {
+		return
+	}
+	fmt.Fprintln(w, msg("similar.found", formatCount(int64(len(clusters)))))
+	for _, files := range clusters {
+		for _, file := range files {
+			fmt.Fprintf(w, "%s (%s, %s)\n", file.Path(), humanReadableSize(file.Size), file.ModTime.Format("2006-01-02 15:04"))
+		}
+		fmt.Fprintln(w)
+	}
+}
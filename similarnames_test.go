@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimilarNames(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"/d/report.docx", "/d/report_v2.docx", true},
+		{"/d/Report final.docx", "/d/report-draft.docx", true},
+		{"/d/report.docx", "/d/report (1).docx", true},
+		{"/d/quarterly report.docx", "/d/quarterley report.docx", true},
+		{"/d/budget 2023.xlsx", "/d/budget 2024.xlsx", false},
+		{"/d/IMG_0001.jpg", "/d/IMG_0002.jpg", false},
+		{"/d/cat.txt", "/d/car.txt", false},
+		{"/d/invoice.pdf", "/d/receipt.pdf", false},
+		{"/d/v2.txt", "/d/v3.txt", false},
+	}
+	for _, test := range tests {
+		if got := similarNames(test.a, test.b); got != test.expected {
+			t.Errorf("%s and %s: Expected: %v, Got: %v", test.a, test.b, test.expected, got)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"größe", "grösse", 2},
+		{"same", "same", 0},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.expected {
+			t.Errorf("%s, %s: Expected: %d, Got: %d", test.a, test.b, test.expected, got)
+		}
+	}
+}
+
+func TestSimilarNameClusters(t *testing.T) {
+	fileMap := map[string][]File{
+		"1": {{path: indexPath("/d/thesis.tex")}},
+		"2": {{path: indexPath("/d/thesis_v2.tex")}, {path: indexPath("/backup/thesis_v2.tex")}},
+		"3": {{path: indexPath("/d/thesis final.tex")}},
+		"4": {{path: indexPath("/d/thesis.pdf")}},
+		"5": {{path: indexPath("/other/thesis_v3.tex")}},
+		"6": {{path: indexPath("/d/notes.tex")}},
+	}
+	clusters := similarNameClusters(fileMap)
+	if len(clusters) != 1 || len(clusters[0]) != 3 {
+		t.Fatalf("Unexpected clusters: %v", clusters)
+	}
+	var paths []string
+	for _, file := range clusters[0] {
+		paths = append(paths, file.Path())
+	}
+	if got := strings.Join(paths, " "); got != "/d/thesis final.tex /d/thesis.tex /d/thesis_v2.tex" {
+		t.Errorf("Unexpected cluster: %s", got)
+	}
+
+	var out bytes.Buffer
+	clusters[0][0].ModTime = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	writeSimilarNames(&out, clusters)
+	if !strings.Contains(out.String(), "/d/thesis final.tex (0.00 B, 2024-05-01 10:00)") {
+		t.Errorf("Unexpected report: %s", out.String())
+	}
+}
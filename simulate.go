@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// simulation totals the outcome of applying a keep policy to all groups.
+type simulation struct {
+	Kept    int
+	Removed int
+	Bytes   int64
+}
+
+// simulate writes, for every group, which files the policy keeps and which
+// it would remove, followed by the totals. With verbose, the scores that
+// decided which file is kept are shown as well. Nothing is changed on disk.
+func simulate(w io.Writer, groups []Group, policy keepPolicy, verbose bool) simulation {
+	var sim simulation
+	for _, group := range groups {
+		keep, remove := policy.apply(group)
+		// Scores only decide groups without protected files.
+		scores := make(map[string]float64)
+		if verbose && len(keep) == 1 && !policy.protected(keep[0].Path()) {
+			for i, score := range policy.scores(group.Files) {
+				scores[group.Files[i].Path()] = score
+			}
+		}
+		line := func(key string, file File) string {
+			text := msg(key, file.Path())
+			if score, ok := scores[file.Path()]; ok {
+				text += "  " + msg("simulate.score", formatDecimal(score, 2))
+			}
+			return text
+		}
+		fmt.Fprintln(w, msg("list.group", group.ID, group.Hash))
+		for _, file := range keep {
+			fmt.Fprintln(w, line("simulate.keep", file))
+		}
+		for _, file := range remove {
+			fmt.Fprintln(w, line("simulate.remove", file))
+			sim.Bytes += file.Size
+		}
+		fmt.Fprintln(w)
+		sim.Kept += len(keep)
+		sim.Removed += len(remove)
+	}
+	fmt.Fprintln(w, msg("simulate.total", formatCount(int64(sim.Kept)), formatCount(int64(sim.Removed)), humanReadableSize(sim.Bytes)))
+	return sim
+}
+
+// runSimulate implements the simulate command, which reports what a cleanup
+// with a keep policy would do, using saved results or a new scan.
+func runSimulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	keep := fs.String("keep", "first", "copy to keep: "+strings.Join(keepPolicies, ", "))
+	keepExpr := fs.String("keep-expr", "", "keep the copy with the highest score of this expression instead, e.g. 'path contains \"/master/\" ? 100 : mtime'")
+	keepWeights := fs.String("keep-weights", "", "keep the copy with the highest weighted score of these criteria instead, e.g. 'path:/master=10,age=1,name=2'")
+	var protect stringList
+	fs.Var(&protect, "protect", "never remove files below this folder (repeatable)")
+	resultsPath := fs.String("results", "", "simulate on a results file written by --save instead of scanning")
+	verbose := fs.Bool("verbose", false, "show the score of every file with --keep-expr or --keep-weights")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s simulate [flags] (--results file | folder)\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if (*resultsPath == "") == (fs.NArg() == 0) {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	policy, err := newKeepPolicy(*keep, protect)
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	if *keepExpr != "" && *keepWeights != "" {
+		log.Fatal("Error: --keep-expr and --keep-weights cannot be combined")
+	}
+	if *keepExpr != "" {
+		if policy.expr, err = parseKeepExpr(*keepExpr); err != nil {
+			log.Fatal("Error:", err)
+		}
+	}
+	if *keepWeights != "" {
+		if policy.weights, err = parseKeepWeights(*keepWeights); err != nil {
+			log.Fatal("Error:", err)
+		}
+	}
+
+	var groups []Group
+	if *resultsPath != "" {
+		results, err := loadResults(*resultsPath)
+		if err != nil {
+			log.Fatalf("Error loading results from %s: %v", *resultsPath, err)
+		}
+		groups = results.Groups
+	} else {
+		fileMap, _, err := scanFolder(formatPath(fs.Arg(0)), nil, nil)
+		if err != nil {
+			log.Fatal("Error:", err)
+		}
+		groups = duplicateGroups(fileMap)
+	}
+	simulate(os.Stdout, groups, policy, *verbose)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	groups := duplicateGroups(map[string][]File{
+		"aaaaaaaaaaaaaaaa": {{path: indexPath("/data/a1"), Size: 100}, {path: indexPath("/data/a2"), Size: 100}, {path: indexPath("/data/a3"), Size: 100}},
+		"bbbbbbbbbbbbbbbb": {{path: indexPath("/data/b1"), Size: 10}, {path: indexPath("/master/b2"), Size: 10}},
+	})
+	policy, err := newKeepPolicy("first", []string{"/master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	sim := simulate(&out, groups, policy, false)
+	if sim != (simulation{Kept: 2, Removed: 3, Bytes: 210}) {
+		t.Errorf("Unexpected simulation: %+v", sim)
+	}
+	expected := []string{
+		"  keep    /data/a1\n  remove  /data/a2\n  remove  /data/a3\n",
+		"  keep    /master/b2\n  remove  /data/b1\n",
+		"Would keep 2 files and remove 3 files, reclaiming 210.00 B.",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Expected output to contain %q, Got: %s", s, out.String())
+		}
+	}
+}
+
+func TestSimulateVerbose(t *testing.T) {
+	groups := duplicateGroups(map[string][]File{
+		"aaaaaaaaaaaaaaaa": {{path: indexPath("/data/a1"), Size: 100}, {path: indexPath("/data/sub/a2"), Size: 100}},
+		"bbbbbbbbbbbbbbbb": {{path: indexPath("/data/b1"), Size: 10}, {path: indexPath("/master/b2"), Size: 10}},
+	})
+	policy, err := newKeepPolicy("first", []string{"/master"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.weights, err = parseKeepWeights("depth=-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	simulate(&out, groups, policy, true)
+	expected := []string{
+		"  keep    /data/sub/a2  (score 0.00)\n  remove  /data/a1  (score -2.00)\n",
+		"  keep    /master/b2\n  remove  /data/b1\n",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Expected output to contain %q, Got: %s", s, out.String())
+		}
+	}
+}
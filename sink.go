@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resultSink, if not nil, sends every hashed file to a coordinator while the
+// scan runs (--sink) instead of keeping it for the review of this run, so
+// that a central service can find the duplicates of a fleet of machines.
+var resultSink *sink
+
+// sinkBatchSize is the number of files sent to a sink at once.
+var sinkBatchSize = 1000
+
+// sinkRetries is how often a batch that failed is sent again, after
+// sinkRetryDelay, which doubles after every attempt.
+const sinkRetries = 3
+
+var sinkRetryDelay = time.Second
+
+// sinkFile is a hashed file as sent to a sink.
+type sinkFile struct {
+	Path    string    `json:"path"`
+	Hash    string    `json:"hash"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// sinkBatch is one message to a sink. The batches of a run share its ID and
+// are numbered from 1, so a coordinator can drop the ones sent again after
+// a retry; the last one of a complete scan has Done set and its totals.
+type sinkBatch struct {
+	Run          string     `json:"run"`
+	Host         string     `json:"host"`
+	Root         string     `json:"root"`
+	Seq          int        `json:"seq"`
+	Files        []sinkFile `json:"files,omitempty"`
+	Done         bool       `json:"done,omitempty"`
+	FilesScanned int        `json:"files_scanned,omitempty"`
+	Errors       int        `json:"errors,omitempty"`
+}
+
+// sinkTarget delivers the batches of a sink.
+type sinkTarget interface {
+	send(batch sinkBatch) error
+	close() error
+}
+
+// httpSinkTarget posts every batch as JSON to a URL.
+type httpSinkTarget struct{ url string }
+
+func (t httpSinkTarget) send(batch sinkBatch) error { return postJSON(t.url, batch) }
+func (t httpSinkTarget) close() error               { return nil }
+
+// commandSinkTarget writes every batch as a JSON line to the standard input
+// of a command, such as the producer of a message queue.
+type commandSinkTarget struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	enc   *json.Encoder
+}
+
+func (t *commandSinkTarget) send(batch sinkBatch) error { return t.enc.Encode(batch) }
+
+func (t *commandSinkTarget) close() error {
+	t.stdin.Close()
+	return t.cmd.Wait()
+}
+
+// parseSinkTarget returns the target of a --sink value: an http or https URL,
+// or exec:COMMAND, which is started right away.
+func parseSinkTarget(spec string) (sinkTarget, error) {
+	switch {
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return httpSinkTarget{spec}, nil
+	case strings.HasPrefix(spec, "exec:"):
+		args, err := splitCommand(strings.TrimPrefix(spec, "exec:"))
+		if err != nil {
+			return nil, err
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &commandSinkTarget{cmd: cmd, stdin: stdin, enc: json.NewEncoder(stdin)}, nil
+	}
+	return nil, fmt.Errorf("invalid sink %q: expected an http(s) URL or exec:COMMAND", spec)
+}
+
+// sink collects hashed files into batches, which a goroutine delivers to
+// its target while the scan goes on. If a batch cannot be delivered, the
+// scan is canceled, since its files would be lost.
+type sink struct {
+	target  sinkTarget
+	control *scanControl
+	header  sinkBatch // the run, host and root of every batch
+
+	mu    sync.Mutex
+	files []sinkFile
+	seq   int
+	sent  int
+
+	queue chan sinkBatch
+	done  chan struct{}
+	err   error // of the delivery, read once done is closed
+}
+
+func newSink(target sinkTarget, root string, control *scanControl) *sink {
+	id := make([]byte, 8)
+	rand.Read(id)
+	host, _ := os.Hostname()
+	s := &sink{
+		target:  target,
+		control: control,
+		header:  sinkBatch{Run: hex.EncodeToString(id), Host: host, Root: root},
+		queue:   make(chan sinkBatch, 2), // holds the scan back if the target is slow
+		done:    make(chan struct{}),
+	}
+	go s.deliver()
+	return s
+}
+
+// add records a hashed file.
+func (s *sink) add(file File) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files = append(s.files, sinkFile{file.Path(), file.Hash, file.Size, file.ModTime})
+	if len(s.files) >= sinkBatchSize {
+		s.flush()
+	}
+}
+
+// flush queues the files added since the last batch; s.mu is held.
+func (s *sink) flush() {
+	if len(s.files) == 0 {
+		return
+	}
+	s.seq++
+	batch := s.header
+	batch.Seq, batch.Files = s.seq, s.files
+	s.sent += len(s.files)
+	s.files = nil
+	s.queue <- batch
+}
+
+func (s *sink) deliver() {
+	defer close(s.done)
+	for batch := range s.queue {
+		if s.err != nil {
+			continue
+		}
+		delay := sinkRetryDelay
+		for attempt := 0; ; attempt++ {
+			err := s.target.send(batch)
+			if err == nil {
+				break
+			}
+			if attempt == sinkRetries {
+				s.err = err
+				s.control.cancel()
+				break
+			}
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+}
+
+// finish sends the files that are left and, if the scan is complete, the
+// last batch with its totals. It returns the number of files sent.
+func (s *sink) finish(progress scanProgress, complete bool) (int, error) {
+	s.mu.Lock()
+	s.flush()
+	if complete {
+		s.seq++
+		last := s.header
+		last.Seq, last.Done, last.FilesScanned, last.Errors = s.seq, true, progress.Scanned, progress.Errors
+		s.queue <- last
+	}
+	sent := s.sent
+	s.mu.Unlock()
+	close(s.queue)
+	<-s.done
+	if err := s.target.close(); s.err == nil {
+		s.err = err
+	}
+	return sent, s.err
+}
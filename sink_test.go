@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSinkBatches(t *testing.T) {
+	defer func(size int) { sinkBatchSize = size }(sinkBatchSize)
+	sinkBatchSize = 2
+	var mu sync.Mutex
+	var batches []sinkBatch
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch sinkBatch
+		json.NewDecoder(r.Body).Decode(&batch)
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	target, err := parseSinkTarget(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSink(target, "/data", newScanControl())
+	for _, path := range []string{"/data/a", "/data/b", "/data/c"} {
+		s.add(File{path: indexPath(path), Hash: "hash", Size: 4})
+	}
+	sent, err := s.finish(scanProgress{Scanned: 3, Errors: 1}, true)
+	if err != nil || sent != 3 {
+		t.Fatalf("Expected: 3 files sent, Got: %d (%v)", sent, err)
+	}
+	if len(batches) != 3 {
+		t.Fatalf("Expected: 3 batches, Got: %+v", batches)
+	}
+	for i, batch := range batches {
+		if batch.Seq != i+1 || batch.Run != batches[0].Run || batch.Root != "/data" {
+			t.Errorf("Unexpected batch %d: %+v", i, batch)
+		}
+	}
+	if len(batches[0].Files) != 2 || batches[1].Files[0].Path != "/data/c" {
+		t.Errorf("Unexpected files: %+v", batches)
+	}
+	if last := batches[2]; !last.Done || last.FilesScanned != 3 || last.Errors != 1 || len(last.Files) != 0 {
+		t.Errorf("Unexpected last batch: %+v", last)
+	}
+}
+
+func TestSinkFailure(t *testing.T) {
+	defer func(delay time.Duration) { sinkRetryDelay = delay }(sinkRetryDelay)
+	sinkRetryDelay = time.Millisecond
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	control := newScanControl()
+	s := newSink(httpSinkTarget{server.URL}, "/data", control)
+	s.add(File{path: indexPath("/data/a"), Hash: "hash"})
+	if _, err := s.finish(scanProgress{Scanned: 1}, true); err == nil {
+		t.Errorf("Expected an error from a failing sink")
+	}
+	if !control.isCanceled() || attempts != sinkRetries+1 {
+		t.Errorf("Expected the scan to be canceled after %d attempts, Got: %v, %d", sinkRetries+1, control.isCanceled(), attempts)
+	}
+}
+
+func TestCommandSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	out := filepath.Join(tempDir, "batches.jsonl")
+
+	target, err := parseSinkTarget(`exec:sh -c "cat > ` + out + `"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSink(target, tempDir, nil)
+	s.add(File{path: indexPath("a"), Hash: "hash", Size: 1})
+	if sent, err := s.finish(scanProgress{Scanned: 1}, true); err != nil || sent != 1 {
+		t.Fatalf("Expected: 1 file sent, Got: %d (%v)", sent, err)
+	}
+	data, _ := ioutil.ReadFile(out)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"done":true`) {
+		t.Errorf("Unexpected output: %s", data)
+	}
+}
+
+func TestParseSinkTarget(t *testing.T) {
+	for _, spec := range []string{"ftp://host", "queue", "exec:"} {
+		if _, err := parseSinkTarget(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
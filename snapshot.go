@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// snapshotFile is a file of a live tree compared with a backup snapshot.
+type snapshotFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// snapshotComparison sorts the files of a live tree by how they compare with
+// the file at the same path in a backup snapshot.
+type snapshotComparison struct {
+	Identical []snapshotFile `json:"identical"` // bit-identical, safe to prune
+	Changed   []snapshotFile `json:"changed"`   // different size or content
+	New       []snapshotFile `json:"new"`       // not in the snapshot
+	Errors    int            `json:"errors"`
+}
+
+// compareSnapshot compares every file below live with the file at the same
+// relative path below snapshot, which is only read. A snapshot inside the
+// live tree is left out of the walk.
+func compareSnapshot(live, snapshot string) (snapshotComparison, error) {
+	live, snapshot = filepath.Clean(live), filepath.Clean(snapshot)
+	var c snapshotComparison
+	err := filepath.Walk(live, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path == snapshot {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(live, path)
+		if err != nil {
+			return err
+		}
+		file := snapshotFile{Path: path, Size: info.Size()}
+		backup, err := os.Lstat(filepath.Join(snapshot, rel))
+		if os.IsNotExist(err) || (err == nil && !backup.Mode().IsRegular()) {
+			c.New = append(c.New, file)
+			return nil
+		}
+		if err != nil {
+			log.Printf("Error comparing %s with the snapshot: %v", path, err)
+			c.Errors++
+			return nil
+		}
+		if backup.Size() != info.Size() {
+			c.Changed = append(c.Changed, file)
+			return nil
+		}
+		same := os.SameFile(info, backup)
+		if !same {
+			if same, err = sameContent(File{path: indexPath(path)}, File{path: indexPath(filepath.Join(snapshot, rel))}); err != nil {
+				log.Printf("Error comparing %s with the snapshot: %v", path, err)
+				c.Errors++
+				return nil
+			}
+		}
+		if same {
+			c.Identical = append(c.Identical, file)
+		} else {
+			c.Changed = append(c.Changed, file)
+		}
+		return nil
+	})
+	return c, err
+}
+
+// writeSnapshotComparison lists the identical, changed and new files with
+// their totals.
+func writeSnapshotComparison(w io.Writer, c snapshotComparison) {
+	for _, section := range []struct {
+		key   string
+		files []snapshotFile
+	}{{"snapshot.identical", c.Identical}, {"snapshot.changed", c.Changed}, {"snapshot.new", c.New}} {
+		var size int64
+		for _, file := range section.files {
+			size += file.Size
+		}
+		fmt.Fprintln(w, msg(section.key, formatCount(int64(len(section.files))), humanReadableSize(size)))
+		for _, file := range section.files {
+			fmt.Fprintln(w, "  "+file.Path)
+		}
+		fmt.Fprintln(w)
+	}
+	if c.Errors > 0 {
+		fmt.Fprintln(w, msg("snapshot.errors", formatCount(int64(c.Errors))))
+	}
+}
+
+// runCompare implements the compare command, which compares a live tree with
+// a backup snapshot of it.
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	fs.StringVar(&sizeUnits, "units", sizeUnits, "size units: iec (1024-based, KiB/MiB) or si (1000-based, KB/MB)")
+	asJSON := fs.Bool("json", false, "print the comparison as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s compare [flags] live-folder snapshot-folder\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	c, err := compareSnapshot(formatPath(fs.Arg(0)), formatPath(fs.Arg(1)))
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	if *asJSON {
+		for _, files := range []*[]snapshotFile{&c.Identical, &c.Changed, &c.New} {
+			if *files == nil {
+				*files = []snapshotFile{}
+			}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(c)
+		return
+	}
+	writeSnapshotComparison(os.Stdout, c)
+}
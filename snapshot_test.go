@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareSnapshot(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	live := filepath.Join(tempDir, "live")
+	snapshot := filepath.Join(live, ".snapshot")
+	for _, dir := range []string{filepath.Join(live, "sub"), filepath.Join(snapshot, "sub")} {
+		os.MkdirAll(dir, 0755)
+	}
+	write := func(root, name, content string) {
+		ioutil.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(content), 0644)
+	}
+	write(live, "same.txt", "unchanged")
+	write(snapshot, "same.txt", "unchanged")
+	write(live, "sub/edited.txt", "new text")
+	write(snapshot, "sub/edited.txt", "old text")
+	write(live, "sub/grown.txt", "longer now")
+	write(snapshot, "sub/grown.txt", "short")
+	write(live, "added.txt", "added")
+	write(snapshot, "deleted.txt", "only in the snapshot")
+
+	c, err := compareSnapshot(live, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := func(files []snapshotFile) string {
+		var names []string
+		for _, file := range files {
+			rel, _ := filepath.Rel(live, file.Path)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return strings.Join(names, ",")
+	}
+	for _, tc := range []struct {
+		name     string
+		files    []snapshotFile
+		expected string
+	}{
+		{"identical", c.Identical, "same.txt"},
+		{"changed", c.Changed, "sub/edited.txt,sub/grown.txt"},
+		{"new", c.New, "added.txt"},
+	} {
+		if got := paths(tc.files); got != tc.expected {
+			t.Errorf("%s: Expected: %s, Got: %s", tc.name, tc.expected, got)
+		}
+	}
+
+	var out bytes.Buffer
+	writeSnapshotComparison(&out, c)
+	expected := []string{
+		"1 files (9.00 B) are identical to the snapshot and safe to prune:\n  " + filepath.Join(live, "same.txt") + "\n",
+		"2 files (18.00 B) differ from the snapshot:",
+		"1 files (5.00 B) are not in the snapshot:",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Expected output to contain %q, Got: %s", s, out.String())
+		}
+	}
+}
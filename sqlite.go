@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// sqliteSchema creates the tables of the SQLite export. Tables from an
+// earlier export to the same database are replaced.
+const sqliteSchema = `DROP TABLE IF EXISTS scans;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS files;
+DROP TABLE IF EXISTS errors;
+CREATE TABLE scans (root TEXT NOT NULL, scanned_at TEXT NOT NULL, files_scanned INTEGER NOT NULL, total_size INTEGER NOT NULL, errors INTEGER NOT NULL);
+CREATE TABLE groups (id TEXT PRIMARY KEY, hash TEXT NOT NULL, files INTEGER NOT NULL, size INTEGER NOT NULL, waste INTEGER NOT NULL);
+CREATE TABLE files (path TEXT PRIMARY KEY, size INTEGER NOT NULL, hash TEXT NOT NULL, group_id TEXT REFERENCES groups(id), kept INTEGER NOT NULL);
+CREATE INDEX files_group_id ON files(group_id);
+CREATE TABLE errors (path TEXT NOT NULL, error TEXT NOT NULL);
+`
+
+// sqlString quotes s as an SQL string literal.
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// writeSQL writes the SQL statements that create and fill the export tables
+// in a single transaction.
+func writeSQL(w io.Writer, data scanExport) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "BEGIN;")
+	bw.WriteString(sqliteSchema)
+	fmt.Fprintf(bw, "INSERT INTO scans VALUES (%s, %s, %d, %d, %d);\n", sqlString(data.Root),
+		sqlString(data.ScannedAt.UTC().Format(time.RFC3339)), data.Progress.Scanned, data.Progress.TotalSize, data.Progress.Errors)
+	for _, group := range duplicateGroups(data.FileMap) {
+		fmt.Fprintf(bw, "INSERT INTO groups VALUES (%s, %s, %d, %d, %d);\n", sqlString(group.ID), sqlString(group.Hash),
+			len(group.Files), group.Files[0].Size, group.Waste())
+	}
+	for _, file := range exportedFiles(data.FileMap) {
+		group, kept := "NULL", 0
+		if file.Group != "" {
+			group = sqlString(file.Group)
+		}
+		if file.Kept {
+			kept = 1
+		}
+		fmt.Fprintf(bw, "INSERT INTO files VALUES (%s, %d, %s, %s, %d);\n", sqlString(file.Path()), file.Size, sqlString(file.Hash), group, kept)
+	}
+	for _, failure := range data.Progress.Failures {
+		fmt.Fprintf(bw, "INSERT INTO errors VALUES (%s, %s);\n", sqlString(failure.Path), sqlString(failure.Err.Error()))
+	}
+	fmt.Fprintln(bw, "COMMIT;")
+	return bw.Flush()
+}
+
+// exportSQL writes the SQL statements of the SQLite export to path.
+func exportSQL(path string, data scanExport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeSQL(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exportSQLite creates the export tables in the SQLite database at path using
+// the sqlite3 command line shell.
+func exportSQLite(path string, data scanExport) error {
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return fmt.Errorf("sqlite3 is required for the sqlite export (use sql:FILE to write the statements instead): %v", err)
+	}
+	cmd := exec.Command(sqlite, "-bail", path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	writeErr := writeSQL(stdin, data)
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return writeErr
+}
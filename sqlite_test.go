@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testScanExport() scanExport {
+	return scanExport{
+		Root:      "/data",
+		ScannedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Progress:  scanProgress{Scanned: 3, TotalSize: 30, Errors: 1, Failures: []HashError{{Path: "/data/locked", Err: errors.New("permission denied")}}},
+		FileMap: map[string][]File{
+			"aaaaaaaaaaaaaaaa": {{path: indexPath("/data/it's.txt"), Hash: "aaaaaaaaaaaaaaaa", Size: 10}, {path: indexPath("/data/copy.txt"), Hash: "aaaaaaaaaaaaaaaa", Size: 10}},
+			"cccccccccccccccc": {{path: indexPath("/data/unique.txt"), Hash: "cccccccccccccccc", Size: 10}},
+		},
+	}
+}
+
+func TestWriteSQL(t *testing.T) {
+	var out strings.Builder
+	if err := writeSQL(&out, testScanExport()); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"BEGIN;\nDROP TABLE IF EXISTS scans;",
+		"INSERT INTO scans VALUES ('/data', '2024-03-01T12:00:00Z', 3, 30, 1);",
+		"INSERT INTO groups VALUES ('aaaaaaaaaaaa', 'aaaaaaaaaaaaaaaa', 2, 10, 10);",
+		"INSERT INTO files VALUES ('/data/copy.txt', 10, 'aaaaaaaaaaaaaaaa', 'aaaaaaaaaaaa', 0);",
+		"INSERT INTO files VALUES ('/data/it''s.txt', 10, 'aaaaaaaaaaaaaaaa', 'aaaaaaaaaaaa', 1);",
+		"INSERT INTO files VALUES ('/data/unique.txt', 10, 'cccccccccccccccc', NULL, 0);",
+		"INSERT INTO errors VALUES ('/data/locked', 'permission denied');\nCOMMIT;\n",
+	}
+	for _, s := range expected {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Expected SQL to contain %q, Got: %s", s, out.String())
+		}
+	}
+}
+
+func TestExportSQLite(t *testing.T) {
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 is not installed")
+	}
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "results.db")
+	for i := 0; i < 2; i++ { // exporting again replaces the tables
+		if err := exportSQLite(path, testScanExport()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := exec.Command(sqlite, path, "SELECT count(*), sum(kept) FROM files; SELECT waste FROM groups;").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "3|1\n10\n" {
+		t.Errorf("Expected: %q, Got: %q", "3|1\n10\n", out)
+	}
+}
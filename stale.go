@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// checkUnchanged returns an error if the file at path no longer has the size
+// and modification time recorded for it. A zero modTime is not checked.
+func checkUnchanged(path string, size int64, modTime time.Time) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != size {
+		return fmt.Errorf("size changed from %d to %d bytes", size, info.Size())
+	}
+	if !modTime.IsZero() && !info.ModTime().Equal(modTime) {
+		return fmt.Errorf("modified at %s, expected %s", info.ModTime().Format(time.RFC3339), modTime.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkHash re-hashes the file at path and returns an error if its content
+// no longer matches hash. The "-N" suffix of groups split by a plugin is
+// ignored.
+func checkHash(path, hash string) error {
+	want := strings.SplitN(hash, "-", 2)[0]
+	var got string
+	if isSampled(want) {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if got, err = sampleHash(f, info.Size(), hashAlgorithmOf(want)); err != nil {
+			return err
+		}
+	} else {
+		file, err := hashFileWith(path, hashAlgorithmOf(want))
+		if err != nil {
+			return err
+		}
+		got = file.Hash
+	}
+	if got != want {
+		return errors.New("content changed")
+	}
+	return nil
+}
+
+// changedSinceScan checks that a duplicate and the kept copy of its group
+// still have the size and modification time seen by the scan, since files can
+// change while the user answers the prompts. A kept copy on a remote must
+// still have the size and hash of the listing; its hash was never sampled.
+func changedSinceScan(kept, file File) error {
+	if isRemotePath(kept.Path()) {
+		if err := checkRemoteUnchanged(kept.Path(), kept.Size, kept.Hash); err != nil {
+			return fmt.Errorf("kept copy %s: %v", kept.Path(), err)
+		}
+	} else if err := checkUnchanged(kept.Path(), kept.Size, kept.ModTime); err != nil {
+		return fmt.Errorf("kept copy %s: %v", kept.Path(), err)
+	}
+	if err := checkUnchanged(file.Path(), file.Size, file.ModTime); err != nil {
+		return err
+	}
+	if isRemotePath(kept.Path()) {
+		return nil
+	}
+	return checkSampledContent(file.Hash, kept.Path(), file.Path())
+}
+
+// modTime returns the modification time of path, or the zero time if it
+// cannot be read.
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckUnchanged(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "file")
+	ioutil.WriteFile(path, []byte("content"), 0644)
+	stamp := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(path, stamp, stamp)
+
+	testCases := []struct {
+		name     string
+		path     string
+		size     int64
+		modTime  time.Time
+		expected string
+	}{
+		{"unchanged", path, 7, stamp, ""},
+		{"unknown time", path, 7, time.Time{}, ""},
+		{"resized", path, 5, stamp, "size changed from 5 to 7 bytes"},
+		{"touched", path, 7, stamp.Add(time.Hour), "modified at 2026-01-02T03:04:05Z, expected 2026-01-02T04:04:05Z"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ""
+			if err := checkUnchanged(tc.path, tc.size, tc.modTime); err != nil {
+				got = err.Error()
+			}
+			if got != tc.expected {
+				t.Errorf("Expected: %s, Got: %s", tc.expected, got)
+			}
+		})
+	}
+	if err := checkUnchanged(filepath.Join(tempDir, "missing"), 7, stamp); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error, Got: %v", err)
+	}
+}
+
+func TestCheckHash(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "file")
+	ioutil.WriteFile(path, []byte("content"), 0644)
+	const hash = "9a0364b9e99bb480dd25e1f0284c8555" // MD5 of "content"
+
+	for _, h := range []string{hash, hash + "-1"} {
+		if err := checkHash(path, h); err != nil {
+			t.Errorf("Expected %s to match, Got: %v", h, err)
+		}
+	}
+	if err := checkHash(path, "00000000000000000000000000000000"); err == nil || err.Error() != "content changed" {
+		t.Errorf("Expected: content changed, Got: %v", err)
+	}
+
+	// The hash is checked with the algorithm it was made with.
+	const sha256Hash = "sha256:ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+	if err := checkHash(path, sha256Hash); err != nil {
+		t.Errorf("Expected %s to match, Got: %v", sha256Hash, err)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestStateDir(t *testing.T) {
+	defer os.Setenv("STATE_DIRECTORY", os.Getenv("STATE_DIRECTORY"))
+	defer os.Setenv("XDG_STATE_HOME", os.Getenv("XDG_STATE_HOME"))
+
+	os.Setenv("STATE_DIRECTORY", "/var/lib/duplicate_finder:/var/lib/other")
+	if dir, _ := stateDir(); dir != "/var/lib/duplicate_finder" {
+		t.Errorf("Expected: %s, Got: %s", "/var/lib/duplicate_finder", dir)
+	}
+
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return
+	}
+	os.Setenv("STATE_DIRECTORY", "")
+	os.Setenv("XDG_STATE_HOME", "/home/u/.state")
+	if dir, _ := stateDir(); dir != filepath.Join("/home/u/.state", serviceName) {
+		t.Errorf("Expected: %s, Got: %s", filepath.Join("/home/u/.state", serviceName), dir)
+	}
+}
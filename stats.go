@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// stateStats summarizes what the state directory records about the runs of
+// the tool. The tool keeps no cache of hashes between runs, so there are no
+// cached hashes to count.
+type stateStats struct {
+	StateDir string `json:"state_dir"`
+
+	Scans          int       `json:"scans"`
+	Roots          int       `json:"roots"`
+	LastScan       time.Time `json:"last_scan"`
+	WastedBytes    int64     `json:"wasted_bytes"` // found by the last scan of every root
+	Cleanups       int       `json:"cleanups"`
+	ReclaimedBytes int64     `json:"reclaimed_bytes"`
+
+	Actions       int   `json:"actions"`
+	FailedActions int   `json:"failed_actions"`
+	DeletedBytes  int64 `json:"deleted_bytes"` // by deletes and purges
+
+	Quarantined       int       `json:"quarantined"`
+	QuarantinedBytes  int64     `json:"quarantined_bytes"`
+	QuarantineMissing int       `json:"quarantine_missing"`
+	OldestQuarantined time.Time `json:"oldest_quarantined"`
+
+	PartialCopies int   `json:"partial_copies"`
+	PartialBytes  int64 `json:"partial_bytes"`
+}
+
+// addHistory adds the scans and cleanups of the history to s.
+func (s *stateStats) addHistory(entries []runSummary) {
+	wasted := make(map[string]int64)
+	for _, e := range entries {
+		switch e.Event {
+		case "scan":
+			s.Scans++
+			wasted[e.Root] = e.ReclaimableBytes
+			if e.Time.After(s.LastScan) {
+				s.LastScan = e.Time
+			}
+		case "cleanup":
+			s.Cleanups++
+			s.ReclaimedBytes += e.BytesReclaimed
+		}
+	}
+	s.Roots = len(wasted)
+	for _, bytes := range wasted {
+		s.WastedBytes += bytes
+	}
+}
+
+// addAudit adds the actions recorded in the audit log r to s.
+func (s *stateStats) addAudit(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry auditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		s.Actions++
+		if entry.Result != "ok" {
+			s.FailedActions++
+			continue
+		}
+		if entry.Action == "delete" || entry.Action == "purge" {
+			s.DeletedBytes += entry.Size
+		}
+	}
+	return scanner.Err()
+}
+
+// addQuarantine adds the moved files that are neither restored nor purged
+// to s. Files that are gone from where they were moved to, or were overwritten
+// there by a later move, are only counted as missing.
+func (s *stateStats) addQuarantine(moved []movedFile) {
+	for _, m := range moved {
+		if _, err := os.Lstat(m.Current); err != nil || m.Lost {
+			s.QuarantineMissing++
+			continue
+		}
+		s.Quarantined++
+		s.QuarantinedBytes += m.Size
+		if s.OldestQuarantined.IsZero() || m.Moved.Before(s.OldestQuarantined) {
+			s.OldestQuarantined = m.Moved
+		}
+	}
+}
+
+// addPartialCopies adds the copies to another volume that are in progress,
+// or were left behind by runs that crashed, to s.
+func (s *stateStats) addPartialCopies(copies []partialCopy) {
+	for _, c := range copies {
+		s.PartialCopies++
+		if info, err := os.Stat(c.Part); err == nil {
+			s.PartialBytes += info.Size()
+		}
+	}
+}
+
+// collectStats summarizes the history and the audit log at the given paths
+// and the partial copies in the state directory. Files that do not exist yet
+// count as empty.
+func collectStats(historyPath, auditPath string) (stateStats, error) {
+	var s stateStats
+	var err error
+	if s.StateDir, err = stateDir(); err != nil {
+		return s, err
+	}
+	entries, err := loadHistory(historyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return s, err
+	}
+	s.addHistory(entries)
+
+	if f, err := os.Open(auditPath); err == nil {
+		err = s.addAudit(f)
+		f.Close()
+		if err != nil {
+			return s, err
+		}
+	} else if !os.IsNotExist(err) {
+		return s, err
+	}
+	moved, err := movedFiles(auditPath)
+	if err != nil && !os.IsNotExist(err) {
+		return s, err
+	}
+	s.addQuarantine(moved)
+
+	copies, _ := loadPartialCopies()
+	s.addPartialCopies(copies)
+	return s, nil
+}
+
+// writeStats writes the summary s for people.
+func writeStats(w io.Writer, s stateStats) {
+	date := func(t time.Time) string { return t.Local().Format("2006-01-02") }
+	fmt.Fprintln(w, msg("stats.dir", s.StateDir))
+	if s.Scans == 0 {
+		fmt.Fprintln(w, msg("stats.scans.none"))
+	} else {
+		fmt.Fprintln(w, msg("stats.scans", formatCount(int64(s.Scans)), formatCount(int64(s.Roots)), date(s.LastScan)))
+		fmt.Fprintln(w, msg("stats.wasted", humanReadableSize(s.WastedBytes)))
+	}
+	fmt.Fprintln(w, msg("stats.cleanups", formatCount(int64(s.Cleanups)), humanReadableSize(s.ReclaimedBytes)))
+	fmt.Fprintln(w, msg("stats.actions", formatCount(int64(s.Actions)), formatCount(int64(s.FailedActions)), humanReadableSize(s.DeletedBytes)))
+	if s.Quarantined == 0 {
+		fmt.Fprintln(w, msg("stats.quarantine.none"))
+	} else {
+		fmt.Fprintln(w, msg("stats.quarantine", formatCount(int64(s.Quarantined)), humanReadableSize(s.QuarantinedBytes), date(s.OldestQuarantined)))
+	}
+	if s.QuarantineMissing > 0 {
+		fmt.Fprintln(w, msg("stats.quarantine.missing", formatCount(int64(s.QuarantineMissing))))
+	}
+	if s.PartialCopies > 0 {
+		fmt.Fprintln(w, msg("stats.partial", formatCount(int64(s.PartialCopies)), humanReadableSize(s.PartialBytes)))
+	}
+}
+
+// runStats implements the stats command, which summarizes the scans,
+// cleanups and moved duplicates recorded in the state directory.
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	fs.StringVar(&sizeUnits, "units", sizeUnits, "size units: iec (1024-based, KiB/MiB) or si (1000-based, KB/MB)")
+	histPath := fs.String("history", "", "history file (default: history.jsonl in the state directory)")
+	auditPath := fs.String("audit-log", "", "audit log (default: audit.jsonl in the state directory)")
+	asJSON := fs.Bool("json", false, "print the statistics as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s stats [flags]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	numberLocale = detectLocale()
+	messageLang = detectMessageLang()
+
+	if *histPath == "" {
+		p, err := historyPath()
+		if err != nil {
+			log.Fatal("Error:", err)
+		}
+		*histPath = p
+	}
+	a, err := openAuditLog(*auditPath)
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	s, err := collectStats(*histPath, a.path)
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(s)
+		return
+	}
+	writeStats(os.Stdout, s)
+}
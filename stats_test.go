@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectStats(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	defer os.Setenv("STATE_DIRECTORY", os.Getenv("STATE_DIRECTORY"))
+	state := filepath.Join(tempDir, "state")
+	os.Setenv("STATE_DIRECTORY", state)
+
+	historyPath := filepath.Join(state, historyFile)
+	auditPath := filepath.Join(state, auditFile)
+	empty, err := collectStats(historyPath, auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty != (stateStats{StateDir: state}) {
+		t.Errorf("Expected empty statistics, Got: %+v", empty)
+	}
+
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, summary := range []runSummary{
+		{Event: "scan", Root: "/a", Time: first, ReclaimableBytes: 500},
+		{Event: "cleanup", Root: "/a", Time: first, Action: "move", BytesReclaimed: 300},
+		{Event: "scan", Root: "/a", Time: first.Add(time.Hour), ReclaimableBytes: 200},
+		{Event: "scan", Root: "/b", Time: first.Add(2 * time.Hour), ReclaimableBytes: 50},
+	} {
+		appendHistory(historyPath, summary)
+	}
+
+	moved := filepath.Join(tempDir, "moved")
+	ioutil.WriteFile(moved, []byte("content"), 0644)
+	audit, _ = openAuditLog(auditPath)
+	defer func() { audit = nil }()
+	audit.record(auditEntry{Action: "move", Path: "/a/dup", To: moved, Size: 7}, nil)
+	audit.record(auditEntry{Action: "move", Path: "/a/gone", To: filepath.Join(tempDir, "gone"), Size: 9}, nil)
+	audit.record(auditEntry{Action: "delete", Path: "/a/deleted", Size: 100}, nil)
+	audit.record(auditEntry{Action: "delete", Path: "/a/failed", Size: 1000}, errors.New("denied"))
+
+	part := filepath.Join(tempDir, "copy") + partSuffix
+	ioutil.WriteFile(part, []byte("part"), 0644)
+	recordPartialCopy(partialCopy{Source: moved, Part: part})
+
+	s, err := collectStats(historyPath, auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Scans != 3 || s.Roots != 2 || !s.LastScan.Equal(first.Add(2*time.Hour)) || s.WastedBytes != 250 {
+		t.Errorf("Unexpected scans: %+v", s)
+	}
+	if s.Cleanups != 1 || s.ReclaimedBytes != 300 {
+		t.Errorf("Unexpected cleanups: %+v", s)
+	}
+	if s.Actions != 4 || s.FailedActions != 1 || s.DeletedBytes != 100 {
+		t.Errorf("Unexpected actions: %+v", s)
+	}
+	if s.Quarantined != 1 || s.QuarantinedBytes != 7 || s.QuarantineMissing != 1 || s.OldestQuarantined.IsZero() {
+		t.Errorf("Unexpected moved duplicates: %+v", s)
+	}
+	if s.PartialCopies != 1 || s.PartialBytes != 4 {
+		t.Errorf("Unexpected partial copies: %+v", s)
+	}
+
+	var out bytes.Buffer
+	writeStats(&out, s)
+	for _, want := range []string{"Scans: 3 of 2 folders", "Moved duplicates: 1 files", "Moved duplicates that are gone: 1", "Unfinished copies: 1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, out.String())
+		}
+	}
+}
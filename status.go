@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net"
+	"net/http"
+)
+
+// statusHandler serves GET /status with the state of the scan of control as
+// JSON, the reply of the status command of the control socket.
+func statusHandler(control *scanControl) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, handleControl("status", control))
+	})
+	return mux
+}
+
+// startStatusServer serves statusHandler for control at addr. It returns a
+// function that stops the server.
+func startStatusServer(addr string, control *scanControl) (stop func(), err error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: statusHandler(control)}
+	go server.Serve(l)
+	return func() { server.Close() }, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusHandler(t *testing.T) {
+	control := newScanControl()
+	control.update(scanProgress{Files: 10, Scanned: 4, Errors: 1, TotalSize: 4096, FoundSize: 8192})
+	server := httptest.NewServer(statusHandler(control))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reply controlReply
+	err = json.NewDecoder(resp.Body).Decode(&reply)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Phase != "scanning" || reply.Files != 10 || reply.Scanned != 4 || reply.Errors != 1 || reply.TotalSize != 4096 || reply.BytesPerSecond <= 0 {
+		t.Errorf("Unexpected status: %+v", reply)
+	}
+
+	control.setPhase("done")
+	resp, err = http.Get(server.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.NewDecoder(resp.Body).Decode(&reply)
+	resp.Body.Close()
+	if reply.Phase != "done" {
+		t.Errorf("Expected: done, Got: %s", reply.Phase)
+	}
+
+	resp, err = http.Post(server.URL+"/status", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected: %d, Got: %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import "runtime"
+
+// storageWorkers are the numbers of files hashed at the same time on the
+// storage types detected by storageType. Hard disks read one file at a time,
+// since concurrent reads make them seek back and forth; network storage gets
+// a few requests in flight. Other storage, including SSDs, hashes one file
+// per CPU.
+var storageWorkers = map[string]int{"hdd": 1, "network": 4}
+
+// hashWorkers returns the number of files hashed at the same time below
+// root: --workers if it is set, and otherwise a default for its storage.
+func hashWorkers(root string) int {
+	if scanWorkers > 0 {
+		return scanWorkers
+	}
+	if n := storageWorkers[storageType(root)]; n > 0 {
+		return n
+	}
+	return runtime.NumCPU()
+}
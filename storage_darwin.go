@@ -0,0 +1,28 @@
+//go:build darwin
+
+package main
+
+import "syscall"
+
+// networkFileSystems are the names of the network file systems of macOS.
+var networkFileSystems = map[string]bool{"nfs": true, "smbfs": true, "afpfs": true, "webdav": true, "cifs": true}
+
+// storageType returns "network" for network file systems and "" otherwise;
+// macOS does not tell hard disks from SSDs through statfs.
+func storageType(path string) string {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return ""
+	}
+	var name []byte
+	for _, c := range fs.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	if networkFileSystems[string(name)] {
+		return "network"
+	}
+	return ""
+}
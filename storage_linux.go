@@ -0,0 +1,44 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// networkFileSystems are the statfs magic numbers of NFS, SMB/CIFS, FUSE
+// (without a block device, e.g. rclone or sshfs), 9p and Ceph.
+var networkFileSystems = map[uint32]bool{
+	0x6969: true, 0x517b: true, 0xff534d42: true, 0xfe534d42: true,
+	0x65735546: true, 0x01021997: true, 0x00c36400: true,
+}
+
+// storageType returns "hdd", "ssd" or "network" for the storage path is on,
+// or "" if it cannot be told.
+func storageType(path string) string {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err == nil {
+		dev := uint64(st.Dev)
+		major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+		minor := dev&0xff | (dev>>12)&^0xff
+		// Partitions have no queue of their own; the disk is their parent.
+		for _, queue := range []string{"queue", "../queue"} {
+			data, err := os.ReadFile(fmt.Sprintf("/sys/dev/block/%d:%d/%s/rotational", major, minor, queue))
+			if err != nil {
+				continue
+			}
+			if strings.TrimSpace(string(data)) == "1" {
+				return "hdd"
+			}
+			return "ssd"
+		}
+	}
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err == nil && networkFileSystems[uint32(fs.Type)] {
+		return "network"
+	}
+	return ""
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+// storageType cannot tell the storage type on this system.
+func storageType(path string) string {
+	return ""
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestStorageType(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	switch kind := storageType(dir); kind {
+	case "", "hdd", "ssd", "network":
+	default:
+		t.Errorf("Expected: hdd, ssd, network or nothing, Got: %s", kind)
+	}
+	if kind := storageType(dir + "/missing"); kind != "" && runtime.GOOS != "windows" {
+		t.Errorf("Expected no storage type for a missing path, Got: %s", kind)
+	}
+}
+
+func TestHashWorkers(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	defer func(n int) { scanWorkers = n }(scanWorkers)
+
+	scanWorkers = 3
+	if n := hashWorkers(dir); n != 3 {
+		t.Errorf("Expected: 3, Got: %d", n)
+	}
+
+	scanWorkers = 0
+	expected := runtime.NumCPU()
+	if n, ok := storageWorkers[storageType(dir)]; ok {
+		expected = n
+	}
+	if n := hashWorkers(dir); n != expected {
+		t.Errorf("Expected: %d, Got: %d", expected, n)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package main
+
+import (
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var procGetDriveTypeW = kernel32.NewProc("GetDriveTypeW")
+
+// driveRemote is the GetDriveType result for network drives.
+const driveRemote = 4
+
+// storageType returns "network" for network drives and UNC paths and ""
+// otherwise.
+func storageType(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	volume := filepath.VolumeName(abs)
+	if len(volume) > 2 && volume[:2] == `\\` {
+		return "network"
+	}
+	root, err := syscall.UTF16PtrFromString(volume + `\`)
+	if err != nil {
+		return ""
+	}
+	if kind, _, _ := procGetDriveTypeW.Call(uintptr(unsafe.Pointer(root))); kind == driveRemote {
+		return "network"
+	}
+	return ""
+}
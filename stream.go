@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// duplicateStream, if not nil, reports duplicates while the scan runs
+// (--stream). It is shared by all folders of a scan.
+var duplicateStream *groupStream
+
+// groupStream prints a duplicate group as soon as its second file is hashed,
+// and every further copy as it is found, so a long scan can be reviewed
+// while it runs. Groups found with a fast hash are only printed once their
+// first two files are confirmed to be identical.
+type groupStream struct {
+	mu     sync.Mutex
+	w      io.Writer
+	first  map[string]File // the first file of every hash
+	counts map[string]int  // files of every hash, 0 once a collision was seen
+}
+
+func newGroupStream(w io.Writer) *groupStream {
+	return &groupStream{w: w, first: make(map[string]File), counts: make(map[string]int)}
+}
+
+// add records a hashed file.
+func (s *groupStream) add(file File) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first, seen := s.first[file.Hash]
+	if !seen {
+		s.first[file.Hash] = file
+		s.counts[file.Hash] = 1
+		return
+	}
+	if s.counts[file.Hash] == 0 {
+		return
+	}
+	s.counts[file.Hash]++
+	id := groupID(file.Hash)
+	if s.counts[file.Hash] > 2 {
+		fmt.Fprintln(s.w, "\r"+msg("stream.more", id, file.Path()))
+		return
+	}
+	if hashAlgorithmOf(file.Hash).fast {
+		if same, err := sameContent(first, file); err != nil || !same {
+			s.counts[file.Hash] = 0 // left to confirmFileMap
+			return
+		}
+	}
+	fmt.Fprintln(s.w, "\r"+msg("stream.group", id, humanReadableSize(file.Size), first.Path(), file.Path()))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGroupStream(t *testing.T) {
+	var out bytes.Buffer
+	s := newGroupStream(&out)
+	s.add(File{path: indexPath("a"), Hash: "9a0364b9e99bb480dd25e1f0284c8555", Size: 7})
+	s.add(File{path: indexPath("unique"), Hash: "00000000000000000000000000000000", Size: 7})
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing before a second copy, Got: %q", out.String())
+	}
+	s.add(File{path: indexPath("b"), Hash: "9a0364b9e99bb480dd25e1f0284c8555", Size: 7})
+	s.add(File{path: indexPath("c"), Hash: "9a0364b9e99bb480dd25e1f0284c8555", Size: 7})
+	expected := "\rDuplicates 9a0364b9e99b (7.00 B each): a and b\n\rDuplicates 9a0364b9e99b: also c\n"
+	if out.String() != expected {
+		t.Errorf("Expected: %q, Got: %q", expected, out.String())
+	}
+
+	var nilStream *groupStream
+	nilStream.add(File{path: indexPath("a")})
+}
+
+func TestGroupStreamConfirmsFastHashes(t *testing.T) {
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	ioutil.WriteFile(a, []byte("same"), 0644)
+	ioutil.WriteFile(b, []byte("diff"), 0644)
+
+	var out bytes.Buffer
+	s := newGroupStream(&out)
+	s.add(File{path: indexPath(a), Hash: "xxh64:00000000000000ff", Size: 4})
+	s.add(File{path: indexPath(b), Hash: "xxh64:00000000000000ff", Size: 4})
+	s.add(File{path: indexPath(a), Hash: "xxh64:00000000000000ff", Size: 4})
+	if strings.Contains(out.String(), "Duplicates") {
+		t.Errorf("Expected a collision not to be streamed, Got: %q", out.String())
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runSummaryFile is the compact JSON object written by --summary-file. It is
+// rewritten after every action, so it always describes the run so far.
+type runSummaryFile struct {
+	Root             string          `json:"root"`
+	StartedAt        time.Time       `json:"started_at"`
+	FinishedAt       time.Time       `json:"finished_at"`
+	DurationSeconds  float64         `json:"duration_seconds"`
+	FilesScanned     int             `json:"files_scanned"`
+	BytesHashed      int64           `json:"bytes_hashed"`
+	DuplicateGroups  int             `json:"duplicate_groups"`
+	DuplicateFiles   int             `json:"duplicate_files"`
+	ReclaimableBytes int64           `json:"reclaimable_bytes"`
+	Errors           int             `json:"errors"`
+	Actions          []appliedAction `json:"actions"`
+}
+
+// appliedAction records one cleanup or command applied to the duplicates.
+type appliedAction struct {
+	Action string `json:"action"`
+	Files  int    `json:"files"`
+	Bytes  int64  `json:"bytes"`
+	Errors int    `json:"errors"`
+}
+
+func newRunSummaryFile(summary runSummary, started time.Time, bytesHashed int64) *runSummaryFile {
+	return &runSummaryFile{
+		Root:             summary.Root,
+		StartedAt:        started,
+		FilesScanned:     summary.FilesScanned,
+		BytesHashed:      bytesHashed,
+		DuplicateGroups:  summary.DuplicateGroups,
+		DuplicateFiles:   summary.DuplicateFiles,
+		ReclaimableBytes: summary.ReclaimableBytes,
+		Errors:           summary.Errors,
+		Actions:          []appliedAction{},
+	}
+}
+
+func (s *runSummaryFile) addAction(action string, stats actionStats) {
+	s.Actions = append(s.Actions, appliedAction{Action: action, Files: stats.Files, Bytes: stats.Bytes, Errors: stats.Errors})
+}
+
+// write replaces the file at path with the current summary. The summary is
+// written to a temporary file first so readers never see a partial object.
+func (s *runSummaryFile) write(path string) error {
+	s.FinishedAt = time.Now()
+	s.DurationSeconds = s.FinishedAt.Sub(s.StartedAt).Seconds()
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".summary-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunSummaryFile(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "summary.json")
+	started := time.Now().Add(-90 * time.Second)
+	summary := runSummary{Root: "/data", FilesScanned: 10, DuplicateGroups: 2, DuplicateFiles: 3, ReclaimableBytes: 300, Errors: 1}
+	s := newRunSummaryFile(summary, started, 4096)
+
+	read := func() map[string]interface{} {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v map[string]interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	if err := s.write(path); err != nil {
+		t.Fatal(err)
+	}
+	first := read()
+	if first["bytes_hashed"] != 4096.0 || first["reclaimable_bytes"] != 300.0 || len(first["actions"].([]interface{})) != 0 {
+		t.Errorf("Unexpected summary: %v", first)
+	}
+	if d := first["duration_seconds"].(float64); d < 90 || d > 100 {
+		t.Errorf("Expected a duration of about 90s, Got: %v", d)
+	}
+
+	s.addAction("delete", actionStats{Files: 3, Bytes: 300})
+	if err := s.write(path); err != nil {
+		t.Fatal(err)
+	}
+	actions := read()["actions"].([]interface{})
+	if len(actions) != 1 || actions[0].(map[string]interface{})["action"] != "delete" || actions[0].(map[string]interface{})["bytes"] != 300.0 {
+		t.Errorf("Unexpected actions: %v", actions)
+	}
+
+	entries, _ := ioutil.ReadDir(tempDir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the summary file, Got: %d files", len(entries))
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// serviceName is the base name of the generated systemd units.
+const serviceName = "duplicate_finder"
+
+// underSystemd reports whether the process was started by systemd, in which
+// case output goes to the journal and should avoid timestamps and progress lines.
+func underSystemd() bool {
+	return os.Getenv("INVOCATION_ID") != "" || os.Getenv("JOURNAL_STREAM") != ""
+}
+
+// sdNotify sends a state such as "READY=1" or "STATUS=..." to the service
+// manager. It does nothing when not running as a Type=notify service.
+func sdNotify(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:] // abstract socket namespace
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte(state))
+}
+
+// systemdQuote quotes an ExecStart argument so that systemd passes it
+// verbatim, including the escaping of specifiers and variables.
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$").Replace(arg)
+	if arg == "" || strings.ContainsAny(arg, " \t'\";") {
+		return `"` + arg + `"`
+	}
+	return arg
+}
+
+// systemdUnits renders the service and timer units that scan periodically
+// with the given arguments. Results are saved to the unit's state directory
+// (/var/lib/duplicate_finder for system units).
+func systemdUnits(executable string, scanArgs []string, onCalendar string) (service, timer string) {
+	execStart := []string{systemdQuote(executable), "--save", "%S/" + serviceName + "/results.json"}
+	for _, arg := range scanArgs {
+		execStart = append(execStart, systemdQuote(arg))
+	}
+
+	service = fmt.Sprintf(`[Unit]
+Description=Find duplicate files
+Documentation=https://github.com/halra/duplicate_finder
+After=local-fs.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart=%s
+StateDirectory=%s
+StandardInput=null
+Nice=10
+IOSchedulingClass=idle
+`, strings.Join(execStart, " "), serviceName)
+
+	timer = fmt.Sprintf(`[Unit]
+Description=Periodic duplicate file scan
+
+[Timer]
+OnCalendar=%s
+Persistent=true
+RandomizedDelaySec=1h
+
+[Install]
+WantedBy=timers.target
+`, onCalendar)
+	return service, timer
+}
+
+func systemdUnitDir(user bool) (string, []string) {
+	if !user {
+		return "/etc/systemd/system", []string{"systemctl"}
+	}
+	config, err := os.UserConfigDir()
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	return filepath.Join(config, "systemd", "user"), []string{"systemctl", "--user"}
+}
+
+func runSystemctl(systemctl []string, command ...string) error {
+	cmd := exec.Command(systemctl[0], append(systemctl[1:], command...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// installSystemdUnits writes and enables a systemd service and timer.
+func installSystemdUnits(opts serviceOptions) {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+	service, timer := systemdUnits(executable, opts.scanArgs, opts.onCalendar)
+	if opts.printOnly {
+		fmt.Printf("# %s.service\n%s\n# %s.timer\n%s", serviceName, service, serviceName, timer)
+		return
+	}
+
+	unitDir, systemctl := systemdUnitDir(opts.user)
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		log.Fatal("Error:", err)
+	}
+	for name, content := range map[string]string{serviceName + ".service": service, serviceName + ".timer": timer} {
+		path := filepath.Join(unitDir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			log.Fatal("Error:", err)
+		}
+		fmt.Println(msg("service.written", path))
+	}
+	for _, command := range [][]string{{"daemon-reload"}, {"enable", "--now", serviceName + ".timer"}} {
+		if err := runSystemctl(systemctl, command...); err != nil {
+			log.Fatalf("Error running systemctl %s: %v", strings.Join(command, " "), err)
+		}
+	}
+}
+
+// uninstallSystemdUnits disables the timer and removes both units.
+func uninstallSystemdUnits(opts serviceOptions) {
+	unitDir, systemctl := systemdUnitDir(opts.user)
+	if err := runSystemctl(systemctl, "disable", "--now", serviceName+".timer"); err != nil {
+		log.Printf("Error disabling %s.timer: %v", serviceName, err)
+	}
+	for _, name := range []string{serviceName + ".service", serviceName + ".timer"} {
+		path := filepath.Join(unitDir, name)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Fatal("Error:", err)
+		}
+		fmt.Println(msg("service.removed", path))
+	}
+	if err := runSystemctl(systemctl, "daemon-reload"); err != nil {
+		log.Printf("Error running systemctl daemon-reload: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSystemdQuote(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"/data", "/data"},
+		{"/my data", `"/my data"`},
+		{"50%", "50%%"},
+		{`say "hi"`, `"say \"hi\""`},
+		{"$HOME", "$$HOME"},
+		{"", `""`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if result := systemdQuote(tc.input); result != tc.expected {
+				t.Errorf("Expected: %s, Got: %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestSystemdUnits(t *testing.T) {
+	service, timer := systemdUnits("/usr/bin/duplicate_finder", []string{"--webhook", "http://hub/x", "/srv/my files"}, "daily")
+	for _, expected := range []string{
+		"Type=notify\n",
+		"ExecStart=/usr/bin/duplicate_finder --save %S/duplicate_finder/results.json --webhook http://hub/x \"/srv/my files\"\n",
+		"StateDirectory=duplicate_finder\n",
+	} {
+		if !strings.Contains(service, expected) {
+			t.Errorf("Expected %q in service:\n%s", expected, service)
+		}
+	}
+	if !strings.Contains(timer, "OnCalendar=daily\n") || !strings.Contains(timer, "WantedBy=timers.target\n") {
+		t.Errorf("Unexpected timer:\n%s", timer)
+	}
+}
+
+func TestSdNotify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires unix sockets")
+	}
+	tempDir, err := os.MkdirTemp("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	defer os.Setenv("NOTIFY_SOCKET", os.Getenv("NOTIFY_SOCKET"))
+	os.Setenv("NOTIFY_SOCKET", path)
+	sdNotify("READY=1")
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "READY=1" {
+		t.Errorf("Expected: READY=1, Got: %s", buf[:n])
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// rawTerminal switches the terminal on stdin to unbuffered input without
+// echo, so single key presses can be read, and returns a function that
+// restores the previous settings.
+func rawTerminal() (restore func(), err error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(saved)) }, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+var procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
+
+const (
+	enableLineInput = 0x2
+	enableEchoInput = 0x4
+)
+
+// rawTerminal switches the console on stdin to unbuffered input without
+// echo, so single key presses can be read, and returns a function that
+// restores the previous mode.
+func rawTerminal() (restore func(), err error) {
+	h := syscall.Handle(os.Stdin.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return nil, err
+	}
+	if r, _, err := procSetConsoleMode.Call(uintptr(h), uintptr(mode&^(enableLineInput|enableEchoInput))); r == 0 {
+		return nil, err
+	}
+	return func() { procSetConsoleMode.Call(uintptr(h), uintptr(mode)) }, nil
+}
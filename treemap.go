@@ -0,0 +1,175 @@
+package main
+
+import (
+	"math"
+	"path"
+	"sort"
+	"strings"
+)
+
+// treemapNode is a directory in the tree of wasted space. Bytes includes the
+// waste of all subdirectories; Own is the waste of files directly inside it.
+type treemapNode struct {
+	Name     string
+	Path     string
+	Bytes    int64
+	Own      int64
+	Children []*treemapNode
+}
+
+// treemapRect is a box of the rendered treemap in percent of its container.
+// Label is only set for boxes large enough to show it.
+type treemapRect struct {
+	Path       string
+	Label      string
+	Bytes      int64
+	X, Y, W, H float64
+	Hue        int
+}
+
+// treemapMinArea drops boxes smaller than this share of the whole map, which
+// would be too small to see.
+const treemapMinArea = 0.0002
+
+// Boxes narrower or lower than this, in percent of the map, are not labeled.
+const (
+	treemapLabelWidth  = 8
+	treemapLabelHeight = 4
+)
+
+// buildTreemap builds the directory tree of the redundant copies below roots.
+func buildTreemap(fileMap map[string][]File, roots []string) *treemapNode {
+	top := &treemapNode{Name: ".", Path: "."}
+	stats, _ := wasteBy(fileMap, directoryKey(roots, 0))
+	for _, stat := range stats {
+		node := top
+		node.Bytes += stat.Bytes
+		if stat.Key != "." {
+			for _, name := range strings.Split(strings.TrimPrefix(stat.Key, "/"), "/") {
+				node = node.child(name)
+				node.Bytes += stat.Bytes
+			}
+		}
+		node.Own += stat.Bytes
+	}
+	return top
+}
+
+func (n *treemapNode) child(name string) *treemapNode {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	c := &treemapNode{Name: name, Path: path.Join(n.Path, name)}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// layoutTreemap lays out the tree as a squarified treemap in a 100x100 box.
+// Every directory is split into its subdirectories and a box for its own
+// files; only those leaf boxes are returned.
+func layoutTreemap(top *treemapNode) []treemapRect {
+	var rects []treemapRect
+	if top.Bytes <= 0 {
+		return rects
+	}
+	var layout func(n *treemapNode, x, y, w, h float64, hue int)
+	layout = func(n *treemapNode, x, y, w, h float64, hue int) {
+		if w*h < treemapMinArea*100*100 {
+			return
+		}
+		type part struct {
+			node  *treemapNode
+			bytes int64
+		}
+		var parts []part
+		if n.Own > 0 {
+			parts = append(parts, part{nil, n.Own})
+		}
+		for _, c := range n.Children {
+			if c.Bytes > 0 {
+				parts = append(parts, part{c, c.Bytes})
+			}
+		}
+		sort.SliceStable(parts, func(i, j int) bool { return parts[i].bytes > parts[j].bytes })
+
+		areas := make([]float64, len(parts))
+		for i, p := range parts {
+			areas[i] = float64(p.bytes) / float64(n.Bytes) * w * h
+		}
+		for i, r := range squarify(areas, x, y, w, h) {
+			p := parts[i]
+			childHue := hue
+			if n == top {
+				childHue = i * 137 % 360 // spread top-level directories over the color wheel
+			}
+			if p.node == nil {
+				if r[2]*r[3] >= treemapMinArea*100*100 {
+					rect := treemapRect{Path: n.Path, Bytes: p.bytes, X: r[0], Y: r[1], W: r[2], H: r[3], Hue: childHue}
+					if r[2] >= treemapLabelWidth && r[3] >= treemapLabelHeight {
+						rect.Label = n.Name
+					}
+					rects = append(rects, rect)
+				}
+				continue
+			}
+			layout(p.node, r[0], r[1], r[2], r[3], childHue)
+		}
+	}
+	layout(top, 0, 0, 100, 100, 210)
+	return rects
+}
+
+// squarify splits the box at x, y of size w×h into boxes with the given
+// areas, sorted largest first, keeping their aspect ratios close to 1
+// (Bruls, Huizing and van Wijk's squarified treemap algorithm).
+func squarify(areas []float64, x, y, w, h float64) [][4]float64 {
+	rects := make([][4]float64, 0, len(areas))
+	for i := 0; i < len(areas); {
+		side := math.Min(w, h)
+		j := i + 1
+		for j < len(areas) && worstRatio(areas[i:j+1], side) <= worstRatio(areas[i:j], side) {
+			j++
+		}
+		var sum float64
+		for _, a := range areas[i:j] {
+			sum += a
+		}
+		if w >= h {
+			cw := sum / h
+			cy := y
+			for _, a := range areas[i:j] {
+				rects = append(rects, [4]float64{x, cy, cw, a / cw})
+				cy += a / cw
+			}
+			x, w = x+cw, w-cw
+		} else {
+			rh := sum / w
+			cx := x
+			for _, a := range areas[i:j] {
+				rects = append(rects, [4]float64{cx, y, a / rh, rh})
+				cx += a / rh
+			}
+			y, h = y+rh, h-rh
+		}
+		i = j
+	}
+	return rects
+}
+
+// worstRatio returns the largest aspect ratio of a row of boxes laid out
+// along a side of the given length.
+func worstRatio(row []float64, side float64) float64 {
+	var sum float64
+	smallest, largest := math.Inf(1), 0.0
+	for _, a := range row {
+		sum += a
+		smallest = math.Min(smallest, a)
+		largest = math.Max(largest, a)
+	}
+	if sum == 0 || smallest == 0 {
+		return math.Inf(1)
+	}
+	return math.Max(side*side*largest/(sum*sum), sum*sum/(side*side*smallest))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSquarify(t *testing.T) {
+	areas := []float64{6, 6, 4, 3, 2, 2, 1}
+	rects := squarify(areas, 0, 0, 6, 4)
+	if len(rects) != len(areas) {
+		t.Fatalf("Expected: %d rects, Got: %d", len(areas), len(rects))
+	}
+	for i, r := range rects {
+		if math.Abs(r[2]*r[3]-areas[i]) > 1e-9 {
+			t.Errorf("Expected area: %v, Got: %v", areas[i], r[2]*r[3])
+		}
+		if r[0] < -1e-9 || r[1] < -1e-9 || r[0]+r[2] > 6+1e-9 || r[1]+r[3] > 4+1e-9 {
+			t.Errorf("Rect %v is outside the box", r)
+		}
+	}
+	// The first row of the classic example holds the two largest boxes.
+	if rects[0][0] != 0 || rects[1][0] != 0 || math.Abs(rects[0][2]-3) > 1e-9 {
+		t.Errorf("Unexpected first row: %v %v", rects[0], rects[1])
+	}
+}
+
+func testTreemapFiles(root string) map[string][]File {
+	file := func(rel string, size int64) File {
+		return File{path: indexPath(filepath.Join(root, filepath.FromSlash(rel))), Size: size}
+	}
+	return map[string][]File{
+		"h1": {file("keep/a", 600), file("photos/2020/a", 600), file("photos/2021/a", 600)},
+		"h2": {file("keep/b", 200), file("docs/b", 200)},
+		"h3": {file("keep/c", 100), file("c", 100)},
+	}
+}
+
+func TestBuildTreemap(t *testing.T) {
+	root := filepath.Join("tmp", "root")
+	top := buildTreemap(testTreemapFiles(root), []string{root})
+	if top.Bytes != 1500 || top.Own != 100 {
+		t.Errorf("Expected: 1500/100, Got: %d/%d", top.Bytes, top.Own)
+	}
+	photos := top.child("photos")
+	if photos.Bytes != 1200 || photos.Own != 0 || len(photos.Children) != 2 {
+		t.Errorf("Unexpected photos node: %+v", photos)
+	}
+	if photos.child("2020").Path != "photos/2020" {
+		t.Errorf("Expected: photos/2020, Got: %s", photos.child("2020").Path)
+	}
+}
+
+func TestLayoutTreemap(t *testing.T) {
+	root := filepath.Join("tmp", "root")
+	rects := layoutTreemap(buildTreemap(testTreemapFiles(root), []string{root}))
+	expected := map[string]int64{"photos/2020": 600, "photos/2021": 600, "docs": 200, ".": 100}
+	if len(rects) != len(expected) {
+		t.Fatalf("Expected: %d rects, Got: %+v", len(expected), rects)
+	}
+	for _, r := range rects {
+		if expected[r.Path] != r.Bytes {
+			t.Errorf("Unexpected rect %+v", r)
+		}
+		if area := r.W * r.H; math.Abs(area-float64(r.Bytes)/1500*10000) > 1e-6 {
+			t.Errorf("Expected area of %s: %v, Got: %v", r.Path, float64(r.Bytes)/1500*10000, area)
+		}
+	}
+	if rects := layoutTreemap(&treemapNode{}); len(rects) != 0 {
+		t.Errorf("Expected no rects for an empty tree, Got: %+v", rects)
+	}
+
+	var out bytes.Buffer
+	if err := writeHTMLReport(&out, runSummary{}, testTreemapFiles(root), []string{root}, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `title="photos/2020: 600.00 B"`) || strings.Contains(out.String(), "ZgotmplZ") {
+		t.Errorf("Unexpected treemap markup: %s", out.String())
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// untouchedGroup is a duplicate group none of whose files was modified or
+// read for a long time.
+type untouchedGroup struct {
+	Group
+	LastUsed time.Time // the latest modification or access of any copy
+}
+
+// lastUsed returns when file was last modified or read before the scan.
+func lastUsed(file File) time.Time {
+	if file.AccessTime.After(file.ModTime) {
+		return file.AccessTime
+	}
+	return file.ModTime
+}
+
+// untouchedGroups returns the duplicate groups of fileMap whose every copy
+// was last used at least age before now, the safest candidates for a
+// thorough cleanup, the most wasteful first.
+func untouchedGroups(fileMap map[string][]File, age time.Duration, now time.Time) []untouchedGroup {
+	var untouched []untouchedGroup
+	for _, group := range duplicateGroups(fileMap) {
+		var last time.Time
+		for _, file := range group.Files {
+			if t := lastUsed(file); t.After(last) {
+				last = t
+			}
+		}
+		if !last.IsZero() && now.Sub(last) >= age {
+			untouched = append(untouched, untouchedGroup{group, last})
+		}
+	}
+	sort.SliceStable(untouched, func(i, j int) bool { return untouched[i].Waste() > untouched[j].Waste() })
+	return untouched
+}
+
+// writeUntouched writes the groups of untouchedGroups as a report section;
+// period is the age they were selected by, as given by the user.
+func writeUntouched(w io.Writer, groups []untouchedGroup, period string) {
+	if len(groups) == 0 {
+		return
+	}
+	var waste int64
+	for _, group := range groups {
+		waste += group.Waste()
+	}
+	fmt.Fprintln(w, msg("untouched.found", formatCount(int64(len(groups))), humanReadableSize(waste), period))
+	for _, group := range groups {
+		fmt.Fprintln(w, "  "+msg("untouched.group", humanReadableSize(group.Waste()), formatCount(int64(len(group.Files))), group.LastUsed.Format("2006-01-02"), group.Files[0].Path()))
+	}
+	fmt.Fprintln(w)
+}
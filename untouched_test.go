@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessTime(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "file")
+	ioutil.WriteFile(path, []byte("data"), 0644)
+	accessed := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chtimes(path, accessed, time.Now())
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := accessTime(info); !got.Equal(accessed) {
+		t.Errorf("Expected: %v, Got: %v", accessed, got)
+	}
+}
+
+func TestUntouchedGroups(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	yearsAgo := func(years int) time.Time { return now.AddDate(-years, 0, 0) }
+	fileMap := map[string][]File{
+		"old": {
+			{path: indexPath("/a/old"), Size: 10, ModTime: yearsAgo(6), AccessTime: yearsAgo(4)},
+			{path: indexPath("/b/old"), Size: 10, ModTime: yearsAgo(6), AccessTime: yearsAgo(5)},
+		},
+		"big": {
+			{path: indexPath("/a/big"), Size: 100, ModTime: yearsAgo(5)},
+			{path: indexPath("/b/big"), Size: 100, ModTime: yearsAgo(5)},
+		},
+		"read": {
+			{path: indexPath("/a/read"), Size: 10, ModTime: yearsAgo(6), AccessTime: yearsAgo(6)},
+			{path: indexPath("/b/read"), Size: 10, ModTime: yearsAgo(6), AccessTime: now.AddDate(0, -1, 0)},
+		},
+		"unique": {{path: indexPath("/a/unique"), ModTime: yearsAgo(9)}},
+		"loaded": {{path: indexPath("/a/x")}, {path: indexPath("/b/x")}},
+	}
+	groups := untouchedGroups(fileMap, 3*365*24*time.Hour, now)
+	if len(groups) != 2 || groups[0].Files[0].Path() != "/a/big" || groups[1].Files[0].Path() != "/a/old" {
+		t.Fatalf("Unexpected groups: %v", groups)
+	}
+	if !groups[1].LastUsed.Equal(yearsAgo(4)) {
+		t.Errorf("Expected the latest access, Got: %v", groups[1].LastUsed)
+	}
+
+	var out bytes.Buffer
+	writeUntouched(&out, groups, "3y")
+	if !strings.Contains(out.String(), "2 duplicate groups wasting 110.00 B") || !strings.Contains(out.String(), "last used "+yearsAgo(4).Format("2006-01-02")+": /a/old") {
+		t.Errorf("Unexpected report: %s", out.String())
+	}
+}
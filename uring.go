@@ -0,0 +1,9 @@
+package main
+
+// ioUring makes hashing read files through io_uring with several reads in
+// flight per file (--io-uring, experimental, Linux only).
+var ioUring bool
+
+// uringMinSize is the smallest file read through io_uring; smaller files
+// take only a few reads anyway.
+const uringMinSize = 1 << 20
@@ -0,0 +1,323 @@
+//go:build linux && (amd64 || arm64 || riscv64 || ppc64le || s390x || loong64)
+
+package main
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	sysIOUringSetup      = 425
+	sysIOUringEnter      = 426
+	ioringOpRead         = 22
+	ioringEnterGetEvents = 1
+	ioringOffSQRing      = 0
+	ioringOffCQRing      = 0x8000000
+	ioringOffSQEs        = 0x10000000
+)
+
+// uringParams is struct io_uring_params.
+type uringParams struct {
+	sqEntries, cqEntries, flags, sqThreadCPU, sqThreadIdle, features, wqFD uint32
+	resv                                                                   [3]uint32
+	sqOff                                                                  struct {
+		head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+		userAddr                                                        uint64
+	}
+	cqOff struct {
+		head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+		userAddr                                                        uint64
+	}
+}
+
+// uringSQE is struct io_uring_sqe.
+type uringSQE struct {
+	opcode, flags uint8
+	ioprio        uint16
+	fd            int32
+	off, addr     uint64
+	len, rwFlags  uint32
+	userData      uint64
+	pad           [3]uint64
+}
+
+// uringCQE is struct io_uring_cqe.
+type uringCQE struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// uring is an io_uring instance with its rings mapped into memory. It is
+// used by one goroutine at a time.
+type uring struct {
+	fd                     int
+	sqRing, cqRing, sqeMem []byte
+	sqTail, sqMask         *uint32
+	cqHead, cqTail, cqMask *uint32
+	sqArray                []uint32
+	sqes                   []uringSQE
+	cqes                   []uringCQE
+	unsubmitted            uint32
+}
+
+func newUring(entries uint32) (*uring, error) {
+	var p uringParams
+	fd, _, errno := syscall.Syscall(sysIOUringSetup, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("io_uring_setup", errno)
+	}
+	u := &uring{fd: int(fd)}
+	mmap := func(offset int64, size uint32) ([]byte, error) {
+		return syscall.Mmap(u.fd, offset, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	}
+	var err error
+	if u.sqRing, err = mmap(ioringOffSQRing, p.sqOff.array+p.sqEntries*4); err == nil {
+		if u.cqRing, err = mmap(ioringOffCQRing, p.cqOff.cqes+p.cqEntries*uint32(unsafe.Sizeof(uringCQE{}))); err == nil {
+			u.sqeMem, err = mmap(ioringOffSQEs, p.sqEntries*uint32(unsafe.Sizeof(uringSQE{})))
+		}
+	}
+	if err != nil {
+		u.close()
+		return nil, err
+	}
+	field := func(ring []byte, offset uint32) *uint32 {
+		return (*uint32)(unsafe.Pointer(&ring[offset]))
+	}
+	u.sqTail, u.sqMask = field(u.sqRing, p.sqOff.tail), field(u.sqRing, p.sqOff.ringMask)
+	u.cqHead, u.cqTail, u.cqMask = field(u.cqRing, p.cqOff.head), field(u.cqRing, p.cqOff.tail), field(u.cqRing, p.cqOff.ringMask)
+	u.sqArray = unsafe.Slice(field(u.sqRing, p.sqOff.array), p.sqEntries)
+	u.sqes = unsafe.Slice((*uringSQE)(unsafe.Pointer(&u.sqeMem[0])), p.sqEntries)
+	u.cqes = unsafe.Slice((*uringCQE)(unsafe.Pointer(&u.cqRing[p.cqOff.cqes])), p.cqEntries)
+	return u, nil
+}
+
+// read queues a read of len(buf) bytes of fd at offset into buf, which must
+// not be Go memory. It is submitted by the next wait.
+func (u *uring) read(fd uintptr, buf []byte, offset int64, userData uint64) {
+	tail := *u.sqTail
+	i := tail & *u.sqMask
+	u.sqes[i] = uringSQE{
+		opcode:   ioringOpRead,
+		fd:       int32(fd),
+		off:      uint64(offset),
+		addr:     uint64(uintptr(unsafe.Pointer(&buf[0]))),
+		len:      uint32(len(buf)),
+		userData: userData,
+	}
+	u.sqArray[i] = i
+	atomic.StoreUint32(u.sqTail, tail+1)
+	u.unsubmitted++
+}
+
+// wait submits the queued reads and returns the next completion.
+func (u *uring) wait() (uringCQE, error) {
+	for {
+		head := *u.cqHead
+		if u.unsubmitted == 0 && head != atomic.LoadUint32(u.cqTail) {
+			cqe := u.cqes[head&*u.cqMask]
+			atomic.StoreUint32(u.cqHead, head+1)
+			return cqe, nil
+		}
+		minComplete := uint32(1)
+		if head != atomic.LoadUint32(u.cqTail) {
+			minComplete = 0
+		}
+		submitted, _, errno := syscall.Syscall6(sysIOUringEnter, uintptr(u.fd), uintptr(u.unsubmitted), uintptr(minComplete), ioringEnterGetEvents, 0, 0)
+		if errno != 0 && errno != syscall.EINTR {
+			return uringCQE{}, os.NewSyscallError("io_uring_enter", errno)
+		}
+		u.unsubmitted -= uint32(submitted)
+	}
+}
+
+func (u *uring) close() {
+	for _, m := range [][]byte{u.sqeMem, u.cqRing, u.sqRing} {
+		if m != nil {
+			syscall.Munmap(m)
+		}
+	}
+	syscall.Close(u.fd)
+}
+
+const (
+	uringDepth = 8         // reads in flight per file
+	uringChunk = 256 << 10 // size of each read
+)
+
+// uringSlot is one buffer of a uringReader and the read it is used for.
+type uringSlot struct {
+	offset int64
+	length int // 0 if the slot is not used
+	done   bool
+	res    int32
+	direct bool // submitted before direct IO was turned off
+}
+
+// uringReader reads a file with several reads in flight through io_uring
+// and returns the data in order. Data past the size the file had when it
+// was opened is read with ordinary reads.
+type uringReader struct {
+	file     *os.File
+	ring     *uring
+	mem      []byte // the slot buffers, mapped outside of the Go heap
+	size     int64
+	next     int64 // offset of the next chunk to queue
+	slots    [uringDepth]uringSlot
+	current  int // slot whose data is returned next
+	inFlight int
+	refill   bool // the data of the current slot was returned
+	buffered bool // direct IO was turned off
+	data     []byte
+	err      error
+}
+
+func newUringReader(file *os.File, size int64) (*uringReader, error) {
+	ring, err := newUring(uringDepth)
+	if err != nil {
+		return nil, err
+	}
+	mem, err := syscall.Mmap(-1, 0, uringDepth*uringChunk, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		ring.close()
+		return nil, err
+	}
+	r := &uringReader{file: file, ring: ring, mem: mem, size: size}
+	for i := range r.slots {
+		r.queue(i)
+	}
+	return r, nil
+}
+
+func (r *uringReader) buffer(slot int) []byte {
+	return r.mem[slot*uringChunk : (slot+1)*uringChunk]
+}
+
+// queue queues the read of the next chunk into slot, or leaves the slot
+// unused once the chunks up to the size of the file are queued.
+func (r *uringReader) queue(slot int) {
+	s := &r.slots[slot]
+	s.offset, s.length = r.next, uringChunk
+	if remaining := r.size - r.next; remaining < uringChunk {
+		// Direct IO needs whole blocks; the read ends at the end of the file.
+		s.length = int((remaining + directIOAlign - 1) / directIOAlign * directIOAlign)
+	}
+	r.next += int64(s.length)
+	if r.next > r.size {
+		r.next = r.size
+	}
+	r.submit(slot)
+}
+
+func (r *uringReader) submit(slot int) {
+	s := &r.slots[slot]
+	s.done = false
+	s.direct = !r.buffered
+	if s.length > 0 {
+		r.ring.read(r.file.Fd(), r.buffer(slot)[:s.length], s.offset, uint64(slot))
+		r.inFlight++
+	}
+}
+
+func (r *uringReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		if r.err != nil {
+			r.close()
+			return 0, r.err
+		}
+		s := &r.slots[r.current]
+		if r.refill {
+			r.refill = false
+			if s.done {
+				r.queue(r.current)
+				r.current = (r.current + 1) % uringDepth
+			} else {
+				r.submit(r.current) // the rest of a short read
+			}
+			continue
+		}
+		if s.length == 0 {
+			// All chunks up to the size are read; read what was appended.
+			if !r.buffered {
+				clearDirect(r.file)
+				r.buffered = true
+			}
+			buf := r.buffer(r.current)
+			n, err := r.file.ReadAt(buf, r.next)
+			r.next += int64(n)
+			r.data, r.err = buf[:n], err
+			continue
+		}
+		for !s.done {
+			cqe, err := r.ring.wait()
+			if err != nil {
+				r.err = err
+				break
+			}
+			r.slots[cqe.userData].done = true
+			r.slots[cqe.userData].res = cqe.res
+			r.inFlight--
+		}
+		switch {
+		case r.err != nil:
+		case s.res == -int32(syscall.EINVAL) && s.direct:
+			// The file system rejected a direct read; read it buffered.
+			if !r.buffered {
+				clearDirect(r.file)
+				r.buffered = true
+			}
+			r.submit(r.current)
+		case s.res < 0:
+			r.err = &os.PathError{Op: "read", Path: r.file.Name(), Err: syscall.Errno(-s.res)}
+		case s.res == 0:
+			r.err = io.EOF // the file was truncated
+		default:
+			// A read rounded up to whole blocks can return data appended
+			// since the file was opened; it is read again after the chunks.
+			n := int64(s.res)
+			if s.offset+n > r.size {
+				n = r.size - s.offset
+			}
+			r.data = r.buffer(r.current)[:n]
+			r.refill = true
+			if s.offset+n < r.size && int(n) < s.length {
+				s.offset += n
+				s.length -= int(n)
+				s.done = false
+			}
+		}
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// close waits for the reads in flight, which still write to the buffers,
+// and releases the ring and the buffers.
+func (r *uringReader) close() {
+	if r.ring == nil {
+		return
+	}
+	for r.inFlight > 0 {
+		if _, err := r.ring.wait(); err != nil {
+			return // leak the buffers rather than have them written to after unmapping
+		}
+		r.inFlight--
+	}
+	r.ring.close()
+	syscall.Munmap(r.mem)
+	r.ring, r.mem = nil, nil
+}
+
+// probeUring reports whether io_uring can be used.
+func probeUring() error {
+	ring, err := newUring(1)
+	if err == nil {
+		ring.close()
+	}
+	return err
+}
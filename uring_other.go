@@ -0,0 +1,19 @@
+//go:build !(linux && (amd64 || arm64 || riscv64 || ppc64le || s390x || loong64))
+
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+var errNoUring = errors.New("io_uring is only supported on Linux on 64-bit systems")
+
+func newUringReader(file *os.File, size int64) (io.Reader, error) {
+	return nil, errNoUring
+}
+
+func probeUring() error {
+	return errNoUring
+}
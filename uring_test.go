@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUringReader(t *testing.T) {
+	if err := probeUring(); err != nil {
+		t.Skip(err)
+	}
+	dir := createTempDirForTest(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "large.bin")
+	data := bytes.Repeat([]byte("0123456789abcdef"), uringMinSize+1234)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		size int64 // size the reader is told the file has
+	}{
+		{"whole file", int64(len(data))},
+		{"appended data", int64(len(data)) / 3},
+		{"truncated file", int64(len(data)) * 2},
+	}
+	open := map[string]func(string) (*os.File, error){"buffered": os.Open}
+	if file, err := openDirect(path); err == nil {
+		file.Close()
+		open["direct"] = openDirect
+	}
+	for name, openFile := range open {
+		for _, test := range tests {
+			file, err := openFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := newUringReader(file, test.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			read, err := ioutil.ReadAll(r)
+			file.Close()
+			if err != nil {
+				t.Fatalf("%s, %s: %v", name, test.name, err)
+			}
+			if !bytes.Equal(read, data) {
+				t.Errorf("Expected %d bytes, Got: %d (%s, %s)", len(data), len(read), name, test.name)
+			}
+		}
+	}
+}
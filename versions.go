@@ -0,0 +1,247 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// versionSimilarity is the smallest share of content-defined chunks that two
+// versions of a document have in common.
+const versionSimilarity = 0.5
+
+// versionReadLimit is how much of a file, or of the decompressed entries of
+// a zip-based document, is compared.
+const versionReadLimit = 64 << 20
+
+// zipDocuments are the extensions of documents stored as zip archives, whose
+// entries are compared decompressed, since a small edit changes most of the
+// compressed data.
+var zipDocuments = map[string]bool{
+	".docx": true, ".xlsx": true, ".pptx": true, ".odt": true, ".ods": true,
+	".odp": true, ".epub": true, ".pages": true, ".numbers": true, ".key": true,
+}
+
+// versionFamily is a set of files that are named like versions of a document
+// and have similar content. The newest file comes first, to be kept.
+type versionFamily struct {
+	Name       string  // the words of the name that the versions share
+	Similarity float64 // the lowest similarity of two versions joined
+	Files      []File
+}
+
+// versionFamilies finds the files of fileMap that are versions of one
+// document: files in the same folder with the same extension whose names
+// only differ in version words such as "v2" or "final" and copy suffixes,
+// and whose content is similar. Each group of duplicates takes part with its
+// first file.
+func versionFamilies(fileMap map[string][]File) []versionFamily {
+	type nameKeyOf struct{ dir, ext, words, numbers string }
+	byName := make(map[nameKeyOf][]File)
+	for _, files := range fileMap {
+		file := files[0]
+		words, numbers := nameKey(file.Path())
+		if words == "" {
+			continue
+		}
+		key := nameKeyOf{filepath.Dir(file.Path()), strings.ToLower(filepath.Ext(file.Path())), words, numbers}
+		byName[key] = append(byName[key], file)
+	}
+
+	var families []versionFamily
+	for key, files := range byName {
+		if len(files) < 2 {
+			continue
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Path() < files[j].Path() })
+		chunks := make([]map[uint64]bool, len(files))
+		for i, file := range files {
+			chunks[i], _ = contentChunks(file.Path())
+		}
+		parent := make([]int, len(files))
+		lowest := make(map[int]float64)
+		for i := range parent {
+			parent[i] = i
+		}
+		var find func(int) int
+		find = func(i int) int {
+			if parent[i] != i {
+				parent[i] = find(parent[i])
+			}
+			return parent[i]
+		}
+		for i := range files {
+			for j := i + 1; j < len(files); j++ {
+				s := similarity(chunks[i], chunks[j])
+				if s < versionSimilarity {
+					continue
+				}
+				ri, rj := find(i), find(j)
+				if ri == rj {
+					continue
+				}
+				parent[rj] = ri
+				low := s
+				for _, r := range []int{ri, rj} {
+					if l, ok := lowest[r]; ok && l < low {
+						low = l
+					}
+				}
+				delete(lowest, rj)
+				lowest[ri] = low
+			}
+		}
+		members := make(map[int][]File)
+		for i, file := range files {
+			root := find(i)
+			members[root] = append(members[root], file)
+		}
+		for root, versions := range members {
+			if len(versions) < 2 {
+				continue
+			}
+			sort.SliceStable(versions, func(i, j int) bool { return versions[i].ModTime.After(versions[j].ModTime) })
+			families = append(families, versionFamily{Name: key.words, Similarity: lowest[root], Files: versions})
+		}
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Files[0].Path() < families[j].Files[0].Path() })
+	return families
+}
+
+// similarity returns the share of the chunks of a and b they have in common.
+func similarity(a, b map[uint64]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for chunk := range a {
+		if b[chunk] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// contentChunks returns the hashes of the content-defined chunks of the file
+// at path, or of the decompressed entries of a zip-based document. Chunk
+// boundaries depend on the content only, so an insertion changes the chunks
+// around it but not the others.
+func contentChunks(path string) (map[uint64]bool, error) {
+	chunker := newChunker()
+	if zipDocuments[strings.ToLower(filepath.Ext(path))] {
+		r, err := zip.OpenReader(path)
+		if err == nil {
+			defer r.Close()
+			entries := append([]*zip.File(nil), r.File...)
+			sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+			remaining := int64(versionReadLimit)
+			for _, entry := range entries {
+				rc, err := entry.Open()
+				if err != nil {
+					return nil, err
+				}
+				n, err := io.Copy(chunker, io.LimitReader(rc, remaining))
+				rc.Close()
+				if err != nil {
+					return nil, err
+				}
+				if remaining -= n; remaining <= 0 {
+					break
+				}
+				chunker.cut() // entries are chunked separately
+			}
+			return chunker.done(), nil
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(chunker, io.LimitReader(bufio.NewReader(f), versionReadLimit)); err != nil {
+		return nil, err
+	}
+	return chunker.done(), nil
+}
+
+// chunkGear are the random values of the gear hash that finds chunk
+// boundaries.
+var chunkGear = func() (gear [256]uint64) {
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range gear {
+		// splitmix64
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+	return gear
+}()
+
+const (
+	chunkMin = 64
+	// chunkMask selects the top bits of the gear hash, which depend on the
+	// last 64 bytes, for chunks of about 512 bytes.
+	chunkMask = (1<<9 - 1) << 55
+)
+
+// chunker splits what is written to it into content-defined chunks.
+type chunker struct {
+	gear   uint64
+	chunk  []byte
+	chunks map[uint64]bool
+}
+
+func newChunker() *chunker {
+	return &chunker{chunks: make(map[uint64]bool)}
+}
+
+func (c *chunker) Write(p []byte) (int, error) {
+	for _, b := range p {
+		c.chunk = append(c.chunk, b)
+		c.gear = c.gear<<1 + chunkGear[b]
+		if len(c.chunk) >= chunkMin && c.gear&chunkMask == 0 {
+			c.cut()
+		}
+	}
+	return len(p), nil
+}
+
+// cut ends the current chunk.
+func (c *chunker) cut() {
+	if len(c.chunk) > 0 {
+		h := fnv.New64a()
+		h.Write(c.chunk)
+		c.chunks[h.Sum64()] = true
+	}
+	c.chunk, c.gear = c.chunk[:0], 0
+}
+
+// done returns the hashes of all chunks.
+func (c *chunker) done() map[uint64]bool {
+	c.cut()
+	return c.chunks
+}
+
+// confirmVersionFamilies shows every family as likely versions, of which
+// only the newest is kept, and adds the ones the user confirms to fileMap.
+func confirmVersionFamilies(w io.Writer, scanner *bufio.Scanner, fileMap map[string][]File, families []versionFamily) int {
+	if len(families) == 0 {
+		return 0
+	}
+	fmt.Fprintln(w, msg("versions.found", formatCount(int64(len(families)))))
+	titles := make([]string, len(families))
+	groups := make([][]File, len(families))
+	for i, family := range families {
+		titles[i] = msg("versions.family", formatCount(int64(len(family.Files))), fmt.Sprintf("%q", family.Name), fmt.Sprintf("%.0f", family.Similarity*100))
+		groups[i] = family.Files
+	}
+	return confirmLikelyDuplicates(w, scanner, fileMap, "version", titles, groups)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testText returns lines of words that differ from seed to seed.
+func testText(seed, lines int) string {
+	words := []string{"the", "quick", "brown", "fox", "jumps", "over", "lazy", "dog", "report", "budget", "meeting", "plan"}
+	var b strings.Builder
+	x := uint32(seed)
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&b, "Paragraph %d:", i)
+		for j := 0; j < 8; j++ {
+			x = x*1664525 + 1013904223
+			b.WriteString(" " + words[(x>>28)%uint32(len(words))])
+		}
+		b.WriteString(".\n")
+	}
+	return b.String()
+}
+
+// testDocx returns a zip archive with a document.xml entry holding text.
+func testDocx(t *testing.T, text string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for name, content := range map[string]string{"[Content_Types].xml": "<Types/>", "word/document.xml": "<w:document>" + text + "</w:document>"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestSimilarity(t *testing.T) {
+	chunks := func(text string) map[uint64]bool {
+		c := newChunker()
+		c.Write([]byte(text))
+		return c.done()
+	}
+	original := testText(1, 200)
+	edited := strings.Replace(original, "Paragraph 100 ", "Paragraph one hundred ", 1)
+	if s := similarity(chunks(original), chunks(edited)); s < 0.9 {
+		t.Errorf("Expected an edited text to be similar, Got: %.2f", s)
+	}
+	if s := similarity(chunks(original), chunks("x"+original)); s < 0.9 {
+		t.Errorf("Expected an insertion to keep the text similar, Got: %.2f", s)
+	}
+	if s := similarity(chunks(original), chunks(strings.ToUpper(original))); s > 0.1 {
+		t.Errorf("Expected different texts to be dissimilar, Got: %.2f", s)
+	}
+}
+
+func TestVersionFamilies(t *testing.T) {
+	tempDir := createTempDirForTest(t)
+	defer os.RemoveAll(tempDir)
+	text := testText(1, 300)
+	now := time.Now()
+	files := []struct {
+		name    string
+		content []byte
+		age     time.Duration
+	}{
+		{"report_v1.docx", testDocx(t, text), 3 * time.Hour},
+		{"report_v2.docx", testDocx(t, strings.Replace(text, "Paragraph 7 ", "Paragraph seven ", 1)), 2 * time.Hour},
+		{"report_final_FINAL.docx", testDocx(t, text+"Signed.\n"), time.Hour},
+		{"report draft.docx", testDocx(t, testText(2, 300)), 0},
+		{"notes_v1.txt", []byte(text), time.Hour},
+		{"notes_v2.txt", []byte(strings.ToUpper(text)), 0},
+		{"plan.txt", []byte(text), 0},
+	}
+	fileMap := make(map[string][]File)
+	for _, f := range files {
+		path := filepath.Join(tempDir, f.name)
+		ioutil.WriteFile(path, f.content, 0644)
+		fileMap[f.name] = []File{{path: indexPath(path), Size: int64(len(f.content)), ModTime: now.Add(-f.age)}}
+	}
+
+	families := versionFamilies(fileMap)
+	if len(families) != 1 || len(families[0].Files) != 3 {
+		t.Fatalf("Unexpected families: %+v", families)
+	}
+	family := families[0]
+	if filepath.Base(family.Files[0].Path()) != "report_final_FINAL.docx" || filepath.Base(family.Files[2].Path()) != "report_v1.docx" {
+		t.Errorf("Expected the newest version first, Got: %v", family.Files)
+	}
+	if family.Name != "report" || family.Similarity < versionSimilarity {
+		t.Errorf("Unexpected family: %s, %.2f", family.Name, family.Similarity)
+	}
+
+	var out bytes.Buffer
+	if n := confirmVersionFamilies(&out, bufio.NewScanner(strings.NewReader("yes\n")), fileMap, families); n != 1 {
+		t.Errorf("Expected: 1 confirmed family, Got: %d", n)
+	}
+	if group := fileMap[pathsKey("version", family.Files)]; len(group) != 3 {
+		t.Errorf("Expected the confirmed family to be added, Got: %v", group)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"time"
+)
+
+// xmlNamespace identifies version 1 of the XML results format described by
+// results.xsd.
+const xmlNamespace = "https://github.com/halra/duplicate_finder/results/1"
+
+type xmlResults struct {
+	XMLName xml.Name   `xml:"results"`
+	Xmlns   string     `xml:"xmlns,attr"`
+	Version int        `xml:"version,attr"`
+	Summary xmlSummary `xml:"summary"`
+	Groups  []xmlGroup `xml:"group"`
+	Errors  []xmlError `xml:"error"`
+}
+
+type xmlSummary struct {
+	Root             string `xml:"root,attr"`
+	ScannedAt        string `xml:"scanned-at,attr"`
+	FilesScanned     int    `xml:"files-scanned,attr"`
+	TotalSize        int64  `xml:"total-size,attr"`
+	DuplicateGroups  int    `xml:"duplicate-groups,attr"`
+	DuplicateFiles   int    `xml:"duplicate-files,attr"`
+	ReclaimableBytes int64  `xml:"reclaimable-bytes,attr"`
+	Errors           int    `xml:"errors,attr"`
+}
+
+type xmlGroup struct {
+	ID    string    `xml:"id,attr"`
+	Hash  string    `xml:"hash,attr"`
+	Size  int64     `xml:"size,attr"`
+	Waste int64     `xml:"waste,attr"`
+	Files []xmlFile `xml:"file"`
+}
+
+type xmlFile struct {
+	Path string `xml:"path,attr"`
+	Kept bool   `xml:"kept,attr,omitempty"`
+}
+
+type xmlError struct {
+	Path    string `xml:"path,attr"`
+	Message string `xml:",chardata"`
+}
+
+// writeXMLResults writes the summary, duplicate groups and read errors of a
+// scan as XML.
+func writeXMLResults(w io.Writer, data scanExport) error {
+	summary := summarize(data.Root, data.Progress.Scanned, data.FileMap, data.Progress.Errors)
+	doc := xmlResults{
+		Xmlns:   xmlNamespace,
+		Version: resultsVersion,
+		Summary: xmlSummary{
+			Root:             data.Root,
+			ScannedAt:        data.ScannedAt.UTC().Format(time.RFC3339),
+			FilesScanned:     data.Progress.Scanned,
+			TotalSize:        data.Progress.TotalSize,
+			DuplicateGroups:  summary.DuplicateGroups,
+			DuplicateFiles:   summary.DuplicateFiles,
+			ReclaimableBytes: summary.ReclaimableBytes,
+			Errors:           data.Progress.Errors,
+		},
+	}
+	for _, group := range duplicateGroups(data.FileMap) {
+		g := xmlGroup{ID: group.ID, Hash: group.Hash, Size: group.Files[0].Size, Waste: group.Waste()}
+		for i, file := range group.Files {
+			g.Files = append(g.Files, xmlFile{Path: file.Path(), Kept: i == 0})
+		}
+		doc.Groups = append(doc.Groups, g)
+	}
+	for _, failure := range data.Progress.Failures {
+		doc.Errors = append(doc.Errors, xmlError{Path: failure.Path, Message: failure.Err.Error()})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// exportXML writes the XML results to path.
+func exportXML(path string, data scanExport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeXMLResults(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
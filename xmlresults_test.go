@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWriteXMLResults(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeXMLResults(&out, testScanExport()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), xml.Header+`<results xmlns="`+xmlNamespace+`" version="1">`) {
+		t.Errorf("Unexpected document start: %s", out.String())
+	}
+
+	var doc xmlResults
+	if err := xml.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Summary.ScannedAt != "2024-03-01T12:00:00Z" || doc.Summary.DuplicateGroups != 1 || doc.Summary.ReclaimableBytes != 10 {
+		t.Errorf("Unexpected summary: %+v", doc.Summary)
+	}
+	if len(doc.Groups) != 1 || len(doc.Groups[0].Files) != 2 || !doc.Groups[0].Files[0].Kept || doc.Groups[0].Files[1].Kept {
+		t.Errorf("Unexpected groups: %+v", doc.Groups)
+	}
+	if doc.Groups[0].Files[0].Path != "/data/it's.txt" {
+		t.Errorf("Expected: %s, Got: %s", "/data/it's.txt", doc.Groups[0].Files[0].Path)
+	}
+	if len(doc.Errors) != 1 || doc.Errors[0].Message != "permission denied" {
+		t.Errorf("Unexpected errors: %+v", doc.Errors)
+	}
+}
+
+func TestResultsSchema(t *testing.T) {
+	data, err := ioutil.ReadFile("results.xsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		TargetNamespace string `xml:"targetNamespace,attr"`
+		Elements        []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"element"`
+		Types []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"complexType"`
+	}
+	if err := xml.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.TargetNamespace != xmlNamespace {
+		t.Errorf("Expected: %s, Got: %s", xmlNamespace, schema.TargetNamespace)
+	}
+	if len(schema.Elements) != 1 || schema.Elements[0].Name != "results" || len(schema.Types) != 4 {
+		t.Errorf("Unexpected schema: %+v", schema)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// xxh64 is the non-cryptographic XXH64 hash with seed 0. It is several
+// times faster than MD5 but not collision resistant, so duplicates found
+// with it are confirmed by confirmFileMap.
+type xxh64 struct {
+	v     [4]uint64
+	total uint64
+	mem   [32]byte
+	n     int // bytes in mem
+}
+
+const (
+	xxhPrime1 uint64 = 11400714785074694791
+	xxhPrime2 uint64 = 14029467366897019727
+	xxhPrime3 uint64 = 1609587929392839161
+	xxhPrime4 uint64 = 9650029242287828579
+	xxhPrime5 uint64 = 2870177450012600261
+)
+
+func newXXH64() hash.Hash {
+	x := &xxh64{}
+	x.Reset()
+	return x
+}
+
+func (x *xxh64) Reset() {
+	p1, p2 := xxhPrime1, xxhPrime2 // variables, so the sums wrap around
+	x.v = [4]uint64{p1 + p2, p2, 0, -p1}
+	x.total, x.n = 0, 0
+}
+
+func (x *xxh64) Size() int      { return 8 }
+func (x *xxh64) BlockSize() int { return 32 }
+
+func xxhRound(acc, input uint64) uint64 {
+	return bits.RotateLeft64(acc+input*xxhPrime2, 31) * xxhPrime1
+}
+
+func xxhMerge(acc, v uint64) uint64 {
+	return (acc^xxhRound(0, v))*xxhPrime1 + xxhPrime4
+}
+
+func (x *xxh64) stripe(b []byte) {
+	x.v[0] = xxhRound(x.v[0], binary.LittleEndian.Uint64(b))
+	x.v[1] = xxhRound(x.v[1], binary.LittleEndian.Uint64(b[8:]))
+	x.v[2] = xxhRound(x.v[2], binary.LittleEndian.Uint64(b[16:]))
+	x.v[3] = xxhRound(x.v[3], binary.LittleEndian.Uint64(b[24:]))
+}
+
+func (x *xxh64) Write(b []byte) (int, error) {
+	written := len(b)
+	x.total += uint64(written)
+	if x.n > 0 {
+		c := copy(x.mem[x.n:], b)
+		x.n += c
+		b = b[c:]
+		if x.n < 32 {
+			return written, nil
+		}
+		x.stripe(x.mem[:])
+		x.n = 0
+	}
+	for ; len(b) >= 32; b = b[32:] {
+		x.stripe(b)
+	}
+	x.n = copy(x.mem[:], b)
+	return written, nil
+}
+
+func (x *xxh64) Sum(b []byte) []byte {
+	var h uint64
+	if x.total >= 32 {
+		h = bits.RotateLeft64(x.v[0], 1) + bits.RotateLeft64(x.v[1], 7) + bits.RotateLeft64(x.v[2], 12) + bits.RotateLeft64(x.v[3], 18)
+		for _, v := range x.v {
+			h = xxhMerge(h, v)
+		}
+	} else {
+		h = x.v[2] + xxhPrime5
+	}
+	h += x.total
+
+	mem := x.mem[:x.n]
+	for ; len(mem) >= 8; mem = mem[8:] {
+		h ^= xxhRound(0, binary.LittleEndian.Uint64(mem))
+		h = bits.RotateLeft64(h, 27)*xxhPrime1 + xxhPrime4
+	}
+	if len(mem) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(mem)) * xxhPrime1
+		h = bits.RotateLeft64(h, 23)*xxhPrime2 + xxhPrime3
+		mem = mem[4:]
+	}
+	for _, c := range mem {
+		h ^= uint64(c) * xxhPrime5
+		h = bits.RotateLeft64(h, 11) * xxhPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxhPrime2
+	h ^= h >> 29
+	h *= xxhPrime3
+	h ^= h >> 32
+	var sum [8]byte
+	binary.BigEndian.PutUint64(sum[:], h)
+	return append(b, sum[:]...)
+}
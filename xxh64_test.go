@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestXXH64(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "ef46db3751d8e999"},
+		{"a", "d24ec4f1a98c6e5b"},
+		{"abc", "44bc2cf5ad770999"},
+		{"Nobody inspects the spammish repetition", "fbcea83c8a378bf1"},
+	}
+	for _, test := range tests {
+		h := newXXH64()
+		h.Write([]byte(test.input))
+		if result := hex.EncodeToString(h.Sum(nil)); result != test.expected {
+			t.Errorf("Expected: %s, Got: %s (%q)", test.expected, result, test.input)
+		}
+	}
+
+	// Writes of any size give the same hash.
+	data := []byte(strings.Repeat("0123456789", 100))
+	whole := newXXH64()
+	whole.Write(data)
+	pieces := newXXH64()
+	for i := 0; i < len(data); i += 7 {
+		end := i + 7
+		if end > len(data) {
+			end = len(data)
+		}
+		pieces.Write(data[i:end])
+	}
+	if a, b := hex.EncodeToString(whole.Sum(nil)), hex.EncodeToString(pieces.Sum(nil)); a != b {
+		t.Errorf("Expected: %s, Got: %s", a, b)
+	}
+}